@@ -2,19 +2,30 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	flag "github.com/spf13/pflag"
 
 	"visualization-api/pkg/config"
 	"visualization-api/pkg/database"
 	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaproxy"
 	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/authentication"
 	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jobs"
+	"visualization-api/pkg/jwtkeys"
 	"visualization-api/pkg/logging"
+	"visualization-api/pkg/notifications"
 	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/outbox"
+	"visualization-api/pkg/policy"
+	"visualization-api/pkg/projectsync"
+	"visualization-api/pkg/reconciler"
 )
 
 var (
@@ -55,14 +66,30 @@ func main() {
 	/*
 		APP INITIALIZATION STEPS
 		1 - initialize config module. It would read config file, env variables
-			and flags and merge them into one structure
+			and flags and merge them into one structure. If migrate subcommand
+			is provided, it is performed instead of the following steps
 		2 - initialize io.Writer that rotates files it is writing to
 		3 - initialize logging module with rotation writer, created in step 2
-		4 - initialize database connection
+		4 - initialize database connection and check that all schema
+			migrations are applied
 		5 - initialize grafana client
-		6 - intiialize openstack client
-		7 - initialize signals handler, to close file in rotation logger
-		8 - initialize http server
+		6 - intiialize openstack client, results of token validation are cached
+		7 - initialize outbox worker performing journaled grafana operations
+			and start it in background
+		8 - initialize reconciler of db and grafana dashboards and start it
+			in background
+		9 - initialize synchronization of openstack projects and grafana
+			organizations and start it in background
+		10 - initialize listener of keystone notifications, if it is enabled,
+			and start it in background
+		11 - initialize worker pool performing asynchronous jobs
+		12 - load access control policy
+		13 - load keys of jwt signature and start removal of revoked and
+			keystone tokens of expired jwt tokens in background
+		14 - initialize proxy forwarding requests of users to grafana, if it
+			is enabled
+		15 - initialize signals handler, to close file in rotation logger
+		16 - initialize http server
 	*/
 
 	flag.Parse()
@@ -79,6 +106,17 @@ func main() {
 	}
 	CONF := config.GetConfig()
 
+	if flag.NArg() > 0 {
+		if flag.Arg(0) != migrateCommand {
+			exitWithError(fmt.Errorf("unknown command '%s'", flag.Arg(0)))
+		}
+		errorMigrating := runMigrate(CONF, flag.Args()[1:])
+		if errorMigrating != nil {
+			exitWithError(errorMigrating)
+		}
+		os.Exit(0)
+	}
+
 	// create rotation logger
 	var rotateInitError error
 	logRotate, rotateInitError = log.NewRotateWriter(CONF.LogFilePath)
@@ -89,16 +127,24 @@ func main() {
 	// initialize logger
 	log.InitializeLogger(logRotate, CONF.ConsoleDebug, CONF.LogLevel)
 
-	// initialize database connection
-	databaseInitializationError := db.InitializeEngine(
-		CONF.MysqlUsername,
-		CONF.MysqlPassword,
-		CONF.MysqlHost,
-		CONF.MysqlDatabaseName,
-		CONF.MysqlPort,
-	)
-	if databaseInitializationError != nil {
-		exitWithError(databaseInitializationError)
+	// initialize database connection, memory driver does not require it
+	var databaseManager db.DatabaseManager
+	if CONF.DatabaseDriver == db.DriverMemory {
+		log.Logger.Warning("Memory database driver is used, all data " +
+			"would be lost on exit")
+		databaseManager = db.NewMemoryManager()
+	} else {
+		settings := databaseSettings(CONF)
+		databaseInitializationError := db.InitializeEngine(settings)
+		if databaseInitializationError != nil {
+			exitWithError(databaseInitializationError)
+		}
+		// api is not served with outdated schema
+		schemaVersionError := checkSchemaVersion(settings)
+		if schemaVersionError != nil {
+			exitWithError(schemaVersionError)
+		}
+		databaseManager = db.NewXORMManager(CONF.KeystoneTokenKey)
 	}
 
 	// initialize grafana session
@@ -121,13 +167,103 @@ func main() {
 	if errorInitializingOpenstackCli != nil {
 		exitWithError(errorInitializingOpenstackCli, "openstack initialization")
 	}
+	var openstackClient openstack.ClientInterface = openstackCli
+	if CONF.OpenstackTokenCacheTTL > 0 {
+		openstackClient = openstack.NewCachingClient(openstackCli,
+			time.Duration(CONF.OpenstackTokenCacheTTL)*time.Second,
+			time.Duration(CONF.OpenstackTokenCacheNegativeTTL)*time.Second,
+			CONF.OpenstackTokenCacheSize)
+	}
+
+	outbox.NewWorker(databaseManager, grafanaSession,
+		CONF.OutboxMaxAttempts).Start(
+		time.Duration(CONF.OutboxInterval) * time.Second)
+
+	dashboardReconciler := reconciler.NewReconciler(databaseManager,
+		grafanaSession, CONF.ReconcilerDeleteOrphans, CONF.OutboxMaxAttempts)
+	if CONF.ReconcilerInterval > 0 {
+		dashboardReconciler.Start(
+			time.Duration(CONF.ReconcilerInterval) * time.Second)
+	}
+
+	projectSyncer := projectsync.NewSyncer(openstackClient, grafanaSession,
+		databaseManager, CONF.ProjectSyncDeleteOrganizations)
+	if CONF.ProjectSyncInterval > 0 {
+		projectSyncer.Start(
+			time.Duration(CONF.ProjectSyncInterval) * time.Second)
+	}
+
+	if CONF.NotificationsDriver != "" {
+		var notificationsSource notifications.Source
+		if CONF.NotificationsDriver == notifications.DriverFile {
+			fileSource, errorOpeningNotifications := notifications.OpenFileSource(
+				CONF.NotificationsFile)
+			if errorOpeningNotifications != nil {
+				exitWithError(errorOpeningNotifications,
+					"notifications initialization")
+			}
+			notificationsSource = fileSource
+		} else {
+			notificationsSource = notifications.NewAMQPSource(
+				notifications.AMQPSettings{
+					URL:        CONF.NotificationsURL,
+					Exchange:   CONF.NotificationsExchange,
+					RoutingKey: CONF.NotificationsRoutingKey,
+					Queue:      CONF.NotificationsQueue,
+				})
+		}
+		errorStartingNotifications := notifications.NewListener(
+			notificationsSource, grafanaSession, databaseManager).Start()
+		if errorStartingNotifications != nil {
+			exitWithError(errorStartingNotifications,
+				"notifications initialization")
+		}
+	}
+
+	jobsManager := jobs.NewManager(CONF.JobsQueueSize)
+	jobsManager.Start(CONF.JobsWorkers)
+
+	accessPolicy, errorLoadingPolicy := policy.Load(CONF.PolicyFile)
+	if errorLoadingPolicy != nil {
+		exitWithError(errorLoadingPolicy, "policy initialization")
+	}
+
+	jwtKeys, errorLoadingKeys := jwtkeys.LoadKeySet(CONF.JWTSecret,
+		CONF.JWTKeysDirectory, CONF.JWTSigningKey)
+	if errorLoadingKeys != nil {
+		exitWithError(errorLoadingKeys, "jwt keys initialization")
+	}
+	httpAuth.NewTokenPurger(databaseManager).Start(
+		time.Duration(CONF.TokenPurgeInterval) * time.Second)
+
+	var grafanaProxy http.Handler
+	if CONF.GrafanaProxy {
+		proxy, errorInitializingProxy := grafanaproxy.NewProxy(
+			CONF.GrafanaURL)
+		if errorInitializingProxy != nil {
+			exitWithError(errorInitializingProxy, "grafana proxy initialization")
+		}
+		grafanaProxy = proxy
+	}
 
 	cleanupOnExit()
 
 	errorInitializingAPI := endpoint.Serve(
-		CONF.JWTSecret,
+		jwtKeys,
 		CONF.HTTPPort,
-		&common.ClientContainer{openstackCli, grafanaSession, db.NewXORMManager()},
+		&common.ClientContainer{openstackClient, grafanaSession, databaseManager,
+			dashboardReconciler, jobsManager, accessPolicy, common.TokenSettings{
+				Lifetime: time.Duration(CONF.JWTLifetime) * time.Second,
+				KeystoneRevalidationInterval: time.Duration(
+					CONF.KeystoneRevalidationInterval) * time.Second,
+			}, common.GrafanaUserSettings{
+				Provision:   CONF.GrafanaProvisionUsers,
+				RoleMapping: CONF.GrafanaRoleMapping,
+				DefaultRole: CONF.GrafanaDefaultRole,
+			}, grafanaProxy, projectSyncer, common.DatasourceSettings{
+				AllowedHosts:    CONF.GrafanaDatasourceHosts,
+				AllowedNetworks: CONF.GrafanaDatasourceNetworks,
+			}},
 	)
 	if errorInitializingAPI != nil {
 		exitWithError(errorInitializingAPI)
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"visualization-api/pkg/config"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/migrations"
+)
+
+// migrateCommand is a subcommand managing database schema
+const migrateCommand = "migrate"
+
+const migrateUsage = "usage: visualizationapi migrate up|down|status"
+
+// databaseSettings returns connection settings of configured database
+func databaseSettings(conf *config.VisualizationAPIConfig) db.ConnectionSettings {
+	return db.ConnectionSettings{
+		Driver:       conf.DatabaseDriver,
+		Username:     conf.MysqlUsername,
+		Password:     conf.MysqlPassword,
+		Host:         conf.MysqlHost,
+		Port:         conf.MysqlPort,
+		DatabaseName: conf.MysqlDatabaseName,
+		Path:         conf.DatabasePath,
+	}
+}
+
+func printMigrationsStatus(statuses []migrations.Status) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "MIGRATION\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.String()
+		}
+		fmt.Fprintf(writer, "%s\t%s\n", status.ID, appliedAt)
+	}
+	return writer.Flush()
+}
+
+// runMigrate performs migrate subcommand with provided arguments
+func runMigrate(conf *config.VisualizationAPIConfig, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	if conf.DatabaseDriver == db.DriverMemory {
+		return errors.New("memory database driver does not use migrations")
+	}
+
+	settings := databaseSettings(conf)
+	connection, err := db.OpenDB(settings)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(connection, conf.DatabaseDriver)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+		if applied > 0 {
+			return rebuildVisualizationTags(settings)
+		}
+	case "down":
+		rolledBack, err := migrations.Down(connection, conf.DatabaseDriver)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migrations\n", rolledBack)
+	case "status":
+		statuses, err := migrations.GetStatus(connection, conf.DatabaseDriver)
+		if err != nil {
+			return err
+		}
+		return printMigrationsStatus(statuses)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
+
+// rebuildVisualizationTags fills tag table of visualizations, which could be
+// created by applied migrations
+func rebuildVisualizationTags(settings db.ConnectionSettings) error {
+	err := db.InitializeEngine(settings)
+	if err != nil {
+		return err
+	}
+	// keystone tokens are not touched by migration, so key of them is not
+	// required
+	rebuilt, err := db.NewXORMManager("").RebuildVisualizationTags()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stored tags of %d visualizations\n", rebuilt)
+	return nil
+}
+
+// checkSchemaVersion returns error if there are migrations, which are not
+// applied to database yet
+func checkSchemaVersion(settings db.ConnectionSettings) error {
+	connection, err := db.OpenDB(settings)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	pending, err := migrations.Pending(connection, settings.Driver)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind, pending migrations: %s. "+
+			"Run 'visualizationapi migrate up' to apply them",
+			strings.Join(pending, ", "))
+	}
+	return nil
+}
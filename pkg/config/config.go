@@ -3,7 +3,9 @@ package config
 import (
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"net"
 	"strings"
+	"visualization-api/pkg/grafanaclient"
 )
 
 // constants
@@ -32,6 +34,20 @@ const configDirPath = "/etc/platformvisibility/visualization-api"
 const logLevelConfigName = "log.level"
 const logFileConfigName = "log.path"
 
+const databaseDriverConfigName = "database.driver"
+const databasePathConfigName = "database.path"
+
+// defaultDatabaseDriver is driver used, when database.driver is not provided
+const defaultDatabaseDriver = "mysql"
+
+// sqliteDatabaseDriver is file based database, which does not use
+// mysql connection settings
+const sqliteDatabaseDriver = "sqlite3"
+
+// memoryDatabaseDriver keeps all data in process memory, it does not use
+// any connection settings
+const memoryDatabaseDriver = "memory"
+
 const mysqlPortConfigName = "mysql.port"
 const mysqlPasswordConfigName = "mysql.password"
 const mysqlHostConfigName = "mysql.host"
@@ -41,17 +57,107 @@ const mysqlDatabaseConfigName = "mysql.database"
 const grafanaURLConfigName = "grafana.url"
 const grafanaUserConfigName = "grafana.username"
 const grafanaPasswordConfigName = "grafana.password"
+const grafanaProvisionUsersConfigName = "grafana.provision_users"
+const grafanaRoleMappingConfigName = "grafana.role_mapping"
+const grafanaDefaultRoleConfigName = "grafana.default_role"
+const grafanaProxyConfigName = "grafana.proxy"
+const grafanaDatasourceAllowlistConfigName = "grafana.datasource_allowlist"
+
+// defaultGrafanaRoleMapping maps keystone roles to grafana organization roles
+const defaultGrafanaRoleMapping = "admin:Admin,_member_:Editor"
 
 const httpPortConfigName = "http_endpoint.port"
 
 // #nosec <- linter thinks that secret is hardcoded, in fact it is setting name
 const httpSecretConfigName = "http_endpoint.jwt_secret"
+const httpKeysDirectoryConfigName = "http_endpoint.jwt_keys_dir"
+const httpSigningKeyConfigName = "http_endpoint.jwt_signing_key"
+const httpJWTLifetimeConfigName = "http_endpoint.jwt_lifetime"
+const httpRevalidationIntervalConfigName = "http_endpoint.keystone_revalidation_interval"
+
+// #nosec <- linter thinks that secret is hardcoded, in fact it is setting name
+const httpKeystoneTokenKeyConfigName = "http_endpoint.keystone_token_key"
+const httpTokenPurgeIntervalConfigName = "http_endpoint.token_purge_interval"
+
+// defaultJWTLifetime is maximum lifetime in seconds of issued JWT
+const defaultJWTLifetime = 10800
+
+// defaultTokenPurgeInterval is interval in seconds between removals of
+// revoked and keystone tokens of expired JWT
+const defaultTokenPurgeInterval = 600
 
 const openstackAuthURLConfigName = "openstack.auth_url"
 const openstackUsernameConfigName = "openstack.username"
 const openstackPasswordConfigName = "openstack.password"
 const openstackProjectConfigName = "openstack.project_name"
 const openstackDomainConfigName = "openstack.domain_name"
+const openstackCacheTTLConfigName = "openstack.token_cache_ttl"
+const openstackCacheNegativeTTLConfigName = "openstack.token_cache_negative_ttl"
+const openstackCacheSizeConfigName = "openstack.token_cache_size"
+
+// defaultOpenstackCacheTTL is time in seconds keystone token validation result
+// is cached for
+const defaultOpenstackCacheTTL = 60
+
+// defaultOpenstackCacheNegativeTTL is time in seconds invalid keystone token
+// is cached for
+const defaultOpenstackCacheNegativeTTL = 10
+
+// defaultOpenstackCacheSize is amount of keystone tokens stored in cache
+const defaultOpenstackCacheSize = 10000
+
+const reconcilerIntervalConfigName = "reconciler.interval"
+const reconcilerDeleteOrphansConfigName = "reconciler.delete_orphans"
+
+// defaultReconcilerInterval is interval in seconds between reconciliation runs
+const defaultReconcilerInterval = 300
+
+const projectSyncIntervalConfigName = "project_sync.interval"
+const projectSyncDeleteOrgsConfigName = "project_sync.delete_orgs"
+
+const notificationsDriverConfigName = "notifications.driver"
+const notificationsURLConfigName = "notifications.url"
+const notificationsExchangeConfigName = "notifications.exchange"
+const notificationsRoutingKeyConfigName = "notifications.routing_key"
+const notificationsQueueConfigName = "notifications.queue"
+const notificationsFileConfigName = "notifications.file"
+
+// amqpNotificationsDriver consumes keystone notifications from message bus
+const amqpNotificationsDriver = "amqp"
+
+// fileNotificationsDriver reads keystone notifications from file or stdin
+const fileNotificationsDriver = "file"
+
+// defaultNotificationsExchange is exchange keystone publishes notifications
+// to by default
+const defaultNotificationsExchange = "keystone"
+
+// defaultNotificationsRoutingKey is routing key of keystone notifications
+// of info priority
+const defaultNotificationsRoutingKey = "notifications.info"
+
+// defaultNotificationsQueue is queue consumed by api
+const defaultNotificationsQueue = "visualization-api"
+
+const outboxIntervalConfigName = "outbox.interval"
+const outboxMaxAttemptsConfigName = "outbox.max_attempts"
+
+// defaultOutboxInterval is interval in seconds between journal drains
+const defaultOutboxInterval = 10
+
+// defaultOutboxMaxAttempts is amount of attempts to perform grafana operation
+const defaultOutboxMaxAttempts = 10
+
+const jobsWorkersConfigName = "jobs.workers"
+const jobsQueueSizeConfigName = "jobs.queue_size"
+
+// defaultJobsWorkers is amount of workers performing asynchronous jobs
+const defaultJobsWorkers = 4
+
+// defaultJobsQueueSize is amount of jobs waiting for free worker
+const defaultJobsQueueSize = 100
+
+const policyFileConfigName = "policy.file"
 
 // VisualizationAPIConfig is a struct that keeps all application config options
 type VisualizationAPIConfig struct {
@@ -60,7 +166,11 @@ type VisualizationAPIConfig struct {
 	LogLevel     string
 	ConsoleDebug bool
 
-	// mysql settings
+	// database settings
+	DatabaseDriver string
+	DatabasePath   string
+
+	// mysql settings, they are used by postgres driver as well
 	MysqlHost         string
 	MysqlPassword     string
 	MysqlUsername     string
@@ -68,8 +178,18 @@ type VisualizationAPIConfig struct {
 	MysqlPort         int
 
 	// http_endpoint settings
-	HTTPPort  int
-	JWTSecret string
+	HTTPPort         int
+	JWTSecret        string
+	JWTKeysDirectory string
+	JWTSigningKey    string
+	JWTLifetime      int
+	// KeystoneRevalidationInterval is 0 if keystone tokens are validated
+	// only on JWT issue
+	KeystoneRevalidationInterval int
+	// KeystoneTokenKey encrypts keystone tokens stored in database, it is
+	// JWTSecret unless configured
+	KeystoneTokenKey   string
+	TokenPurgeInterval int
 
 	// openstack settings
 	OpenstackAuthURL  string
@@ -78,10 +198,50 @@ type VisualizationAPIConfig struct {
 	OpenstackProject  string
 	OpenstackDomain   string
 
+	OpenstackTokenCacheTTL         int
+	OpenstackTokenCacheNegativeTTL int
+	OpenstackTokenCacheSize        int
+
 	// grafana settings
 	GrafanaURL      string
 	GrafanaUsername string
 	GrafanaPassword string
+
+	GrafanaProvisionUsers bool
+	GrafanaRoleMapping    map[string]string
+	GrafanaDefaultRole    string
+	GrafanaProxy          bool
+	// hosts and networks datasources created by users can point to
+	GrafanaDatasourceHosts    []string
+	GrafanaDatasourceNetworks []*net.IPNet
+
+	// reconciler settings
+	ReconcilerInterval      int
+	ReconcilerDeleteOrphans bool
+
+	// project synchronization settings
+	ProjectSyncInterval            int
+	ProjectSyncDeleteOrganizations bool
+
+	// notifications settings, NotificationsDriver is empty if keystone
+	// notifications are not consumed
+	NotificationsDriver     string
+	NotificationsURL        string
+	NotificationsExchange   string
+	NotificationsRoutingKey string
+	NotificationsQueue      string
+	NotificationsFile       string
+
+	// outbox settings
+	OutboxInterval    int
+	OutboxMaxAttempts int
+
+	// jobs settings
+	JobsWorkers   int
+	JobsQueueSize int
+
+	// policy settings
+	PolicyFile string
 }
 
 var (
@@ -93,6 +253,10 @@ var envReplacer = strings.NewReplacer(".", "_")
 
 var _ = flag.String(flagReplacer.Replace(logFileConfigName), "",
 	"Path to log file")
+var _ = flag.String(flagReplacer.Replace(databaseDriverConfigName),
+	defaultDatabaseDriver, "Database driver: mysql, postgres, sqlite3 or memory")
+var _ = flag.String(flagReplacer.Replace(databasePathConfigName), "",
+	"Path to database file used by sqlite3 driver")
 var _ = flag.Int(flagReplacer.Replace(mysqlPortConfigName), 0,
 	"Port mysql server is listening on")
 var _ = flag.String(flagReplacer.Replace(mysqlPasswordConfigName), "",
@@ -109,11 +273,38 @@ var _ = flag.String(flagReplacer.Replace(grafanaUserConfigName), "",
 	"Username for Grafana server")
 var _ = flag.String(flagReplacer.Replace(grafanaPasswordConfigName), "",
 	"Password for Grafana server")
+var _ = flag.Bool(flagReplacer.Replace(grafanaProvisionUsersConfigName), true,
+	"Create Grafana users for keystone users on login")
+var _ = flag.String(flagReplacer.Replace(grafanaRoleMappingConfigName),
+	defaultGrafanaRoleMapping,
+	"Comma separated keystone_role:GrafanaRole pairs, GrafanaRole is one of Admin, Editor, Viewer")
+var _ = flag.String(flagReplacer.Replace(grafanaDefaultRoleConfigName),
+	grafanaclient.RoleViewer,
+	"Grafana role of users without mapped keystone roles, such users are not added to organization if empty")
+var _ = flag.Bool(flagReplacer.Replace(grafanaProxyConfigName), false,
+	"Forward /v1/grafana/ requests to Grafana configured with auth proxy authentication")
+var _ = flag.String(flagReplacer.Replace(grafanaDatasourceAllowlistConfigName),
+	"", "Comma separated host names and CIDRs datasources of users can point to, "+
+		"if empty datasources of proxy access can not point to private networks")
 var _ = flag.Bool("debug", false, "display debug messages in stdout")
 var _ = flag.Int(flagReplacer.Replace(httpPortConfigName), 0,
 	"Port to serve http API")
 var _ = flag.String(flagReplacer.Replace(httpSecretConfigName), "",
 	"Secret to use for JsonWebToken signature")
+var _ = flag.String(flagReplacer.Replace(httpKeysDirectoryConfigName), "",
+	"Directory of PEM encoded RSA and ECDSA keys of JsonWebToken signature named <key id>.pem")
+var _ = flag.String(flagReplacer.Replace(httpSigningKeyConfigName), "",
+	"Id of key from keys directory to sign JsonWebToken, secret is used if empty")
+var _ = flag.Int(flagReplacer.Replace(httpJWTLifetimeConfigName),
+	defaultJWTLifetime,
+	"Maximum lifetime in seconds of JsonWebToken, it never outlives keystone token")
+var _ = flag.Int(flagReplacer.Replace(httpRevalidationIntervalConfigName), 0,
+	"Interval in seconds between validations of keystone token JsonWebToken was issued for, 0 disables them")
+var _ = flag.String(flagReplacer.Replace(httpKeystoneTokenKeyConfigName), "",
+	"Secret encrypting keystone tokens stored in database, JsonWebToken secret is used if empty")
+var _ = flag.Int(flagReplacer.Replace(httpTokenPurgeIntervalConfigName),
+	defaultTokenPurgeInterval,
+	"Interval in seconds between removals of revoked and keystone tokens of expired JsonWebToken")
 
 var _ = flag.String(flagReplacer.Replace(openstackAuthURLConfigName), "",
 	"Auth url of openstack keystone")
@@ -125,11 +316,58 @@ var _ = flag.String(flagReplacer.Replace(openstackProjectConfigName), "",
 	"Project name to auth in openstack keystone")
 var _ = flag.String(flagReplacer.Replace(openstackDomainConfigName), "",
 	"Domain name to auth in openstack keystone")
+var _ = flag.Int(flagReplacer.Replace(openstackCacheTTLConfigName),
+	defaultOpenstackCacheTTL,
+	"Seconds to cache keystone token validation result, 0 disables cache")
+var _ = flag.Int(flagReplacer.Replace(openstackCacheNegativeTTLConfigName),
+	defaultOpenstackCacheNegativeTTL, "Seconds to cache invalid keystone token")
+var _ = flag.Int(flagReplacer.Replace(openstackCacheSizeConfigName),
+	defaultOpenstackCacheSize, "Amount of keystone tokens stored in cache")
+
+var _ = flag.Int(flagReplacer.Replace(reconcilerIntervalConfigName),
+	defaultReconcilerInterval,
+	"Interval in seconds between db and grafana reconciliations, 0 disables it")
+var _ = flag.Bool(flagReplacer.Replace(reconcilerDeleteOrphansConfigName), false,
+	"Remove grafana dashboards uploaded by api and not matching any "+
+		"visualization during reconciliation")
+var _ = flag.Int(flagReplacer.Replace(projectSyncIntervalConfigName), 0,
+	"Interval in seconds between synchronizations of openstack projects and "+
+		"grafana organizations, 0 disables it")
+var _ = flag.Bool(flagReplacer.Replace(projectSyncDeleteOrgsConfigName), false,
+	"Remove grafana organizations of removed projects instead of archiving them")
+var _ = flag.String(flagReplacer.Replace(notificationsDriverConfigName), "",
+	"Driver of keystone notifications: amqp or file, empty disables them")
+var _ = flag.String(flagReplacer.Replace(notificationsURLConfigName), "",
+	"URL of AMQP broker keystone notifications are consumed from")
+var _ = flag.String(flagReplacer.Replace(notificationsExchangeConfigName),
+	defaultNotificationsExchange,
+	"AMQP exchange keystone publishes notifications to")
+var _ = flag.String(flagReplacer.Replace(notificationsRoutingKeyConfigName),
+	defaultNotificationsRoutingKey, "Routing key of keystone notifications")
+var _ = flag.String(flagReplacer.Replace(notificationsQueueConfigName),
+	defaultNotificationsQueue,
+	"AMQP queue keystone notifications are consumed from")
+var _ = flag.String(flagReplacer.Replace(notificationsFileConfigName), "",
+	"Path to file keystone notifications are read from, - reads stdin")
+var _ = flag.Int(flagReplacer.Replace(outboxIntervalConfigName),
+	defaultOutboxInterval,
+	"Interval in seconds between retries of journaled grafana operations")
+var _ = flag.Int(flagReplacer.Replace(outboxMaxAttemptsConfigName),
+	defaultOutboxMaxAttempts,
+	"Amount of attempts to perform journaled grafana operation")
+var _ = flag.Int(flagReplacer.Replace(jobsWorkersConfigName),
+	defaultJobsWorkers, "Amount of workers performing asynchronous jobs")
+var _ = flag.Int(flagReplacer.Replace(jobsQueueSizeConfigName),
+	defaultJobsQueueSize, "Amount of asynchronous jobs waiting for free worker")
+var _ = flag.String(flagReplacer.Replace(policyFileConfigName), "",
+	"Path to json file of access control policy, default rules are used if empty")
 
 func initializeCommandLineFlags() error {
 
 	flagsToBind := []string{
 		logFileConfigName,
+		databaseDriverConfigName,
+		databasePathConfigName,
 		mysqlHostConfigName,
 		mysqlPortConfigName,
 		mysqlUserConfigName,
@@ -138,13 +376,42 @@ func initializeCommandLineFlags() error {
 		grafanaURLConfigName,
 		grafanaUserConfigName,
 		grafanaPasswordConfigName,
+		grafanaProvisionUsersConfigName,
+		grafanaRoleMappingConfigName,
+		grafanaDefaultRoleConfigName,
+		grafanaProxyConfigName,
+		grafanaDatasourceAllowlistConfigName,
 		httpPortConfigName,
 		httpSecretConfigName,
+		httpKeysDirectoryConfigName,
+		httpSigningKeyConfigName,
+		httpJWTLifetimeConfigName,
+		httpRevalidationIntervalConfigName,
+		httpKeystoneTokenKeyConfigName,
+		httpTokenPurgeIntervalConfigName,
 		openstackAuthURLConfigName,
 		openstackUsernameConfigName,
 		openstackPasswordConfigName,
 		openstackProjectConfigName,
 		openstackDomainConfigName,
+		openstackCacheTTLConfigName,
+		openstackCacheNegativeTTLConfigName,
+		openstackCacheSizeConfigName,
+		reconcilerIntervalConfigName,
+		reconcilerDeleteOrphansConfigName,
+		projectSyncIntervalConfigName,
+		projectSyncDeleteOrgsConfigName,
+		notificationsDriverConfigName,
+		notificationsURLConfigName,
+		notificationsExchangeConfigName,
+		notificationsRoutingKeyConfigName,
+		notificationsQueueConfigName,
+		notificationsFileConfigName,
+		outboxIntervalConfigName,
+		outboxMaxAttemptsConfigName,
+		jobsWorkersConfigName,
+		jobsQueueSizeConfigName,
+		policyFileConfigName,
 	}
 	for _, configName := range flagsToBind {
 		err := viper.BindPFlag(configName, flag.Lookup(
@@ -183,6 +450,36 @@ func parseLoggingValues() error {
 	return nil
 }
 
+func parseDatabaseValues() error {
+	databaseDriverConfigValue := viper.GetString(
+		databaseDriverConfigName)
+	if databaseDriverConfigValue == "" {
+		return NewParseError(
+			"databaseDriver", "driver", "database", "DATABASE_DRIVER",
+			"--database-driver")
+	}
+	singleToneConfig.DatabaseDriver = databaseDriverConfigValue
+
+	if databaseDriverConfigValue == memoryDatabaseDriver {
+		return nil
+	}
+
+	// sqlite keeps database in file, database server settings are not
+	// required for it
+	if databaseDriverConfigValue == sqliteDatabaseDriver {
+		databasePathConfigValue := viper.GetString(
+			databasePathConfigName)
+		if databasePathConfigValue == "" {
+			return NewParseError(
+				"databasePath", "path", "database", "DATABASE_PATH",
+				"--database-path")
+		}
+		singleToneConfig.DatabasePath = databasePathConfigValue
+		return nil
+	}
+	return parseMysqlValues()
+}
+
 func parseMysqlValues() error {
 	// parseLoggingValues aggregates logic for getting values from viper
 	// framework. Parsing options by groups reduces cyclomatic complexity of
@@ -240,15 +537,72 @@ func parseHTTPEndpointValues() error {
 	}
 	singleToneConfig.HTTPPort = httpPortConfigValue
 
+	singleToneConfig.JWTKeysDirectory = viper.GetString(
+		httpKeysDirectoryConfigName)
+	singleToneConfig.JWTSigningKey = viper.GetString(httpSigningKeyConfigName)
+	if singleToneConfig.JWTSigningKey != "" &&
+		singleToneConfig.JWTKeysDirectory == "" {
+		return NewParseError(
+			"httpEndpointKeysDirectory", "jwt_keys_dir", "http_endpoint",
+			"HTTP_ENDPOINT_JWT_KEYS_DIR", "--http-endpoint-jwt-keys-dir")
+	}
+
+	// secret is required only to sign tokens, if keys are used it only
+	// verifies tokens issued before keys rotation
 	httpSecretConfigValue := viper.GetString(
 		httpSecretConfigName)
-	if httpSecretConfigValue == "" {
+	if httpSecretConfigValue == "" && singleToneConfig.JWTSigningKey == "" {
 		return NewParseError(
 			"httpEndpointSecret", "secret", "http_endpoint",
 			"HTTP_ENDPOINT_JWT_SECRET", "--http-endpoint-jwt-secret")
 	}
 	singleToneConfig.JWTSecret = httpSecretConfigValue
 
+	httpJWTLifetimeConfigValue := viper.GetInt(httpJWTLifetimeConfigName)
+	if httpJWTLifetimeConfigValue <= 0 {
+		return NewParseError(
+			"httpEndpointJWTLifetime", "jwt_lifetime", "http_endpoint",
+			"HTTP_ENDPOINT_JWT_LIFETIME", "--http-endpoint-jwt-lifetime")
+	}
+	singleToneConfig.JWTLifetime = httpJWTLifetimeConfigValue
+
+	httpRevalidationIntervalConfigValue := viper.GetInt(
+		httpRevalidationIntervalConfigName)
+	if httpRevalidationIntervalConfigValue < 0 {
+		return NewParseError(
+			"httpEndpointKeystoneRevalidationInterval",
+			"keystone_revalidation_interval", "http_endpoint",
+			"HTTP_ENDPOINT_KEYSTONE_REVALIDATION_INTERVAL",
+			"--http-endpoint-keystone-revalidation-interval")
+	}
+	singleToneConfig.KeystoneRevalidationInterval =
+		httpRevalidationIntervalConfigValue
+
+	// keystone tokens are stored to refresh and revalidate JWT, so they
+	// have to be encrypted by key shared by all api instances
+	httpKeystoneTokenKeyConfigValue := viper.GetString(
+		httpKeystoneTokenKeyConfigName)
+	if httpKeystoneTokenKeyConfigValue == "" {
+		httpKeystoneTokenKeyConfigValue = httpSecretConfigValue
+	}
+	if httpKeystoneTokenKeyConfigValue == "" {
+		return NewParseError(
+			"httpEndpointKeystoneTokenKey", "keystone_token_key",
+			"http_endpoint", "HTTP_ENDPOINT_KEYSTONE_TOKEN_KEY",
+			"--http-endpoint-keystone-token-key")
+	}
+	singleToneConfig.KeystoneTokenKey = httpKeystoneTokenKeyConfigValue
+
+	httpTokenPurgeIntervalConfigValue := viper.GetInt(
+		httpTokenPurgeIntervalConfigName)
+	if httpTokenPurgeIntervalConfigValue <= 0 {
+		return NewParseError(
+			"httpEndpointTokenPurgeInterval", "token_purge_interval",
+			"http_endpoint", "HTTP_ENDPOINT_TOKEN_PURGE_INTERVAL",
+			"--http-endpoint-token-purge-interval")
+	}
+	singleToneConfig.TokenPurgeInterval = httpTokenPurgeIntervalConfigValue
+
 	return nil
 }
 
@@ -298,6 +652,40 @@ func parseOpenstackValues() error {
 	}
 	singleToneConfig.OpenstackDomain = openstackDomainConfigValue
 
+	return parseOpenstackCacheValues()
+}
+
+func parseOpenstackCacheValues() error {
+	// cache options have default values, only sanity of them is checked
+	openstackCacheTTLConfigValue := viper.GetInt(
+		openstackCacheTTLConfigName)
+	if openstackCacheTTLConfigValue < 0 {
+		return NewParseError(
+			"OpenstackTokenCacheTTL", "token_cache_ttl", "openstack",
+			"OPENSTACK_TOKEN_CACHE_TTL", "--openstack-token-cache-ttl")
+	}
+	singleToneConfig.OpenstackTokenCacheTTL = openstackCacheTTLConfigValue
+
+	openstackCacheNegativeTTLConfigValue := viper.GetInt(
+		openstackCacheNegativeTTLConfigName)
+	if openstackCacheNegativeTTLConfigValue < 0 {
+		return NewParseError(
+			"OpenstackTokenCacheNegativeTTL", "token_cache_negative_ttl",
+			"openstack", "OPENSTACK_TOKEN_CACHE_NEGATIVE_TTL",
+			"--openstack-token-cache-negative-ttl")
+	}
+	singleToneConfig.OpenstackTokenCacheNegativeTTL =
+		openstackCacheNegativeTTLConfigValue
+
+	openstackCacheSizeConfigValue := viper.GetInt(
+		openstackCacheSizeConfigName)
+	if openstackCacheSizeConfigValue <= 0 {
+		return NewParseError(
+			"OpenstackTokenCacheSize", "token_cache_size", "openstack",
+			"OPENSTACK_TOKEN_CACHE_SIZE", "--openstack-token-cache-size")
+	}
+	singleToneConfig.OpenstackTokenCacheSize = openstackCacheSizeConfigValue
+
 	return nil
 }
 
@@ -329,6 +717,217 @@ func parseGrafanaValues() error {
 	}
 	singleToneConfig.GrafanaPassword = grafanaPasswordConfigValue
 
+	return parseGrafanaUsersValues()
+}
+
+// isGrafanaRole checks if role is role of user in grafana organization
+func isGrafanaRole(role string) bool {
+	return role == grafanaclient.RoleViewer ||
+		role == grafanaclient.RoleEditor || role == grafanaclient.RoleAdmin
+}
+
+// parseRoleMapping parses comma separated keystone_role:GrafanaRole pairs
+func parseRoleMapping(value string) (map[string]string, bool) {
+	result := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		roles := strings.Split(pair, ":")
+		if len(roles) != 2 || roles[0] == "" || !isGrafanaRole(roles[1]) {
+			return nil, false
+		}
+		result[roles[0]] = roles[1]
+	}
+	return result, true
+}
+
+// parseAllowlist parses comma separated host names and CIDRs
+func parseAllowlist(value string) ([]string, []*net.IPNet, bool) {
+	hosts := []string{}
+	networks := []*net.IPNet{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			hosts = append(hosts, entry)
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, nil, false
+		}
+		networks = append(networks, network)
+	}
+	return hosts, networks, true
+}
+
+func parseGrafanaUsersValues() error {
+	singleToneConfig.GrafanaProvisionUsers = viper.GetBool(
+		grafanaProvisionUsersConfigName)
+
+	grafanaRoleMapping, ok := parseRoleMapping(viper.GetString(
+		grafanaRoleMappingConfigName))
+	if !ok {
+		return NewParseError(
+			"grafanaRoleMapping", "role_mapping", "grafana",
+			"GRAFANA_ROLE_MAPPING", "--grafana-role-mapping")
+	}
+	singleToneConfig.GrafanaRoleMapping = grafanaRoleMapping
+
+	grafanaDefaultRoleConfigValue := viper.GetString(
+		grafanaDefaultRoleConfigName)
+	if grafanaDefaultRoleConfigValue != "" &&
+		!isGrafanaRole(grafanaDefaultRoleConfigValue) {
+		return NewParseError(
+			"grafanaDefaultRole", "default_role", "grafana",
+			"GRAFANA_DEFAULT_ROLE", "--grafana-default-role")
+	}
+	singleToneConfig.GrafanaDefaultRole = grafanaDefaultRoleConfigValue
+
+	singleToneConfig.GrafanaProxy = viper.GetBool(grafanaProxyConfigName)
+
+	hosts, networks, ok := parseAllowlist(viper.GetString(
+		grafanaDatasourceAllowlistConfigName))
+	if !ok {
+		return NewParseError(
+			"grafanaDatasourceAllowlist", "datasource_allowlist", "grafana",
+			"GRAFANA_DATASOURCE_ALLOWLIST", "--grafana-datasource-allowlist")
+	}
+	singleToneConfig.GrafanaDatasourceHosts = hosts
+	singleToneConfig.GrafanaDatasourceNetworks = networks
+
+	return nil
+}
+
+func parseReconcilerValues() error {
+	// reconciler options have default values, only sanity of them is checked
+	reconcilerIntervalConfigValue := viper.GetInt(
+		reconcilerIntervalConfigName)
+	if reconcilerIntervalConfigValue < 0 {
+		return NewParseError(
+			"reconcilerInterval", "interval", "reconciler",
+			"RECONCILER_INTERVAL", "--reconciler-interval")
+	}
+	singleToneConfig.ReconcilerInterval = reconcilerIntervalConfigValue
+	singleToneConfig.ReconcilerDeleteOrphans = viper.GetBool(
+		reconcilerDeleteOrphansConfigName)
+
+	return nil
+}
+
+func parseProjectSyncValues() error {
+	// project synchronization options have default values, only sanity of
+	// them is checked
+	projectSyncIntervalConfigValue := viper.GetInt(
+		projectSyncIntervalConfigName)
+	if projectSyncIntervalConfigValue < 0 {
+		return NewParseError(
+			"projectSyncInterval", "interval", "project_sync",
+			"PROJECT_SYNC_INTERVAL", "--project-sync-interval")
+	}
+	singleToneConfig.ProjectSyncInterval = projectSyncIntervalConfigValue
+	singleToneConfig.ProjectSyncDeleteOrganizations = viper.GetBool(
+		projectSyncDeleteOrgsConfigName)
+
+	return nil
+}
+
+func parseNotificationsValues() error {
+	// notifications are optional, settings of driver are required only if
+	// it is enabled
+	notificationsDriverConfigValue := viper.GetString(
+		notificationsDriverConfigName)
+	switch notificationsDriverConfigValue {
+	case "":
+		return nil
+	case fileNotificationsDriver:
+		notificationsFileConfigValue := viper.GetString(
+			notificationsFileConfigName)
+		if notificationsFileConfigValue == "" {
+			return NewParseError(
+				"notificationsFile", "file", "notifications",
+				"NOTIFICATIONS_FILE", "--notifications-file")
+		}
+		singleToneConfig.NotificationsFile = notificationsFileConfigValue
+	case amqpNotificationsDriver:
+		notificationsURLConfigValue := viper.GetString(
+			notificationsURLConfigName)
+		if notificationsURLConfigValue == "" {
+			return NewParseError(
+				"notificationsURL", "url", "notifications",
+				"NOTIFICATIONS_URL", "--notifications-url")
+		}
+		singleToneConfig.NotificationsURL = notificationsURLConfigValue
+
+		notificationsQueueConfigValue := viper.GetString(
+			notificationsQueueConfigName)
+		if notificationsQueueConfigValue == "" {
+			return NewParseError(
+				"notificationsQueue", "queue", "notifications",
+				"NOTIFICATIONS_QUEUE", "--notifications-queue")
+		}
+		singleToneConfig.NotificationsQueue = notificationsQueueConfigValue
+		singleToneConfig.NotificationsExchange = viper.GetString(
+			notificationsExchangeConfigName)
+		singleToneConfig.NotificationsRoutingKey = viper.GetString(
+			notificationsRoutingKeyConfigName)
+	default:
+		return NewParseError(
+			"notificationsDriver", "driver", "notifications",
+			"NOTIFICATIONS_DRIVER", "--notifications-driver")
+	}
+	singleToneConfig.NotificationsDriver = notificationsDriverConfigValue
+
+	return nil
+}
+
+func parseOutboxValues() error {
+	// outbox options have default values, only sanity of them is checked
+	outboxIntervalConfigValue := viper.GetInt(
+		outboxIntervalConfigName)
+	if outboxIntervalConfigValue <= 0 {
+		return NewParseError(
+			"outboxInterval", "interval", "outbox",
+			"OUTBOX_INTERVAL", "--outbox-interval")
+	}
+	singleToneConfig.OutboxInterval = outboxIntervalConfigValue
+
+	outboxMaxAttemptsConfigValue := viper.GetInt(
+		outboxMaxAttemptsConfigName)
+	if outboxMaxAttemptsConfigValue <= 0 {
+		return NewParseError(
+			"outboxMaxAttempts", "max_attempts", "outbox",
+			"OUTBOX_MAX_ATTEMPTS", "--outbox-max-attempts")
+	}
+	singleToneConfig.OutboxMaxAttempts = outboxMaxAttemptsConfigValue
+
+	return nil
+}
+
+func parseJobsValues() error {
+	// jobs options have default values, only sanity of them is checked
+	jobsWorkersConfigValue := viper.GetInt(
+		jobsWorkersConfigName)
+	if jobsWorkersConfigValue <= 0 {
+		return NewParseError(
+			"jobsWorkers", "workers", "jobs",
+			"JOBS_WORKERS", "--jobs-workers")
+	}
+	singleToneConfig.JobsWorkers = jobsWorkersConfigValue
+
+	jobsQueueSizeConfigValue := viper.GetInt(
+		jobsQueueSizeConfigName)
+	if jobsQueueSizeConfigValue <= 0 {
+		return NewParseError(
+			"jobsQueueSize", "queue_size", "jobs",
+			"JOBS_QUEUE_SIZE", "--jobs-queue-size")
+	}
+	singleToneConfig.JobsQueueSize = jobsQueueSizeConfigValue
+
 	return nil
 }
 
@@ -371,7 +970,7 @@ func InitializeConfig() error {
 	if err != nil {
 		return err
 	}
-	err = parseMysqlValues()
+	err = parseDatabaseValues()
 	if err != nil {
 		return err
 	}
@@ -387,6 +986,29 @@ func InitializeConfig() error {
 	if err != nil {
 		return err
 	}
+	err = parseReconcilerValues()
+	if err != nil {
+		return err
+	}
+	err = parseProjectSyncValues()
+	if err != nil {
+		return err
+	}
+	err = parseNotificationsValues()
+	if err != nil {
+		return err
+	}
+	err = parseOutboxValues()
+	if err != nil {
+		return err
+	}
+	err = parseJobsValues()
+	if err != nil {
+		return err
+	}
+
+	// policy file is optional, default rules are used without it
+	singleToneConfig.PolicyFile = viper.GetString(policyFileConfigName)
 
 	// console debug has default values - no need to check
 	singleToneConfig.ConsoleDebug = viper.GetBool(
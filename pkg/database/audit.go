@@ -0,0 +1,129 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// AuditFilter describes which audit records are requested. Empty fields are
+// not used as filters. Zero Limit means that all records following Offset are
+// returned
+type AuditFilter struct {
+	UserID         string
+	OrganizationID string
+	Method         string
+	Route          string
+	Target         string
+	Outcome        string
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+	Offset         int
+}
+
+// EncodeAuditTargets returns ids of objects api call was made on the way they
+// are stored in audit record
+func EncodeAuditTargets(targets map[string]string) (string, error) {
+	encodedTargets, err := json.Marshal(targets)
+	if err != nil {
+		return "", err
+	}
+	return string(encodedTargets), nil
+}
+
+// getTargetPattern returns LIKE pattern matching encoded targets containing
+// provided id. Encoded id includes quotes, so only whole ids are matched
+func getTargetPattern(target string) (string, error) {
+	encodedTarget, err := json.Marshal(target)
+	if err != nil {
+		return "", err
+	}
+	return "%:" + likeReplacer.Replace(string(encodedTarget)) + "%", nil
+}
+
+// getAuditQuery returns condition matching audit records described by filter
+func getAuditQuery(filter AuditFilter) (string, []interface{}, error) {
+	conditions := []string{}
+	params := []interface{}{}
+
+	columnValues := []struct {
+		column string
+		value  string
+	}{
+		{models.AuditRecordUserColumn, filter.UserID},
+		{models.AuditRecordOrgColumn, filter.OrganizationID},
+		{models.AuditRecordMethodColumn, filter.Method},
+		{models.AuditRecordRouteColumn, filter.Route},
+		{models.AuditRecordOutcomeColumn, filter.Outcome},
+	}
+	for _, columnValue := range columnValues {
+		if columnValue.value != "" {
+			conditions = append(conditions, fmt.Sprintf("%s = ?",
+				columnValue.column))
+			params = append(params, columnValue.value)
+		}
+	}
+
+	if filter.Target != "" {
+		pattern, err := getTargetPattern(filter.Target)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("%s LIKE ? ESCAPE '%s'",
+			models.AuditRecordTargetsColumn, likeEscape))
+		params = append(params, pattern)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("%s >= ?",
+			models.AuditRecordCreatedColumn))
+		params = append(params, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("%s < ?",
+			models.AuditRecordCreatedColumn))
+		params = append(params, filter.Until)
+	}
+	return strings.Join(conditions, " AND "), params, nil
+}
+
+// CreateAuditRecord stores audit record of api call
+func (m *XORMManager) CreateAuditRecord(record *models.AuditRecord) error {
+	_, err := m.engine.Insert(record)
+	if err != nil {
+		log.Logger.Errorf("Error on storing audit record to db: '%s'", err)
+	}
+	return err
+}
+
+// QueryAuditRecords returns audit records matching filter, newest first
+func (m *XORMManager) QueryAuditRecords(filter AuditFilter) (
+	[]*models.AuditRecord, error) {
+	query, queryParams, err := getAuditQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	session := m.engine.Where(query, queryParams...).Desc(
+		models.AuditRecordIDColumn)
+	if filter.Limit > 0 || filter.Offset > 0 {
+		// offset can not be used without limit in some databases
+		limit := filter.Limit
+		if limit == 0 {
+			limit = math.MaxInt32
+		}
+		session = session.Limit(limit, filter.Offset)
+	}
+	records := []*models.AuditRecord{}
+	err = session.Find(&records)
+	if err != nil {
+		log.Logger.Errorf("Error on getting audit records from db: '%s'", err)
+		return nil, err
+	}
+	return records, nil
+}
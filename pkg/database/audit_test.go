@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditQuery(t *testing.T) {
+	query, params, err := getAuditQuery(AuditFilter{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, "", query)
+	assert.Equal(t, []interface{}{}, params)
+
+	since := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC)
+	query, params, err = getAuditQuery(AuditFilter{
+		UserID:  "user",
+		Method:  "DELETE",
+		Target:  "50%_off",
+		Outcome: "failure",
+		Since:   since,
+		Until:   until,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "user_id = ? AND method = ? AND outcome = ? AND "+
+		"targets LIKE ? ESCAPE '!' AND created_at >= ? AND created_at < ?", query)
+	assert.Equal(t, []interface{}{"user", "DELETE", "failure", `%:"50!%!_off"%`,
+		since, until}, params)
+}
+
+func TestEncodeAuditTargets(t *testing.T) {
+	targets, err := EncodeAuditTargets(map[string]string{
+		"userID": "2", "organizationID": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"organizationID":"1","userID":"2"}`, targets)
+}
@@ -1,51 +1,97 @@
 package db
 
 import (
+	"crypto/cipher"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 	"visualization-api/pkg/logging"
-	// import mysql driver for side-effect required for xorm package
+	// import database drivers for side-effect required for xorm package
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/go-xorm/xorm"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/satori/go.uuid"
 	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/search"
 )
 
 var (
-	engine *xorm.Engine
+	engine        *xorm.Engine
+	engineDialect dialect
 )
 
 // DatabaseManager represents what functionality we are expecting from db layer
 type DatabaseManager interface {
 	QueryVisualizationsDashboards(string, string, string, map[string]interface{}) (
 		*map[models.Visualization][]*models.Dashboard, error)
+	QueryVisualizationsPage(string, search.Query, Pagination) (
+		*VisualizationsPage, error)
 	CreateVisualizationsWithDashboards(string, string, map[string]interface{},
-		[]string, []string) (*models.Visualization, []*models.Dashboard, error)
+		[]string, []string) (*models.Visualization, []*models.Dashboard,
+		[]*models.GrafanaOperation, error)
 	DeleteVisualization(*models.Visualization) error
+	DeleteVisualizationWithOperations(*models.Visualization, []*models.Dashboard) (
+		[]*models.GrafanaOperation, error)
+	UpdateVisualizationWithOperations(*models.Visualization, string,
+		map[string]interface{}, []*models.Dashboard, []*models.Dashboard,
+		[]*models.Dashboard) ([]*models.GrafanaOperation, error)
 	BulkUpdateDashboard([]*models.Dashboard) error
+	UpdateDashboardSlug(string, string, string) (bool, error)
 	BulkDeleteDashboard([]*models.Dashboard) error
 	GetVisualizationWithDashboardsBySlug(string, string) (*models.Visualization, []*models.Dashboard, error)
+	QueryOrganizationIDs() ([]string, error)
+	DeleteOrganizationVisualizations(string) (int64, error)
+	GetOrganizationMapping(string) (*models.OrganizationMapping, error)
+	QueryOrganizationMappings() ([]*models.OrganizationMapping, error)
+	QueryOrganizationMappingsOfOrganization(string) (
+		[]*models.OrganizationMapping, error)
+	SaveOrganizationMapping(*models.OrganizationMapping) error
+	DeleteOrganizationMapping(string) error
+	RemapOrganizations([]*OrganizationRemap) error
+	QueryTemplates(string, string, int) ([]*models.Template, error)
+	CreateTemplate(string, string, int, map[string]interface{}, string) (
+		*models.Template, error)
+	DeleteTemplate(int, string) (*models.Template, error)
+	QueryGrafanaOperations(time.Time, int) ([]*models.GrafanaOperation, error)
+	ClaimGrafanaOperation(*models.GrafanaOperation, string) (bool, error)
+	CompleteGrafanaOperation(*models.GrafanaOperation, string) error
+	FailGrafanaOperation(*models.GrafanaOperation, string) error
+	CreateAuditRecord(*models.AuditRecord) error
+	QueryAuditRecords(AuditFilter) ([]*models.AuditRecord, error)
+	RevokeToken(string, time.Time) (bool, error)
+	IsTokenRevoked(string) (bool, error)
+	SaveKeystoneToken(string, string, time.Time) error
+	GetKeystoneToken(string) (string, error)
+	DeleteExpiredTokens(time.Time) (int64, error)
 }
 
-// InitializeEngine initializes connection to db
-func InitializeEngine(mysqlUsername, mysqlPassword, mysqlHost,
-	mysqlDatabaseName string, mysqlPort int) error {
+// InitializeEngine initializes connection to db using driver provided in
+// settings
+func InitializeEngine(settings ConnectionSettings) error {
 	var err error
-	// connection parameter has to look like
-	// user:password@tcp(host:port)/dbname))
-	engine, err = xorm.NewEngine("mysql", fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/%s?charset=utf8",
-		mysqlUsername,
-		mysqlPassword,
-		mysqlHost,
-		mysqlPort,
-		mysqlDatabaseName,
-	))
+	engineDialect, err = getDialect(settings.Driver)
+	if err != nil {
+		return err
+	}
+	engine, err = xorm.NewEngine(settings.Driver,
+		engineDialect.dataSourceName(settings))
 	return err
 }
 
+// OpenDB opens plain sql connection to database provided in settings. It is
+// used by tools working with database without xorm, like schema migrations
+func OpenDB(settings ConnectionSettings) (*sql.DB, error) {
+	sqlDialect, err := getDialect(settings.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open(settings.Driver, sqlDialect.dataSourceName(settings))
+}
+
 // GetEngine returns database connection
 func GetEngine() *xorm.Engine {
 	return engine
@@ -53,12 +99,17 @@ func GetEngine() *xorm.Engine {
 
 // XORMManager is an implementation of DatabaseManager
 type XORMManager struct {
-	engine *xorm.Engine
+	engine  *xorm.Engine
+	dialect dialect
+	// tokenCipher encrypts stored keystone tokens, it is nil if key of
+	// keystone tokens is not provided
+	tokenCipher cipher.AEAD
 }
 
-// NewXORMManager is XORMManager constructor
-func NewXORMManager() *XORMManager {
-	return &XORMManager{engine}
+// NewXORMManager is XORMManager constructor. Keystone tokens are encrypted by
+// key derived from tokenSecret, they can not be stored if it is empty
+func NewXORMManager(tokenSecret string) *XORMManager {
+	return &XORMManager{engine, engineDialect, newTokenCipher(tokenSecret)}
 }
 
 // CreateVisualizationFromParam takes provided arguments and returns created model
@@ -88,8 +139,8 @@ func (m *XORMManager) CreateVisualizationFromParam(name, organizationID string,
 	return visualization, nil
 }
 
-func getVisualizationLookupQuery(slug, name, organizationID string,
-	tags map[string]interface{}) (string, []interface{}) {
+func getVisualizationLookupQuery(slug, organizationID string,
+	query search.Query) (string, []interface{}, error) {
 
 	// create Query, with ? placeholders for queries. This would protect from
 	// sql injection attacks. Function returns query and parameters to be passed to it
@@ -102,42 +153,43 @@ func getVisualizationLookupQuery(slug, name, organizationID string,
 		queryParams = append(queryParams, slug)
 	}
 
-	if name != "" {
-		queryChunks = append(queryChunks, fmt.Sprintf("%s.%s = ?",
-			models.VisualizationTableName, models.VisualizationNameColumn))
-		queryParams = append(queryParams, name)
-	}
-
 	if organizationID != "" {
 		queryChunks = append(queryChunks, fmt.Sprintf("%s.%s = ?",
 			models.VisualizationTableName, models.VisualizationOrgColumn))
 		queryParams = append(queryParams, organizationID)
 	}
 
-	// use JSON_EXTRACT to query json_field
-	for tagName, tagValue := range tags {
-		queryChunks = append(queryChunks, fmt.Sprintf(
-			"JSON_EXTRACT(%s, '$.%s') = ?", models.VisualizationTagsColumn, tagName))
-		queryParams = append(queryParams, tagValue)
+	// tags are looked up in tag table
+	for _, group := range query {
+		condition, conditionParams, err := getGroupCondition(group)
+		if err != nil {
+			return "", nil, err
+		}
+		queryChunks = append(queryChunks, condition)
+		queryParams = append(queryParams, conditionParams...)
 	}
 
-	query := strings.Join(queryChunks, " AND ")
+	lookupQuery := strings.Join(queryChunks, " AND ")
 
-	log.Logger.Debugf("Got lookup query '%s'", query)
-	return query, queryParams
+	log.Logger.Debugf("Got lookup query '%s'", lookupQuery)
+	return lookupQuery, queryParams, nil
 }
 
 // QueryVisualizationsDashboards takes name, tags and organizationID and returns matched entries
 func (m *XORMManager) QueryVisualizationsDashboards(slug, name, organizationID string,
 	tags map[string]interface{}) (*map[models.Visualization][]*models.Dashboard, error) {
 
-	query, queryParams := getVisualizationLookupQuery(slug, name, organizationID, tags)
+	query, queryParams, err := getVisualizationLookupQuery(slug,
+		organizationID, search.FromTags(name, tags))
+	if err != nil {
+		return nil, err
+	}
 
 	var queryResult []struct {
 		Visualization models.Visualization `xorm:"extends"`
 		Dashboard     models.Dashboard     `xorm:"extends"`
 	}
-	err := m.engine.Table(models.VisualizationTableName).Join(
+	err = m.engine.Table(models.VisualizationTableName).Join(
 		"INNER", models.DashboardTableName,
 		fmt.Sprintf("%s.%s = %s.%s", models.DashboardTableName,
 			models.DashboardVisualizationColumn,
@@ -162,15 +214,17 @@ func (m *XORMManager) QueryVisualizationsDashboards(slug, name, organizationID s
 func (m *XORMManager) GetVisualizationWithDashboardsBySlug(
 	slug, organizationID string) (*models.Visualization, []*models.Dashboard, error) {
 	// TODO(oshyman) fix lookup query
-	noNameProvided := ""
-	noTagsProvided := map[string]interface{}{}
-	query, queryParams := getVisualizationLookupQuery(slug, noNameProvided, organizationID, noTagsProvided)
+	query, queryParams, err := getVisualizationLookupQuery(slug,
+		organizationID, search.Query{})
+	if err != nil {
+		return nil, nil, err
+	}
 
 	var queryResult []struct {
 		Visualization models.Visualization `xorm:"extends"`
 		Dashboard     models.Dashboard     `xorm:"extends"`
 	}
-	err := m.engine.Table("visualization").Join("INNER", models.DashboardTableName,
+	err = m.engine.Table("visualization").Join("INNER", models.DashboardTableName,
 		fmt.Sprintf("%s.%s = %s.%s", models.DashboardTableName,
 			models.DashboardVisualizationColumn,
 			models.VisualizationTableName,
@@ -190,30 +244,29 @@ func (m *XORMManager) GetVisualizationWithDashboardsBySlug(
 	return visualizationDatabase, dashboardsDatabase, nil
 }
 
-func getBulkDeleteDashboardQuery(dashboards []*models.Dashboard) (string, []interface{}) {
-	if len(dashboards) > 0 {
-		// create Query, with ? placeholders for queries. This would protect from
-		// sql injection attacks. Function returns query and parameters to be passed to it
-		ids := strings.Repeat("?, ", len(dashboards)-1)
-		ids = ids + "?"
-		queryParams := []interface{}{}
-		for _, dashboard := range dashboards {
-			queryParams = append(queryParams, dashboard.ID)
-		}
-		query := fmt.Sprintf("DELETE FROM %s WHERE id IN (%s);",
-			models.DashboardTableName, ids)
-		log.Logger.Debugf("Bulk Delete dashboard query is '%s'", query)
-		return query, queryParams
+// QueryOrganizationIDs returns ids of all organizations having visualizations
+func (m *XORMManager) QueryOrganizationIDs() ([]string, error) {
+	var organizationIDs []string
+	err := m.engine.Table(models.VisualizationTableName).Distinct(
+		models.VisualizationOrgColumn).Find(&organizationIDs)
+	if err != nil {
+		log.Logger.Errorf("Error on getting organizations from db: '%s'", err)
+		return nil, err
 	}
-	return "", nil
+	return organizationIDs, nil
 }
 
 // BulkDeleteDashboard removes multiple Dashboards at once
 func (m *XORMManager) BulkDeleteDashboard(dashboards []*models.Dashboard) error {
-	// Xorm does not support bulk delete
 	if len(dashboards) > 0 {
-		query, queryParams := getBulkDeleteDashboardQuery(dashboards)
-		_, err := engine.Exec(query, queryParams...)
+		// IN condition is rendered by xorm, that's why query does not
+		// depend on database
+		dashboardIDs := []interface{}{}
+		for _, dashboard := range dashboards {
+			dashboardIDs = append(dashboardIDs, dashboard.ID)
+		}
+		_, err := m.engine.In(models.DashboardIDColumn, dashboardIDs...).Delete(
+			&models.Dashboard{})
 		return err
 	}
 	return nil
@@ -226,14 +279,9 @@ func (m *XORMManager) BulkUpdateDashboard(dashboards []*models.Dashboard) error
 		table := m.engine.TableInfo(models.Dashboard{})
 		queryParams := []interface{}{}
 		columnDBNames := []string{}
-		columnDBValues := []string{}
 		for _, colName := range table.Columns() {
 			columnDBNames = append(columnDBNames, colName.Name)
-			columnDBValues = append(columnDBValues,
-				fmt.Sprintf("%s=VALUES(%[1]s)", colName.Name))
 		}
-		columnDBNamesString := strings.Join(columnDBNames, ", ")
-		columnUpdateString := strings.Join(columnDBValues, ", ")
 
 		amountOfParameters := reflect.ValueOf(
 			&models.Dashboard{}).Elem().NumField()
@@ -249,17 +297,31 @@ func (m *XORMManager) BulkUpdateDashboard(dashboards []*models.Dashboard) error
 				queryParams = append(queryParams, field.Interface())
 			}
 		}
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE %s;",
-			models.DashboardTableName, columnDBNamesString,
-			renderedParameters, columnUpdateString)
+		// upsert syntax depends on database
+		query := m.dialect.upsertQuery(models.DashboardTableName,
+			models.DashboardIDColumn, columnDBNames, renderedParameters)
 		log.Logger.Debugf("Bulk Update dashboard query is '%s'", query)
 
-		_, err := engine.Exec(query, queryParams...)
+		_, err := m.engine.Exec(query, queryParams...)
 		return err
 	}
 	return nil
 }
 
+// UpdateDashboardSlug stores new slug of dashboard, only if dashboard still
+// has old slug. false is returned if dashboard was changed or removed
+func (m *XORMManager) UpdateDashboardSlug(dashboardID, oldSlug,
+	newSlug string) (bool, error) {
+	affected, err := m.engine.Where(fmt.Sprintf("%s = ? AND %s = ?",
+		models.DashboardIDColumn, models.DashboardSlugColumn), dashboardID,
+		oldSlug).Cols(models.DashboardSlugColumn).Update(
+		&models.Dashboard{Slug: newSlug})
+	if err != nil {
+		return false, err
+	}
+	return affected != 0, nil
+}
+
 // DeleteVisualization removes visualization model from db
 func (m *XORMManager) DeleteVisualization(visualization *models.Visualization) error {
 	if visualization != nil {
@@ -270,15 +332,16 @@ func (m *XORMManager) DeleteVisualization(visualization *models.Visualization) e
 }
 
 // CreateVisualizationsWithDashboards creates all data for single visualization
-// in one transaction
+// in one transaction. Upload of every dashboard to grafana is journaled in the
+// same transaction
 func (m *XORMManager) CreateVisualizationsWithDashboards(name, organizationID string,
 	tags map[string]interface{}, dashboardNames, renderedTemplates []string) (
-	*models.Visualization, []*models.Dashboard, error) {
+	*models.Visualization, []*models.Dashboard, []*models.GrafanaOperation, error) {
 
 	// validate data for visualization
 	visualization, err := m.CreateVisualizationFromParam(name, organizationID, tags)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	session := m.engine.NewSession()
@@ -286,33 +349,47 @@ func (m *XORMManager) CreateVisualizationsWithDashboards(name, organizationID st
 
 	err = session.Begin()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	_, err = session.Insert(visualization)
 	if err != nil {
 		session.Rollback()
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	err = replaceVisualizationTags(session, visualization)
+	if err != nil {
+		session.Rollback()
+		return nil, nil, nil, err
 	}
 
 	var dashboards []*models.Dashboard
+	var operations []*models.GrafanaOperation
 	for index, name := range dashboardNames {
-		dashboards = append(dashboards, &models.Dashboard{
+		dashboard := &models.Dashboard{
 			ID:               uuid.NewV4().String(),
 			Visualization:    visualization.ID,
 			Name:             name,
 			RenderedTemplate: renderedTemplates[index],
-		})
+		}
+		dashboards = append(dashboards, dashboard)
+		operations = append(operations, newUploadOperation(organizationID, dashboard))
 	}
 
 	_, err = session.Insert(dashboards)
 	if err != nil {
 		session.Rollback()
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	err = insertOperations(session, operations)
+	if err != nil {
+		session.Rollback()
+		return nil, nil, nil, err
 	}
 
 	err = session.Commit()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	return visualization, dashboards, nil
+	return visualization, dashboards, operations, nil
 }
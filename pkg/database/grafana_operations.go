@@ -0,0 +1,323 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-xorm/xorm"
+	"github.com/satori/go.uuid"
+	"time"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// GrafanaOperationLease is time, journaled operation is reserved for its
+// owner. Created operations are owned by their creator, so they are not
+// performed by outbox worker while creator performs them
+const GrafanaOperationLease = time.Minute
+
+func newUploadOperation(organizationID string,
+	dashboard *models.Dashboard) *models.GrafanaOperation {
+	return &models.GrafanaOperation{
+		Operation:      models.GrafanaOperationUpload,
+		OrganizationID: organizationID,
+		DashboardID:    dashboard.ID,
+		Payload:        dashboard.RenderedTemplate,
+		Owner:          uuid.NewV4().String(),
+		LockedUntil:    time.Now().Add(GrafanaOperationLease),
+	}
+}
+
+func newDeleteOperation(organizationID, dashboardID,
+	slug string) *models.GrafanaOperation {
+	return &models.GrafanaOperation{
+		Operation:      models.GrafanaOperationDelete,
+		OrganizationID: organizationID,
+		DashboardID:    dashboardID,
+		Slug:           slug,
+		Owner:          uuid.NewV4().String(),
+		LockedUntil:    time.Now().Add(GrafanaOperationLease),
+	}
+}
+
+func insertOperations(session *xorm.Session,
+	operations []*models.GrafanaOperation) error {
+	// operations are inserted one by one, because ids of inserted
+	// operations are required by caller
+	for _, operation := range operations {
+		_, err := session.Insert(operation)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deletePendingUploads(session *xorm.Session,
+	dashboardIDs []interface{}) error {
+	if len(dashboardIDs) == 0 {
+		return nil
+	}
+	_, err := session.Where(fmt.Sprintf("%s = ?",
+		models.GrafanaOperationTypeColumn), models.GrafanaOperationUpload).In(
+		models.GrafanaOperationDashboardColumn, dashboardIDs...).Delete(
+		&models.GrafanaOperation{})
+	return err
+}
+
+// DeleteVisualizationWithOperations removes visualization with its dashboards
+// and journals removal of their grafana dashboards in one transaction.
+// Pending uploads of removed dashboards are dropped
+func (m *XORMManager) DeleteVisualizationWithOperations(
+	visualization *models.Visualization, dashboards []*models.Dashboard) (
+	[]*models.GrafanaOperation, error) {
+
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err := session.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	dashboardIDs := []interface{}{}
+	operations := []*models.GrafanaOperation{}
+	for _, dashboard := range dashboards {
+		dashboardIDs = append(dashboardIDs, dashboard.ID)
+		if dashboard.Slug != "" {
+			operations = append(operations, newDeleteOperation(
+				visualization.OrganizationID, dashboard.ID, dashboard.Slug))
+		}
+	}
+
+	err = deletePendingUploads(session, dashboardIDs)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	err = insertOperations(session, operations)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	// dashboards are removed by foreign key cascade
+	_, err = session.Id(visualization.ID).Delete(&models.Visualization{})
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// UpdateVisualizationWithOperations stores new name and tags of visualization
+// together with changed, created and removed dashboards in one transaction.
+// Upload of every changed and created dashboard and removal of every removed
+// grafana dashboard are journaled in the same transaction. Pending uploads of
+// changed and removed dashboards are dropped, as they are outdated now.
+// Operations are returned in order: changed, created, removed
+func (m *XORMManager) UpdateVisualizationWithOperations(
+	visualization *models.Visualization, name string,
+	tags map[string]interface{}, changed, created,
+	removed []*models.Dashboard) ([]*models.GrafanaOperation, error) {
+	encodedTags, err := json.Marshal(tags)
+	if err != nil {
+		log.Logger.Errorf("Error on storing not serializable map[string]interface{}"+
+			" to json field : '%s'", err)
+		return nil, err
+	}
+	visualization.Name = name
+	visualization.Tags = string(encodedTags)
+
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err = session.Begin()
+	if err != nil {
+		return nil, err
+	}
+	_, err = session.Id(visualization.ID).Cols(
+		models.VisualizationNameColumn, models.VisualizationTagsColumn).Update(
+		visualization)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	err = replaceVisualizationTags(session, visualization)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	outdatedIDs := []interface{}{}
+	operations := []*models.GrafanaOperation{}
+	for _, dashboard := range changed {
+		outdatedIDs = append(outdatedIDs, dashboard.ID)
+		_, err = session.Id(dashboard.ID).Cols(
+			models.DashboardRenderedTemplateColumn).Update(dashboard)
+		if err != nil {
+			session.Rollback()
+			return nil, err
+		}
+		operations = append(operations, newUploadOperation(
+			visualization.OrganizationID, dashboard))
+	}
+	if len(created) > 0 {
+		_, err = session.Insert(created)
+		if err != nil {
+			session.Rollback()
+			return nil, err
+		}
+	}
+	for _, dashboard := range created {
+		operations = append(operations, newUploadOperation(
+			visualization.OrganizationID, dashboard))
+	}
+	removedIDs := []interface{}{}
+	for _, dashboard := range removed {
+		outdatedIDs = append(outdatedIDs, dashboard.ID)
+		removedIDs = append(removedIDs, dashboard.ID)
+		if dashboard.Slug != "" {
+			operations = append(operations, newDeleteOperation(
+				visualization.OrganizationID, dashboard.ID, dashboard.Slug))
+		}
+	}
+
+	err = deletePendingUploads(session, outdatedIDs)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+	if len(removedIDs) > 0 {
+		_, err = session.In(models.DashboardIDColumn, removedIDs...).Delete(
+			&models.Dashboard{})
+		if err != nil {
+			session.Rollback()
+			return nil, err
+		}
+	}
+	err = insertOperations(session, operations)
+	if err != nil {
+		session.Rollback()
+		return nil, err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// QueryGrafanaOperations returns journaled operations created before provided
+// time, which were attempted less than maxAttempts times, oldest first
+func (m *XORMManager) QueryGrafanaOperations(createdBefore time.Time,
+	maxAttempts int) ([]*models.GrafanaOperation, error) {
+	operations := []*models.GrafanaOperation{}
+	err := m.engine.Table(models.GrafanaOperationTableName).Where(
+		fmt.Sprintf("%s < ? AND %s < ?", models.GrafanaOperationCreatedColumn,
+			models.GrafanaOperationAttemptsColumn),
+		createdBefore, maxAttempts).Asc("id").Find(&operations)
+	if err != nil {
+		log.Logger.Errorf("Error on getting grafana operations from db: '%s'", err)
+		return nil, err
+	}
+	return operations, nil
+}
+
+// CompleteGrafanaOperation removes performed operation from journal. For
+// upload operation received grafana slug is stored to dashboard. If title of
+// dashboard was changed, grafana creates new dashboard instead of overwriting
+// previous one, so removal of previous grafana dashboard is journaled. If
+// dashboard was removed meanwhile, removal of uploaded grafana dashboard
+// is journaled instead
+func (m *XORMManager) CompleteGrafanaOperation(
+	operation *models.GrafanaOperation, slug string) error {
+
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err := session.Begin()
+	if err != nil {
+		return err
+	}
+
+	if operation.Operation == models.GrafanaOperationUpload {
+		dashboard := models.Dashboard{}
+		found, getErr := session.Id(operation.DashboardID).Get(&dashboard)
+		if getErr != nil {
+			session.Rollback()
+			return getErr
+		}
+		if found {
+			previousSlug := dashboard.Slug
+			dashboard.Slug = slug
+			_, err = session.Id(operation.DashboardID).Cols("slug").Update(&dashboard)
+			if err == nil && previousSlug != "" && previousSlug != slug {
+				log.Logger.Debugf("Dashboard '%s' was renamed, journaling removal "+
+					"of grafana dashboard '%s'", operation.DashboardID, previousSlug)
+				_, err = session.Insert(newDeleteOperation(
+					operation.OrganizationID, operation.DashboardID, previousSlug))
+			}
+		} else {
+			log.Logger.Debugf("Dashboard '%s' was removed, journaling removal "+
+				"of grafana dashboard '%s'", operation.DashboardID, slug)
+			_, err = session.Insert(newDeleteOperation(
+				operation.OrganizationID, operation.DashboardID, slug))
+		}
+		if err != nil {
+			session.Rollback()
+			return err
+		}
+	}
+
+	_, err = session.Id(operation.ID).Delete(&models.GrafanaOperation{})
+	if err != nil {
+		session.Rollback()
+		return err
+	}
+	return session.Commit()
+}
+
+// ClaimGrafanaOperation reserves operation for provided owner for
+// GrafanaOperationLease. Operation can be claimed by its current owner or by
+// anybody after lease of current owner expired. false is returned if
+// operation is reserved by another owner or was already performed
+func (m *XORMManager) ClaimGrafanaOperation(operation *models.GrafanaOperation,
+	owner string) (bool, error) {
+	now := time.Now()
+	claimed := &models.GrafanaOperation{
+		Owner:       owner,
+		LockedUntil: now.Add(GrafanaOperationLease),
+	}
+	affected, err := m.engine.Where(fmt.Sprintf("id = ? AND (%s = ? OR %s < ?)",
+		models.GrafanaOperationOwnerColumn,
+		models.GrafanaOperationLockedUntilColumn), operation.ID, owner, now).Cols(
+		models.GrafanaOperationOwnerColumn,
+		models.GrafanaOperationLockedUntilColumn).Update(claimed)
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	operation.Owner = claimed.Owner
+	operation.LockedUntil = claimed.LockedUntil
+	return true, nil
+}
+
+// FailGrafanaOperation stores failed attempt of operation
+func (m *XORMManager) FailGrafanaOperation(operation *models.GrafanaOperation,
+	lastError string) error {
+	operation.Attempts++
+	operation.LastError = lastError
+	_, err := m.engine.Id(operation.ID).Cols(
+		models.GrafanaOperationAttemptsColumn,
+		models.GrafanaOperationLastErrorColumn).Update(operation)
+	return err
+}
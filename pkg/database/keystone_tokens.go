@@ -0,0 +1,139 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// ErrTokenKeyMissing is returned if keystone token is stored or read by
+// manager created without key encrypting keystone tokens
+var ErrTokenKeyMissing = errors.New("key of keystone tokens is not configured")
+
+// newTokenCipher returns cipher encrypting keystone tokens stored in db, its
+// key is derived from secret. Nil is returned if secret is empty
+func newTokenCipher(secret string) cipher.AEAD {
+	if secret == "" {
+		return nil
+	}
+	key := sha256.Sum256([]byte(secret))
+	// key of AES-256 size is always accepted
+	block, _ := aes.NewCipher(key[:])
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+// encryptToken encrypts keystone token of jwt token. Id of jwt token is
+// authenticated too, so encrypted token can not be moved to other row
+func encryptToken(aead cipher.AEAD, tokenID, token string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(token), []byte(tokenID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptToken decrypts keystone token of jwt token encrypted by encryptToken
+func decryptToken(aead cipher.AEAD, tokenID, encrypted string) (string,
+	error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted keystone token is too short")
+	}
+	nonce := sealed[:aead.NonceSize()]
+	token, err := aead.Open(nil, nonce, sealed[aead.NonceSize():],
+		[]byte(tokenID))
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// SaveKeystoneToken stores keystone token jwt token of provided id was issued
+// for. Keystone token is encrypted, it has to be stored only until jwt token
+// expires
+func (m *XORMManager) SaveKeystoneToken(tokenID, keystoneTokenID string,
+	expiresAt time.Time) error {
+	if m.tokenCipher == nil {
+		return ErrTokenKeyMissing
+	}
+	encryptedToken, err := encryptToken(m.tokenCipher, tokenID,
+		keystoneTokenID)
+	if err != nil {
+		log.Logger.Errorf("Error on encrypting keystone token: '%s'", err)
+		return err
+	}
+
+	_, err = m.engine.Insert(&models.KeystoneToken{
+		TokenID:         tokenID,
+		KeystoneTokenID: encryptedToken,
+		ExpiresAt:       expiresAt,
+	})
+	if err != nil {
+		log.Logger.Errorf("Error on storing keystone token to db: '%s'", err)
+	}
+	return err
+}
+
+// GetKeystoneToken returns keystone token jwt token of provided id was issued
+// for. Empty string is returned if there is no such token or it can not be
+// decrypted, like tokens encrypted by other key
+func (m *XORMManager) GetKeystoneToken(tokenID string) (string, error) {
+	if m.tokenCipher == nil {
+		return "", ErrTokenKeyMissing
+	}
+	token := &models.KeystoneToken{}
+	found, err := m.engine.Where(fmt.Sprintf("%s = ?",
+		models.KeystoneTokenIDColumn), tokenID).Get(token)
+	if err != nil {
+		log.Logger.Errorf("Error on getting keystone token from db: '%s'", err)
+		return "", err
+	}
+	if !found {
+		return "", nil
+	}
+	keystoneTokenID, err := decryptToken(m.tokenCipher, tokenID,
+		token.KeystoneTokenID)
+	if err != nil {
+		log.Logger.Warningf("Keystone token of token '%s' can not be "+
+			"decrypted: '%s'", tokenID, err)
+		return "", nil
+	}
+	return keystoneTokenID, nil
+}
+
+// DeleteExpiredTokens removes revoked and keystone tokens of jwt tokens
+// expired before provided time. Amount of removed rows is returned
+func (m *XORMManager) DeleteExpiredTokens(now time.Time) (int64, error) {
+	revoked, err := m.engine.Where(fmt.Sprintf("%s < ?",
+		models.RevokedTokenExpiresColumn), now).Delete(
+		&models.RevokedToken{})
+	if err != nil {
+		log.Logger.Errorf("Error on removing expired revoked tokens: '%s'",
+			err)
+		return 0, err
+	}
+	keystone, err := m.engine.Where(fmt.Sprintf("%s < ?",
+		models.KeystoneTokenExpiresColumn), now).Delete(
+		&models.KeystoneToken{})
+	if err != nil {
+		log.Logger.Errorf("Error on removing expired keystone tokens: '%s'",
+			err)
+		return revoked, err
+	}
+	return revoked + keystone, nil
+}
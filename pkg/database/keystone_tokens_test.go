@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeystoneTokenEncryption(t *testing.T) {
+	assert.Nil(t, newTokenCipher(""), "tokens are not encrypted without key")
+
+	aead := newTokenCipher("secret")
+	encrypted, err := encryptToken(aead, "jwt", "keystone")
+	assert.Nil(t, err)
+	assert.NotContains(t, encrypted, "keystone")
+	other, err := encryptToken(aead, "jwt", "keystone")
+	assert.Nil(t, err)
+	assert.NotEqual(t, encrypted, other, "every token has its own nonce")
+
+	token, err := decryptToken(aead, "jwt", encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "keystone", token)
+
+	_, err = decryptToken(aead, "other_jwt", encrypted)
+	assert.NotNil(t, err, "token of other jwt token must not be decrypted")
+	_, err = decryptToken(newTokenCipher("other"), "jwt", encrypted)
+	assert.NotNil(t, err, "token must not be decrypted by other key")
+	_, err = decryptToken(aead, "jwt", "keystone")
+	assert.NotNil(t, err, "plain token must not be decrypted")
+	_, err = decryptToken(aead, "jwt", "")
+	assert.NotNil(t, err, "empty token must not be decrypted")
+}
@@ -0,0 +1,177 @@
+package db_test
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/migrations"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/search"
+)
+
+// newSQLiteManager returns XORMManager working with migrated sqlite database
+// stored in temporary file. Returned function removes the file
+func newSQLiteManager(t *testing.T) (db.DatabaseManager, func()) {
+	file, err := ioutil.TempFile("", "visualization-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	cleanup := func() { os.Remove(file.Name()) }
+
+	settings := db.ConnectionSettings{Driver: db.DriverSQLite,
+		Path: file.Name()}
+	connection, err := db.OpenDB(settings)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	_, err = migrations.Up(connection, db.DriverSQLite)
+	connection.Close()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	err = db.InitializeEngine(settings)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return db.NewXORMManager("secret"), cleanup
+}
+
+// TestManagersSearch checks that memory manager matches visualizations the
+// same way sql queries do
+func TestManagersSearch(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	sqliteManager, cleanup := newSQLiteManager(t)
+	defer cleanup()
+
+	typedQuery, err := search.Parse(url.Values{"size": {"1"}}, nil)
+	assert.Nil(t, err)
+	quotedQuery, err := search.Parse(url.Values{"size": {`"1"`}}, nil)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description   string
+		query         search.Query
+		expectedNames []string
+	}{
+		{
+			description:   "name equals value",
+			query:         search.Query{{{"name", search.Equal, []interface{}{"Web-Prod"}}}},
+			expectedNames: []string{"Web-Prod"},
+		},
+		{
+			description:   "name prefix is case insensitive",
+			query:         search.Query{{{"name", search.Prefix, []interface{}{"WEB-"}}}},
+			expectedNames: []string{"Web-Prod", "web-dev"},
+		},
+		{
+			description:   "name substring is case insensitive",
+			query:         search.Query{{{"name", search.Contains, []interface{}{"PROD"}}}},
+			expectedNames: []string{"DB-prod", "Web-Prod"},
+		},
+		{
+			description:   "name substring is not a pattern",
+			query:         search.Query{{{"name", search.Contains, []interface{}{"_"}}}},
+			expectedNames: []string{},
+		},
+		{
+			description:   "tag equals value",
+			query:         search.Query{{{"env", search.Equal, []interface{}{"prod"}}}},
+			expectedNames: []string{"DB-prod", "Web-Prod"},
+		},
+		{
+			description:   "typed value matches its string form",
+			query:         typedQuery,
+			expectedNames: []string{"DB-prod", "Web-Prod"},
+		},
+		{
+			description:   "quoted value matches only string",
+			query:         quotedQuery,
+			expectedNames: []string{"Web-Prod"},
+		},
+	}
+
+	for managerName, manager := range map[string]db.DatabaseManager{
+		"memory": db.NewMemoryManager(),
+		"sqlite": sqliteManager,
+	} {
+		for name, tags := range map[string]map[string]interface{}{
+			"Web-Prod": {"env": "prod", "size": "1"},
+			"web-dev":  {"env": "dev"},
+			"DB-prod":  {"env": "prod", "size": 1},
+		} {
+			_, _, _, err := manager.CreateVisualizationsWithDashboards(name, "3",
+				tags, []string{"dashboard"}, []string{"template"})
+			assert.Nil(t, err, managerName)
+		}
+
+		for _, testCase := range tests {
+			page, err := manager.QueryVisualizationsPage("3", testCase.query,
+				db.Pagination{})
+			assert.Nil(t, err, "%s: %s", managerName, testCase.description)
+			names := []string{}
+			for _, visualization := range page.Visualizations {
+				names = append(names, visualization.Name)
+			}
+			sort.Strings(names)
+			assert.Equal(t, testCase.expectedNames, names, "%s: %s",
+				managerName, testCase.description)
+		}
+	}
+}
+
+// TestManagersTokens checks that revoked and keystone tokens are kept by
+// managers the same way
+func TestManagersTokens(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	sqliteManager, cleanup := newSQLiteManager(t)
+	defer cleanup()
+
+	now := time.Now()
+	for managerName, manager := range map[string]db.DatabaseManager{
+		"memory": db.NewMemoryManager(),
+		"sqlite": sqliteManager,
+	} {
+		assert.Nil(t, manager.SaveKeystoneToken("expired", "first",
+			now.Add(-time.Minute)), managerName)
+		assert.Nil(t, manager.SaveKeystoneToken("active", "second",
+			now.Add(time.Hour)), managerName)
+		keystoneToken, err := manager.GetKeystoneToken("active")
+		assert.Nil(t, err, managerName)
+		assert.Equal(t, "second", keystoneToken, managerName)
+
+		revoked, err := manager.RevokeToken("revoked", now.Add(-time.Minute))
+		assert.Nil(t, err, managerName)
+		assert.True(t, revoked, managerName)
+		revoked, err = manager.RevokeToken("revoked", now.Add(-time.Minute))
+		assert.Nil(t, err, managerName)
+		assert.False(t, revoked, "%s: token is revoked only once", managerName)
+
+		removed, err := manager.DeleteExpiredTokens(now)
+		assert.Nil(t, err, managerName)
+		assert.Equal(t, int64(2), removed, managerName)
+		keystoneToken, err = manager.GetKeystoneToken("expired")
+		assert.Nil(t, err, managerName)
+		assert.Equal(t, "", keystoneToken, managerName)
+		keystoneToken, err = manager.GetKeystoneToken("active")
+		assert.Nil(t, err, managerName)
+		assert.Equal(t, "second", keystoneToken, managerName)
+	}
+
+	// keystone tokens are not stored in plain text
+	stored := &models.KeystoneToken{}
+	found, err := db.GetEngine().Where("token_id = ?", "active").Get(stored)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.NotEqual(t, "second", stored.KeystoneTokenID)
+}
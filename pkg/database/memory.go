@@ -0,0 +1,1022 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/search"
+)
+
+// DriverMemory is a name of database driver keeping all data in process
+// memory. It is not backed by sql database and is meant for tests and demo
+const DriverMemory = "memory"
+
+// MemoryManager is an implementation of DatabaseManager keeping all data in
+// memory. Every method is performed under single lock, that's why all of
+// them are atomic the same way transactions of XORMManager are. Copies of
+// stored models are returned, so they could be changed by caller the same
+// way models loaded from db are
+type MemoryManager struct {
+	lock sync.Mutex
+
+	visualizations map[int]*models.Visualization
+	dashboards     map[string]*models.Dashboard
+	templates      map[int]*models.Template
+	operations     map[int]*models.GrafanaOperation
+	auditRecords   []*models.AuditRecord
+	revokedTokens  map[string]*models.RevokedToken
+	keystoneTokens map[string]*models.KeystoneToken
+	mappings       map[string]*models.OrganizationMapping
+
+	// last used autoincrement ids
+	lastVisualizationID int
+	lastTemplateID      int
+	lastOperationID     int
+	lastAuditRecordID   int
+}
+
+// NewMemoryManager is MemoryManager constructor
+func NewMemoryManager() *MemoryManager {
+	return &MemoryManager{
+		visualizations: map[int]*models.Visualization{},
+		dashboards:     map[string]*models.Dashboard{},
+		templates:      map[int]*models.Template{},
+		operations:     map[int]*models.GrafanaOperation{},
+		auditRecords:   []*models.AuditRecord{},
+		revokedTokens:  map[string]*models.RevokedToken{},
+		keystoneTokens: map[string]*models.KeystoneToken{},
+		mappings:       map[string]*models.OrganizationMapping{},
+	}
+}
+
+func copyDashboards(dashboards []*models.Dashboard) []*models.Dashboard {
+	result := []*models.Dashboard{}
+	for _, dashboard := range dashboards {
+		dashboardCopy := *dashboard
+		result = append(result, &dashboardCopy)
+	}
+	return result
+}
+
+func copyOperations(
+	operations []*models.GrafanaOperation) []*models.GrafanaOperation {
+	result := []*models.GrafanaOperation{}
+	for _, operation := range operations {
+		operationCopy := *operation
+		result = append(result, &operationCopy)
+	}
+	return result
+}
+
+// tagValues returns encoded values of tags of visualization by tag name.
+// Tags are flattened the same way they are stored in tag table, so nested
+// fields and names containing dots are matched the way sql backends match them
+func tagValues(visualization *models.Visualization) map[string][]string {
+	values := map[string][]string{}
+	tags, err := getVisualizationTags(visualization)
+	if err != nil {
+		return values
+	}
+	for _, tag := range tags {
+		values[tag.Name] = append(values[tag.Name], tag.Value)
+	}
+	return values
+}
+
+// tagMatches checks tag the same way sql built by getTagCondition does:
+// missing tag does not match and values are compared as encoded json
+func tagMatches(values map[string][]string, field string,
+	value interface{}) bool {
+	encodedValue, err := encodeTagValue(value)
+	if err != nil {
+		return false
+	}
+	for _, storedValue := range values[field] {
+		if storedValue == encodedValue {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionMatches checks search condition the same way sql built by
+// getGroupCondition does
+func conditionMatches(visualization *models.Visualization,
+	values map[string][]string, condition search.Condition) bool {
+	if condition.Operator == search.Exists ||
+		condition.Operator == search.NotExists {
+		_, found := values[condition.Field]
+		return found == (condition.Operator == search.Exists)
+	}
+
+	for _, value := range condition.Values {
+		var matched bool
+		switch {
+		case condition.Field != search.NameField:
+			matched = tagMatches(values, condition.Field, value)
+		case condition.Operator == search.Prefix:
+			matched = strings.HasPrefix(strings.ToLower(visualization.Name),
+				strings.ToLower(fmt.Sprint(value)))
+		case condition.Operator == search.Contains:
+			matched = strings.Contains(strings.ToLower(visualization.Name),
+				strings.ToLower(fmt.Sprint(value)))
+		default:
+			matched = visualization.Name == fmt.Sprint(value)
+		}
+		if matched {
+			return condition.Operator != search.NotEqual
+		}
+	}
+	return condition.Operator == search.NotEqual
+}
+
+func visualizationMatches(visualization *models.Visualization, slug,
+	organizationID string, query search.Query) bool {
+	// empty arguments are not used as filters, the same way
+	// getVisualizationLookupQuery skips them
+	if slug != "" && visualization.Slug != slug {
+		return false
+	}
+	if organizationID != "" && visualization.OrganizationID != organizationID {
+		return false
+	}
+	values := tagValues(visualization)
+	for _, group := range query {
+		groupMatched := false
+		for _, condition := range group {
+			if conditionMatches(visualization, values, condition) {
+				groupMatched = true
+				break
+			}
+		}
+		if !groupMatched {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *MemoryManager) visualizationDashboards(
+	visualizationID int) []*models.Dashboard {
+	// must be called under lock. Dashboards are sorted by id to have
+	// stable order
+	result := []*models.Dashboard{}
+	for _, dashboard := range m.dashboards {
+		if dashboard.Visualization == visualizationID {
+			result = append(result, dashboard)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func (m *MemoryManager) insertOperation(operation *models.GrafanaOperation) {
+	// must be called under lock. Id and creation time are set to provided
+	// operation the same way xorm does on insert
+	m.lastOperationID++
+	operation.ID = m.lastOperationID
+	operation.CreatedAt = time.Now()
+	operationCopy := *operation
+	m.operations[operation.ID] = &operationCopy
+}
+
+func (m *MemoryManager) deleteVisualization(visualizationID int) {
+	// must be called under lock. Dashboards are removed the same way
+	// foreign key cascade does
+	delete(m.visualizations, visualizationID)
+	for dashboardID, dashboard := range m.dashboards {
+		if dashboard.Visualization == visualizationID {
+			delete(m.dashboards, dashboardID)
+		}
+	}
+}
+
+// QueryVisualizationsDashboards takes name, tags and organizationID and returns matched entries
+func (m *MemoryManager) QueryVisualizationsDashboards(slug, name,
+	organizationID string, tags map[string]interface{}) (
+	*map[models.Visualization][]*models.Dashboard, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := map[models.Visualization][]*models.Dashboard{}
+	for _, visualization := range m.visualizations {
+		if !visualizationMatches(visualization, slug, organizationID,
+			search.FromTags(name, tags)) {
+			continue
+		}
+		// visualizations are joined with dashboards, so visualizations
+		// without dashboards are not returned
+		dashboards := m.visualizationDashboards(visualization.ID)
+		if len(dashboards) > 0 {
+			result[*visualization] = copyDashboards(dashboards)
+		}
+	}
+	return &result, nil
+}
+
+// visualizationPrecedes reports whether visualization a goes before b in
+// provided sort order
+func visualizationPrecedes(a, b *models.Visualization, sortBy string) bool {
+	if sortBy == SortByName && a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
+
+// QueryVisualizationsPage returns requested page of visualizations matching
+// search query and organizationID
+func (m *MemoryManager) QueryVisualizationsPage(organizationID string,
+	searchQuery search.Query, pagination Pagination) (
+	*VisualizationsPage, error) {
+	if _, err := getPageOrder(pagination.Sort); err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	noSlugProvided := ""
+	matched := []*models.Visualization{}
+	var marker *models.Visualization
+	for _, visualization := range m.visualizations {
+		if pagination.Marker != "" && visualization.Slug == pagination.Marker &&
+			visualization.OrganizationID == organizationID {
+			marker = visualization
+		}
+		if visualizationMatches(visualization, noSlugProvided, organizationID,
+			searchQuery) {
+			matched = append(matched, visualization)
+		}
+	}
+	if pagination.Marker != "" && marker == nil {
+		return nil, ErrMarkerNotFound
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return visualizationPrecedes(matched[i], matched[j], pagination.Sort)
+	})
+
+	selected := []*models.Visualization{}
+	for _, visualization := range matched {
+		if marker == nil ||
+			visualizationPrecedes(marker, visualization, pagination.Sort) {
+			selected = append(selected, visualization)
+		}
+	}
+	if pagination.Offset >= len(selected) {
+		selected = []*models.Visualization{}
+	} else {
+		selected = selected[pagination.Offset:]
+	}
+	if pagination.Limit > 0 && pagination.Limit < len(selected) {
+		selected = selected[:pagination.Limit]
+	}
+
+	page := &VisualizationsPage{
+		Visualizations: []*models.Visualization{},
+		Dashboards:     map[int][]*models.Dashboard{},
+		Total:          int64(len(matched)),
+	}
+	for _, visualization := range selected {
+		visualizationCopy := *visualization
+		page.Visualizations = append(page.Visualizations, &visualizationCopy)
+		dashboards := m.visualizationDashboards(visualization.ID)
+		if len(dashboards) > 0 {
+			page.Dashboards[visualization.ID] = copyDashboards(dashboards)
+		}
+	}
+	return page, nil
+}
+
+// GetVisualizationWithDashboardsBySlug returs visualization with all related dashboards
+func (m *MemoryManager) GetVisualizationWithDashboardsBySlug(
+	slug, organizationID string) (*models.Visualization, []*models.Dashboard, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, visualization := range m.visualizations {
+		if !visualizationMatches(visualization, slug, organizationID,
+			search.Query{}) {
+			continue
+		}
+		dashboards := m.visualizationDashboards(visualization.ID)
+		if len(dashboards) == 0 {
+			break
+		}
+		visualizationCopy := *visualization
+		return &visualizationCopy, copyDashboards(dashboards), nil
+	}
+	return nil, []*models.Dashboard{}, nil
+}
+
+// QueryOrganizationIDs returns ids of all organizations having visualizations
+func (m *MemoryManager) QueryOrganizationIDs() ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	organizations := map[string]bool{}
+	organizationIDs := []string{}
+	for _, visualization := range m.visualizations {
+		if !organizations[visualization.OrganizationID] {
+			organizations[visualization.OrganizationID] = true
+			organizationIDs = append(organizationIDs, visualization.OrganizationID)
+		}
+	}
+	sort.Strings(organizationIDs)
+	return organizationIDs, nil
+}
+
+// DeleteOrganizationVisualizations removes all visualizations of organization
+// with their dashboards and returns amount of removed visualizations. Pending
+// grafana operations of organization are dropped
+func (m *MemoryManager) DeleteOrganizationVisualizations(
+	organizationID string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for operationID, operation := range m.operations {
+		if operation.OrganizationID == organizationID {
+			delete(m.operations, operationID)
+		}
+	}
+	var removed int64
+	for visualizationID, visualization := range m.visualizations {
+		if visualization.OrganizationID == organizationID {
+			m.deleteVisualization(visualizationID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// CreateVisualizationsWithDashboards creates all data for single visualization
+// at once. Upload of every dashboard to grafana is journaled as well
+func (m *MemoryManager) CreateVisualizationsWithDashboards(name,
+	organizationID string, tags map[string]interface{}, dashboardNames,
+	renderedTemplates []string) (*models.Visualization, []*models.Dashboard,
+	[]*models.GrafanaOperation, error) {
+	log.Logger.Debugf("Creating new Visualization entry named '%s'", name)
+	encodedTags, err := json.Marshal(tags)
+	if err != nil {
+		log.Logger.Errorf("Error on storing not serializable map[string]interface{}"+
+			" to json field : '%s'", err)
+		return nil, nil, nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.lastVisualizationID++
+	visualization := &models.Visualization{
+		ID:             m.lastVisualizationID,
+		Slug:           uuid.NewV4().String(),
+		Name:           name,
+		OrganizationID: organizationID,
+		Tags:           string(encodedTags),
+	}
+	visualizationCopy := *visualization
+	m.visualizations[visualization.ID] = &visualizationCopy
+
+	var dashboards []*models.Dashboard
+	var operations []*models.GrafanaOperation
+	for index, dashboardName := range dashboardNames {
+		dashboard := &models.Dashboard{
+			ID:               uuid.NewV4().String(),
+			Visualization:    visualization.ID,
+			Name:             dashboardName,
+			RenderedTemplate: renderedTemplates[index],
+		}
+		dashboardCopy := *dashboard
+		m.dashboards[dashboard.ID] = &dashboardCopy
+		dashboards = append(dashboards, dashboard)
+
+		operation := newUploadOperation(organizationID, dashboard)
+		m.insertOperation(operation)
+		operations = append(operations, operation)
+	}
+	return visualization, dashboards, operations, nil
+}
+
+// DeleteVisualization removes visualization with its dashboards
+func (m *MemoryManager) DeleteVisualization(
+	visualization *models.Visualization) error {
+	if visualization != nil {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		m.deleteVisualization(visualization.ID)
+	}
+	return nil
+}
+
+// DeleteVisualizationWithOperations removes visualization with its dashboards
+// and journals removal of their grafana dashboards at once. Pending uploads
+// of removed dashboards are dropped
+func (m *MemoryManager) DeleteVisualizationWithOperations(
+	visualization *models.Visualization, dashboards []*models.Dashboard) (
+	[]*models.GrafanaOperation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	removedDashboards := map[string]bool{}
+	for _, dashboard := range dashboards {
+		removedDashboards[dashboard.ID] = true
+	}
+	for operationID, operation := range m.operations {
+		if operation.Operation == models.GrafanaOperationUpload &&
+			removedDashboards[operation.DashboardID] {
+			delete(m.operations, operationID)
+		}
+	}
+
+	operations := []*models.GrafanaOperation{}
+	for _, dashboard := range dashboards {
+		if dashboard.Slug != "" {
+			operation := newDeleteOperation(visualization.OrganizationID,
+				dashboard.ID, dashboard.Slug)
+			m.insertOperation(operation)
+			operations = append(operations, operation)
+		}
+	}
+
+	m.deleteVisualization(visualization.ID)
+	return operations, nil
+}
+
+// UpdateVisualizationWithOperations stores new name and tags of visualization
+// together with changed, created and removed dashboards at once. Upload of
+// every changed and created dashboard and removal of every removed grafana
+// dashboard are journaled as well. Pending uploads of changed and removed
+// dashboards are dropped. Operations are returned in order: changed,
+// created, removed
+func (m *MemoryManager) UpdateVisualizationWithOperations(
+	visualization *models.Visualization, name string,
+	tags map[string]interface{}, changed, created,
+	removed []*models.Dashboard) ([]*models.GrafanaOperation, error) {
+	encodedTags, err := json.Marshal(tags)
+	if err != nil {
+		log.Logger.Errorf("Error on storing not serializable map[string]interface{}"+
+			" to json field : '%s'", err)
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stored, ok := m.visualizations[visualization.ID]
+	if !ok {
+		return nil, fmt.Errorf("visualization '%d' does not exist",
+			visualization.ID)
+	}
+	visualization.Name = name
+	visualization.Tags = string(encodedTags)
+	stored.Name = visualization.Name
+	stored.Tags = visualization.Tags
+
+	outdatedDashboards := map[string]bool{}
+	for _, dashboard := range changed {
+		outdatedDashboards[dashboard.ID] = true
+	}
+	for _, dashboard := range removed {
+		outdatedDashboards[dashboard.ID] = true
+	}
+	for operationID, operation := range m.operations {
+		if operation.Operation == models.GrafanaOperationUpload &&
+			outdatedDashboards[operation.DashboardID] {
+			delete(m.operations, operationID)
+		}
+	}
+
+	operations := []*models.GrafanaOperation{}
+	for _, dashboard := range changed {
+		if storedDashboard, ok := m.dashboards[dashboard.ID]; ok {
+			storedDashboard.RenderedTemplate = dashboard.RenderedTemplate
+		}
+		operations = append(operations, newUploadOperation(
+			visualization.OrganizationID, dashboard))
+	}
+	for _, dashboard := range created {
+		dashboardCopy := *dashboard
+		m.dashboards[dashboard.ID] = &dashboardCopy
+		operations = append(operations, newUploadOperation(
+			visualization.OrganizationID, dashboard))
+	}
+	for _, dashboard := range removed {
+		delete(m.dashboards, dashboard.ID)
+		if dashboard.Slug != "" {
+			operations = append(operations, newDeleteOperation(
+				visualization.OrganizationID, dashboard.ID, dashboard.Slug))
+		}
+	}
+	for _, operation := range operations {
+		m.insertOperation(operation)
+	}
+	return operations, nil
+}
+
+// BulkUpdateDashboard updates multiple records at once, missing records
+// are inserted
+func (m *MemoryManager) BulkUpdateDashboard(dashboards []*models.Dashboard) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// foreign key is checked for every dashboard before any change is made
+	for _, dashboard := range dashboards {
+		if _, ok := m.visualizations[dashboard.Visualization]; !ok {
+			return fmt.Errorf("visualization '%d' of dashboard '%s' does not exist",
+				dashboard.Visualization, dashboard.ID)
+		}
+	}
+	for _, dashboard := range dashboards {
+		dashboardCopy := *dashboard
+		m.dashboards[dashboard.ID] = &dashboardCopy
+	}
+	return nil
+}
+
+// UpdateDashboardSlug stores new slug of dashboard, only if dashboard still
+// has old slug. false is returned if dashboard was changed or removed
+func (m *MemoryManager) UpdateDashboardSlug(dashboardID, oldSlug,
+	newSlug string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	dashboard, ok := m.dashboards[dashboardID]
+	if !ok || dashboard.Slug != oldSlug {
+		return false, nil
+	}
+	dashboard.Slug = newSlug
+	return true, nil
+}
+
+// BulkDeleteDashboard removes multiple Dashboards at once
+func (m *MemoryManager) BulkDeleteDashboard(dashboards []*models.Dashboard) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, dashboard := range dashboards {
+		delete(m.dashboards, dashboard.ID)
+	}
+	return nil
+}
+
+// QueryTemplates takes name and version and returns matched templates of
+// organization
+// ordered from the latest version to the oldest one
+func (m *MemoryManager) QueryTemplates(organizationID, name string,
+	version int) ([]*models.Template, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	templates := []*models.Template{}
+	for _, template := range m.templates {
+		// zero values of arguments mean that templates are not filtered
+		if template.OrganizationID != organizationID {
+			continue
+		}
+		if name != "" && template.Name != name {
+			continue
+		}
+		if version != 0 && template.Version != version {
+			continue
+		}
+		templateCopy := *template
+		templates = append(templates, &templateCopy)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Version != templates[j].Version {
+			return templates[i].Version > templates[j].Version
+		}
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+// CreateTemplate stores new version of template of organization. If version
+// is not provided (equals 0) - version next to the latest stored one is used
+func (m *MemoryManager) CreateTemplate(name, organizationID string, version int,
+	parameters map[string]interface{}, body string) (*models.Template, error) {
+	log.Logger.Debugf("Creating new Template entry named '%s'", name)
+	encodedParameters, err := json.Marshal(parameters)
+	if err != nil {
+		log.Logger.Errorf("Error on storing not serializable map[string]interface{}"+
+			" to json field : '%s'", err)
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	latestVersion := 0
+	for _, template := range m.templates {
+		if template.OrganizationID != organizationID || template.Name != name {
+			continue
+		}
+		// the same unique key is used by sql databases
+		if template.Version == version {
+			return nil, ErrDuplicate
+		}
+		if template.Version > latestVersion {
+			latestVersion = template.Version
+		}
+	}
+	if version == 0 {
+		version = latestVersion + 1
+	}
+
+	m.lastTemplateID++
+	template := &models.Template{
+		ID:             m.lastTemplateID,
+		Name:           name,
+		Version:        version,
+		OrganizationID: organizationID,
+		Parameters:     string(encodedParameters),
+		Body:           body,
+	}
+	templateCopy := *template
+	m.templates[template.ID] = &templateCopy
+	return template, nil
+}
+
+// DeleteTemplate removes template owned by organization. nil is returned if
+// there is no such template
+func (m *MemoryManager) DeleteTemplate(templateID int, organizationID string) (
+	*models.Template, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	template, ok := m.templates[templateID]
+	if !ok || template.OrganizationID != organizationID {
+		return nil, nil
+	}
+	delete(m.templates, templateID)
+	return template, nil
+}
+
+// QueryGrafanaOperations returns journaled operations created before provided
+// time, which were attempted less than maxAttempts times, oldest first
+func (m *MemoryManager) QueryGrafanaOperations(createdBefore time.Time,
+	maxAttempts int) ([]*models.GrafanaOperation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	operations := []*models.GrafanaOperation{}
+	for _, operation := range m.operations {
+		if operation.CreatedAt.Before(createdBefore) &&
+			operation.Attempts < maxAttempts {
+			operations = append(operations, operation)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		return operations[i].ID < operations[j].ID
+	})
+	return copyOperations(operations), nil
+}
+
+// CompleteGrafanaOperation removes performed operation from journal. For
+// upload operation received grafana slug is stored to dashboard. Removal of
+// previous grafana dashboard is journaled if title of dashboard was changed.
+// If dashboard was removed meanwhile, removal of uploaded grafana dashboard
+// is journaled instead
+func (m *MemoryManager) CompleteGrafanaOperation(
+	operation *models.GrafanaOperation, slug string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if operation.Operation == models.GrafanaOperationUpload {
+		if dashboard, ok := m.dashboards[operation.DashboardID]; ok {
+			if dashboard.Slug != "" && dashboard.Slug != slug {
+				log.Logger.Debugf("Dashboard '%s' was renamed, journaling removal "+
+					"of grafana dashboard '%s'", operation.DashboardID, dashboard.Slug)
+				m.insertOperation(newDeleteOperation(operation.OrganizationID,
+					operation.DashboardID, dashboard.Slug))
+			}
+			dashboard.Slug = slug
+		} else {
+			log.Logger.Debugf("Dashboard '%s' was removed, journaling removal "+
+				"of grafana dashboard '%s'", operation.DashboardID, slug)
+			m.insertOperation(newDeleteOperation(operation.OrganizationID,
+				operation.DashboardID, slug))
+		}
+	}
+	delete(m.operations, operation.ID)
+	return nil
+}
+
+// ClaimGrafanaOperation reserves operation for provided owner for
+// GrafanaOperationLease. Operation can be claimed by its current owner or by
+// anybody after lease of current owner expired
+func (m *MemoryManager) ClaimGrafanaOperation(
+	operation *models.GrafanaOperation, owner string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	stored, ok := m.operations[operation.ID]
+	if !ok || (stored.Owner != owner && !stored.LockedUntil.Before(now)) {
+		return false, nil
+	}
+	stored.Owner = owner
+	stored.LockedUntil = now.Add(GrafanaOperationLease)
+	operation.Owner = stored.Owner
+	operation.LockedUntil = stored.LockedUntil
+	return true, nil
+}
+
+// FailGrafanaOperation stores failed attempt of operation
+func (m *MemoryManager) FailGrafanaOperation(operation *models.GrafanaOperation,
+	lastError string) error {
+	operation.Attempts++
+	operation.LastError = lastError
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if stored, ok := m.operations[operation.ID]; ok {
+		stored.Attempts = operation.Attempts
+		stored.LastError = operation.LastError
+	}
+	return nil
+}
+
+// auditRecordMatches checks if audit record is described by filter the same
+// way getAuditQuery does
+func auditRecordMatches(record *models.AuditRecord, filter AuditFilter) bool {
+	columnValues := []struct {
+		stored   string
+		expected string
+	}{
+		{record.UserID, filter.UserID},
+		{record.OrganizationID, filter.OrganizationID},
+		{record.Method, filter.Method},
+		{record.Route, filter.Route},
+		{record.Outcome, filter.Outcome},
+	}
+	for _, columnValue := range columnValues {
+		if columnValue.expected != "" &&
+			columnValue.stored != columnValue.expected {
+			return false
+		}
+	}
+
+	if filter.Target != "" {
+		targets := map[string]string{}
+		if json.Unmarshal([]byte(record.Targets), &targets) != nil {
+			return false
+		}
+		targetFound := false
+		for _, target := range targets {
+			if target == filter.Target {
+				targetFound = true
+			}
+		}
+		if !targetFound {
+			return false
+		}
+	}
+	if !filter.Since.IsZero() && record.CreatedAt.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !record.CreatedAt.Before(filter.Until) {
+		return false
+	}
+	return true
+}
+
+// CreateAuditRecord stores audit record of api call
+func (m *MemoryManager) CreateAuditRecord(record *models.AuditRecord) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// id and creation time are set the same way xorm does on insert
+	m.lastAuditRecordID++
+	record.ID = m.lastAuditRecordID
+	record.CreatedAt = time.Now()
+	recordCopy := *record
+	m.auditRecords = append(m.auditRecords, &recordCopy)
+	return nil
+}
+
+// QueryAuditRecords returns audit records matching filter, newest first
+func (m *MemoryManager) QueryAuditRecords(filter AuditFilter) (
+	[]*models.AuditRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	records := []*models.AuditRecord{}
+	for i := len(m.auditRecords) - 1; i >= 0; i-- {
+		if auditRecordMatches(m.auditRecords[i], filter) {
+			recordCopy := *m.auditRecords[i]
+			records = append(records, &recordCopy)
+		}
+	}
+
+	if filter.Offset >= len(records) {
+		return []*models.AuditRecord{}, nil
+	}
+	records = records[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(records) {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// RevokeToken stores id of revoked jwt token. False is returned if token is
+// already revoked
+func (m *MemoryManager) RevokeToken(tokenID string, expiresAt time.Time) (
+	bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.revokedTokens[tokenID]; ok {
+		return false, nil
+	}
+	m.revokedTokens[tokenID] = &models.RevokedToken{
+		ID:        tokenID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+	delete(m.keystoneTokens, tokenID)
+	return true, nil
+}
+
+// IsTokenRevoked checks if jwt token of provided id was revoked
+func (m *MemoryManager) IsTokenRevoked(tokenID string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.revokedTokens[tokenID]
+	return ok, nil
+}
+
+// SaveKeystoneToken stores keystone token of jwt token. Tokens are kept in
+// process memory only, so they are not encrypted
+func (m *MemoryManager) SaveKeystoneToken(tokenID, keystoneTokenID string,
+	expiresAt time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.keystoneTokens[tokenID] = &models.KeystoneToken{
+		TokenID:         tokenID,
+		KeystoneTokenID: keystoneTokenID,
+		ExpiresAt:       expiresAt,
+		CreatedAt:       time.Now(),
+	}
+	return nil
+}
+
+// GetKeystoneToken returns keystone token of jwt token, empty string is
+// returned if there is none
+func (m *MemoryManager) GetKeystoneToken(tokenID string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	token, ok := m.keystoneTokens[tokenID]
+	if !ok {
+		return "", nil
+	}
+	return token.KeystoneTokenID, nil
+}
+
+// DeleteExpiredTokens removes revoked and keystone tokens of jwt tokens
+// expired before provided time
+func (m *MemoryManager) DeleteExpiredTokens(now time.Time) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var removed int64
+	for id, token := range m.revokedTokens {
+		if token.ExpiresAt.Before(now) {
+			delete(m.revokedTokens, id)
+			removed++
+		}
+	}
+	for id, token := range m.keystoneTokens {
+		if token.ExpiresAt.Before(now) {
+			delete(m.keystoneTokens, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// GetOrganizationMapping returns mapping of project, nil is returned if
+// project is not mapped yet
+func (m *MemoryManager) GetOrganizationMapping(projectID string) (
+	*models.OrganizationMapping, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mapping, ok := m.mappings[projectID]
+	if !ok {
+		return nil, nil
+	}
+	mappingCopy := *mapping
+	return &mappingCopy, nil
+}
+
+// QueryOrganizationMappings returns mappings of all projects ordered by
+// project id
+func (m *MemoryManager) QueryOrganizationMappings() (
+	[]*models.OrganizationMapping, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mappings := []*models.OrganizationMapping{}
+	for _, mapping := range m.mappings {
+		mappingCopy := *mapping
+		mappings = append(mappings, &mappingCopy)
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].ProjectID < mappings[j].ProjectID
+	})
+	return mappings, nil
+}
+
+// QueryOrganizationMappingsOfOrganization returns mappings of projects
+// mapped to organization ordered by project id
+func (m *MemoryManager) QueryOrganizationMappingsOfOrganization(
+	organizationID string) ([]*models.OrganizationMapping, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mappings := []*models.OrganizationMapping{}
+	for _, mapping := range m.mappings {
+		if mapping.OrganizationID != organizationID {
+			continue
+		}
+		mappingCopy := *mapping
+		mappings = append(mappings, &mappingCopy)
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].ProjectID < mappings[j].ProjectID
+	})
+	return mappings, nil
+}
+
+// SaveOrganizationMapping creates or replaces mapping of project
+func (m *MemoryManager) SaveOrganizationMapping(
+	mapping *models.OrganizationMapping) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mapping.UpdatedAt = time.Now()
+	mappingCopy := *mapping
+	m.mappings[mapping.ProjectID] = &mappingCopy
+	return nil
+}
+
+// DeleteOrganizationMapping removes mapping of project
+func (m *MemoryManager) DeleteOrganizationMapping(projectID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.mappings, projectID)
+	return nil
+}
+
+// RemapOrganizations moves visualizations, templates and pending grafana
+// operations of projects to their new organizations and updates mappings.
+// All organizations are remapped at once, so organization could get id of
+// other remapped organization
+func (m *MemoryManager) RemapOrganizations(remaps []*OrganizationRemap) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	newOrganizationIDs := map[string]string{}
+	for _, remap := range remaps {
+		newOrganizationIDs[remap.OldOrganizationID] = remap.NewOrganizationID
+		remap.RewrittenVisualizations = 0
+	}
+	rewrittenVisualizations := map[string]int64{}
+	for _, visualization := range m.visualizations {
+		if newID, ok := newOrganizationIDs[visualization.OrganizationID]; ok {
+			rewrittenVisualizations[visualization.OrganizationID]++
+			visualization.OrganizationID = newID
+		}
+	}
+	for _, template := range m.templates {
+		if newID, ok := newOrganizationIDs[template.OrganizationID]; ok {
+			template.OrganizationID = newID
+		}
+	}
+	for _, operation := range m.operations {
+		if newID, ok := newOrganizationIDs[operation.OrganizationID]; ok {
+			operation.OrganizationID = newID
+		}
+	}
+
+	now := time.Now()
+	for _, remap := range remaps {
+		remap.RewrittenVisualizations = rewrittenVisualizations[remap.OldOrganizationID]
+		if mapping, ok := m.mappings[remap.ProjectID]; ok {
+			mapping.OrganizationID = remap.NewOrganizationID
+			mapping.OrganizationName = remap.NewOrganizationName
+			mapping.UpdatedAt = now
+		}
+	}
+	return nil
+}
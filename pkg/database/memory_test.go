@@ -0,0 +1,660 @@
+package db
+
+import (
+	"io/ioutil"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/search"
+)
+
+func TestMemoryManagerTagsLookup(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	_, _, _, err := manager.CreateVisualizationsWithDashboards("first", "3",
+		map[string]interface{}{"env": "prod", "size": 1,
+			"owner": map[string]interface{}{"team": "ops"}},
+		[]string{"dashboard"}, []string{"template"})
+	assert.Nil(t, err)
+	_, _, _, err = manager.CreateVisualizationsWithDashboards("second", "3",
+		map[string]interface{}{"env": "dev"},
+		[]string{"dashboard"}, []string{"template"})
+	assert.Nil(t, err)
+	// visualization without dashboards is never returned, because
+	// visualizations are joined with dashboards
+	_, _, _, err = manager.CreateVisualizationsWithDashboards("empty", "3",
+		map[string]interface{}{"env": "prod"}, []string{}, []string{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description    string
+		name           string
+		organizationID string
+		tags           map[string]interface{}
+		expectedNames  []string
+	}{
+		{
+			description:    "organization filter",
+			organizationID: "3",
+			tags:           map[string]interface{}{},
+			expectedNames:  []string{"first", "second"},
+		},
+		{
+			description:    "other organization",
+			organizationID: "4",
+			tags:           map[string]interface{}{},
+			expectedNames:  []string{},
+		},
+		{
+			description:   "name filter",
+			name:          "second",
+			tags:          map[string]interface{}{},
+			expectedNames: []string{"second"},
+		},
+		{
+			description:   "string tag",
+			tags:          map[string]interface{}{"env": "prod"},
+			expectedNames: []string{"first"},
+		},
+		{
+			description:   "number tag does not match string",
+			tags:          map[string]interface{}{"size": "1"},
+			expectedNames: []string{},
+		},
+		{
+			description:   "number tag",
+			tags:          map[string]interface{}{"size": 1},
+			expectedNames: []string{"first"},
+		},
+		{
+			description:   "nested tag",
+			tags:          map[string]interface{}{"owner.team": "ops"},
+			expectedNames: []string{"first"},
+		},
+		{
+			description:   "missing tag",
+			tags:          map[string]interface{}{"missing": "prod"},
+			expectedNames: []string{},
+		},
+	}
+
+	for _, testCase := range tests {
+		result, err := manager.QueryVisualizationsDashboards("", testCase.name,
+			testCase.organizationID, testCase.tags)
+		assert.Nil(t, err, testCase.description)
+		names := []string{}
+		for visualization := range *result {
+			names = append(names, visualization.Name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, testCase.expectedNames, names, testCase.description)
+	}
+}
+
+func TestMemoryManagerPagination(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	slugs := map[string]string{}
+	for _, name := range []string{"c", "a", "b", "a"} {
+		visualization, _, _, err := manager.CreateVisualizationsWithDashboards(
+			name, "3", map[string]interface{}{}, []string{"dashboard"},
+			[]string{"template"})
+		assert.Nil(t, err)
+		slugs[name] = visualization.Slug
+	}
+
+	tests := []struct {
+		description string
+		pagination  Pagination
+		expectedIDs []int
+	}{
+		{
+			description: "creation order by default",
+			pagination:  Pagination{},
+			expectedIDs: []int{1, 2, 3, 4},
+		},
+		{
+			description: "name order, equal names are ordered by creation",
+			pagination:  Pagination{Sort: SortByName},
+			expectedIDs: []int{2, 4, 3, 1},
+		},
+		{
+			description: "limit and offset",
+			pagination:  Pagination{Limit: 2, Offset: 1, Sort: SortByName},
+			expectedIDs: []int{4, 3},
+		},
+		{
+			description: "offset after last visualization",
+			pagination:  Pagination{Offset: 10},
+			expectedIDs: []int{},
+		},
+		{
+			description: "marker",
+			pagination:  Pagination{Limit: 2, Marker: slugs["b"], Sort: SortByCreated},
+			expectedIDs: []int{4},
+		},
+		{
+			description: "marker sorted by name",
+			pagination:  Pagination{Limit: 2, Marker: slugs["b"], Sort: SortByName},
+			expectedIDs: []int{1},
+		},
+	}
+
+	for _, testCase := range tests {
+		page, err := manager.QueryVisualizationsPage("3", search.Query{},
+			testCase.pagination)
+		assert.Nil(t, err, testCase.description)
+		ids := []int{}
+		for _, visualization := range page.Visualizations {
+			ids = append(ids, visualization.ID)
+			assert.Equal(t, 1, len(page.Dashboards[visualization.ID]),
+				testCase.description)
+		}
+		assert.Equal(t, testCase.expectedIDs, ids, testCase.description)
+		assert.Equal(t, int64(4), page.Total, testCase.description)
+	}
+
+	_, err := manager.QueryVisualizationsPage("4", search.Query{},
+		Pagination{Marker: slugs["a"]})
+	assert.Equal(t, ErrMarkerNotFound, err, "marker of other organization")
+	_, err = manager.QueryVisualizationsPage("3", search.Query{},
+		Pagination{Sort: "tags"})
+	assert.EqualError(t, err, "unsupported sort 'tags'")
+}
+
+func TestMemoryManagerSearch(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	for name, tags := range map[string]map[string]interface{}{
+		"web-prod":  {"env": "prod", "owner": map[string]interface{}{"team": "web"}},
+		"web-dev":   {"env": "dev", "release.channel": "beta"},
+		"db-prod":   {"env": "prod", "critical": true},
+		"untagged%": {},
+	} {
+		_, _, _, err := manager.CreateVisualizationsWithDashboards(name, "3",
+			tags, []string{"dashboard"}, []string{"template"})
+		assert.Nil(t, err)
+	}
+
+	tests := []struct {
+		description   string
+		query         search.Query
+		expectedNames []string
+	}{
+		{
+			description:   "any of values",
+			query:         search.Query{{{"env", search.Equal, []interface{}{"prod", "dev"}}}},
+			expectedNames: []string{"db-prod", "web-dev", "web-prod"},
+		},
+		{
+			description:   "not equal matches missing tag",
+			query:         search.Query{{{"env", search.NotEqual, []interface{}{"prod"}}}},
+			expectedNames: []string{"untagged%", "web-dev"},
+		},
+		{
+			description:   "nested tag exists",
+			query:         search.Query{{{"owner.team", search.Exists, nil}}},
+			expectedNames: []string{"web-prod"},
+		},
+		{
+			description:   "tag name containing dot is matched as stored",
+			query:         search.Query{{{"release.channel", search.Equal, []interface{}{"beta"}}}},
+			expectedNames: []string{"web-dev"},
+		},
+		{
+			description:   "object tag is matched as a whole",
+			query:         search.Query{{{"owner", search.Equal, []interface{}{map[string]interface{}{"team": "web"}}}}},
+			expectedNames: []string{"web-prod"},
+		},
+		{
+			description:   "tag does not exist",
+			query:         search.Query{{{"env", search.NotExists, nil}}},
+			expectedNames: []string{"untagged%"},
+		},
+		{
+			description:   "name prefix",
+			query:         search.Query{{{"name", search.Prefix, []interface{}{"web-"}}}},
+			expectedNames: []string{"web-dev", "web-prod"},
+		},
+		{
+			description:   "name substring is not a pattern",
+			query:         search.Query{{{"name", search.Contains, []interface{}{"%"}}}},
+			expectedNames: []string{"untagged%"},
+		},
+		{
+			description: "or group and condition",
+			query: search.Query{
+				{
+					{"critical", search.Exists, nil},
+					{"env", search.Equal, []interface{}{"dev"}},
+				},
+				{{"name", search.NotEqual, []interface{}{"web-dev"}}},
+			},
+			expectedNames: []string{"db-prod"},
+		},
+	}
+
+	for _, testCase := range tests {
+		page, err := manager.QueryVisualizationsPage("3", testCase.query,
+			Pagination{Sort: SortByName})
+		assert.Nil(t, err, testCase.description)
+		names := []string{}
+		for _, visualization := range page.Visualizations {
+			names = append(names, visualization.Name)
+		}
+		assert.Equal(t, testCase.expectedNames, names, testCase.description)
+	}
+}
+
+func TestMemoryManagerDeleteWithOperations(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	visualization, dashboards, uploads, err := manager.CreateVisualizationsWithDashboards(
+		"first", "3", map[string]interface{}{}, []string{"uploaded", "pending"},
+		[]string{"first_template", "second_template"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(uploads))
+
+	// changes of returned models are not stored until they are saved
+	dashboards[0].Slug = "uploaded_slug"
+	stored, storedDashboards, err := manager.GetVisualizationWithDashboardsBySlug(
+		visualization.Slug, "3")
+	assert.Nil(t, err)
+	assert.Equal(t, visualization, stored)
+	for _, dashboard := range storedDashboards {
+		assert.Equal(t, "", dashboard.Slug)
+	}
+
+	err = manager.CompleteGrafanaOperation(uploads[0], "uploaded_slug")
+	assert.Nil(t, err)
+
+	deletions, err := manager.DeleteVisualizationWithOperations(visualization,
+		dashboards)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deletions))
+	assert.Equal(t, models.GrafanaOperationDelete, deletions[0].Operation)
+	assert.Equal(t, "uploaded_slug", deletions[0].Slug)
+
+	// dashboards are removed by cascade, pending upload is dropped
+	stored, storedDashboards, err = manager.GetVisualizationWithDashboardsBySlug(
+		visualization.Slug, "3")
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+	assert.Equal(t, 0, len(storedDashboards))
+	assert.Equal(t, 0, len(manager.dashboards))
+	operations, err := manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, deletions, operations)
+}
+
+func TestMemoryManagerUpdateDashboardSlug(t *testing.T) {
+	manager := NewMemoryManager()
+	visualization, dashboards, _, err := manager.CreateVisualizationsWithDashboards(
+		"first", "3", map[string]interface{}{}, []string{"first", "second"},
+		[]string{"first_template", "second_template"})
+	assert.Nil(t, err)
+
+	updated, err := manager.UpdateDashboardSlug(dashboards[0].ID, "",
+		"first_slug")
+	assert.Nil(t, err)
+	assert.True(t, updated)
+	// slug is not stored if dashboard was changed or removed
+	updated, err = manager.UpdateDashboardSlug(dashboards[0].ID, "",
+		"other_slug")
+	assert.Nil(t, err)
+	assert.False(t, updated)
+	updated, err = manager.UpdateDashboardSlug("missing", "", "other_slug")
+	assert.Nil(t, err)
+	assert.False(t, updated)
+
+	_, storedDashboards, err := manager.GetVisualizationWithDashboardsBySlug(
+		visualization.Slug, "3")
+	assert.Nil(t, err)
+	slugs := map[string]string{}
+	for _, dashboard := range storedDashboards {
+		slugs[dashboard.Name] = dashboard.Slug
+	}
+	assert.Equal(t, map[string]string{"first": "first_slug", "second": ""},
+		slugs)
+}
+
+func TestMemoryManagerUpdateWithOperations(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	visualization, dashboards, uploads, err := manager.CreateVisualizationsWithDashboards(
+		"first", "3", map[string]interface{}{}, []string{"changed", "removed"},
+		[]string{"old_template", "removed_template"})
+	assert.Nil(t, err)
+	for index, slug := range []string{"changed_slug", "removed_slug"} {
+		err = manager.CompleteGrafanaOperation(uploads[index], slug)
+		assert.Nil(t, err)
+		dashboards[index].Slug = slug
+	}
+
+	dashboards[0].RenderedTemplate = "new_template"
+	created := &models.Dashboard{ID: "created", Visualization: visualization.ID,
+		Name: "created", RenderedTemplate: "created_template"}
+	operations, err := manager.UpdateVisualizationWithOperations(visualization,
+		"second", map[string]interface{}{"tag": "value"},
+		[]*models.Dashboard{dashboards[0]}, []*models.Dashboard{created},
+		[]*models.Dashboard{dashboards[1]})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(operations))
+	assert.Equal(t, models.GrafanaOperationUpload, operations[0].Operation)
+	assert.Equal(t, "new_template", operations[0].Payload)
+	assert.Equal(t, models.GrafanaOperationUpload, operations[1].Operation)
+	assert.Equal(t, "created", operations[1].DashboardID)
+	assert.Equal(t, models.GrafanaOperationDelete, operations[2].Operation)
+	assert.Equal(t, "removed_slug", operations[2].Slug)
+
+	stored, storedDashboards, err := manager.GetVisualizationWithDashboardsBySlug(
+		visualization.Slug, "3")
+	assert.Nil(t, err)
+	assert.Equal(t, "second", stored.Name)
+	assert.Equal(t, 2, len(storedDashboards))
+	storedByName := map[string]*models.Dashboard{}
+	for _, dashboard := range storedDashboards {
+		storedByName[dashboard.Name] = dashboard
+	}
+	assert.Equal(t, "new_template", storedByName["changed"].RenderedTemplate)
+	assert.Equal(t, "created", storedByName["created"].ID)
+	journaled, err := manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, operations, journaled)
+
+	// renamed dashboard gets new slug, removal of previous one is journaled
+	err = manager.CompleteGrafanaOperation(operations[0], "renamed_slug")
+	assert.Nil(t, err)
+	journaled, err = manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(journaled))
+	assert.Equal(t, models.GrafanaOperationDelete, journaled[2].Operation)
+	assert.Equal(t, "changed_slug", journaled[2].Slug)
+	assert.Equal(t, "renamed_slug", manager.dashboards[dashboards[0].ID].Slug)
+}
+
+func TestMemoryManagerDeleteOrganizationVisualizations(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	for _, organizationID := range []string{"3", "3", "4"} {
+		_, _, _, err := manager.CreateVisualizationsWithDashboards("name",
+			organizationID, map[string]interface{}{}, []string{"dashboard"},
+			[]string{"template"})
+		assert.Nil(t, err)
+	}
+
+	removed, err := manager.DeleteOrganizationVisualizations("3")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), removed)
+
+	// visualizations, dashboards and pending uploads of other organizations
+	// are kept
+	organizationIDs, err := manager.QueryOrganizationIDs()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"4"}, organizationIDs)
+	assert.Equal(t, 1, len(manager.dashboards))
+	operations, err := manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(operations))
+	assert.Equal(t, "4", operations[0].OrganizationID)
+
+	removed, err = manager.DeleteOrganizationVisualizations("3")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), removed)
+}
+
+func TestMemoryManagerRemapOrganizations(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	for _, organizationID := range []string{"3", "3", "4"} {
+		_, _, _, err := manager.CreateVisualizationsWithDashboards("name",
+			organizationID, map[string]interface{}{}, []string{"dashboard"},
+			[]string{"template"})
+		assert.Nil(t, err)
+	}
+	for projectID, organizationID := range map[string]string{"a": "3",
+		"b": "4"} {
+		err := manager.SaveOrganizationMapping(&models.OrganizationMapping{
+			ProjectID: projectID, OrganizationID: organizationID,
+			OrganizationName: projectID})
+		assert.Nil(t, err)
+	}
+
+	// organizations were recreated in swapped order
+	remaps := []*OrganizationRemap{
+		{ProjectID: "a", OldOrganizationID: "3", NewOrganizationID: "4",
+			NewOrganizationName: "a"},
+		{ProjectID: "b", OldOrganizationID: "4", NewOrganizationID: "3",
+			NewOrganizationName: "renamed"},
+	}
+	assert.Nil(t, manager.RemapOrganizations(remaps))
+	assert.Equal(t, int64(2), remaps[0].RewrittenVisualizations)
+	assert.Equal(t, int64(1), remaps[1].RewrittenVisualizations)
+
+	visualizations, err := manager.QueryVisualizationsDashboards("", "", "4",
+		map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(*visualizations))
+	mappings, err := manager.QueryOrganizationMappings()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(mappings))
+	assert.Equal(t, "a", mappings[0].ProjectID)
+	assert.Equal(t, "4", mappings[0].OrganizationID)
+	assert.Equal(t, "3", mappings[1].OrganizationID)
+	assert.Equal(t, "renamed", mappings[1].OrganizationName)
+	mappings, err = manager.QueryOrganizationMappingsOfOrganization("3")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mappings))
+	assert.Equal(t, "b", mappings[0].ProjectID)
+
+	assert.Nil(t, manager.DeleteOrganizationMapping("a"))
+	mapping, err := manager.GetOrganizationMapping("a")
+	assert.Nil(t, err)
+	assert.Nil(t, mapping)
+}
+
+func TestMemoryManagerGrafanaOperations(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	_, dashboards, uploads, err := manager.CreateVisualizationsWithDashboards(
+		"first", "3", map[string]interface{}{}, []string{"dashboard"},
+		[]string{"template"})
+	assert.Nil(t, err)
+
+	operations, err := manager.QueryGrafanaOperations(
+		time.Now().Add(-time.Minute), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(operations), "recent operations are not returned")
+
+	// created operation is leased by its creator
+	claimed, err := manager.ClaimGrafanaOperation(uploads[0], "worker")
+	assert.Nil(t, err)
+	assert.False(t, claimed, "leased operation can not be claimed")
+	creator := uploads[0].Owner
+	claimed, err = manager.ClaimGrafanaOperation(uploads[0], creator)
+	assert.Nil(t, err)
+	assert.True(t, claimed, "operation is claimed by its owner")
+	manager.operations[uploads[0].ID].LockedUntil = time.Now().Add(-time.Second)
+	claimed, err = manager.ClaimGrafanaOperation(uploads[0], "worker")
+	assert.Nil(t, err)
+	assert.True(t, claimed, "expired lease is taken over")
+	assert.Equal(t, "worker", uploads[0].Owner)
+	claimed, err = manager.ClaimGrafanaOperation(uploads[0], creator)
+	assert.Nil(t, err)
+	assert.False(t, claimed, "creator lost lease")
+
+	err = manager.FailGrafanaOperation(uploads[0], "test")
+	assert.Nil(t, err)
+	operations, err = manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(operations), "exhausted operations are not returned")
+	operations, err = manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, operations[0].Attempts)
+	assert.Equal(t, "test", operations[0].LastError)
+
+	// dashboard removed before upload completed leads to journaled removal
+	err = manager.BulkDeleteDashboard(dashboards)
+	assert.Nil(t, err)
+	err = manager.CompleteGrafanaOperation(operations[0], "slug")
+	assert.Nil(t, err)
+	operations, err = manager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(operations))
+	assert.Equal(t, models.GrafanaOperationDelete, operations[0].Operation)
+	assert.Equal(t, "slug", operations[0].Slug)
+}
+
+func TestMemoryManagerAuditRecords(t *testing.T) {
+	manager := NewMemoryManager()
+	records := []*models.AuditRecord{
+		{UserID: "first", Method: "POST", Targets: `{}`,
+			Outcome: models.AuditOutcomeSuccess},
+		{UserID: "second", Method: "DELETE",
+			Targets: `{"visualizationID":"slug"}`,
+			Outcome: models.AuditOutcomeFailure},
+		{UserID: "first", Method: "DELETE",
+			Targets: `{"visualizationID":"slug"}`,
+			Outcome: models.AuditOutcomeSuccess},
+	}
+	for _, record := range records {
+		assert.Nil(t, manager.CreateAuditRecord(record))
+	}
+	assert.Equal(t, 3, records[2].ID, "id is set to stored record")
+
+	tests := []struct {
+		description string
+		filter      AuditFilter
+		expectedIDs []int
+	}{
+		{"all records newest first", AuditFilter{}, []int{3, 2, 1}},
+		{"user", AuditFilter{UserID: "first"}, []int{3, 1}},
+		{"target", AuditFilter{Target: "slug", Method: "DELETE"}, []int{3, 2}},
+		{"outcome", AuditFilter{Outcome: models.AuditOutcomeFailure}, []int{2}},
+		{"page", AuditFilter{Limit: 1, Offset: 1}, []int{2}},
+		{"offset after end", AuditFilter{Offset: 5}, []int{}},
+		{"since", AuditFilter{Since: time.Now().Add(time.Minute)}, []int{}},
+		{"until", AuditFilter{Until: time.Now().Add(time.Minute)}, []int{3, 2, 1}},
+	}
+	for _, testCase := range tests {
+		found, err := manager.QueryAuditRecords(testCase.filter)
+		assert.Nil(t, err, testCase.description)
+		ids := []int{}
+		for _, record := range found {
+			ids = append(ids, record.ID)
+		}
+		assert.Equal(t, testCase.expectedIDs, ids, testCase.description)
+	}
+}
+
+func TestMemoryManagerRevokedTokens(t *testing.T) {
+	manager := NewMemoryManager()
+	revoked, err := manager.RevokeToken("expired", time.Now().Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+	revoked, err = manager.RevokeToken("active", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	// token is revoked only once
+	revoked, err = manager.RevokeToken("active", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = manager.IsTokenRevoked("active")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+	revoked, err = manager.IsTokenRevoked("unknown")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	// expired tokens are removed by purge
+	removed, err := manager.DeleteExpiredTokens(time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+	revoked, err = manager.IsTokenRevoked("expired")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+	revoked, err = manager.IsTokenRevoked("active")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+}
+
+func TestMemoryManagerKeystoneTokens(t *testing.T) {
+	manager := NewMemoryManager()
+	assert.Nil(t, manager.SaveKeystoneToken("expired", "first",
+		time.Now().Add(-time.Minute)))
+	assert.Nil(t, manager.SaveKeystoneToken("active", "second",
+		time.Now().Add(time.Hour)))
+
+	keystoneToken, err := manager.GetKeystoneToken("active")
+	assert.Nil(t, err)
+	assert.Equal(t, "second", keystoneToken)
+	keystoneToken, err = manager.GetKeystoneToken("unknown")
+	assert.Nil(t, err)
+	assert.Equal(t, "", keystoneToken)
+
+	// expired tokens are removed by purge
+	removed, err := manager.DeleteExpiredTokens(time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), removed)
+	keystoneToken, err = manager.GetKeystoneToken("expired")
+	assert.Nil(t, err)
+	assert.Equal(t, "", keystoneToken)
+
+	// keystone token of revoked token is removed
+	_, err = manager.RevokeToken("active", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	keystoneToken, err = manager.GetKeystoneToken("active")
+	assert.Nil(t, err)
+	assert.Equal(t, "", keystoneToken)
+}
+
+func TestMemoryManagerTemplates(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	manager := NewMemoryManager()
+	first, err := manager.CreateTemplate("template", "3", 0,
+		map[string]interface{}{}, "first")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, first.Version)
+	second, err := manager.CreateTemplate("template", "3", 0,
+		map[string]interface{}{}, "second")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, second.Version)
+	_, err = manager.CreateTemplate("template", "3", 2,
+		map[string]interface{}{}, "duplicate")
+	assert.Equal(t, ErrDuplicate, err)
+
+	// templates of other organizations are neither visible nor taken into
+	// account by versioning
+	other, err := manager.CreateTemplate("template", "4", 0,
+		map[string]interface{}{}, "other")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, other.Version)
+	_, err = manager.CreateTemplate("template", "4", 3,
+		map[string]interface{}{}, "takeover")
+	assert.Nil(t, err)
+
+	templates, err := manager.QueryTemplates("3", "template", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.Template{second, first}, templates,
+		"latest version goes first")
+
+	deleted, err := manager.DeleteTemplate(first.ID, "4")
+	assert.Nil(t, err)
+	assert.Nil(t, deleted, "template of other organization is not removed")
+	deleted, err = manager.DeleteTemplate(first.ID, "3")
+	assert.Nil(t, err)
+	assert.Equal(t, first, deleted)
+}
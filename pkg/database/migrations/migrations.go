@@ -0,0 +1,124 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rubenv/sql-migrate"
+	"visualization-api/pkg/database"
+)
+
+// migration is content of single migration in sql-migrate format. Ids are
+// names of files migrations were applied from by sql-migrate tool, so
+// already deployed databases keep their migration records
+type migration struct {
+	id      string
+	content string
+}
+
+// Status describes single migration, AppliedAt is nil if migration is pending
+type Status struct {
+	ID        string
+	AppliedAt *time.Time
+}
+
+func getMigrations(driver string) ([]migration, error) {
+	switch driver {
+	case db.DriverMySQL:
+		return mysqlMigrations, nil
+	case db.DriverPostgres:
+		return postgresMigrations, nil
+	case db.DriverSQLite:
+		return sqliteMigrations, nil
+	}
+	return nil, fmt.Errorf("there are no migrations for database driver '%s'",
+		driver)
+}
+
+// getSource parses embedded migrations of database driver
+func getSource(driver string) (*migrate.MemoryMigrationSource, error) {
+	migrations, err := getMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	source := &migrate.MemoryMigrationSource{}
+	for _, embedded := range migrations {
+		parsed, parseErr := migrate.ParseMigration(embedded.id,
+			strings.NewReader(embedded.content))
+		if parseErr != nil {
+			return nil, fmt.Errorf("unable to parse migration '%s': '%s'",
+				embedded.id, parseErr)
+		}
+		source.Migrations = append(source.Migrations, parsed)
+	}
+	return source, nil
+}
+
+// Up applies all pending migrations, amount of applied migrations is returned
+func Up(connection *sql.DB, driver string) (int, error) {
+	source, err := getSource(driver)
+	if err != nil {
+		return 0, err
+	}
+	return migrate.Exec(connection, driver, source, migrate.Up)
+}
+
+// Down rolls back the latest applied migration, amount of rolled back
+// migrations is returned
+func Down(connection *sql.DB, driver string) (int, error) {
+	source, err := getSource(driver)
+	if err != nil {
+		return 0, err
+	}
+	return migrate.ExecMax(connection, driver, source, migrate.Down, 1)
+}
+
+// Pending returns ids of migrations, which are not applied yet
+func Pending(connection *sql.DB, driver string) ([]string, error) {
+	source, err := getSource(driver)
+	if err != nil {
+		return nil, err
+	}
+	planned, _, err := migrate.PlanMigration(connection, driver, source, migrate.Up, 0)
+	if err != nil {
+		return nil, err
+	}
+	pending := []string{}
+	for _, plannedMigration := range planned {
+		pending = append(pending, plannedMigration.Id)
+	}
+	return pending, nil
+}
+
+// GetStatus returns status of every embedded migration in order they are
+// applied
+func GetStatus(connection *sql.DB, driver string) ([]Status, error) {
+	source, err := getSource(driver)
+	if err != nil {
+		return nil, err
+	}
+	records, err := migrate.GetMigrationRecords(connection, driver)
+	if err != nil {
+		return nil, err
+	}
+	appliedAt := map[string]time.Time{}
+	for _, record := range records {
+		appliedAt[record.Id] = record.AppliedAt
+	}
+
+	migrations, err := source.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, sourceMigration := range migrations {
+		status := Status{ID: sourceMigration.Id}
+		if applied, ok := appliedAt[sourceMigration.Id]; ok {
+			status.AppliedAt = &applied
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, driver := range []string{db.DriverMySQL, db.DriverPostgres,
+		db.DriverSQLite} {
+		embedded, err := getMigrations(driver)
+		assert.Nil(t, err, driver)
+		for _, migration := range embedded {
+			assert.True(t, strings.HasSuffix(migration.id, ".sql"),
+				"%s: id '%s' matches name of migration file", driver, migration.id)
+			assert.Contains(t, migration.content, "-- +migrate Up", driver)
+			assert.Contains(t, migration.content, "-- +migrate Down", driver)
+		}
+
+		source, err := getSource(driver)
+		assert.Nil(t, err, driver)
+		assert.Equal(t, len(embedded), len(source.Migrations), driver)
+	}
+}
+
+func TestMigrationsOfUnknownDriver(t *testing.T) {
+	source, err := getSource(db.DriverMemory)
+	assert.Nil(t, source)
+	assert.EqualError(t, err,
+		"there are no migrations for database driver 'memory'")
+}
@@ -0,0 +1,234 @@
+package migrations
+
+// mysqlTest is applied by already deployed databases, that's why it is kept
+const mysqlTest = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE user (
+    id int NOT NULL AUTO_INCREMENT,
+    usr_name Varchar(255) NOT NULL,
+    PRIMARY KEY(id)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE user;
+`
+
+const mysqlVisualizations = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE visualization (
+    id int unsigned NOT NULL AUTO_INCREMENT,
+    name Varchar(255) NOT NULL,
+    slug Varchar(36) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    tags json DEFAULT NULL,
+    PRIMARY KEY(id)
+);
+
+CREATE TABLE dashboard (
+    id Varchar(36) NOT NULL,
+    visualization_id int unsigned NOT NULL,
+    name Varchar(255) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    rendered_template MEDIUMTEXT NOT NULL,
+    PRIMARY KEY(id),
+    FOREIGN KEY (visualization_id)
+        REFERENCES visualization(id)
+        ON DELETE CASCADE
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE dashboard;
+DROP TABLE visualization;
+`
+
+const mysqlTemplates = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE template (
+    id int unsigned NOT NULL AUTO_INCREMENT,
+    name Varchar(255) NOT NULL,
+    version int unsigned NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    parameters json DEFAULT NULL,
+    body MEDIUMTEXT NOT NULL,
+    PRIMARY KEY(id),
+    UNIQUE KEY template_name_version (name, version)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE template;
+`
+
+const mysqlGrafanaOperations = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE grafana_operation (
+    id int unsigned NOT NULL AUTO_INCREMENT,
+    operation Varchar(16) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    dashboard_id Varchar(36) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    payload MEDIUMTEXT NOT NULL,
+    attempts int unsigned NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY(id),
+    KEY grafana_operation_dashboard (dashboard_id)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE grafana_operation;
+`
+
+const mysqlVisualizationTags = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE visualization_tag (
+    id int unsigned NOT NULL AUTO_INCREMENT,
+    visualization_id int unsigned NOT NULL,
+    name Varchar(255) NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY(id),
+    KEY visualization_tag_name_value (name, value(191)),
+    FOREIGN KEY (visualization_id)
+        REFERENCES visualization(id)
+        ON DELETE CASCADE
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE visualization_tag;
+`
+
+const mysqlAuditRecords = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE audit_record (
+    id int unsigned NOT NULL AUTO_INCREMENT,
+    user_id Varchar(64) NOT NULL,
+    user_name Varchar(255) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    is_admin BOOL NOT NULL,
+    method Varchar(16) NOT NULL,
+    route Varchar(255) NOT NULL,
+    path TEXT NOT NULL,
+    targets TEXT NOT NULL,
+    status int unsigned NOT NULL,
+    outcome Varchar(16) NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY(id),
+    KEY audit_record_created (created_at),
+    KEY audit_record_user (user_id),
+    KEY audit_record_organization (organization_id)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE audit_record;
+`
+
+const mysqlRevokedTokens = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE revoked_token (
+    id Varchar(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY(id),
+    KEY revoked_token_expires (expires_at)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE revoked_token;
+`
+
+const mysqlOrganizationMappings = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE organization_mapping (
+    project_id Varchar(64) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    organization_name Varchar(255) NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY(project_id),
+    KEY organization_mapping_organization (organization_id)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE organization_mapping;
+`
+
+const mysqlTemplatesOrganizationScope = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE template DROP INDEX template_name_version,
+    ADD UNIQUE KEY template_organization_name_version (organization_id, name, version);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE template DROP INDEX template_organization_name_version,
+    ADD UNIQUE KEY template_name_version (name, version);
+`
+
+const mysqlGrafanaOperationLease = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE grafana_operation ADD COLUMN owner Varchar(36) NOT NULL DEFAULT '',
+    ADD COLUMN locked_until DATETIME DEFAULT NULL;
+UPDATE grafana_operation SET locked_until = created_at;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE grafana_operation DROP COLUMN owner, DROP COLUMN locked_until;
+`
+
+const mysqlKeystoneTokens = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE keystone_token (
+    token_id Varchar(64) NOT NULL,
+    keystone_token_id TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY(token_id),
+    KEY keystone_token_expires (expires_at)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE keystone_token;
+`
+
+const mysqlAuditRecordProject = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE audit_record ADD COLUMN project_id Varchar(64) NOT NULL DEFAULT '';
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE audit_record DROP COLUMN project_id;
+`
+
+// mysqlMigrations are migrations of MySQL database in order they are applied
+var mysqlMigrations = []migration{
+	{"1_test.sql", mysqlTest},
+	{"1498257323_visualizations.sql", mysqlVisualizations},
+	{"1500383246_templates.sql", mysqlTemplates},
+	{"1500989341_grafana_operations.sql", mysqlGrafanaOperations},
+	{"1501513200_visualization_tags.sql", mysqlVisualizationTags},
+	{"1501772400_audit_records.sql", mysqlAuditRecords},
+	{"1502118000_revoked_tokens.sql", mysqlRevokedTokens},
+	{"1502463600_organization_mappings.sql", mysqlOrganizationMappings},
+	{"1502809200_templates_organization_scope.sql", mysqlTemplatesOrganizationScope},
+	{"1502895600_grafana_operation_lease.sql", mysqlGrafanaOperationLease},
+	{"1502982000_keystone_tokens.sql", mysqlKeystoneTokens},
+	{"1503068400_audit_record_project.sql", mysqlAuditRecordProject},
+}
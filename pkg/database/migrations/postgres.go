@@ -0,0 +1,226 @@
+package migrations
+
+const postgresVisualizations = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE visualization (
+    id serial NOT NULL,
+    name Varchar(255) NOT NULL,
+    slug Varchar(36) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    tags jsonb DEFAULT NULL,
+    PRIMARY KEY(id)
+);
+
+CREATE TABLE dashboard (
+    id Varchar(36) NOT NULL,
+    visualization_id integer NOT NULL,
+    name Varchar(255) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    rendered_template TEXT NOT NULL,
+    PRIMARY KEY(id),
+    FOREIGN KEY (visualization_id)
+        REFERENCES visualization(id)
+        ON DELETE CASCADE
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE dashboard;
+DROP TABLE visualization;
+`
+
+const postgresTemplates = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE template (
+    id serial NOT NULL,
+    name Varchar(255) NOT NULL,
+    version integer NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    parameters jsonb DEFAULT NULL,
+    body TEXT NOT NULL,
+    PRIMARY KEY(id),
+    CONSTRAINT template_name_version UNIQUE (name, version)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE template;
+`
+
+const postgresGrafanaOperations = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE grafana_operation (
+    id serial NOT NULL,
+    operation Varchar(16) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    dashboard_id Varchar(36) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(id)
+);
+
+CREATE INDEX grafana_operation_dashboard ON grafana_operation (dashboard_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE grafana_operation;
+`
+
+const postgresVisualizationTags = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE visualization_tag (
+    id serial NOT NULL,
+    visualization_id integer NOT NULL,
+    name Varchar(255) NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY(id),
+    FOREIGN KEY (visualization_id)
+        REFERENCES visualization(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX visualization_tag_name_value ON visualization_tag (name, value);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE visualization_tag;
+`
+
+const postgresAuditRecords = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE audit_record (
+    id serial NOT NULL,
+    user_id Varchar(64) NOT NULL,
+    user_name Varchar(255) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    is_admin boolean NOT NULL,
+    method Varchar(16) NOT NULL,
+    route Varchar(255) NOT NULL,
+    path TEXT NOT NULL,
+    targets TEXT NOT NULL,
+    status integer NOT NULL,
+    outcome Varchar(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(id)
+);
+
+CREATE INDEX audit_record_created ON audit_record (created_at);
+CREATE INDEX audit_record_user ON audit_record (user_id);
+CREATE INDEX audit_record_organization ON audit_record (organization_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE audit_record;
+`
+
+const postgresRevokedTokens = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE revoked_token (
+    id Varchar(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(id)
+);
+
+CREATE INDEX revoked_token_expires ON revoked_token (expires_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE revoked_token;
+`
+
+const postgresOrganizationMappings = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE organization_mapping (
+    project_id Varchar(64) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    organization_name Varchar(255) NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(project_id)
+);
+
+CREATE INDEX organization_mapping_organization ON organization_mapping (organization_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE organization_mapping;
+`
+
+const postgresTemplatesOrganizationScope = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE template DROP CONSTRAINT template_name_version;
+ALTER TABLE template ADD CONSTRAINT template_organization_name_version
+    UNIQUE (organization_id, name, version);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE template DROP CONSTRAINT template_organization_name_version;
+ALTER TABLE template ADD CONSTRAINT template_name_version UNIQUE (name, version);
+`
+
+const postgresGrafanaOperationLease = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE grafana_operation ADD COLUMN owner Varchar(36) NOT NULL DEFAULT '';
+ALTER TABLE grafana_operation ADD COLUMN locked_until TIMESTAMP DEFAULT NULL;
+UPDATE grafana_operation SET locked_until = created_at;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE grafana_operation DROP COLUMN owner;
+ALTER TABLE grafana_operation DROP COLUMN locked_until;
+`
+
+const postgresKeystoneTokens = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE keystone_token (
+    token_id Varchar(64) NOT NULL,
+    keystone_token_id TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(token_id)
+);
+
+CREATE INDEX keystone_token_expires ON keystone_token (expires_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE keystone_token;
+`
+
+const postgresAuditRecordProject = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE audit_record ADD COLUMN project_id Varchar(64) NOT NULL DEFAULT '';
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+ALTER TABLE audit_record DROP COLUMN project_id;
+`
+
+// postgresMigrations are migrations of PostgreSQL database in order they are applied
+var postgresMigrations = []migration{
+	{"1498257323_visualizations.sql", postgresVisualizations},
+	{"1500383246_templates.sql", postgresTemplates},
+	{"1500989341_grafana_operations.sql", postgresGrafanaOperations},
+	{"1501513200_visualization_tags.sql", postgresVisualizationTags},
+	{"1501772400_audit_records.sql", postgresAuditRecords},
+	{"1502118000_revoked_tokens.sql", postgresRevokedTokens},
+	{"1502463600_organization_mappings.sql", postgresOrganizationMappings},
+	{"1502809200_templates_organization_scope.sql", postgresTemplatesOrganizationScope},
+	{"1502895600_grafana_operation_lease.sql", postgresGrafanaOperationLease},
+	{"1502982000_keystone_tokens.sql", postgresKeystoneTokens},
+	{"1503068400_audit_record_project.sql", postgresAuditRecordProject},
+}
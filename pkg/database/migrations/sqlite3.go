@@ -0,0 +1,281 @@
+package migrations
+
+const sqliteVisualizations = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE visualization (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name Varchar(255) NOT NULL,
+    slug Varchar(36) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    tags TEXT DEFAULT NULL
+);
+
+CREATE TABLE dashboard (
+    id Varchar(36) NOT NULL,
+    visualization_id INTEGER NOT NULL,
+    name Varchar(255) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    rendered_template TEXT NOT NULL,
+    PRIMARY KEY(id),
+    FOREIGN KEY (visualization_id)
+        REFERENCES visualization(id)
+        ON DELETE CASCADE
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE dashboard;
+DROP TABLE visualization;
+`
+
+const sqliteTemplates = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE template (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name Varchar(255) NOT NULL,
+    version INTEGER NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    parameters TEXT DEFAULT NULL,
+    body TEXT NOT NULL,
+    CONSTRAINT template_name_version UNIQUE (name, version)
+);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE template;
+`
+
+const sqliteGrafanaOperations = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE grafana_operation (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation Varchar(16) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    dashboard_id Varchar(36) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX grafana_operation_dashboard ON grafana_operation (dashboard_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE grafana_operation;
+`
+
+const sqliteVisualizationTags = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE visualization_tag (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    visualization_id INTEGER NOT NULL,
+    name Varchar(255) NOT NULL,
+    value TEXT NOT NULL,
+    FOREIGN KEY (visualization_id)
+        REFERENCES visualization(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX visualization_tag_name_value ON visualization_tag (name, value);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE visualization_tag;
+`
+
+const sqliteAuditRecords = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE audit_record (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id Varchar(64) NOT NULL,
+    user_name Varchar(255) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    is_admin BOOLEAN NOT NULL,
+    method Varchar(16) NOT NULL,
+    route Varchar(255) NOT NULL,
+    path TEXT NOT NULL,
+    targets TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    outcome Varchar(16) NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX audit_record_created ON audit_record (created_at);
+CREATE INDEX audit_record_user ON audit_record (user_id);
+CREATE INDEX audit_record_organization ON audit_record (organization_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE audit_record;
+`
+
+const sqliteRevokedTokens = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE revoked_token (
+    id Varchar(64) NOT NULL PRIMARY KEY,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX revoked_token_expires ON revoked_token (expires_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE revoked_token;
+`
+
+const sqliteOrganizationMappings = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE organization_mapping (
+    project_id Varchar(64) NOT NULL PRIMARY KEY,
+    organization_id Varchar(36) NOT NULL,
+    organization_name Varchar(255) NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX organization_mapping_organization ON organization_mapping (organization_id);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE organization_mapping;
+`
+
+// sqlite can not change constraints of table, so template table is recreated
+const sqliteTemplatesOrganizationScope = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE template_scoped (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name Varchar(255) NOT NULL,
+    version INTEGER NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    parameters TEXT DEFAULT NULL,
+    body TEXT NOT NULL,
+    CONSTRAINT template_organization_name_version UNIQUE (organization_id, name, version)
+);
+INSERT INTO template_scoped (id, name, version, organization_id, parameters, body)
+    SELECT id, name, version, organization_id, parameters, body FROM template;
+DROP TABLE template;
+ALTER TABLE template_scoped RENAME TO template;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+CREATE TABLE template_global (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name Varchar(255) NOT NULL,
+    version INTEGER NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    parameters TEXT DEFAULT NULL,
+    body TEXT NOT NULL,
+    CONSTRAINT template_name_version UNIQUE (name, version)
+);
+INSERT INTO template_global (id, name, version, organization_id, parameters, body)
+    SELECT id, name, version, organization_id, parameters, body FROM template;
+DROP TABLE template;
+ALTER TABLE template_global RENAME TO template;
+`
+
+// sqlite can not drop columns, so grafana_operation table is recreated on
+// rollback
+const sqliteGrafanaOperationLease = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE grafana_operation ADD COLUMN owner Varchar(36) NOT NULL DEFAULT '';
+ALTER TABLE grafana_operation ADD COLUMN locked_until DATETIME DEFAULT NULL;
+UPDATE grafana_operation SET locked_until = created_at;
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+CREATE TABLE grafana_operation_unleased (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    operation Varchar(16) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    dashboard_id Varchar(36) NOT NULL,
+    slug Varchar(255) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+INSERT INTO grafana_operation_unleased (id, operation, organization_id,
+    dashboard_id, slug, payload, attempts, last_error, created_at)
+    SELECT id, operation, organization_id, dashboard_id, slug, payload,
+        attempts, last_error, created_at FROM grafana_operation;
+DROP TABLE grafana_operation;
+ALTER TABLE grafana_operation_unleased RENAME TO grafana_operation;
+CREATE INDEX grafana_operation_dashboard ON grafana_operation (dashboard_id);
+`
+
+const sqliteKeystoneTokens = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE TABLE keystone_token (
+    token_id Varchar(64) NOT NULL PRIMARY KEY,
+    keystone_token_id TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX keystone_token_expires ON keystone_token (expires_at);
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP TABLE keystone_token;
+`
+
+// sqlite can not drop columns, so audit_record table is recreated on rollback
+const sqliteAuditRecordProject = `-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE audit_record ADD COLUMN project_id Varchar(64) NOT NULL DEFAULT '';
+
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+CREATE TABLE audit_record_unscoped (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id Varchar(64) NOT NULL,
+    user_name Varchar(255) NOT NULL,
+    organization_id Varchar(36) NOT NULL,
+    is_admin BOOLEAN NOT NULL,
+    method Varchar(16) NOT NULL,
+    route Varchar(255) NOT NULL,
+    path TEXT NOT NULL,
+    targets TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    outcome Varchar(16) NOT NULL,
+    created_at DATETIME NOT NULL
+);
+INSERT INTO audit_record_unscoped (id, user_id, user_name, organization_id,
+    is_admin, method, route, path, targets, status, outcome, created_at)
+    SELECT id, user_id, user_name, organization_id, is_admin, method, route,
+        path, targets, status, outcome, created_at FROM audit_record;
+DROP TABLE audit_record;
+ALTER TABLE audit_record_unscoped RENAME TO audit_record;
+CREATE INDEX audit_record_created ON audit_record (created_at);
+CREATE INDEX audit_record_user ON audit_record (user_id);
+CREATE INDEX audit_record_organization ON audit_record (organization_id);
+`
+
+// sqliteMigrations are migrations of SQLite database in order they are applied
+var sqliteMigrations = []migration{
+	{"1498257323_visualizations.sql", sqliteVisualizations},
+	{"1500383246_templates.sql", sqliteTemplates},
+	{"1500989341_grafana_operations.sql", sqliteGrafanaOperations},
+	{"1501513200_visualization_tags.sql", sqliteVisualizationTags},
+	{"1501772400_audit_records.sql", sqliteAuditRecords},
+	{"1502118000_revoked_tokens.sql", sqliteRevokedTokens},
+	{"1502463600_organization_mappings.sql", sqliteOrganizationMappings},
+	{"1502809200_templates_organization_scope.sql", sqliteTemplatesOrganizationScope},
+	{"1502895600_grafana_operation_lease.sql", sqliteGrafanaOperationLease},
+	{"1502982000_keystone_tokens.sql", sqliteKeystoneTokens},
+	{"1503068400_audit_record_project.sql", sqliteAuditRecordProject},
+}
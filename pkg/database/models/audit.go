@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// AuditRecord describes mutating api call made by user
+type AuditRecord struct {
+	ID             int       `xorm:"autoincr pk 'id'"`
+	UserID         string    `xorm:"user_id"`
+	UserName       string    `xorm:"user_name"`
+	OrganizationID string    `xorm:"organization_id"`
+	ProjectID      string    `xorm:"project_id"`
+	IsAdmin        bool      `xorm:"is_admin"`
+	Method         string    `xorm:"method"`
+	Route          string    `xorm:"route"`
+	Path           string    `xorm:"path"`
+	Targets        string    `xorm:"targets"`
+	Status         int       `xorm:"status"`
+	Outcome        string    `xorm:"outcome"`
+	CreatedAt      time.Time `xorm:"created 'created_at'"`
+}
+
+// AuditOutcomeSuccess means that api call was completed
+const AuditOutcomeSuccess = "success"
+
+// AuditOutcomeFailure means that api call returned error response
+const AuditOutcomeFailure = "failure"
+
+// AuditRecordTableName describes database table name (not to use reflect)
+const AuditRecordTableName = "audit_record"
+
+// AuditRecordIDColumn describes database column name (not to use reflect)
+const AuditRecordIDColumn = "id"
+
+// AuditRecordUserColumn describes database column name (not to use reflect)
+const AuditRecordUserColumn = "user_id"
+
+// AuditRecordOrgColumn describes database column name (not to use reflect)
+const AuditRecordOrgColumn = "organization_id"
+
+// AuditRecordMethodColumn describes database column name (not to use reflect)
+const AuditRecordMethodColumn = "method"
+
+// AuditRecordRouteColumn describes database column name (not to use reflect)
+const AuditRecordRouteColumn = "route"
+
+// AuditRecordTargetsColumn describes database column name (not to use reflect)
+const AuditRecordTargetsColumn = "targets"
+
+// AuditRecordOutcomeColumn describes database column name (not to use reflect)
+const AuditRecordOutcomeColumn = "outcome"
+
+// AuditRecordCreatedColumn describes database column name (not to use reflect)
+const AuditRecordCreatedColumn = "created_at"
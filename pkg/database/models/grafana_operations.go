@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// GrafanaOperation represents pending grafana call journaled in db
+type GrafanaOperation struct {
+	ID             int       `xorm:"autoincr pk 'id'"`
+	Operation      string    `xorm:"operation"`
+	OrganizationID string    `xorm:"organization_id"`
+	DashboardID    string    `xorm:"dashboard_id"`
+	Slug           string    `xorm:"slug"`
+	Payload        string    `xorm:"payload"`
+	Attempts       int       `xorm:"attempts"`
+	LastError      string    `xorm:"last_error"`
+	CreatedAt      time.Time `xorm:"created 'created_at'"`
+	Owner          string    `xorm:"owner"`
+	LockedUntil    time.Time `xorm:"locked_until"`
+}
+
+// GrafanaOperationUpload means that rendered template of dashboard has to be
+// uploaded to grafana and received slug has to be stored to dashboard
+const GrafanaOperationUpload = "upload"
+
+// GrafanaOperationDelete means that grafana dashboard has to be removed
+const GrafanaOperationDelete = "delete"
+
+// GrafanaOperationTableName describes database table name (not to use reflect)
+const GrafanaOperationTableName = "grafana_operation"
+
+// GrafanaOperationTypeColumn describes database column name (not to use reflect)
+const GrafanaOperationTypeColumn = "operation"
+
+// GrafanaOperationOrgColumn describes database column name (not to use reflect)
+const GrafanaOperationOrgColumn = "organization_id"
+
+// GrafanaOperationDashboardColumn describes database column name (not to use reflect)
+const GrafanaOperationDashboardColumn = "dashboard_id"
+
+// GrafanaOperationAttemptsColumn describes database column name (not to use reflect)
+const GrafanaOperationAttemptsColumn = "attempts"
+
+// GrafanaOperationLastErrorColumn describes database column name (not to use reflect)
+const GrafanaOperationLastErrorColumn = "last_error"
+
+// GrafanaOperationCreatedColumn describes database column name (not to use reflect)
+const GrafanaOperationCreatedColumn = "created_at"
+
+// GrafanaOperationOwnerColumn describes database column name (not to use reflect)
+const GrafanaOperationOwnerColumn = "owner"
+
+// GrafanaOperationLockedUntilColumn describes database column name (not to use reflect)
+const GrafanaOperationLockedUntilColumn = "locked_until"
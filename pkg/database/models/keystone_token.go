@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// KeystoneToken describes keystone token jwt token was issued for. It is kept
+// on server side, so jwt token does not disclose it, and it is stored
+// encrypted. Token is identified by jti claim of jwt token
+type KeystoneToken struct {
+	TokenID         string    `xorm:"pk 'token_id'"`
+	KeystoneTokenID string    `xorm:"keystone_token_id"`
+	ExpiresAt       time.Time `xorm:"expires_at"`
+	CreatedAt       time.Time `xorm:"created 'created_at'"`
+}
+
+// KeystoneTokenTableName describes database table name (not to use reflect)
+const KeystoneTokenTableName = "keystone_token"
+
+// KeystoneTokenIDColumn describes database column name (not to use reflect)
+const KeystoneTokenIDColumn = "token_id"
+
+// KeystoneTokenExpiresColumn describes database column name (not to use reflect)
+const KeystoneTokenExpiresColumn = "expires_at"
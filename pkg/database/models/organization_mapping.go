@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// OrganizationMapping binds openstack project to grafana organization.
+// Visualizations and templates of project are stored with id of its
+// organization, so it has to stay stable when organizations are recreated
+type OrganizationMapping struct {
+	ProjectID        string    `xorm:"pk 'project_id'"`
+	OrganizationID   string    `xorm:"organization_id"`
+	OrganizationName string    `xorm:"organization_name"`
+	UpdatedAt        time.Time `xorm:"updated 'updated_at'"`
+}
+
+// OrganizationMappingTableName describes database table name (not to use reflect)
+const OrganizationMappingTableName = "organization_mapping"
+
+// OrganizationMappingProjectColumn describes database column name (not to use reflect)
+const OrganizationMappingProjectColumn = "project_id"
+
+// OrganizationMappingOrgColumn describes database column name (not to use reflect)
+const OrganizationMappingOrgColumn = "organization_id"
+
+// OrganizationMappingNameColumn describes database column name (not to use reflect)
+const OrganizationMappingNameColumn = "organization_name"
+
+// OrganizationMappingUpdatedColumn describes database column name (not to use reflect)
+const OrganizationMappingUpdatedColumn = "updated_at"
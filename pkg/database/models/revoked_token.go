@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RevokedToken describes jwt token, which is not accepted any more although
+// it is not expired. Token is identified by its jti claim
+type RevokedToken struct {
+	ID        string    `xorm:"pk 'id'"`
+	ExpiresAt time.Time `xorm:"expires_at"`
+	CreatedAt time.Time `xorm:"created 'created_at'"`
+}
+
+// RevokedTokenTableName describes database table name (not to use reflect)
+const RevokedTokenTableName = "revoked_token"
+
+// RevokedTokenIDColumn describes database column name (not to use reflect)
+const RevokedTokenIDColumn = "id"
+
+// RevokedTokenExpiresColumn describes database column name (not to use reflect)
+const RevokedTokenExpiresColumn = "expires_at"
@@ -0,0 +1,26 @@
+package models
+
+// Template represents dashboard template in db
+type Template struct {
+	ID             int    `xorm:"autoincr pk 'id'"`
+	Name           string `xorm:"name"`
+	Version        int    `xorm:"version"`
+	OrganizationID string `xorm:"organization_id"`
+	Parameters     string `xorm:"parameters"`
+	Body           string `xorm:"body"`
+}
+
+// TemplateTableName describes database table name (not to use reflect)
+const TemplateTableName = "template"
+
+// TemplateIDColumn describes database column name (not to use reflect)
+const TemplateIDColumn = "id"
+
+// TemplateNameColumn describes database column name (not to use reflect)
+const TemplateNameColumn = "name"
+
+// TemplateVersionColumn describes database column name (not to use reflect)
+const TemplateVersionColumn = "version"
+
+// TemplateOrgColumn describes database column name (not to use reflect)
+const TemplateOrgColumn = "organization_id"
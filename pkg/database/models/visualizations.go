@@ -18,12 +18,30 @@ type Dashboard struct {
 	Slug             string `xorm:"slug"`
 }
 
+// VisualizationTag represents single tag of visualization in db. Nested tags
+// are stored with dot separated names, values are stored json encoded
+type VisualizationTag struct {
+	ID            int    `xorm:"autoincr pk 'id'"`
+	Visualization int    `xorm:"visualization_id"`
+	Name          string `xorm:"name"`
+	Value         string `xorm:"value"`
+}
+
 // DashboardTableName describes database table name (not to use reflect)
 const DashboardTableName = "dashboard"
 
+// DashboardIDColumn describes database column name (not to use reflect)
+const DashboardIDColumn = "id"
+
 // DashboardVisualizationColumn describes database column name (not to use reflect)
 const DashboardVisualizationColumn = "visualization_id"
 
+// DashboardRenderedTemplateColumn describes database column name (not to use reflect)
+const DashboardRenderedTemplateColumn = "rendered_template"
+
+// DashboardSlugColumn describes database column name (not to use reflect)
+const DashboardSlugColumn = "slug"
+
 // VisualizationTableName describes database table name (not to use reflect)
 const VisualizationTableName = "visualization"
 
@@ -41,3 +59,15 @@ const VisualizationNameColumn = "name"
 
 // VisualizationOrgColumn describes database column name (not to use reflect)
 const VisualizationOrgColumn = "organization_id"
+
+// VisualizationTagTableName describes database table name (not to use reflect)
+const VisualizationTagTableName = "visualization_tag"
+
+// VisualizationTagVisualizationColumn describes database column name (not to use reflect)
+const VisualizationTagVisualizationColumn = "visualization_id"
+
+// VisualizationTagNameColumn describes database column name (not to use reflect)
+const VisualizationTagNameColumn = "name"
+
+// VisualizationTagValueColumn describes database column name (not to use reflect)
+const VisualizationTagValueColumn = "value"
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-xorm/xorm"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// OrganizationRemap describes move of project data to other grafana
+// organization
+type OrganizationRemap struct {
+	ProjectID           string
+	OldOrganizationID   string
+	NewOrganizationID   string
+	NewOrganizationName string
+	// RewrittenVisualizations is amount of visualizations moved to new
+	// organization, it is set by RemapOrganizations
+	RewrittenVisualizations int64
+}
+
+// remapPlaceholder is organization id rows of remapped organization have
+// between two steps of remap. Grafana ids are positive numbers, so it is
+// never id of other organization
+func remapPlaceholder(remap *OrganizationRemap) string {
+	return "-" + remap.OldOrganizationID
+}
+
+// GetOrganizationMapping returns mapping of project, nil is returned if
+// project is not mapped yet
+func (m *XORMManager) GetOrganizationMapping(projectID string) (
+	*models.OrganizationMapping, error) {
+	mapping := &models.OrganizationMapping{}
+	found, err := m.engine.Id(projectID).Get(mapping)
+	if err != nil {
+		log.Logger.Errorf("Error on getting organization mapping of project "+
+			"'%s' from db: '%s'", projectID, err)
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return mapping, nil
+}
+
+// QueryOrganizationMappings returns mappings of all projects ordered by
+// project id
+func (m *XORMManager) QueryOrganizationMappings() (
+	[]*models.OrganizationMapping, error) {
+	mappings := []*models.OrganizationMapping{}
+	err := m.engine.Asc(models.OrganizationMappingProjectColumn).Find(
+		&mappings)
+	if err != nil {
+		log.Logger.Errorf("Error on getting organization mappings from db: "+
+			"'%s'", err)
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// QueryOrganizationMappingsOfOrganization returns mappings of projects
+// mapped to grafana organization ordered by project id
+func (m *XORMManager) QueryOrganizationMappingsOfOrganization(
+	organizationID string) ([]*models.OrganizationMapping, error) {
+	mappings := []*models.OrganizationMapping{}
+	err := m.engine.Where(fmt.Sprintf("%s = ?",
+		models.OrganizationMappingOrgColumn), organizationID).Asc(
+		models.OrganizationMappingProjectColumn).Find(&mappings)
+	if err != nil {
+		log.Logger.Errorf("Error on getting mappings of organization '%s' "+
+			"from db: '%s'", organizationID, err)
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// SaveOrganizationMapping creates or replaces mapping of project. Only
+// mapping is changed, data stored with id of previous organization is kept,
+// see RemapOrganizations
+func (m *XORMManager) SaveOrganizationMapping(
+	mapping *models.OrganizationMapping) error {
+	// concurrent logins of project users store the same mapping, that's
+	// why upsert is used
+	mapping.UpdatedAt = time.Now()
+	query := m.dialect.upsertQuery(models.OrganizationMappingTableName,
+		models.OrganizationMappingProjectColumn, []string{
+			models.OrganizationMappingProjectColumn,
+			models.OrganizationMappingOrgColumn,
+			models.OrganizationMappingNameColumn,
+			models.OrganizationMappingUpdatedColumn}, "(?, ?, ?, ?)")
+	_, err := m.engine.Exec(query, mapping.ProjectID, mapping.OrganizationID,
+		mapping.OrganizationName, mapping.UpdatedAt)
+	if err != nil {
+		log.Logger.Errorf("Error on storing organization mapping of "+
+			"project '%s' to db: '%s'", mapping.ProjectID, err)
+	}
+	return err
+}
+
+// DeleteOrganizationMapping removes mapping of project
+func (m *XORMManager) DeleteOrganizationMapping(projectID string) error {
+	_, err := m.engine.Id(projectID).Delete(&models.OrganizationMapping{})
+	return err
+}
+
+// remapOrganizationColumn changes organization id of rows in table from one
+// id to another
+func remapOrganizationColumn(session *xorm.Session, table, from,
+	to string) (int64, error) {
+	result, err := session.Exec(fmt.Sprintf(
+		"UPDATE %s SET organization_id = ? WHERE organization_id = ?", table),
+		to, from)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RemapOrganizations moves visualizations, templates and pending grafana
+// operations of projects to their new organizations and updates mappings in
+// one transaction. Rows are moved in two steps, so organization could get
+// id of other remapped organization. Audit records are history and are not
+// changed
+func (m *XORMManager) RemapOrganizations(remaps []*OrganizationRemap) error {
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err := session.Begin()
+	if err != nil {
+		return err
+	}
+
+	tables := []string{models.VisualizationTableName,
+		models.TemplateTableName, models.GrafanaOperationTableName}
+	for _, remap := range remaps {
+		for _, table := range tables {
+			rewritten, err := remapOrganizationColumn(session, table,
+				remap.OldOrganizationID, remapPlaceholder(remap))
+			if err != nil {
+				session.Rollback()
+				return err
+			}
+			if table == models.VisualizationTableName {
+				remap.RewrittenVisualizations = rewritten
+			}
+		}
+	}
+	for _, remap := range remaps {
+		for _, table := range tables {
+			_, err := remapOrganizationColumn(session, table,
+				remapPlaceholder(remap), remap.NewOrganizationID)
+			if err != nil {
+				session.Rollback()
+				return err
+			}
+		}
+		_, err = session.Id(remap.ProjectID).Cols(
+			models.OrganizationMappingOrgColumn,
+			models.OrganizationMappingNameColumn).Update(
+			&models.OrganizationMapping{
+				ProjectID:        remap.ProjectID,
+				OrganizationID:   remap.NewOrganizationID,
+				OrganizationName: remap.NewOrganizationName,
+			})
+		if err != nil {
+			session.Rollback()
+			return err
+		}
+	}
+
+	err = session.Commit()
+	if err != nil {
+		log.Logger.Errorf("Error on remapping organizations: '%s'", err)
+		return err
+	}
+	return nil
+}
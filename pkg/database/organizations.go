@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// DeleteOrganizationVisualizations removes all visualizations of organization
+// with their dashboards in one transaction and returns amount of removed
+// visualizations. Pending grafana operations of organization are dropped,
+// because organization is expected to be removed from grafana
+func (m *XORMManager) DeleteOrganizationVisualizations(
+	organizationID string) (int64, error) {
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err := session.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = session.Where(fmt.Sprintf("%s = ?",
+		models.GrafanaOperationOrgColumn), organizationID).Delete(
+		&models.GrafanaOperation{})
+	if err != nil {
+		session.Rollback()
+		log.Logger.Errorf("Error on removing grafana operations of "+
+			"organization '%s': '%s'", organizationID, err)
+		return 0, err
+	}
+
+	// dashboards and tags are removed by foreign key cascade
+	removed, err := session.Where(fmt.Sprintf("%s = ?",
+		models.VisualizationOrgColumn), organizationID).Delete(
+		&models.Visualization{})
+	if err != nil {
+		session.Rollback()
+		log.Logger.Errorf("Error on removing visualizations of "+
+			"organization '%s': '%s'", organizationID, err)
+		return 0, err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/search"
+)
+
+// SortByName orders visualizations by name
+const SortByName = "name"
+
+// SortByCreated orders visualizations in order they were created
+const SortByCreated = "created"
+
+// ErrMarkerNotFound is returned if page marker does not match any
+// visualization of organization
+var ErrMarkerNotFound = errors.New("marker not found")
+
+// Pagination describes requested page of visualizations. Zero Limit means
+// that all visualizations following Marker or Offset are returned. Marker is
+// slug of the last visualization of previous page
+type Pagination struct {
+	Limit  int
+	Offset int
+	Marker string
+	Sort   string
+}
+
+// VisualizationsPage is ordered page of visualizations with their dashboards.
+// Total is amount of all visualizations matching query
+type VisualizationsPage struct {
+	Visualizations []*models.Visualization
+	Dashboards     map[int][]*models.Dashboard
+	Total          int64
+}
+
+// getPageOrder returns columns visualizations are sorted by. Id is used as
+// creation order, because it is autoincremented
+func getPageOrder(sort string) ([]string, error) {
+	idColumn := fmt.Sprintf("%s.%s", models.VisualizationTableName,
+		models.VisualizationIDColumn)
+	switch sort {
+	case SortByCreated, "":
+		return []string{idColumn}, nil
+	case SortByName:
+		return []string{fmt.Sprintf("%s.%s", models.VisualizationTableName,
+			models.VisualizationNameColumn), idColumn}, nil
+	}
+	return nil, fmt.Errorf("unsupported sort '%s'", sort)
+}
+
+// getPageMarkerQuery returns condition matching visualizations following
+// marker in provided sort order
+func getPageMarkerQuery(sort string, marker *models.Visualization) (
+	string, []interface{}) {
+	if sort == SortByName {
+		return fmt.Sprintf("(%[1]s.%[2]s > ? OR (%[1]s.%[2]s = ? AND %[1]s.%[3]s > ?))",
+				models.VisualizationTableName, models.VisualizationNameColumn,
+				models.VisualizationIDColumn),
+			[]interface{}{marker.Name, marker.Name, marker.ID}
+	}
+	return fmt.Sprintf("%s.%s > ?", models.VisualizationTableName,
+		models.VisualizationIDColumn), []interface{}{marker.ID}
+}
+
+// QueryVisualizationsPage returns requested page of visualizations matching
+// search query and organizationID. Sorting, marker and limits are applied by
+// database
+func (m *XORMManager) QueryVisualizationsPage(organizationID string,
+	searchQuery search.Query, pagination Pagination) (
+	*VisualizationsPage, error) {
+	orderColumns, err := getPageOrder(pagination.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	noSlugProvided := ""
+	query, queryParams, err := getVisualizationLookupQuery(noSlugProvided,
+		organizationID, searchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := m.engine.Where(query, queryParams...).Count(
+		&models.Visualization{})
+	if err != nil {
+		log.Logger.Errorf("Error on counting visualizations in db: '%s'", err)
+		return nil, err
+	}
+
+	if pagination.Marker != "" {
+		marker := &models.Visualization{}
+		found, getErr := m.engine.Where(fmt.Sprintf("%s = ? AND %s = ?",
+			models.VisualizationSlugColumn, models.VisualizationOrgColumn),
+			pagination.Marker, organizationID).Get(marker)
+		if getErr != nil {
+			log.Logger.Errorf("Error on getting marker from db: '%s'", getErr)
+			return nil, getErr
+		}
+		if !found {
+			return nil, ErrMarkerNotFound
+		}
+		markerQuery, markerParams := getPageMarkerQuery(pagination.Sort, marker)
+		if query != "" {
+			query = query + " AND "
+		}
+		query = query + markerQuery
+		queryParams = append(queryParams, markerParams...)
+	}
+
+	session := m.engine.Where(query, queryParams...).Asc(orderColumns...)
+	if pagination.Limit > 0 || pagination.Offset > 0 {
+		// offset can not be used without limit in some databases
+		limit := pagination.Limit
+		if limit == 0 {
+			limit = math.MaxInt32
+		}
+		session = session.Limit(limit, pagination.Offset)
+	}
+	visualizations := []*models.Visualization{}
+	err = session.Find(&visualizations)
+	if err != nil {
+		log.Logger.Errorf("Error on getting visualizations from db: '%s'", err)
+		return nil, err
+	}
+
+	page := &VisualizationsPage{
+		Visualizations: visualizations,
+		Dashboards:     map[int][]*models.Dashboard{},
+		Total:          total,
+	}
+	if len(visualizations) == 0 {
+		return page, nil
+	}
+
+	visualizationIDs := []interface{}{}
+	for _, visualization := range visualizations {
+		visualizationIDs = append(visualizationIDs, visualization.ID)
+	}
+	dashboards := []*models.Dashboard{}
+	err = m.engine.In(models.DashboardVisualizationColumn,
+		visualizationIDs...).Find(&dashboards)
+	if err != nil {
+		log.Logger.Errorf("Error on getting dashboards from db: '%s'", err)
+		return nil, err
+	}
+	for _, dashboard := range dashboards {
+		page.Dashboards[dashboard.Visualization] = append(
+			page.Dashboards[dashboard.Visualization], dashboard)
+	}
+	return page, nil
+}
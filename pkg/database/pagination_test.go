@@ -0,0 +1,31 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/models"
+)
+
+func TestPageOrder(t *testing.T) {
+	columns, err := getPageOrder(SortByCreated)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"visualization.id"}, columns)
+	columns, err = getPageOrder(SortByName)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"visualization.name", "visualization.id"}, columns)
+	columns, err = getPageOrder("tags")
+	assert.Nil(t, columns)
+	assert.EqualError(t, err, "unsupported sort 'tags'")
+}
+
+func TestPageMarkerQuery(t *testing.T) {
+	marker := &models.Visualization{ID: 5, Name: "name"}
+	query, params := getPageMarkerQuery(SortByCreated, marker)
+	assert.Equal(t, "visualization.id > ?", query)
+	assert.Equal(t, []interface{}{5}, params)
+	query, params = getPageMarkerQuery(SortByName, marker)
+	assert.Equal(t, "(visualization.name > ? OR (visualization.name = ? AND visualization.id > ?))",
+		query)
+	assert.Equal(t, []interface{}{"name", "name", 5}, params)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// RevokeToken stores id of revoked jwt token. Token has to be stored only
+// until it expires, it is removed by DeleteExpiredTokens then. Keystone token
+// of revoked token is not needed any more, it is removed at once. Revoking of
+// already revoked token is not an error, false is returned then, so only one
+// of concurrent callers revokes token
+func (m *XORMManager) RevokeToken(tokenID string, expiresAt time.Time) (
+	bool, error) {
+	_, err := m.engine.Insert(&models.RevokedToken{
+		ID:        tokenID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		if m.dialect.isUniqueViolation(err) {
+			log.Logger.Debugf("Token '%s' is already revoked", tokenID)
+			return false, nil
+		}
+		log.Logger.Errorf("Error on storing revoked token to db: '%s'", err)
+		return false, err
+	}
+
+	_, err = m.engine.Where(fmt.Sprintf("%s = ?",
+		models.KeystoneTokenIDColumn), tokenID).Delete(&models.KeystoneToken{})
+	if err != nil {
+		log.Logger.Errorf("Error on removing keystone token of revoked "+
+			"token: '%s'", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// IsTokenRevoked checks if jwt token of provided id was revoked
+func (m *XORMManager) IsTokenRevoked(tokenID string) (bool, error) {
+	count, err := m.engine.Where(fmt.Sprintf("%s = ?",
+		models.RevokedTokenIDColumn), tokenID).Count(&models.RevokedToken{})
+	if err != nil {
+		log.Logger.Errorf("Error on getting revoked token from db: '%s'", err)
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/search"
+)
+
+// likeEscape is escape character of LIKE patterns. Backslash is not used,
+// because databases treat it differently in string literals
+const likeEscape = "!"
+
+var likeReplacer = strings.NewReplacer(likeEscape, likeEscape+likeEscape,
+	"%", likeEscape+"%", "_", likeEscape+"_")
+
+// joinConditions joins conditions with provided operator, result is wrapped
+// into parentheses if there are several of them
+func joinConditions(conditions []string, operator string) string {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return fmt.Sprintf("(%s)", strings.Join(conditions, operator))
+}
+
+// placeholders returns list of count ? placeholders
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+// getGroupCondition returns condition matching any of conditions of group
+func getGroupCondition(group search.Group) (string, []interface{}, error) {
+	conditions := []string{}
+	params := []interface{}{}
+	for _, condition := range group {
+		var query string
+		var conditionParams []interface{}
+		var err error
+		if condition.Field == search.NameField {
+			query, conditionParams = getNameCondition(condition)
+		} else {
+			query, conditionParams, err = getTagCondition(condition)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+		conditions = append(conditions, query)
+		params = append(params, conditionParams...)
+	}
+	return joinConditions(conditions, " OR "), params, nil
+}
+
+// getNameCondition returns condition comparing visualization name
+func getNameCondition(condition search.Condition) (string, []interface{}) {
+	column := fmt.Sprintf("%s.%s", models.VisualizationTableName,
+		models.VisualizationNameColumn)
+
+	switch {
+	case condition.Operator == search.Equal && len(condition.Values) == 1:
+		return fmt.Sprintf("%s = ?", column), condition.Values
+	case condition.Operator == search.Equal:
+		return fmt.Sprintf("%s IN (%s)", column,
+			placeholders(len(condition.Values))), condition.Values
+	case condition.Operator == search.NotEqual:
+		return fmt.Sprintf("%s NOT IN (%s)", column,
+			placeholders(len(condition.Values))), condition.Values
+	}
+
+	// LIKE is case sensitive in some databases, postgres for example, so
+	// both sides are folded to have the same results everywhere
+	conditions := []string{}
+	params := []interface{}{}
+	for _, value := range condition.Values {
+		pattern := likeReplacer.Replace(fmt.Sprint(value)) + "%"
+		if condition.Operator == search.Contains {
+			pattern = "%" + pattern
+		}
+		conditions = append(conditions, fmt.Sprintf(
+			"LOWER(%s) LIKE LOWER(?) ESCAPE '%s'", column, likeEscape))
+		params = append(params, pattern)
+	}
+	return joinConditions(conditions, " OR "), params
+}
+
+// getTagCondition returns condition looking for tag of visualization in tag
+// table. Values are compared json encoded, so their types have to match too
+func getTagCondition(condition search.Condition) (string, []interface{}, error) {
+	subquery := fmt.Sprintf("SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = %[3]s.%[4]s AND %[1]s.%[5]s = ?",
+		models.VisualizationTagTableName,
+		models.VisualizationTagVisualizationColumn,
+		models.VisualizationTableName, models.VisualizationIDColumn,
+		models.VisualizationTagNameColumn)
+	params := []interface{}{condition.Field}
+
+	if len(condition.Values) > 0 {
+		subquery = fmt.Sprintf("%s AND %s.%s IN (%s)", subquery,
+			models.VisualizationTagTableName, models.VisualizationTagValueColumn,
+			placeholders(len(condition.Values)))
+		for _, value := range condition.Values {
+			encodedValue, err := encodeTagValue(value)
+			if err != nil {
+				return "", nil, err
+			}
+			params = append(params, encodedValue)
+		}
+	}
+
+	// visualizations without tag do not equal to any value
+	if condition.Operator == search.NotEqual ||
+		condition.Operator == search.NotExists {
+		return fmt.Sprintf("NOT EXISTS (%s)", subquery), params, nil
+	}
+	return fmt.Sprintf("EXISTS (%s)", subquery), params, nil
+}
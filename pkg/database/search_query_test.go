@@ -0,0 +1,86 @@
+package db
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/search"
+)
+
+const tagSubquery = "SELECT 1 FROM visualization_tag WHERE visualization_tag.visualization_id = visualization.id AND visualization_tag.name = ?"
+
+func TestGroupCondition(t *testing.T) {
+	tests := []struct {
+		description    string
+		group          search.Group
+		expectedQuery  string
+		expectedParams []interface{}
+	}{
+		{
+			description:    "name equals any of values",
+			group:          search.Group{{"name", search.Equal, []interface{}{"a", "b"}}},
+			expectedQuery:  "visualization.name IN (?, ?)",
+			expectedParams: []interface{}{"a", "b"},
+		},
+		{
+			description:    "name differs from value",
+			group:          search.Group{{"name", search.NotEqual, []interface{}{"a"}}},
+			expectedQuery:  "visualization.name NOT IN (?)",
+			expectedParams: []interface{}{"a"},
+		},
+		{
+			description:    "name prefix is escaped",
+			group:          search.Group{{"name", search.Prefix, []interface{}{"50%_!"}}},
+			expectedQuery:  "LOWER(visualization.name) LIKE LOWER(?) ESCAPE '!'",
+			expectedParams: []interface{}{"50!%!_!!%"},
+		},
+		{
+			description:    "name contains any of values",
+			group:          search.Group{{"name", search.Contains, []interface{}{"a", "b"}}},
+			expectedQuery:  "(LOWER(visualization.name) LIKE LOWER(?) ESCAPE '!' OR LOWER(visualization.name) LIKE LOWER(?) ESCAPE '!')",
+			expectedParams: []interface{}{"%a%", "%b%"},
+		},
+		{
+			description:    "tag equals any of typed values",
+			group:          search.Group{{"env", search.Equal, []interface{}{"prod", 1.0, true}}},
+			expectedQuery:  "EXISTS (" + tagSubquery + " AND visualization_tag.value IN (?, ?, ?))",
+			expectedParams: []interface{}{"env", `"prod"`, "1", "true"},
+		},
+		{
+			description:    "nested tag differs from object",
+			group:          search.Group{{"owner", search.NotEqual, []interface{}{map[string]interface{}{"team": "ops", "lead": "bob"}}}},
+			expectedQuery:  "NOT EXISTS (" + tagSubquery + " AND visualization_tag.value IN (?))",
+			expectedParams: []interface{}{"owner", `{"lead":"bob","team":"ops"}`},
+		},
+		{
+			description:    "tag exists or is missing",
+			group:          search.Group{{"env", search.Exists, nil}, {"owner.team", search.NotExists, nil}},
+			expectedQuery:  "(EXISTS (" + tagSubquery + ") OR NOT EXISTS (" + tagSubquery + "))",
+			expectedParams: []interface{}{"env", "owner.team"},
+		},
+	}
+
+	for _, testCase := range tests {
+		query, params, err := getGroupCondition(testCase.group)
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, testCase.expectedQuery, query, testCase.description)
+		assert.Equal(t, testCase.expectedParams, params, testCase.description)
+	}
+
+	_, _, err := getGroupCondition(search.Group{{"env", search.Equal,
+		[]interface{}{func() {}}}})
+	assert.NotNil(t, err, "not serializable value")
+}
+
+func TestVisualizationLookupQuery(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	query, params, err := getVisualizationLookupQuery("slug", "3",
+		search.FromTags("name", map[string]interface{}{"tag1": "value1"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "visualization.slug = ? AND visualization.organization_id = ? AND "+
+		"visualization.name = ? AND EXISTS ("+tagSubquery+
+		" AND visualization_tag.value IN (?))", query)
+	assert.Equal(t, []interface{}{"slug", "3", "name", "tag1", `"value1"`}, params)
+}
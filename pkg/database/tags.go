@@ -0,0 +1,125 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/go-xorm/xorm"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+// encodeTagValue returns value the way it is stored in tag table. Maps are
+// encoded with sorted keys, so equal values are always encoded the same way
+func encodeTagValue(value interface{}) (string, error) {
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encodedValue), nil
+}
+
+// flattenTags appends rows of tag table describing provided tags to result.
+// Objects are stored both as a whole and field by field, so nested fields
+// could be queried too
+func flattenTags(visualizationID int, prefix string,
+	tags map[string]interface{}, result []*models.VisualizationTag) (
+	[]*models.VisualizationTag, error) {
+	names := []string{}
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fullName := name
+		if prefix != "" {
+			fullName = fmt.Sprintf("%s.%s", prefix, name)
+		}
+		encodedValue, err := encodeTagValue(tags[name])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &models.VisualizationTag{
+			Visualization: visualizationID,
+			Name:          fullName,
+			Value:         encodedValue,
+		})
+		if nested, ok := tags[name].(map[string]interface{}); ok {
+			result, err = flattenTags(visualizationID, fullName, nested, result)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// getVisualizationTags returns rows of tag table describing tags of
+// visualization
+func getVisualizationTags(visualization *models.Visualization) (
+	[]*models.VisualizationTag, error) {
+	tags := map[string]interface{}{}
+	if visualization.Tags != "" {
+		err := json.Unmarshal([]byte(visualization.Tags), &tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return flattenTags(visualization.ID, "", tags, []*models.VisualizationTag{})
+}
+
+// replaceVisualizationTags stores tags of visualization to tag table using
+// provided session, previously stored tags are removed
+func replaceVisualizationTags(session *xorm.Session,
+	visualization *models.Visualization) error {
+	_, err := session.Where(fmt.Sprintf("%s = ?",
+		models.VisualizationTagVisualizationColumn), visualization.ID).Delete(
+		&models.VisualizationTag{})
+	if err != nil {
+		return err
+	}
+	tags, err := getVisualizationTags(visualization)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err = session.Insert(tags)
+	return err
+}
+
+// RebuildVisualizationTags fills tag table with tags of all stored
+// visualizations. It is required for visualizations created before tag table
+// was added. Amount of processed visualizations is returned
+func (m *XORMManager) RebuildVisualizationTags() (int, error) {
+	visualizations := []*models.Visualization{}
+	err := m.engine.Find(&visualizations)
+	if err != nil {
+		return 0, err
+	}
+
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err = session.Begin()
+	if err != nil {
+		return 0, err
+	}
+	for _, visualization := range visualizations {
+		err = replaceVisualizationTags(session, visualization)
+		if err != nil {
+			log.Logger.Errorf("Error on storing tags of visualization '%s': '%s'",
+				visualization.Slug, err)
+			session.Rollback()
+			return 0, err
+		}
+	}
+	err = session.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(visualizations), nil
+}
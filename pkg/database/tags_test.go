@@ -0,0 +1,29 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/models"
+)
+
+func TestVisualizationTags(t *testing.T) {
+	visualization := &models.Visualization{ID: 3, Tags: `{"env": "prod",
+		"size": 1, "owner": {"team": "ops", "on_call": true}}`}
+	tags, err := getVisualizationTags(visualization)
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.VisualizationTag{
+		{Visualization: 3, Name: "env", Value: `"prod"`},
+		{Visualization: 3, Name: "owner", Value: `{"on_call":true,"team":"ops"}`},
+		{Visualization: 3, Name: "owner.on_call", Value: "true"},
+		{Visualization: 3, Name: "owner.team", Value: `"ops"`},
+		{Visualization: 3, Name: "size", Value: "1"},
+	}, tags)
+
+	tags, err = getVisualizationTags(&models.Visualization{ID: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.VisualizationTag{}, tags)
+
+	_, err = getVisualizationTags(&models.Visualization{ID: 3, Tags: "tags"})
+	assert.NotNil(t, err)
+}
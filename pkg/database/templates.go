@@ -0,0 +1,141 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/logging"
+)
+
+func getTemplateLookupQuery(organizationID, name string, version int) (
+	string, []interface{}) {
+	// create Query, with ? placeholders for queries. Templates are always
+	// filtered by organization, zero values of other arguments mean that
+	// templates are not filtered by corresponding column
+	queryChunks := []string{fmt.Sprintf("%s.%s = ?",
+		models.TemplateTableName, models.TemplateOrgColumn)}
+	queryParams := []interface{}{organizationID}
+
+	if name != "" {
+		queryChunks = append(queryChunks, fmt.Sprintf("%s.%s = ?",
+			models.TemplateTableName, models.TemplateNameColumn))
+		queryParams = append(queryParams, name)
+	}
+
+	if version != 0 {
+		queryChunks = append(queryChunks, fmt.Sprintf("%s.%s = ?",
+			models.TemplateTableName, models.TemplateVersionColumn))
+		queryParams = append(queryParams, version)
+	}
+
+	query := strings.Join(queryChunks, " AND ")
+
+	log.Logger.Debugf("Got template lookup query '%s'", query)
+	return query, queryParams
+}
+
+// QueryTemplates takes name and version and returns matched templates of
+// organization ordered from the latest version to the oldest one
+func (m *XORMManager) QueryTemplates(organizationID, name string,
+	version int) ([]*models.Template, error) {
+
+	query, queryParams := getTemplateLookupQuery(organizationID, name, version)
+
+	templates := []*models.Template{}
+	err := m.engine.Table(models.TemplateTableName).Where(
+		query, queryParams...).Desc(models.TemplateVersionColumn).Find(&templates)
+	if err != nil {
+		log.Logger.Errorf("Error on getting templates from db: '%s'", err)
+		return nil, err
+	}
+	return templates, nil
+}
+
+// CreateTemplate stores new version of template of organization. If version
+// is not provided (equals 0) - version next to the latest stored one is used.
+// ErrDuplicate is returned if organization already has such version
+func (m *XORMManager) CreateTemplate(name, organizationID string, version int,
+	parameters map[string]interface{}, body string) (*models.Template, error) {
+
+	log.Logger.Debugf("Creating new Template entry named '%s'", name)
+
+	// XORM processes json fields as serialized json string, the same way
+	// it is done for visualization tags
+	encodedParameters, err := json.Marshal(parameters)
+	if err != nil {
+		log.Logger.Errorf("Error on storing not serializable map[string]interface{}"+
+			" to json field : '%s'", err)
+		return nil, err
+	}
+
+	session := m.engine.NewSession()
+	defer session.Close()
+
+	err = session.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		latestTemplate := &models.Template{}
+		_, err = session.Where(fmt.Sprintf("%s = ? AND %s = ?",
+			models.TemplateOrgColumn, models.TemplateNameColumn),
+			organizationID, name).Desc(models.TemplateVersionColumn).Get(
+			latestTemplate)
+		if err != nil {
+			session.Rollback()
+			return nil, err
+		}
+		// latestTemplate.Version is 0 if template was not found
+		version = latestTemplate.Version + 1
+	}
+
+	template := &models.Template{
+		Name:           name,
+		Version:        version,
+		OrganizationID: organizationID,
+		Parameters:     string(encodedParameters),
+		Body:           body,
+	}
+
+	_, err = session.Insert(template)
+	if err != nil {
+		session.Rollback()
+		// concurrent request could store the same version after it was
+		// checked by api
+		if m.dialect.isUniqueViolation(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+
+	err = session.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// DeleteTemplate removes template owned by organization from db. nil is
+// returned if there is no such template
+func (m *XORMManager) DeleteTemplate(templateID int, organizationID string) (
+	*models.Template, error) {
+
+	template := &models.Template{}
+	found, err := m.engine.Where(fmt.Sprintf("%s = ? AND %s = ?",
+		models.TemplateIDColumn, models.TemplateOrgColumn), templateID, organizationID).Get(template)
+	if err != nil {
+		log.Logger.Errorf("Error on getting template from db: '%s'", err)
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	_, err = m.engine.Id(template.ID).Delete(&models.Template{})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
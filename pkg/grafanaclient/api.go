@@ -24,21 +24,36 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
+	neturl "net/url"
+	"strings"
 	"time"
 )
 
 const timeout = 5
 const grafanaOrgHeader = "X-Grafana-Org-Id"
+const dashboardSearchType = "dash-db"
+const dashboardURIPrefix = "db/"
+
+// ManagedDashboardTag marks grafana dashboards uploaded by this client, so
+// they could be told apart from dashboards created by users in grafana
+const ManagedDashboardTag = "visualization-api"
+
+// Roles of users in grafana organization
+const (
+	RoleViewer = "Viewer"
+	RoleEditor = "Editor"
+	RoleAdmin  = "Admin"
+)
 
 // SessionInterface Interface with all method definations
 type SessionInterface interface {
 	DoLogon() error
 	GetOrCreateOrgByName(string) (*OrgID, error)
-	CreateDataSource(DataSource) error
-	GetDataSourceName(string) (DataSource, error)
-	DeleteDataSource(int) error
-	GetDataSourceList() ([]DataSource, error)
-	GetDataSourceListID(int) (DataSource, error)
+	CreateDataSource(DataSource, string) (int, error)
+	GetDataSourceName(string, string) (DataSource, error)
+	DeleteDataSource(int, string) error
+	GetDataSourceList(string) ([]DataSource, error)
+	GetDataSourceListID(int, string) (DataSource, error)
 	GetUsers() ([]User, error)
 	GetUserID(int) (User, error)
 	CreateUser(AdminCreateUser) error
@@ -47,11 +62,16 @@ type SessionInterface interface {
 	CreateOrganization(Org) error
 	GetOrganizationID(int) (OrgList, error)
 	DeleteOrganization(int) error
+	UpdateOrganization(int, string) error
 	GetOrganizationUsers(int) ([]OrgUserList, error)
 	CreateOrganizationUser(int, CreateOrganizationUser) error
 	UploadDashboard([]byte, string, bool) (string, error)
 	DeleteDashboard(string, string) error
+	SearchDashboards(string) ([]DashboardSearchResult, error)
 	DeleteOrganizationUser(int, int) error
+	GetUserByLogin(string) (User, error)
+	AddOrganizationUser(int, string, string) error
+	UpdateOrganizationUserRole(int, int, string) error
 }
 
 // GrafanaError is a error structure to handle error messages in this library
@@ -191,6 +211,17 @@ type User struct {
 	Login string `json:"login"`
 }
 
+// DashboardSearchResult contains the json structure of Grafana dashboard
+// search entry
+type DashboardSearchResult struct {
+	ID    int      `json:"id"`
+	Title string   `json:"title"`
+	URI   string   `json:"uri"`
+	Type  string   `json:"type"`
+	Tags  []string `json:"tags"`
+	Slug  string   `json:"-"`
+}
+
 // NewSession It returns a Session struct pointer.
 func NewSession(user string, password string, url string) (*Session, error) {
 	jar, err := cookiejar.New(nil)
@@ -280,10 +311,10 @@ func (s *Session) DoLogon() (err error) {
 	return
 }
 
-// CreateDataSource creates a Grafana DataSource.
-// It take a DataSource struct in parameter.
-// It returns a error if it cannot perform the creation.
-func (s *Session) CreateDataSource(ds DataSource) (err error) {
+// CreateDataSource creates a Grafana DataSource in organization.
+// It take a DataSource struct and organization id in parameter.
+// It returns id of created DataSource and a error if it cannot perform the creation.
+func (s *Session) CreateDataSource(ds DataSource, orgID string) (dsID int, err error) {
 	reqURL := s.url + "/api/datasources"
 
 	jsonStr, err := json.Marshal(ds)
@@ -291,16 +322,34 @@ func (s *Session) CreateDataSource(ds DataSource) (err error) {
 		return
 	}
 
-	_, err = s.httpRequest("POST", reqURL, bytes.NewBuffer(jsonStr))
+	body, err := s.httpRequestWithOrgHeader("POST", reqURL, orgID, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 409 {
+				return 0, Exists{}
+			}
+			return 0, err
+		default:
+			return 0, err
+		}
+	}
 
+	var response struct {
+		ID      int    `json:"id"`
+		Message string `json:"message"`
+	}
+	dec := json.NewDecoder(body)
+	err = dec.Decode(&response)
+	dsID = response.ID
 	return
 }
 
-// GetDataSourceName get a existing DataSource by name.
+// GetDataSourceName get a existing DataSource of organization by name.
 // It return a DataSource struct.
 // It returns a error if a problem occurs when trying to retrieve the DataSource.
-func (s *Session) GetDataSourceName(name string) (ds DataSource, err error) {
-	dslist, err := s.GetDataSourceList()
+func (s *Session) GetDataSourceName(name, orgID string) (ds DataSource, err error) {
+	dslist, err := s.GetDataSourceList(orgID)
 	if err != nil {
 		return
 	}
@@ -313,30 +362,36 @@ func (s *Session) GetDataSourceName(name string) (ds DataSource, err error) {
 	return
 }
 
-// DeleteDataSource deletes a Grafana DataSource.
-// It take a existing DataSource struct in parameter.
+// DeleteDataSource deletes a Grafana DataSource of organization.
+// It take a existing DataSource id and organization id in parameter.
 // It returns a error if it cannot perform the deletion.
-func (s *Session) DeleteDataSource(ID int) (err error) {
+func (s *Session) DeleteDataSource(ID int, orgID string) (err error) {
 
 	reqURL := fmt.Sprintf("%s/api/datasources/%d", s.url, ID)
 
-	jsonStr, err := json.Marshal(ID)
+	_, err = s.httpRequestWithOrgHeader("DELETE", reqURL, orgID, nil)
 	if err != nil {
-		return
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 404 {
+				return NotFound{}
+			}
+			return err
+		default:
+			return err
+		}
 	}
 
-	_, err = s.httpRequest("DELETE", reqURL, bytes.NewBuffer(jsonStr))
-
 	return
 }
 
-// GetDataSourceList return a list of existing Grafana DataSources.
+// GetDataSourceList return a list of existing Grafana DataSources of organization.
 // It return a array of DataSource struct.
 // It returns a error if it cannot get the DataSource list.
-func (s *Session) GetDataSourceList() (ds []DataSource, err error) {
+func (s *Session) GetDataSourceList(orgID string) (ds []DataSource, err error) {
 	reqURL := s.url + "/api/datasources"
 
-	body, err := s.httpRequest("GET", reqURL, nil)
+	body, err := s.httpRequestWithOrgHeader("GET", reqURL, orgID, nil)
 	if err != nil {
 		return
 	}
@@ -345,15 +400,23 @@ func (s *Session) GetDataSourceList() (ds []DataSource, err error) {
 	return
 }
 
-// GetDataSourceListID by ID returns single Grafana DataSources.
+// GetDataSourceListID by ID returns single Grafana DataSources of organization.
 // It return a array of DataSource struct.
 // It returns a error if it cannot get the DataSource list.
-func (s *Session) GetDataSourceListID(ID int) (ds DataSource, err error) {
+func (s *Session) GetDataSourceListID(ID int, orgID string) (ds DataSource, err error) {
 	reqURL := fmt.Sprintf("%s/api/datasources/%d", s.url, ID)
 
-	body, err := s.httpRequest("GET", reqURL, nil)
+	body, err := s.httpRequestWithOrgHeader("GET", reqURL, orgID, nil)
 	if err != nil {
-		return
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 404 {
+				return DataSource{}, NotFound{}
+			}
+			return DataSource{}, err
+		default:
+			return DataSource{}, err
+		}
 	}
 	dec := json.NewDecoder(body)
 	err = dec.Decode(&ds)
@@ -570,6 +633,31 @@ func (s *Session) DeleteOrganization(ID int) (err error) {
 	return
 }
 
+// UpdateOrganization renames the organization with given id, Exists is
+// returned if name is taken by other organization
+func (s *Session) UpdateOrganization(ID int, name string) (err error) {
+	reqURL := fmt.Sprintf("%s/api/orgs/%d", s.url, ID)
+	jsonStr, err := json.Marshal(Org{name})
+	if err != nil {
+		return
+	}
+
+	_, err = s.httpRequest("PUT", reqURL, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 400 ||
+				err.(GrafanaError).Response.StatusCode == 409 {
+				return Exists{}
+			}
+			return err
+		default:
+			return err
+		}
+	}
+	return
+}
+
 // GetOrganizationUsers gets Users in Organisation
 func (s *Session) GetOrganizationUsers(ID int) (org []OrgUserList, err error) {
 	reqURL := fmt.Sprintf("%s/api/orgs/%d/users", s.url, ID)
@@ -662,6 +750,79 @@ func (s *Session) DeleteOrganizationUser(userID int, orgID int) (err error) {
 	return
 }
 
+// GetUserByLogin gets user by login or email, NotFound is returned if there
+// is no such user
+func (s *Session) GetUserByLogin(login string) (user User, err error) {
+	reqURL := fmt.Sprintf("%s/api/users/lookup?loginOrEmail=%s", s.url,
+		neturl.QueryEscape(login))
+	body, err := s.httpRequest("GET", reqURL, nil)
+
+	if err != nil {
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 404 {
+				return User{}, NotFound{}
+			}
+			return User{}, err
+		default:
+			return User{}, err
+		}
+	}
+	dec := json.NewDecoder(body)
+	err = dec.Decode(&user)
+	return
+}
+
+// AddOrganizationUser adds existing user to organization with role, Exists is
+// returned if user is already member of organization
+func (s *Session) AddOrganizationUser(orgID int, loginOrEmail, role string) (
+	err error) {
+	var orguser struct {
+		LoginOrEmail string `json:"loginOrEmail"`
+		Role         string `json:"role"`
+	}
+	orguser.LoginOrEmail = loginOrEmail
+	orguser.Role = role
+
+	reqURL := fmt.Sprintf("%s/api/orgs/%d/users", s.url, orgID)
+	jsonStr, err := json.Marshal(orguser)
+	if err != nil {
+		return
+	}
+
+	_, err = s.httpRequest("POST", reqURL, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 409 {
+				return Exists{}
+			}
+			return err
+		default:
+			return err
+		}
+	}
+	return
+}
+
+// UpdateOrganizationUserRole changes role of user in organization
+func (s *Session) UpdateOrganizationUserRole(orgID, userID int, role string) (
+	err error) {
+	var orguser struct {
+		Role string `json:"role"`
+	}
+	orguser.Role = role
+
+	reqURL := fmt.Sprintf("%s/api/orgs/%d/users/%d", s.url, orgID, userID)
+	jsonStr, err := json.Marshal(orguser)
+	if err != nil {
+		return
+	}
+
+	_, err = s.httpRequest("PATCH", reqURL, bytes.NewBuffer(jsonStr))
+	return
+}
+
 // UploadDashboard upload a new Dashboard.
 func (s *Session) UploadDashboard(dashboard []byte, orgID string, overwrite bool) (
 	slug string, err error) {
@@ -676,6 +837,7 @@ func (s *Session) UploadDashboard(dashboard []byte, orgID string, overwrite bool
 	if err != nil {
 		return
 	}
+	addDashboardTag(content.Dashboard, ManagedDashboardTag)
 	content.Overwrite = overwrite
 	jsonStr, err := json.Marshal(content)
 	if err != nil {
@@ -699,9 +861,66 @@ func (s *Session) UploadDashboard(dashboard []byte, orgID string, overwrite bool
 	return result.Slug, nil
 }
 
+// addDashboardTag appends tag to tags of dashboard model, unless it is
+// already there
+func addDashboardTag(dashboard map[string]interface{}, tag string) {
+	tags, _ := dashboard["tags"].([]interface{})
+	for _, existingTag := range tags {
+		if existingTag == tag {
+			return
+		}
+	}
+	dashboard["tags"] = append(tags, tag)
+}
+
+// HasTag checks if dashboard is marked with provided tag
+func (d DashboardSearchResult) HasTag(tag string) bool {
+	for _, dashboardTag := range d.Tags {
+		if dashboardTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteDashboard delete a Grafana Dashboard.
 func (s *Session) DeleteDashboard(slug, orgID string) (err error) {
 	reqURL := fmt.Sprintf("%s/api/dashboards/db/%s", s.url, slug)
 	_, err = s.httpRequestWithOrgHeader("DELETE", reqURL, orgID, nil)
+	if err != nil {
+		switch err.(type) {
+		case GrafanaError:
+			if err.(GrafanaError).Response.StatusCode == 404 {
+				return NotFound{}
+			}
+			return err
+		default:
+			return err
+		}
+	}
+	return
+}
+
+// SearchDashboards returns all dashboards of organization
+func (s *Session) SearchDashboards(orgID string) (dashboards []DashboardSearchResult, err error) {
+	reqURL := fmt.Sprintf("%s/api/search", s.url)
+	body, err := s.httpRequestWithOrgHeader("GET", reqURL, orgID, nil)
+	if err != nil {
+		return
+	}
+	var result []DashboardSearchResult
+	dec := json.NewDecoder(body)
+	err = dec.Decode(&result)
+	if err != nil {
+		return
+	}
+	// search returns folders as well, only db dashboards are needed
+	for _, dashboard := range result {
+		if dashboard.Type != dashboardSearchType {
+			continue
+		}
+		dashboard.Slug = strings.TrimPrefix(dashboard.URI, dashboardURIPrefix)
+		dashboards = append(dashboards, dashboard)
+	}
 	return
 }
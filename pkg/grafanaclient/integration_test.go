@@ -19,6 +19,9 @@ var ds = DataSource{Name: "testme",
 	Database:  "test",
 	IsDefault: true}
 
+// datasources are created in default organization
+var dsOrgID = "1"
+
 var usr = AdminCreateUser{Email: "test@me.com",
 	Login:    "testme",
 	Name:     "testme",
@@ -57,7 +60,7 @@ func Test_CreateDataSource(t *testing.T) {
 	session, _ := NewSession(user, pass, url)
 	err := session.DoLogon()
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
-	err = session.CreateDataSource(ds)
+	_, err = session.CreateDataSource(ds, dsOrgID)
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when creating DataSource: %s", err))
 }
 
@@ -66,7 +69,7 @@ func Test_GetDataSourceList(t *testing.T) {
 	session, _ := NewSession(user, pass, url)
 	err := session.DoLogon()
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
-	dslist, err := session.GetDataSourceList()
+	dslist, err := session.GetDataSourceList(dsOrgID)
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one getting DataSource: %s", err))
 	var check bool
 	for _, ds := range dslist {
@@ -83,11 +86,11 @@ func Test_GetDataSourceListID(t *testing.T) {
 	session, _ := NewSession(user, pass, url)
 	err := session.DoLogon()
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
-	dslist, err := session.GetDataSourceList()
+	dslist, err := session.GetDataSourceList(dsOrgID)
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one getting DataSource: %s", err))
 	for _, ds := range dslist {
 		if ds.Name == "testme" {
-			resDs, _ := session.GetDataSourceListID(ds.ID)
+			resDs, _ := session.GetDataSourceListID(ds.ID, dsOrgID)
 
 			assert.Equal(t, "testme", resDs.Name, "We are expecting to retrieve DataSource with ID 1 and didn't get it")
 		}
@@ -100,7 +103,7 @@ func Test_GetDataSourceName(t *testing.T) {
 	err := session.DoLogon()
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
 
-	resDs, _ := session.GetDataSourceName("testme")
+	resDs, _ := session.GetDataSourceName("testme", dsOrgID)
 
 	assert.Equal(t, "testme", resDs.Name, "We are expecting to retrieve testme DataSource and didn't get it")
 }
@@ -111,10 +114,10 @@ func Test_DeleteDataSource(t *testing.T) {
 	err := session.DoLogon()
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
 
-	resDs, err := session.GetDataSourceName("testme")
+	resDs, err := session.GetDataSourceName("testme", dsOrgID)
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Getting Datasource details: %s", err))
 
-	err = session.DeleteDataSource(resDs.ID)
+	err = session.DeleteDataSource(resDs.ID, dsOrgID)
 	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Deleting: %s", err))
 }
 
@@ -221,6 +224,24 @@ func Test_GetOrgID(t *testing.T) {
 	}
 }
 
+func Test_UpdateOrg(t *testing.T) {
+	session, _ := NewSession(user, pass, url)
+	err := session.DoLogon()
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
+
+	renamed, err := session.CreateOrg(Org{Name: "testme_rename"})
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when creating Org: %s", err))
+	err = session.UpdateOrganization(renamed.ID, "testme_renamed")
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Updating Org: %s", err))
+	resOrg, _ := session.GetOrganizationID(renamed.ID)
+	assert.Equal(t, "testme_renamed", resOrg.Name, "We are expecting organization to be renamed")
+
+	err = session.UpdateOrganization(renamed.ID, org.Name)
+	assert.Equal(t, Exists{}, err, "We are expecting name of other organization to be taken")
+	err = session.DeleteOrganization(renamed.ID)
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Deleting Org: %s", err))
+}
+
 func Test_CreateOrgUser(t *testing.T) {
 	session, _ := NewSession(user, pass, url)
 	err := session.DoLogon()
@@ -261,6 +282,44 @@ func Test_GetOrgUsers(t *testing.T) {
 	assert.Equal(t, true, check, "We didn't find the admin user in Main organization")
 }
 
+func Test_GetUserByLogin(t *testing.T) {
+	session, _ := NewSession(user, pass, url)
+	err := session.DoLogon()
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
+
+	resUser, err := session.GetUserByLogin("test")
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Getting User: %s", err))
+	assert.Equal(t, "test", resUser.Name, "We didn't find the test user")
+
+	_, err = session.GetUserByLogin("missing")
+	assert.Equal(t, NotFound{}, err, "We are expecting NotFound error for unknown login")
+}
+
+func Test_AddOrgUserAndUpdateRole(t *testing.T) {
+	session, _ := NewSession(user, pass, url)
+	err := session.DoLogon()
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Login: %s", err))
+
+	resUser, err := session.GetUserByLogin("test")
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Getting User: %s", err))
+	err = session.AddOrganizationUser(org_list.ID, "test", RoleViewer)
+	if _, exists := err.(Exists); !exists {
+		assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Adding User: %s", err))
+	}
+	err = session.UpdateOrganizationUserRole(org_list.ID, resUser.ID, RoleEditor)
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one when Updating Role: %s", err))
+
+	orgUsers, err := session.GetOrganizationUsers(org_list.ID)
+	assert.Nil(t, err, fmt.Sprintf("We are expecting no error and got one getting users in Organization: %s", err))
+	var role string
+	for _, orgUser := range orgUsers {
+		if orgUser.Login == "test" {
+			role = orgUser.Role
+		}
+	}
+	assert.Equal(t, RoleEditor, role, "We didn't find the test user with Editor role")
+}
+
 func Test_DeleteOrgUser(t *testing.T) {
 	session, _ := NewSession(user, pass, url)
 	err := session.DoLogon()
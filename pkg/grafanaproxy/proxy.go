@@ -0,0 +1,112 @@
+// Package grafanaproxy forwards requests of authenticated api users to
+// grafana configured with auth proxy authentication. Grafana trusts
+// X-WEBAUTH-USER header, so grafana has to be reachable only through api
+package grafanaproxy
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// UserHeader is header of grafana auth proxy carrying login of user
+const UserHeader = "X-WEBAUTH-USER"
+
+// OrgHeader is header selecting grafana organization of request
+const OrgHeader = "X-Grafana-Org-Id"
+
+// TokenCookieName is cookie carrying jwt token of browsers, which can not set
+// Authorization header on page navigation
+const TokenCookieName = "visualization_api_token"
+
+// TokenCookie returns value of Set-Cookie header storing jwt token for
+// requests to path. Cookie is not available to scripts, it is sent only over
+// https and only by pages of the same site. net/http does not support
+// SameSite attribute, so it is appended to serialized cookie
+func TokenCookie(path, token string, expiresAt time.Time) string {
+	cookie := &http.Cookie{
+		Name:     TokenCookieName,
+		Value:    token,
+		Path:     path,
+		Expires:  expiresAt.UTC(),
+		HttpOnly: true,
+		Secure:   true,
+	}
+	return cookie.String() + "; SameSite=Strict"
+}
+
+// ExpiredTokenCookie returns value of Set-Cookie header removing cookie of
+// jwt token for requests to path
+func ExpiredTokenCookie(path string) string {
+	cookie := &http.Cookie{
+		Name:     TokenCookieName,
+		Path:     path,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+	}
+	return cookie.String() + "; SameSite=Strict"
+}
+
+// Proxy is http.Handler forwarding requests to grafana on behalf of user
+// stored in request context by authentication middleware. Login of grafana
+// user is keystone user id, organization is organization of jwt token. Path
+// of request is forwarded as is, router has to remove its prefix
+type Proxy struct {
+	proxy *httputil.ReverseProxy
+}
+
+// NewProxy returns proxy to grafana of grafanaURL
+func NewProxy(grafanaURL string) (*Proxy, error) {
+	target, err := url.Parse(grafanaURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Proxy{proxy: httputil.NewSingleHostReverseProxy(target)}, nil
+}
+
+// removeTokenCookie removes jwt token from cookies forwarded to grafana
+func removeTokenCookie(r *http.Request) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != TokenCookieName {
+			r.AddCookie(cookie)
+		}
+	}
+}
+
+// ServeHTTP forwards request to grafana. Credentials of api are removed from
+// request, headers of auth proxy sent by client are replaced
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	identity := common.IdentityFromContext(r.Context())
+	organizationID, _ := r.Context().Value(
+		common.OrganizationIDContext).(string)
+	if identity.UserID == "" || organizationID == "" {
+		// token was issued before identity was stored in it
+		common.WriteErrorToResponse(w, http.StatusForbidden,
+			http.StatusText(http.StatusForbidden),
+			"Token has no user or organization, log in again")
+		return
+	}
+
+	forwarded := new(http.Request)
+	*forwarded = *r
+	forwarded.Header = http.Header{}
+	for name, values := range r.Header {
+		forwarded.Header[name] = values
+	}
+	forwarded.Header.Del("Authorization")
+	removeTokenCookie(forwarded)
+	forwarded.Header.Set(UserHeader, identity.UserID)
+	forwarded.Header.Set(OrgHeader, organizationID)
+
+	log.Logger.Debugf("Forwarding %s %s of user '%s' to grafana "+
+		"organization %s", r.Method, r.URL.Path, identity.UserID,
+		organizationID)
+	p.proxy.ServeHTTP(w, forwarded)
+}
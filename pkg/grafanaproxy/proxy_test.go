@@ -0,0 +1,80 @@
+package grafanaproxy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+func TestProxy(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	var forwarded *http.Request
+	grafana := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			forwarded = r
+			w.Write([]byte("grafana"))
+		}))
+	defer grafana.Close()
+
+	proxy, err := NewProxy(grafana.URL)
+	assert.Nil(t, err)
+
+	request, _ := http.NewRequest("GET", "/api/dashboards/home?orgId=1",
+		nil)
+	request.Header.Set("Authorization", "Bearer jwt")
+	request.Header.Set("X-Webauth-User", "admin")
+	request.Header.Set(OrgHeader, "1")
+	request.AddCookie(&http.Cookie{Name: TokenCookieName, Value: "jwt"})
+	request.AddCookie(&http.Cookie{Name: "grafana_sess", Value: "session"})
+	ctx := context.WithValue(request.Context(), common.OrganizationIDContext,
+		"3")
+	ctx = context.WithValue(ctx, common.IdentityContext,
+		common.Identity{UserID: "user"})
+	response := httptest.NewRecorder()
+	proxy.ServeHTTP(response, request.WithContext(ctx))
+
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "grafana", response.Body.String())
+	assert.Equal(t, "/api/dashboards/home", forwarded.URL.Path)
+	assert.Equal(t, "orgId=1", forwarded.URL.RawQuery)
+	// credentials of api are not forwarded, headers of client are replaced
+	assert.Equal(t, "", forwarded.Header.Get("Authorization"))
+	assert.Equal(t, []string{"user"}, forwarded.Header["X-Webauth-User"])
+	assert.Equal(t, []string{"3"}, forwarded.Header[OrgHeader])
+	assert.Equal(t, "grafana_sess=session", forwarded.Header.Get("Cookie"))
+	// request of client is not changed
+	assert.Equal(t, "Bearer jwt", request.Header.Get("Authorization"))
+}
+
+func TestProxyWithoutIdentity(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	proxy, err := NewProxy("http://grafana:3000")
+	assert.Nil(t, err)
+
+	request, _ := http.NewRequest("GET", "/api/dashboards/home", nil)
+	ctx := context.WithValue(request.Context(), common.OrganizationIDContext,
+		"3")
+	response := httptest.NewRecorder()
+	proxy.ServeHTTP(response, request.WithContext(ctx))
+
+	assert.Equal(t, 403, response.Code)
+	assert.Equal(t, `{"code":403,"message":"Forbidden","details":"Token has no user or organization, log in again"}`,
+		response.Body.String())
+}
+
+func TestTokenCookie(t *testing.T) {
+	expiresAt := time.Date(2017, 6, 15, 0, 48, 41, 0, time.UTC)
+	assert.Equal(t, "visualization_api_token=jwt; Path=/v1/grafana/; "+
+		"Expires=Thu, 15 Jun 2017 00:48:41 GMT; HttpOnly; Secure; "+
+		"SameSite=Strict", TokenCookie("/v1/grafana/", "jwt", expiresAt))
+	assert.Equal(t, "visualization_api_token=; Path=/v1/grafana/; "+
+		"Max-Age=0; HttpOnly; Secure; SameSite=Strict",
+		ExpiredTokenCookie("/v1/grafana/"))
+}
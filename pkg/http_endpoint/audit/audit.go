@@ -0,0 +1,133 @@
+package httpAudit
+
+import (
+	"context"
+	"github.com/pressly/chi"
+	"net/http"
+	"strings"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// wildcardParam is url parameter of mounted routers, it is not an id of
+// object api call is made on
+const wildcardParam = "*"
+
+// mutatingMethods are http methods of api calls, which are audited
+var mutatingMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// statusRecorder remembers status code written to response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// routePattern returns pattern of route request was routed to. Patterns of
+// mounted routers end with wildcard, which is removed
+func routePattern(routeContext *chi.Context) string {
+	pattern := strings.Join(routeContext.RoutePatterns, "")
+	for strings.Contains(pattern, "/*/") {
+		pattern = strings.Replace(pattern, "/*/", "/", -1)
+	}
+	return pattern
+}
+
+// newAuditRecord builds audit record of completed api call. Route and url
+// parameters are known only after request was routed, ids of created objects
+// are added to request context by handlers
+func newAuditRecord(r *http.Request, status int) (*models.AuditRecord, error) {
+	if status == 0 {
+		// nothing was written to response, net/http replies with 200 then
+		status = http.StatusOK
+	}
+	outcome := models.AuditOutcomeSuccess
+	if status >= http.StatusBadRequest {
+		outcome = models.AuditOutcomeFailure
+	}
+
+	// context of request is empty if authentication failed
+	identity := common.IdentityFromContext(r.Context())
+	organizationID, _ := r.Context().Value(common.OrganizationIDContext).(string)
+	route := r.URL.Path
+	targets := map[string]string{}
+	if routeContext, ok := r.Context().Value(chi.RouteCtxKey).(*chi.Context); ok {
+		route = routePattern(routeContext)
+		for i, key := range routeContext.URLParams.Keys {
+			if key != wildcardParam {
+				targets[key] = routeContext.URLParams.Values[i]
+			}
+		}
+	}
+	created, ok := r.Context().Value(common.AuditTargetsContext).(common.AuditTargets)
+	if ok {
+		for key, value := range created {
+			targets[key] = value
+		}
+	}
+	encodedTargets, err := db.EncodeAuditTargets(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditRecord{
+		UserID:         identity.UserID,
+		UserName:       identity.UserName,
+		OrganizationID: organizationID,
+		ProjectID:      identity.ProjectID,
+		IsAdmin:        identity.IsAdmin,
+		Method:         r.Method,
+		Route:          route,
+		Path:           r.URL.Path,
+		Targets:        encodedTargets,
+		Status:         status,
+		Outcome:        outcome,
+	}, nil
+}
+
+// AuditMiddleware stores audit record of every mutating api call after it is
+// completed. It has to follow authentication middleware, which puts identity
+// of user to request context. Audit failures are logged, but they do not
+// change response, because api call is already made
+func AuditMiddleware(databaseManager db.DatabaseManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !mutatingMethods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(),
+				common.AuditTargetsContext, common.AuditTargets{}))
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			record, err := newAuditRecord(r, recorder.status)
+			if err == nil {
+				err = databaseManager.CreateAuditRecord(record)
+			}
+			if err != nil {
+				log.Logger.Errorf("Error on auditing %s call of %s: '%s'",
+					r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
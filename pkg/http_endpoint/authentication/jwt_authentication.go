@@ -2,48 +2,104 @@ package httpAuth
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/auth0/go-jwt-middleware"
 	"github.com/dgrijalva/jwt-go"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+	"visualization-api/pkg/database"
 	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jwtkeys"
 	"visualization-api/pkg/logging"
+	"visualization-api/pkg/policy"
 )
 
 const contextJWTProperty = "AuthToken"
 
-// CustomClaims defines what data would be stored in jwt token
+// revokedTokenMessage is message of response to api call made with revoked
+// token or token of invalid keystone token, it matches message of
+// jwtmiddleware errors
+const revokedTokenMessage = "Unauthorized. Token is invalid or expired."
+
+// CustomClaims defines what data would be stored in jwt token. ProjectID is
+// id of grafana organization project was mapped to on issue, requests work
+// with organization project is mapped to at the moment, keystone project is
+// stored in KeystoneProjectID.
+// Id of jwt token (jti claim) is used to revoke it and to find keystone token
+// it was issued for, keystone token is not stored in jwt token
 type CustomClaims struct {
-	IsAdmin   bool   `json:"isAdmin"`
-	ProjectID string `json:"orgId"`
+	IsAdmin           bool     `json:"isAdmin"`
+	ProjectID         string   `json:"orgId"`
+	UserID            string   `json:"userId"`
+	UserName          string   `json:"userName"`
+	KeystoneProjectID string   `json:"projectId"`
+	ProjectName       string   `json:"projectName"`
+	DomainID          string   `json:"domainId"`
+	DomainName        string   `json:"domainName"`
+	Roles             []string `json:"roles"`
 	jwt.StandardClaims
 }
 
+// Identity returns keystone identity of user stored in claims
+func (c *CustomClaims) Identity() common.Identity {
+	roles := c.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return common.Identity{
+		UserID:      c.UserID,
+		UserName:    c.UserName,
+		ProjectID:   c.KeystoneProjectID,
+		ProjectName: c.ProjectName,
+		DomainID:    c.DomainID,
+		DomainName:  c.DomainName,
+		Roles:       roles,
+		IsAdmin:     c.IsAdmin,
+	}
+}
+
+// Token returns description of jwt token stored in claims
+func (c *CustomClaims) Token() common.Token {
+	return common.Token{
+		ID:        c.Id,
+		ExpiresAt: time.Unix(c.ExpiresAt, 0).UTC(),
+	}
+}
+
 // JWTTokenFromParams creates jwt token given claims data
-func JWTTokenFromParams(secret string, isAdmin bool, projectID string,
-	expiresAt time.Time) (string, error) {
+func JWTTokenFromParams(keys *jwtkeys.KeySet, projectID string,
+	identity common.Identity, token common.Token) (string, error) {
 
 	claims := CustomClaims{
-		isAdmin,
+		identity.IsAdmin,
 		projectID,
+		identity.UserID,
+		identity.UserName,
+		identity.ProjectID,
+		identity.ProjectName,
+		identity.DomainID,
+		identity.DomainName,
+		identity.Roles,
 		jwt.StandardClaims{
-			ExpiresAt: expiresAt.Unix(),
+			Id:        token.ID,
+			ExpiresAt: token.ExpiresAt.Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	result, err := token.SignedString([]byte(secret))
+	result, err := keys.Sign(claims)
 	if err != nil {
 		log.Logger.Debugf("Error signing JWTToken %s", err)
 	}
 	return result, err
 }
 
-func parseJWTTokenClaims(tokenString, secret string) (*CustomClaims, error) {
+func parseJWTTokenClaims(tokenString string,
+	keys *jwtkeys.KeySet) (*CustomClaims, error) {
 	tokenValue, err := jwt.ParseWithClaims(
-		tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
+		tokenString, &CustomClaims{}, keys.VerificationKey)
 
 	if claims, ok := tokenValue.Claims.(*CustomClaims); ok && tokenValue.Valid {
 		return claims, nil
@@ -51,31 +107,176 @@ func parseJWTTokenClaims(tokenString, secret string) (*CustomClaims, error) {
 	return nil, err
 }
 
-// AdminAuthenticationMiddleware checks admin field in jwt token
-func AdminAuthenticationMiddleware(secret string) func(http.Handler) http.Handler {
+// PolicyMiddleware checks that policy rule allows api call to user. Roles
+// of user are taken from request context, so it has to follow
+// AuthenticationMiddleware
+func PolicyMiddleware(accessPolicy *policy.Policy,
+	rule string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := common.IdentityFromContext(r.Context())
+			if !accessPolicy.Enforce(rule, identity.Roles) {
+				log.Logger.Debugf("Policy rule '%s' does not allow call of "+
+					"user '%s'", rule, identity.UserID)
+				common.WriteErrorToResponse(w, http.StatusForbidden,
+					http.StatusText(http.StatusForbidden),
+					fmt.Sprintf("Policy does not allow '%s'", rule))
+				return
+			}
 
-	/*
-		Check if stored JWT token has 'IsAdmin' field set to true
-	*/
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// safeMethods are http methods, which do not change state. Requests of them
+// forged by other sites can not do anything on behalf of user
+var safeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// sameOrigin checks that request was made by page served by api host.
+// Browsers send Origin header with cross-origin and POST requests, Referer is
+// checked if there is no Origin. Requests without both are not trusted
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return false
+	}
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+	return sourceURL.Host != "" && strings.EqualFold(sourceURL.Host, r.Host)
+}
 
+// CookieTokenMiddleware copies jwt token of cookie to Authorization header,
+// if request has no such header. Browsers can not set header on page
+// navigation, so routes they navigate to accept token of cookie too. Browsers
+// send cookie with requests forged by other sites, so requests changing state
+// are authenticated by cookie only if they are made by pages of api host
+func CookieTokenMiddleware(cookieName string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			storedToken := r.Context().Value(contextJWTProperty)
-			claims, err := parseJWTTokenClaims(storedToken.(*jwt.Token).Raw, secret)
-			if err != nil || !claims.IsAdmin {
-				common.WriteErrorToResponse(w, http.StatusUnauthorized,
-					http.StatusText(http.StatusUnauthorized),
-					"User has no admin role")
+			cookie, err := r.Cookie(cookieName)
+			if err != nil || r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
 				return
 			}
-
+			if !safeMethods[r.Method] && !sameOrigin(r) {
+				common.WriteErrorToResponse(w, http.StatusForbidden,
+					http.StatusText(http.StatusForbidden),
+					"Cross-site request is not authenticated by cookie")
+				return
+			}
+			r.Header.Set("Authorization",
+				fmt.Sprintf("Bearer %s", cookie.Value))
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// AuthenticationMiddleware checks if provided jwt token is valid and not expired
-func AuthenticationMiddleware(secret string) func(http.Handler) http.Handler {
+// JWKSHandler publishes public keys of jwt signature, so other services can
+// verify tokens issued by api
+func JWKSHandler(keys *jwtkeys.KeySet) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serializedResult, err := json.Marshal(keys.JWKS())
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
+
+// isRevoked checks if token of claims was revoked. Tokens without id can not
+// be revoked
+func isRevoked(databaseManager db.DatabaseManager,
+	claims *CustomClaims) (bool, error) {
+	if claims.Id == "" {
+		return false, nil
+	}
+	return databaseManager.IsTokenRevoked(claims.Id)
+}
+
+// checkKeystoneToken validates keystone token of claims again and writes
+// error to response if it is not valid any more. Jwt token of invalid keystone
+// token is revoked, so it is not accepted by other api instances too
+func checkKeystoneToken(w http.ResponseWriter,
+	databaseManager db.DatabaseManager, revalidator *KeystoneRevalidator,
+	claims *CustomClaims) bool {
+	token := claims.Token()
+	valid, err := revalidator.Validate(token)
+	if err != nil {
+		log.Logger.Errorf("Error on validating keystone token of token "+
+			"'%s': '%s'", claims.Id, err)
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return false
+	}
+	if valid {
+		return true
+	}
+
+	log.Logger.Debugf("Keystone token of token '%s' is not valid any more, "+
+		"revoking it", claims.Id)
+	_, err = databaseManager.RevokeToken(token.ID, token.ExpiresAt)
+	if err != nil {
+		log.Logger.Errorf("Error on revoking token '%s': '%s'", claims.Id, err)
+	}
+	common.WriteErrorToResponse(w, http.StatusUnauthorized,
+		revokedTokenMessage, "keystone token is not valid any more")
+	return false
+}
+
+// mappedOrganization returns id of grafana organization keystone project of
+// claims is mapped to and writes error to response if there is no such
+// organization. Mapping is read on each request, so remapped project is
+// served by its new organization without issuing of new token
+func mappedOrganization(w http.ResponseWriter,
+	databaseManager db.DatabaseManager, claims *CustomClaims) (string, bool) {
+	if claims.KeystoneProjectID == "" {
+		common.WriteErrorToResponse(w, http.StatusUnauthorized,
+			revokedTokenMessage, "token has no project, log in again")
+		return "", false
+	}
+	mapping, err := databaseManager.GetOrganizationMapping(
+		claims.KeystoneProjectID)
+	if err != nil {
+		log.Logger.Errorf("Error on getting organization of project '%s': "+
+			"'%s'", claims.KeystoneProjectID, err)
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return "", false
+	}
+	if mapping == nil {
+		common.WriteErrorToResponse(w, http.StatusUnauthorized,
+			revokedTokenMessage,
+			"project is not mapped to organization, log in again")
+		return "", false
+	}
+	return mapping.OrganizationID, true
+}
+
+// AuthenticationMiddleware checks if provided jwt token is valid, not expired
+// and not revoked. Signature is verified with key referred by "kid" header of
+// token. If revalidator is not nil, keystone token jwt token was issued for
+// has to be still valid, otherwise jwt token is revoked. Organization of
+// request is the one keystone project of token is mapped to
+func AuthenticationMiddleware(keys *jwtkeys.KeySet,
+	databaseManager db.DatabaseManager,
+	revalidator *KeystoneRevalidator) func(http.Handler) http.Handler {
 
 	/*
 	   go-jwt-middleware does not provide support for chi framework, that's
@@ -88,11 +289,9 @@ func AuthenticationMiddleware(secret string) func(http.Handler) http.Handler {
 	*/
 
 	jwtMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
-		ValidationKeyGetter: func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		},
-		SigningMethod: jwt.SigningMethodHS256,
-		UserProperty:  contextJWTProperty,
+		// signing method depends on key, it is checked by key set
+		ValidationKeyGetter: keys.VerificationKey,
+		UserProperty:        contextJWTProperty,
 	})
 
 	return func(next http.Handler) http.Handler {
@@ -103,10 +302,41 @@ func AuthenticationMiddleware(secret string) func(http.Handler) http.Handler {
 				return
 			}
 			storedToken := r.Context().Value(contextJWTProperty)
-			claims, err := parseJWTTokenClaims(storedToken.(*jwt.Token).Raw, secret)
+			claims, err := parseJWTTokenClaims(storedToken.(*jwt.Token).Raw, keys)
 			if err == nil {
-				newRequest := r.WithContext(context.WithValue(r.Context(),
-					common.OrganizationIDContext, claims.ProjectID))
+				revoked, err := isRevoked(databaseManager, claims)
+				if err != nil {
+					log.Logger.Errorf("Error on checking revocation of token "+
+						"'%s': '%s'", claims.Id, err)
+					common.WriteErrorToResponse(w,
+						http.StatusInternalServerError,
+						http.StatusText(http.StatusInternalServerError),
+						"Internal server error occured")
+					return
+				}
+				if revoked {
+					common.WriteErrorToResponse(w, http.StatusUnauthorized,
+						revokedTokenMessage, "token is revoked")
+					return
+				}
+				if revalidator != nil && !checkKeystoneToken(w,
+					databaseManager, revalidator, claims) {
+					return
+				}
+
+				organizationID, ok := mappedOrganization(w, databaseManager,
+					claims)
+				if !ok {
+					return
+				}
+
+				ctx := context.WithValue(r.Context(),
+					common.OrganizationIDContext, organizationID)
+				ctx = context.WithValue(ctx, common.IdentityContext,
+					claims.Identity())
+				ctx = context.WithValue(ctx, common.TokenContext,
+					claims.Token())
+				newRequest := r.WithContext(ctx)
 				*r = *newRequest
 			}
 			next.ServeHTTP(w, r)
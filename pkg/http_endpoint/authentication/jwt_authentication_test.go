@@ -0,0 +1,181 @@
+package httpAuth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jwtkeys"
+)
+
+// projectIdentity is identity of tokens, which project is mapped to
+// organization by newMappedManager
+var projectIdentity = common.Identity{ProjectID: "project"}
+
+// newMappedManager returns memory manager mapping project of projectIdentity
+// to organization "3"
+func newMappedManager(t *testing.T) db.DatabaseManager {
+	databaseManager := db.NewMemoryManager()
+	assert.Nil(t, databaseManager.SaveOrganizationMapping(
+		&models.OrganizationMapping{ProjectID: projectIdentity.ProjectID,
+			OrganizationID: "3"}))
+	return databaseManager
+}
+
+func TestAuthenticationMiddlewareIdentity(t *testing.T) {
+	keys := jwtkeys.NewSecretKeySet("secret")
+	identity := common.Identity{
+		UserID:      "user",
+		UserName:    "name",
+		ProjectID:   "project",
+		ProjectName: "project name",
+		DomainID:    "default",
+		DomainName:  "Default",
+		Roles:       []string{"reader", "observer"},
+	}
+	tokenInfo := common.Token{
+		ID:        "a3c8f0e2-5d1b-4f7e-9c6a-2b8d4e1f0a9c",
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second).UTC(),
+	}
+	// project was remapped after token was issued
+	token, err := JWTTokenFromParams(keys, "2", identity, tokenInfo)
+	assert.Nil(t, err)
+
+	var organizationID interface{}
+	var storedIdentity common.Identity
+	var storedToken common.Token
+	handler := AuthenticationMiddleware(keys, newMappedManager(t), nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			organizationID = r.Context().Value(common.OrganizationIDContext)
+			storedIdentity = common.IdentityFromContext(r.Context())
+			storedToken = common.TokenFromContext(r.Context())
+		}))
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.Equal(t, "3", organizationID)
+	assert.Equal(t, identity, storedIdentity)
+	assert.True(t, storedIdentity.HasRole("reader"))
+	assert.False(t, storedIdentity.HasRole("admin"))
+	assert.Equal(t, tokenInfo, storedToken)
+}
+
+func TestAuthenticationMiddlewareRevocation(t *testing.T) {
+	keys := jwtkeys.NewSecretKeySet("secret")
+	databaseManager := newMappedManager(t)
+	expiresAt := time.Now().Add(time.Hour)
+	_, err := databaseManager.RevokeToken("revoked", expiresAt)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description  string
+		tokenID      string
+		expectedCode int
+		expectedBody string
+	}{
+		{"token is not revoked", "active", 200, ""},
+		{"token without id can not be revoked", "", 200, ""},
+		{"token is revoked", "revoked", 401, `{"code":401,"message":"Unauthorized. Token is invalid or expired.","details":"token is revoked"}`},
+	}
+	handler := AuthenticationMiddleware(keys, databaseManager, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, testCase := range tests {
+		token, err := JWTTokenFromParams(keys, "3", projectIdentity,
+			common.Token{ID: testCase.tokenID, ExpiresAt: expiresAt})
+		assert.Nil(t, err)
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		assert.Equal(t, testCase.expectedBody, response.Body.String(),
+			testCase.description)
+	}
+}
+
+func TestAuthenticationMiddlewareOrganization(t *testing.T) {
+	keys := jwtkeys.NewSecretKeySet("secret")
+	tests := []struct {
+		description  string
+		projectID    string
+		expectedCode int
+		expectedBody string
+	}{
+		{"project is mapped", projectIdentity.ProjectID, 200, ""},
+		{"token has no project", "", 401, `{"code":401,"message":"Unauthorized. Token is invalid or expired.","details":"token has no project, log in again"}`},
+		{"project is not mapped", "other", 401, `{"code":401,"message":"Unauthorized. Token is invalid or expired.","details":"project is not mapped to organization, log in again"}`},
+	}
+	handler := AuthenticationMiddleware(keys, newMappedManager(t), nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, testCase := range tests {
+		token, err := JWTTokenFromParams(keys, "3",
+			common.Identity{ProjectID: testCase.projectID},
+			common.Token{ExpiresAt: time.Now().Add(time.Hour)})
+		assert.Nil(t, err)
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		assert.Equal(t, testCase.expectedBody, response.Body.String(),
+			testCase.description)
+	}
+}
+
+func TestIdentityOfClaimsWithoutRoles(t *testing.T) {
+	claims := CustomClaims{IsAdmin: true, UserID: "user"}
+	assert.Equal(t, common.Identity{UserID: "user", Roles: []string{},
+		IsAdmin: true}, claims.Identity())
+}
+
+func TestAuthenticationMiddlewareKeys(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	keyData, err := x509.MarshalECPrivateKey(privateKey)
+	assert.Nil(t, err)
+	key, err := jwtkeys.ParseKey("2017-08", pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyData}))
+	assert.Nil(t, err)
+	keys, err := jwtkeys.NewKeySet(key.ID, []*jwtkeys.Key{key,
+		jwtkeys.NewSecretKey("", "secret")})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description  string
+		keys         *jwtkeys.KeySet
+		expectedCode int
+	}{
+		{"token signed by private key", keys, 200},
+		{"token signed by secret", jwtkeys.NewSecretKeySet("secret"), 200},
+		{"token signed by unknown secret", jwtkeys.NewSecretKeySet("other"),
+			401},
+	}
+	handler := AuthenticationMiddleware(keys, newMappedManager(t), nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, testCase := range tests {
+		token, err := JWTTokenFromParams(testCase.keys, "3", projectIdentity,
+			common.Token{ExpiresAt: time.Now().Add(time.Hour)})
+		assert.Nil(t, err)
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+	}
+}
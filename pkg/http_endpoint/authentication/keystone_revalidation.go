@@ -0,0 +1,122 @@
+package httpAuth
+
+import (
+	"sync"
+	"time"
+
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/openstack"
+)
+
+// keystoneValidation is result of the last successful validation of keystone
+// token jwt token was issued for
+type keystoneValidation struct {
+	validatedAt time.Time
+	// expiresAt is expiration time of jwt token, validation is forgotten
+	// after it
+	expiresAt time.Time
+}
+
+// KeystoneRevalidator periodically validates keystone tokens jwt tokens were
+// issued for, so access to api is lost soon after keystone token is revoked.
+// Keystone tokens are stored in db by jwt token id, time of the last
+// validation is kept in memory. Cached results of token validation are not
+// used, cached result of revoked keystone token is removed
+type KeystoneRevalidator struct {
+	openstack       openstack.ClientInterface
+	cache           *openstack.CachingClient
+	databaseManager db.DatabaseManager
+	interval        time.Duration
+
+	lock        sync.Mutex
+	validations map[string]keystoneValidation
+	// now returns current time, it is replaced in tests
+	now func() time.Time
+}
+
+// NewKeystoneRevalidator returns revalidator validating keystone token of
+// every jwt token once per interval. Tokens are validated by client wrapped
+// by token cache, if client caches them
+func NewKeystoneRevalidator(client openstack.ClientInterface,
+	databaseManager db.DatabaseManager,
+	interval time.Duration) *KeystoneRevalidator {
+	revalidator := &KeystoneRevalidator{
+		openstack:       client,
+		databaseManager: databaseManager,
+		interval:        interval,
+		validations:     map[string]keystoneValidation{},
+		now:             time.Now,
+	}
+	if cache, ok := client.(*openstack.CachingClient); ok {
+		revalidator.openstack = cache.Uncached()
+		revalidator.cache = cache
+	}
+	return revalidator
+}
+
+// isValidated checks if keystone token of jwt token was validated during the
+// last interval
+func (v *KeystoneRevalidator) isValidated(tokenID string, now time.Time) bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	validation, ok := v.validations[tokenID]
+	return ok && now.Sub(validation.validatedAt) < v.interval
+}
+
+// store saves time of validation and forgets validations of expired tokens
+func (v *KeystoneRevalidator) store(token common.Token, now time.Time) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	for tokenID, validation := range v.validations {
+		if !now.Before(validation.expiresAt) {
+			delete(v.validations, tokenID)
+		}
+	}
+	v.validations[token.ID] = keystoneValidation{
+		validatedAt: now,
+		expiresAt:   token.ExpiresAt,
+	}
+}
+
+// forget removes validation of jwt token
+func (v *KeystoneRevalidator) forget(tokenID string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	delete(v.validations, tokenID)
+}
+
+// Validate checks if keystone token of jwt token is still valid, keystone is
+// asked at most once per interval. Tokens issued before keystone token was
+// stored for them are not validated
+func (v *KeystoneRevalidator) Validate(token common.Token) (bool, error) {
+	if token.ID == "" {
+		return true, nil
+	}
+	now := v.now()
+	if v.isValidated(token.ID, now) {
+		return true, nil
+	}
+
+	keystoneTokenID, err := v.databaseManager.GetKeystoneToken(token.ID)
+	if err != nil {
+		return false, err
+	}
+	if keystoneTokenID == "" {
+		return true, nil
+	}
+	valid, err := v.openstack.ValidateToken(keystoneTokenID)
+	if err != nil {
+		return false, err
+	}
+	if !valid {
+		v.forget(token.ID)
+		if v.cache != nil {
+			// logins with revoked keystone token have to fail too
+			v.cache.Forget(keystoneTokenID)
+		}
+		return false, nil
+	}
+	v.store(token, now)
+	return true, nil
+}
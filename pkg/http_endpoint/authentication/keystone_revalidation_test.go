@@ -0,0 +1,161 @@
+package httpAuth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/openstack/mock"
+)
+
+func TestKeystoneRevalidation(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+
+	keys := jwtkeys.NewSecretKeySet("secret")
+	databaseManager := newMappedManager(t)
+	now := time.Now()
+	revalidator := NewKeystoneRevalidator(mockedOpenstack, databaseManager,
+		time.Minute)
+	revalidator.now = func() time.Time { return now }
+	handler := AuthenticationMiddleware(keys, databaseManager, revalidator)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Nil(t, databaseManager.SaveKeystoneToken("active", "keystone",
+		now.Add(time.Hour)))
+	token, err := JWTTokenFromParams(keys, "3", projectIdentity,
+		common.Token{ID: "active", ExpiresAt: now.Add(time.Hour)})
+	assert.Nil(t, err)
+	legacyToken, err := JWTTokenFromParams(keys, "3", projectIdentity,
+		common.Token{ExpiresAt: now.Add(time.Hour)})
+	assert.Nil(t, err)
+	unknownToken, err := JWTTokenFromParams(keys, "3", projectIdentity,
+		common.Token{ID: "unknown", ExpiresAt: now.Add(time.Hour)})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description  string
+		token        string
+		after        time.Duration
+		keystone     interface{}
+		expectedCode int
+		expectedBody string
+	}{
+		{"keystone token is validated on first use", token, 0, true, 200, ""},
+		{"keystone token is not validated during interval", token,
+			30 * time.Second, nil, 200, ""},
+		{"keystone token is not available", token, time.Minute,
+			errors.New("keystone is not available"), 500,
+			`{"code":500,"message":"Internal Server Error","details":"Internal server error occured"}`},
+		{"keystone token is validated after interval", token, time.Minute,
+			true, 200, ""},
+		{"token without id is not validated", legacyToken,
+			2 * time.Minute, nil, 200, ""},
+		{"token without stored keystone token is not validated",
+			unknownToken, 0, nil, 200, ""},
+		{"keystone token is revoked", token, 2 * time.Minute, false, 401,
+			`{"code":401,"message":"Unauthorized. Token is invalid or expired.","details":"keystone token is not valid any more"}`},
+		{"token of invalid keystone token is revoked", token,
+			2 * time.Minute, nil, 401,
+			`{"code":401,"message":"Unauthorized. Token is invalid or expired.","details":"token is revoked"}`},
+	}
+	for _, testCase := range tests {
+		now = now.Add(testCase.after)
+		switch keystone := testCase.keystone.(type) {
+		case bool:
+			mockedOpenstack.EXPECT().ValidateToken("keystone").Return(keystone,
+				nil)
+		case error:
+			mockedOpenstack.EXPECT().ValidateToken("keystone").Return(false,
+				keystone)
+		}
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s",
+			testCase.token))
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		assert.Equal(t, testCase.expectedBody, response.Body.String(),
+			testCase.description)
+	}
+}
+
+func TestKeystoneRevalidatorForgetsExpiredTokens(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+	mockedOpenstack.EXPECT().ValidateToken("keystone").Return(true,
+		nil).Times(2)
+
+	now := time.Now()
+	databaseManager := db.NewMemoryManager()
+	assert.Nil(t, databaseManager.SaveKeystoneToken("expiring", "keystone",
+		now.Add(time.Second)))
+	assert.Nil(t, databaseManager.SaveKeystoneToken("other", "keystone",
+		now.Add(time.Hour)))
+	revalidator := NewKeystoneRevalidator(mockedOpenstack, databaseManager,
+		time.Minute)
+	revalidator.now = func() time.Time { return now }
+
+	valid, err := revalidator.Validate(common.Token{ID: "expiring",
+		ExpiresAt: now.Add(time.Second)})
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	now = now.Add(time.Second)
+	valid, err = revalidator.Validate(common.Token{ID: "other",
+		ExpiresAt: now.Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 1, len(revalidator.validations))
+}
+
+func TestKeystoneRevalidatorBypassesTokenCache(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+	now := time.Now()
+	cache := openstack.NewCachingClient(mockedOpenstack, time.Hour,
+		time.Hour, 10)
+	databaseManager := db.NewMemoryManager()
+	assert.Nil(t, databaseManager.SaveKeystoneToken("active", "keystone",
+		now.Add(time.Hour)))
+	revalidator := NewKeystoneRevalidator(cache, databaseManager,
+		time.Minute)
+
+	// login caches keystone token as valid
+	gomock.InOrder(
+		mockedOpenstack.EXPECT().ValidateToken("keystone").Return(true, nil),
+		mockedOpenstack.EXPECT().GetTokenInfo("keystone").Return(
+			&openstack.TokenInfo{ExpiresAt: now.Add(time.Hour)}, nil),
+		// keystone token is revoked, revalidation asks keystone
+		mockedOpenstack.EXPECT().ValidateToken("keystone").Return(false, nil),
+		// cached result of revoked token is removed
+		mockedOpenstack.EXPECT().ValidateToken("keystone").Return(false, nil),
+	)
+	valid, err := cache.ValidateToken("keystone")
+	assert.Nil(t, err)
+	assert.True(t, valid)
+	valid, err = revalidator.Validate(common.Token{ID: "active",
+		ExpiresAt: now.Add(time.Hour)})
+	assert.Nil(t, err)
+	assert.False(t, valid)
+	valid, err = cache.ValidateToken("keystone")
+	assert.Nil(t, err)
+	assert.False(t, valid)
+}
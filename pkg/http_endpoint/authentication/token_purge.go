@@ -0,0 +1,45 @@
+package httpAuth
+
+import (
+	"time"
+
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/logging"
+)
+
+// TokenPurger removes revoked and keystone tokens of expired jwt tokens. They
+// are needed only until jwt token expires, because expired jwt tokens are not
+// accepted anyway
+type TokenPurger struct {
+	databaseManager db.DatabaseManager
+}
+
+// NewTokenPurger is TokenPurger constructor
+func NewTokenPurger(databaseManager db.DatabaseManager) *TokenPurger {
+	return &TokenPurger{databaseManager: databaseManager}
+}
+
+// Start purges expired tokens periodically in background goroutine
+func (p *TokenPurger) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.Purge(time.Now())
+		}
+	}()
+}
+
+// Purge removes tokens of jwt tokens expired before provided time. Amount of
+// removed tokens is returned
+func (p *TokenPurger) Purge(now time.Time) int64 {
+	removed, err := p.databaseManager.DeleteExpiredTokens(now)
+	if err != nil {
+		log.Logger.Errorf("Unable to remove expired tokens: '%s'", err)
+		return 0
+	}
+	if removed > 0 {
+		log.Logger.Debugf("Removed %d expired tokens", removed)
+	}
+	return removed
+}
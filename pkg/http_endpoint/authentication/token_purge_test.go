@@ -0,0 +1,34 @@
+package httpAuth
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/logging"
+)
+
+func TestTokenPurger(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	databaseManager := db.NewMemoryManager()
+	now := time.Now()
+	_, err := databaseManager.RevokeToken("revoked", now.Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.Nil(t, databaseManager.SaveKeystoneToken("expired", "keystone",
+		now.Add(-time.Minute)))
+	assert.Nil(t, databaseManager.SaveKeystoneToken("active", "keystone",
+		now.Add(time.Hour)))
+
+	purger := NewTokenPurger(databaseManager)
+	assert.Equal(t, int64(2), purger.Purge(now))
+	assert.Equal(t, int64(0), purger.Purge(now), "nothing is left to purge")
+
+	revoked, err := databaseManager.IsTokenRevoked("revoked")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+	keystoneToken, err := databaseManager.GetKeystoneToken("active")
+	assert.Nil(t, err)
+	assert.Equal(t, "keystone", keystoneToken)
+}
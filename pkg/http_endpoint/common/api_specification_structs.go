@@ -1,5 +1,10 @@
 package common
 
+import (
+	"time"
+	"visualization-api/pkg/openstack"
+)
+
 // VisualizationPOSTData - POST data expected by visualization api
 type VisualizationPOSTData struct {
 	Name       string `json:"name"`
@@ -15,15 +20,15 @@ type VisualizationPOSTData struct {
 
 // VisualizationResponseEntry describes what data would be returned to user
 type VisualizationResponseEntry struct {
-	Slug string `json:"id"`
-	Name string `json:"name"`
-	Tags string `json:"tags"`
+	Slug string                 `json:"id"`
+	Name string                 `json:"name"`
+	Tags map[string]interface{} `json:"tags" deepcopier:"skip"`
 }
 
 // DashboardResponseEntry describes what data would be returned to user
 type DashboardResponseEntry struct {
 	Name             string `json:"name"`
-	RenderedTemplate string `json:"renderedTemplate"`
+	RenderedTemplate string `json:"renderedTemplate,omitempty"`
 	Slug             string `json:"id"`
 }
 
@@ -32,3 +37,104 @@ type VisualizationWithDashboards struct {
 	*VisualizationResponseEntry
 	Dashboards []*DashboardResponseEntry `json:"dashboards"`
 }
+
+// TemplatePOSTData - POST data expected by templates api
+type TemplatePOSTData struct {
+	Name         string                 `json:"name"`
+	Version      int                    `json:"version"`
+	Parameters   map[string]interface{} `json:"parameters"`
+	TemplateBody string                 `json:"templateBody"`
+}
+
+// TemplateResponseEntry describes what template data would be returned to user
+type TemplateResponseEntry struct {
+	ID           int                    `json:"id"`
+	Name         string                 `json:"name"`
+	Version      int                    `json:"version"`
+	Parameters   map[string]interface{} `json:"parameters"`
+	TemplateBody string                 `json:"templateBody"`
+}
+
+// DatasourcePOSTData - POST data expected by datasources api
+type DatasourcePOSTData struct {
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	Access            string `json:"access"`
+	URL               string `json:"url"`
+	Database          string `json:"database"`
+	User              string `json:"user"`
+	Password          string `json:"password"`
+	BasicAuth         bool   `json:"basicAuth"`
+	BasicAuthUser     string `json:"basicAuthUser"`
+	BasicAuthPassword string `json:"basicAuthPassword"`
+	IsDefault         bool   `json:"isDefault"`
+}
+
+// DatasourceResponseEntry describes what datasource data would be returned
+// to user. Passwords are never returned
+type DatasourceResponseEntry struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Access        string `json:"access"`
+	URL           string `json:"url"`
+	Database      string `json:"database"`
+	User          string `json:"user"`
+	BasicAuth     bool   `json:"basicAuth"`
+	BasicAuthUser string `json:"basicAuthUser"`
+	IsDefault     bool   `json:"isDefault"`
+}
+
+// AuditRecordResponseEntry describes what audit record data would be returned
+// to user
+type AuditRecordResponseEntry struct {
+	ID             int               `json:"id"`
+	UserID         string            `json:"userId"`
+	UserName       string            `json:"userName"`
+	OrganizationID string            `json:"organizationId"`
+	ProjectID      string            `json:"projectId"`
+	IsAdmin        bool              `json:"isAdmin"`
+	Method         string            `json:"method"`
+	Route          string            `json:"route"`
+	Path           string            `json:"path"`
+	Targets        map[string]string `json:"targets"`
+	Status         int               `json:"status"`
+	Outcome        string            `json:"outcome"`
+	CreatedAt      time.Time         `json:"createdAt"`
+}
+
+// MetricsResponseEntry describes usage of api internals returned to admin
+type MetricsResponseEntry struct {
+	KeystoneTokenCache openstack.TokenCacheStats `json:"keystoneTokenCache"`
+}
+
+// OrganizationMappingResponseEntry describes grafana organization of project
+// returned to admin
+type OrganizationMappingResponseEntry struct {
+	ProjectID        string    `json:"projectId"`
+	OrganizationID   string    `json:"organizationId"`
+	OrganizationName string    `json:"organizationName"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Actions of organization remap
+const (
+	// OrganizationRemapped means that project is mapped to other existing
+	// organization
+	OrganizationRemapped = "remapped"
+	// OrganizationCreated means that organization of project was missing
+	// and is created
+	OrganizationCreated = "created"
+)
+
+// OrganizationRemapResponseEntry describes project moved to other grafana
+// organization. On dry run new organization id is empty if organization would
+// be created
+type OrganizationRemapResponseEntry struct {
+	ProjectID               string `json:"projectId"`
+	OrganizationName        string `json:"organizationName"`
+	OldOrganizationID       string `json:"oldOrganizationId"`
+	NewOrganizationID       string `json:"newOrganizationId"`
+	Action                  string `json:"action"`
+	RewrittenVisualizations int64  `json:"rewrittenVisualizations"`
+}
@@ -0,0 +1,15 @@
+package common
+
+import "context"
+
+// AuditTargets are ids of objects api call is made on. Audit middleware puts
+// them to request context, so handlers can add ids of objects they create
+type AuditTargets map[string]string
+
+// AddAuditTarget records id of object created by api call. Nothing is
+// recorded if api call is not audited
+func AddAuditTarget(ctx context.Context, key, value string) {
+	if targets, ok := ctx.Value(AuditTargetsContext).(AuditTargets); ok {
+		targets[key] = value
+	}
+}
@@ -2,3 +2,13 @@ package common
 
 // OrganizationIDContext is the name of organizationid stored in context
 const OrganizationIDContext = "orgId"
+
+// IdentityContext is the name of keystone identity of user stored in context
+const IdentityContext = "identity"
+
+// TokenContext is the name of jwt token api call is made with stored in context
+const TokenContext = "token"
+
+// AuditTargetsContext is the name of ids of objects api call is made on
+// stored in context
+const AuditTargetsContext = "auditTargets"
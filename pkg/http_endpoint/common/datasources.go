@@ -0,0 +1,103 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DatasourceAccessDirect is access mode of datasources queried by browsers of
+// users, grafana does not connect to them
+const DatasourceAccessDirect = "direct"
+
+// privateNetworks are loopback, private and link local networks. Datasources
+// of proxy access are queried by grafana server, so they can not point there
+// unless networks are allowed explicitly
+var privateNetworks = mustParseNetworks("0.0.0.0/8", "10.0.0.0/8",
+	"100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.168.0.0/16", "::/128", "::1/128", "fc00::/7", "fe80::/10")
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// DatasourceSettings restricts addresses datasources created by users point
+// to. If neither hosts nor networks are allowed, datasources of proxy access
+// can point to any address except of private networks
+type DatasourceSettings struct {
+	// AllowedHosts are host names datasources can point to
+	AllowedHosts []string
+	// AllowedNetworks are networks datasources can point to
+	AllowedNetworks []*net.IPNet
+	// LookupIP resolves host names of datasources, net.LookupIP is used if
+	// it is nil
+	LookupIP func(string) ([]net.IP, error)
+}
+
+func (s DatasourceSettings) lookupIP(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	if s.LookupIP != nil {
+		return s.LookupIP(host)
+	}
+	return net.LookupIP(host)
+}
+
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL returns UserDataError if datasource of access mode can not point
+// to url. Allowed host names are matched as is, addresses of other hosts
+// have to be in allowed networks
+func (s DatasourceSettings) CheckURL(rawURL, access string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || (parsedURL.Scheme != "http" &&
+		parsedURL.Scheme != "https") || parsedURL.Hostname() == "" {
+		return NewUserDataError(fmt.Sprintf(
+			"Datasource url '%s' is not valid http url", rawURL))
+	}
+	host := strings.ToLower(parsedURL.Hostname())
+	allowlist := len(s.AllowedHosts) != 0 || len(s.AllowedNetworks) != 0
+	if !allowlist && access == DatasourceAccessDirect {
+		return nil
+	}
+	for _, allowedHost := range s.AllowedHosts {
+		if host == allowedHost {
+			return nil
+		}
+	}
+
+	ips, err := s.lookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return NewUserDataError(fmt.Sprintf(
+			"Host of datasource url '%s' is not resolved", rawURL))
+	}
+	for _, ip := range ips {
+		if allowlist && !inNetworks(ip, s.AllowedNetworks) {
+			return NewUserDataError(fmt.Sprintf(
+				"Datasource can not point to '%s', it is not allowed", host))
+		}
+		if !allowlist && inNetworks(ip, privateNetworks) {
+			return NewUserDataError(fmt.Sprintf(
+				"Datasource of proxy access can not point to private "+
+					"address '%s'", ip))
+		}
+	}
+	return nil
+}
@@ -47,6 +47,34 @@ func (e ClientError) Error() string {
 	return e.Msg
 }
 
+// ConflictError means that resource provided by user already exists
+type ConflictError struct {
+	Msg string
+}
+
+// NewConflictError return new ConflictError
+func NewConflictError(msg string) ConflictError {
+	return ConflictError{msg}
+}
+
+func (e ConflictError) Error() string {
+	return e.Msg
+}
+
+// NotFoundError means that resource requested by user does not exist
+type NotFoundError struct {
+	Msg string
+}
+
+// NewNotFoundError return new NotFoundError
+func NewNotFoundError(msg string) NotFoundError {
+	return NotFoundError{msg}
+}
+
+func (e NotFoundError) Error() string {
+	return e.Msg
+}
+
 type errorResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -0,0 +1,35 @@
+package common
+
+import "visualization-api/pkg/grafanaclient"
+
+// grafanaRolePriority orders roles of grafana organization by privileges
+var grafanaRolePriority = map[string]int{
+	grafanaclient.RoleViewer: 1,
+	grafanaclient.RoleEditor: 2,
+	grafanaclient.RoleAdmin:  3,
+}
+
+// GrafanaUserSettings configures grafana users provisioned for keystone users
+// on login
+type GrafanaUserSettings struct {
+	// Provision enables creation of grafana users on login
+	Provision bool
+	// RoleMapping maps keystone roles to roles in grafana organization
+	RoleMapping map[string]string
+	// DefaultRole is role of users without mapped keystone roles, if it is
+	// empty such users are not added to organization
+	DefaultRole string
+}
+
+// OrgRole returns the most privileged grafana organization role keystone
+// roles are mapped to
+func (s GrafanaUserSettings) OrgRole(roles []string) string {
+	result := s.DefaultRole
+	for _, role := range roles {
+		mapped, ok := s.RoleMapping[role]
+		if ok && grafanaRolePriority[mapped] > grafanaRolePriority[result] {
+			result = mapped
+		}
+	}
+	return result
+}
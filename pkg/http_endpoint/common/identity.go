@@ -0,0 +1,36 @@
+package common
+
+import "context"
+
+// Identity describes keystone user api call is made by. It is stored in jwt
+// token and in request context
+type Identity struct {
+	UserID      string
+	UserName    string
+	ProjectID   string
+	ProjectName string
+	DomainID    string
+	DomainName  string
+	Roles       []string
+	IsAdmin     bool
+}
+
+// HasRole checks if user has keystone role with provided name
+func (i *Identity) HasRole(name string) bool {
+	for _, role := range i.Roles {
+		if role == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityFromContext returns identity stored in request context by
+// authentication middleware. Empty identity is returned if there is none
+func IdentityFromContext(ctx context.Context) Identity {
+	identity, ok := ctx.Value(IdentityContext).(Identity)
+	if !ok {
+		return Identity{Roles: []string{}}
+	}
+	return identity
+}
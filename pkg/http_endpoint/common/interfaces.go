@@ -1,10 +1,17 @@
 package common
 
 import (
+	"net/http"
 	"time"
 	"visualization-api/pkg/database"
 	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/jobs"
+	"visualization-api/pkg/jwtkeys"
 	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/policy"
+	"visualization-api/pkg/projectsync"
+	"visualization-api/pkg/reconciler"
+	"visualization-api/pkg/search"
 )
 
 /*ClientContainer represents container for storing different clients
@@ -13,12 +20,24 @@ type ClientContainer struct {
 	Openstack       openstack.ClientInterface
 	Grafana         grafanaclient.SessionInterface
 	DatabaseManager db.DatabaseManager
+	Reconciler      reconciler.RunnerInterface
+	Jobs            jobs.ManagerInterface
+	Policy          *policy.Policy
+	TokenSettings   TokenSettings
+	GrafanaUsers    GrafanaUserSettings
+	// GrafanaProxy forwards requests of users to grafana, it is nil if
+	// proxy is disabled
+	GrafanaProxy http.Handler
+	ProjectSync  projectsync.SyncerInterface
+	Datasources  DatasourceSettings
 }
 
 /*HandlerInterface represents set of handlers for api
 It was created to have mockable architecture*/
 type HandlerInterface interface {
-	AuthOpenstack(*ClientContainer, ClockInterface, string, string) ([]byte, error)
+	AuthOpenstack(*ClientContainer, ClockInterface, string, *jwtkeys.KeySet) ([]byte, error)
+	AuthRefresh(*ClientContainer, ClockInterface, Token, *jwtkeys.KeySet) ([]byte, error)
+	AuthRevoke(*ClientContainer, Token) error
 	GetUsers(*ClientContainer) ([]byte, error)
 	GetUserID(*ClientContainer, int) ([]byte, error)
 	DeleteUser(*ClientContainer, int) error
@@ -30,11 +49,36 @@ type HandlerInterface interface {
 	CreateOrganizationUser(*ClientContainer, int, []byte) error
 	DeleteOrganizationUser(*ClientContainer, int, int) error
 	GetOrganizationUsers(*ClientContainer, int) ([]byte, error)
-	VisualizationsGet(*ClientContainer, string, string,
-		map[string]interface{}) (*[]VisualizationWithDashboards, error)
+	VisualizationsGet(*ClientContainer, string, search.Query, db.Pagination) (
+		*[]VisualizationWithDashboards, int64, error)
 	VisualizationsPost(*ClientContainer, VisualizationPOSTData, string) (
 		*VisualizationWithDashboards, error)
+	VisualizationsPostAsync(*ClientContainer, VisualizationPOSTData, string) (
+		*jobs.Job, error)
+	JobGet(*ClientContainer, string, string) (*jobs.Job, error)
+	VisualizationGet(*ClientContainer, string, string, bool) (*VisualizationWithDashboards, error)
 	VisualizationDelete(*ClientContainer, string, string) (*VisualizationWithDashboards, error)
+	VisualizationUpdate(*ClientContainer, VisualizationPOSTData, string, string) (
+		*VisualizationWithDashboards, error)
+	TemplatesGet(*ClientContainer, string, string, int) (
+		*[]TemplateResponseEntry, error)
+	TemplatesPost(*ClientContainer, TemplatePOSTData, string) (*TemplateResponseEntry, error)
+	TemplateDelete(*ClientContainer, string, int) (*TemplateResponseEntry, error)
+	DatasourcesGet(*ClientContainer, string, string) (*[]DatasourceResponseEntry, error)
+	DatasourceGet(*ClientContainer, string, int) (*DatasourceResponseEntry, error)
+	DatasourcesPost(*ClientContainer, DatasourcePOSTData, string) (*DatasourceResponseEntry, error)
+	DatasourceDelete(*ClientContainer, string, int) (*DatasourceResponseEntry, error)
+	ReconciliationReport(*ClientContainer) (*reconciler.Report, error)
+	Reconcile(*ClientContainer) (*reconciler.Report, error)
+	AuditRecordsGet(*ClientContainer, db.AuditFilter) (
+		*[]AuditRecordResponseEntry, error)
+	MetricsGet(*ClientContainer) (*MetricsResponseEntry, error)
+	ProjectSyncReport(*ClientContainer) (*projectsync.Report, error)
+	ProjectSync(*ClientContainer, bool) (*projectsync.Report, error)
+	OrganizationMappingsGet(*ClientContainer) (
+		*[]OrganizationMappingResponseEntry, error)
+	OrganizationsRemap(*ClientContainer, bool) (
+		*[]OrganizationRemapResponseEntry, error)
 }
 
 // ClockInterface serves for testing purposes of functions, that require time
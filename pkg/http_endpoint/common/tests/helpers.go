@@ -6,11 +6,17 @@ import (
 	"net/http"
 	"time"
 	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
 	"visualization-api/pkg/grafanaclient/mock"
 	"visualization-api/pkg/http_endpoint/authentication"
 	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jobs/mock"
+	"visualization-api/pkg/jwtkeys"
 	"visualization-api/pkg/logging"
 	"visualization-api/pkg/openstack/mock"
+	"visualization-api/pkg/policy"
+	"visualization-api/pkg/projectsync/mock"
+	"visualization-api/pkg/reconciler/mock"
 )
 
 const tokenHeaderName = "Authorization"
@@ -31,19 +37,58 @@ func MockClientContainer(mockCtrl *gomock.Controller) *common.ClientContainer {
 	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
 	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
 	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
-	return &common.ClientContainer{mockedOpenstack, mockedGrafana, mockedDatabaseManager}
+	mockedReconciler := mock_reconciler.NewMockRunnerInterface(mockCtrl)
+	mockedJobs := mock_jobs.NewMockManagerInterface(mockCtrl)
+	mockedProjectSync := mock_projectsync.NewMockSyncerInterface(mockCtrl)
+	// mutating api calls are audited, tests checking audit records have to
+	// build their own container
+	mockedDatabaseManager.EXPECT().CreateAuditRecord(gomock.Any()).AnyTimes()
+	return &common.ClientContainer{mockedOpenstack, mockedGrafana,
+		mockedDatabaseManager, mockedReconciler, mockedJobs,
+		policy.NewDefaultPolicy(),
+		common.TokenSettings{Lifetime: common.DefaultTokenLifetime},
+		common.GrafanaUserSettings{}, nil, mockedProjectSync,
+		common.DatasourceSettings{}}
+}
+
+// TestIdentity is keystone identity stored in tokens returned by GetAuthToken
+var TestIdentity = common.Identity{
+	UserID:      "b7e3f5d09e1e4a4c8cc2b9a4e8d2ff10",
+	UserName:    "tester",
+	ProjectID:   "821fb77b2ab94232a1ff3d40028f63b4",
+	ProjectName: "test",
+	DomainID:    "default",
+	DomainName:  "Default",
+	Roles:       []string{"admin", "_member_"},
+	IsAdmin:     true,
 }
 
 // GetAuthToken returns admin token with expiration date in 2037
 func GetAuthToken(secret string, projectID string) string {
 	parsedTime, _ := time.Parse(time.RFC3339, "2037-06-15T00:48:41Z")
-	token, _ := httpAuth.JWTTokenFromParams(secret, true, projectID,
-		parsedTime)
+	token, _ := httpAuth.JWTTokenFromParams(jwtkeys.NewSecretKeySet(secret),
+		projectID, TestIdentity, common.Token{ExpiresAt: parsedTime})
 	return token
 }
 
-// SetRequestAuthHeader sets authorization bearer header for you
-func SetRequestAuthHeader(secret string, projectID string, request *http.Request) {
+// ExpectOrganizationMapping makes mocked database manager of clients map
+// project of TestIdentity to organizationID, authentication middleware reads
+// organization of request from the mapping
+func ExpectOrganizationMapping(clients *common.ClientContainer,
+	organizationID string) {
+	mockedDatabaseManager := clients.DatabaseManager.(*mock_database.MockDatabaseManager)
+	mockedDatabaseManager.EXPECT().GetOrganizationMapping(
+		TestIdentity.ProjectID).Return(&models.OrganizationMapping{
+		ProjectID:      TestIdentity.ProjectID,
+		OrganizationID: organizationID,
+	}, nil).AnyTimes()
+}
+
+// SetRequestAuthHeader sets authorization bearer header for you, project of
+// token is mapped to projectID organization by database manager of clients
+func SetRequestAuthHeader(clients *common.ClientContainer, secret string,
+	projectID string, request *http.Request) {
+	ExpectOrganizationMapping(clients, projectID)
 	token := GetAuthToken(secret, projectID)
 	request.Header.Set(tokenHeaderName, fmt.Sprintf("Bearer %s", token))
 }
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+// Token describes jwt token api call is authenticated with. It is stored in
+// request context
+type Token struct {
+	// ID is jti claim used to revoke token and to find keystone token it was
+	// issued for, tokens issued before revocation was supported have no id
+	ID        string
+	ExpiresAt time.Time
+}
+
+// TokenFromContext returns token stored in request context by authentication
+// middleware. Empty token is returned if there is none
+func TokenFromContext(ctx context.Context) Token {
+	token, _ := ctx.Value(TokenContext).(Token)
+	return token
+}
+
+// DefaultTokenLifetime is lifetime of jwt tokens if it is not configured
+const DefaultTokenLifetime = 3 * time.Hour
+
+// TokenSettings configures jwt tokens issued by api
+type TokenSettings struct {
+	// Lifetime is maximum lifetime of issued token, tokens never outlive
+	// keystone tokens they are issued for
+	Lifetime time.Duration
+	// KeystoneRevalidationInterval is how often keystone token jwt token was
+	// issued for is validated again by authentication middleware, zero
+	// disables revalidation
+	KeystoneRevalidationInterval time.Duration
+}
+
+// ExpirationTime returns expiration time of token issued at issuedAt for
+// keystone token expiring at keystoneExpiresAt
+func (s TokenSettings) ExpirationTime(issuedAt,
+	keystoneExpiresAt time.Time) time.Time {
+	lifetime := s.Lifetime
+	if lifetime <= 0 {
+		lifetime = DefaultTokenLifetime
+	}
+	expirationTime := issuedAt.Add(lifetime)
+	if !keystoneExpiresAt.IsZero() && keystoneExpiresAt.Before(expirationTime) {
+		return keystoneExpiresAt
+	}
+	return expirationTime
+}
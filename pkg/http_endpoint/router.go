@@ -5,23 +5,30 @@ import (
 	"github.com/pressly/chi"
 	"net/http"
 
+	"visualization-api/pkg/http_endpoint/authentication"
 	"visualization-api/pkg/http_endpoint/common"
 	"visualization-api/pkg/http_endpoint/v1"
+	"visualization-api/pkg/jwtkeys"
 )
 
 const v1ApiPrefix = "/v1"
 
+// jwksPath is well known path of json web key set, RFC 8414
+const jwksPath = "/.well-known/jwks.json"
+
 // InitializeRouter would initialize all routers of our api
 func InitializeRouter(clients *common.ClientContainer,
-	handler common.HandlerInterface, secret string) *chi.Mux {
+	handler common.HandlerInterface, keys *jwtkeys.KeySet) *chi.Mux {
 	rootRouter := chi.NewRouter()
+	rootRouter.Get(jwksPath, httpAuth.JWKSHandler(keys))
 	rootRouter.Mount(v1ApiPrefix, v1Api.InitializeRouter(clients, handler,
-		secret))
+		keys))
 	return rootRouter
 }
 
 // Serve is an entry point to our HTTP API
-func Serve(secret string, httpPort int, clients *common.ClientContainer) error {
+func Serve(keys *jwtkeys.KeySet, httpPort int,
+	clients *common.ClientContainer) error {
 	return http.ListenAndServe(fmt.Sprintf(":%d", httpPort), InitializeRouter(
-		clients, &v1Api.V1Handler{}, secret))
+		clients, &v1Api.V1Handler{}, keys))
 }
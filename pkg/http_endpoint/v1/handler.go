@@ -2,31 +2,182 @@ package v1Api
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/satori/go.uuid"
+	"visualization-api/pkg/database/models"
 	"visualization-api/pkg/grafanaclient"
 	"visualization-api/pkg/http_endpoint/authentication"
 	"visualization-api/pkg/http_endpoint/common"
 	v1handlers "visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
 	"visualization-api/pkg/logging"
+	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/policy"
+	"visualization-api/pkg/projectsync"
 )
 
-// TokenIssueHours defines on how much hours our token would be issued
-const TokenIssueHours = 3
-
 // V1Handler is implementation of Handler interface
 type V1Handler struct {
 	v1handlers.V1UsersOrgs
 	v1handlers.V1Visualizations
+	v1handlers.V1Templates
+	v1handlers.V1Datasources
+	v1handlers.V1Reconciliation
+	v1handlers.V1Jobs
+	v1handlers.V1Audit
+	v1handlers.V1Metrics
+	v1handlers.V1ProjectSync
+	v1handlers.V1OrganizationMappings
 }
 
 // AuthOpenstack uses provided keystone token to create jwt token
 func (h *V1Handler) AuthOpenstack(clients *common.ClientContainer,
 	clock common.ClockInterface, openstackToken string,
-	secret string) ([]byte, error) {
+	keys *jwtkeys.KeySet) ([]byte, error) {
+	return issueToken(clients, clients.Openstack, clock, openstackToken, keys)
+}
+
+// AuthRefresh issues new jwt token for keystone token provided token was
+// issued for, keystone token has to be still valid. Cached results of token
+// validation are not used, so keystone token revoked since it was cached is
+// not refreshed. Provided token is revoked before new one is issued, so it
+// is refreshed only once by concurrent requests
+func (h *V1Handler) AuthRefresh(clients *common.ClientContainer,
+	clock common.ClockInterface, token common.Token,
+	keys *jwtkeys.KeySet) ([]byte, error) {
+	if token.ID == "" {
+		// token was issued before refresh was supported
+		return nil, common.InvalidOpenstackToken{}
+	}
+	keystoneTokenID, err := clients.DatabaseManager.GetKeystoneToken(token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if keystoneTokenID == "" {
+		// keystone token was not stored for token
+		return nil, common.InvalidOpenstackToken{}
+	}
+	revoked, err := clients.DatabaseManager.RevokeToken(token.ID,
+		token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// token was refreshed or revoked by concurrent request
+		return nil, common.InvalidOpenstackToken{}
+	}
+
+	openstackClient := clients.Openstack
+	cache, cached := openstackClient.(*openstack.CachingClient)
+	if cached {
+		openstackClient = cache.Uncached()
+	}
+	result, err := issueToken(clients, openstackClient, clock,
+		keystoneTokenID, keys)
+	if _, invalid := err.(common.InvalidOpenstackToken); invalid && cached {
+		// logins with revoked keystone token have to fail too
+		cache.Forget(keystoneTokenID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	tokenValid, err := clients.Openstack.ValidateToken(openstackToken)
+// AuthRevoke revokes jwt token, it is not accepted by api any more
+func (h *V1Handler) AuthRevoke(clients *common.ClientContainer,
+	token common.Token) error {
+	if token.ID == "" {
+		return common.NewUserDataError(
+			"token was issued without id, it can not be revoked")
+	}
+	log.Logger.Debugf("Revoking token '%s'", token.ID)
+	// token revoked by concurrent request is revoked as well
+	_, err := clients.DatabaseManager.RevokeToken(token.ID, token.ExpiresAt)
+	return err
+}
+
+// projectOrganization returns grafana organization mapped to project. Project
+// is mapped to organization of its name on first login. Mapped organization,
+// which is missing or is mapped to other project too, is not replaced, because
+// its visualizations have to be remapped by admin. Names of organizations are
+// changed by project synchronization and admins, so they do not tell owner
+func projectOrganization(clients *common.ClientContainer, projectID,
+	projectName string) (*grafanaclient.OrgID, error) {
+	mapping, err := clients.DatabaseManager.GetOrganizationMapping(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping == nil {
+		grafanaOrg, err := clients.Grafana.GetOrCreateOrgByName(
+			projectsync.OrganizationName(projectName, projectID))
+		if err != nil {
+			return nil, err
+		}
+		err = clients.DatabaseManager.SaveOrganizationMapping(
+			&models.OrganizationMapping{
+				ProjectID:        projectID,
+				OrganizationID:   strconv.Itoa(grafanaOrg.ID),
+				OrganizationName: grafanaOrg.Name,
+			})
+		if err != nil {
+			return nil, err
+		}
+		return grafanaOrg, nil
+	}
+
+	organizationID, err := strconv.Atoi(mapping.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	grafanaOrg, err := clients.Grafana.GetOrganizationID(organizationID)
+	if _, notFound := err.(grafanaclient.NotFound); notFound {
+		return nil, common.NewConflictError(fmt.Sprintf("grafana "+
+			"organization '%s' of project '%s' is missing, organizations "+
+			"have to be remapped", mapping.OrganizationID, projectID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	// id of recreated organization could be taken by organization of other
+	// project
+	owners, err := clients.DatabaseManager.QueryOrganizationMappingsOfOrganization(
+		mapping.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		if owner.ProjectID != projectID {
+			return nil, common.NewConflictError(fmt.Sprintf("grafana "+
+				"organization '%s' of project '%s' is mapped to project "+
+				"'%s' too, organizations have to be remapped",
+				mapping.OrganizationID, projectID, owner.ProjectID))
+		}
+	}
+
+	if grafanaOrg.Name != mapping.OrganizationName {
+		// organization was renamed by project synchronization
+		mapping.OrganizationName = grafanaOrg.Name
+		err = clients.DatabaseManager.SaveOrganizationMapping(mapping)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &grafanaclient.OrgID{ID: grafanaOrg.ID, Name: grafanaOrg.Name}, nil
+}
+
+// issueToken validates keystone token by provided client and creates jwt
+// token of keystone identity. Keystone token is stored in db by id of jwt
+// token to refresh it later, jwt token expires not later than keystone token
+func issueToken(clients *common.ClientContainer,
+	openstackClient openstack.ClientInterface, clock common.ClockInterface,
+	openstackToken string, keys *jwtkeys.KeySet) ([]byte, error) {
+
+	tokenValid, err := openstackClient.ValidateToken(openstackToken)
 	if err != nil {
 		log.Logger.Errorf("Error validating openstack Token: %s", err)
 		return nil, err
@@ -35,23 +186,52 @@ func (h *V1Handler) AuthOpenstack(clients *common.ClientContainer,
 		return nil, common.InvalidOpenstackToken{}
 	}
 
-	tokenInfo, err := clients.Openstack.GetTokenInfo(openstackToken)
+	tokenInfo, err := openstackClient.GetTokenInfo(openstackToken)
 	if err != nil {
 		log.Logger.Errorf("Error retrieving openstack Token: %s", err)
 		return nil, err
 	}
 
-	expirationTime := clock.Now().Add(TokenIssueHours * time.Hour)
+	expirationTime := clients.TokenSettings.ExpirationTime(clock.Now(),
+		tokenInfo.ExpiresAt)
 
-	grafanaOrg, err := clients.Grafana.GetOrCreateOrgByName(
-		tokenInfo.ProjectName + "-" + tokenInfo.ProjectID)
+	grafanaOrg, err := projectOrganization(clients, tokenInfo.ProjectID,
+		tokenInfo.ProjectName)
 	if err != nil {
 		return nil, err
 	}
 	grafanaOrgID := strconv.Itoa(grafanaOrg.ID)
 
-	token, err := httpAuth.JWTTokenFromParams(secret, tokenInfo.IsAdmin(),
-		grafanaOrgID, expirationTime)
+	// admin roles are configured by policy
+	roles := tokenInfo.RoleNames()
+	identity := common.Identity{
+		UserID:      tokenInfo.UserID,
+		UserName:    tokenInfo.UserName,
+		ProjectID:   tokenInfo.ProjectID,
+		ProjectName: tokenInfo.ProjectName,
+		DomainID:    tokenInfo.DomainID,
+		DomainName:  tokenInfo.DomainName,
+		Roles:       roles,
+		IsAdmin:     clients.Policy.Enforce(policy.RuleAdmin, roles),
+	}
+
+	if clients.GrafanaUsers.Provision {
+		err = provisionGrafanaUser(clients, grafanaOrg.ID, identity)
+		if err != nil {
+			log.Logger.Errorf("Error provisioning grafana user for user "+
+				"'%s': %s", identity.UserID, err)
+			return nil, err
+		}
+	}
+
+	tokenID := uuid.NewV4().String()
+	token, err := httpAuth.JWTTokenFromParams(keys, grafanaOrgID, identity,
+		common.Token{ID: tokenID, ExpiresAt: expirationTime})
+	if err != nil {
+		return nil, err
+	}
+	err = clients.DatabaseManager.SaveKeystoneToken(tokenID, openstackToken,
+		expirationTime)
 	if err != nil {
 		return nil, err
 	}
@@ -59,20 +239,126 @@ func (h *V1Handler) AuthOpenstack(clients *common.ClientContainer,
 	var payload struct {
 		JWT   string `json:"jwt"`
 		Token struct {
+			ID             string    `json:"id"`
 			OrganizationID string    `json:"organizationId"`
 			ExpiresAt      time.Time `json:"expiresAt"`
 			IsAdmin        bool      `json:"isAdmin"`
+			UserID         string    `json:"userId"`
+			UserName       string    `json:"userName"`
+			ProjectID      string    `json:"projectId"`
+			ProjectName    string    `json:"projectName"`
+			DomainID       string    `json:"domainId"`
+			DomainName     string    `json:"domainName"`
+			Roles          []string  `json:"roles"`
 		} `json:"token"`
 	}
 
 	payload.JWT = token
+	payload.Token.ID = tokenID
 	payload.Token.OrganizationID = grafanaOrgID
 	payload.Token.ExpiresAt = expirationTime
-	payload.Token.IsAdmin = tokenInfo.IsAdmin()
+	payload.Token.IsAdmin = identity.IsAdmin
+	payload.Token.UserID = identity.UserID
+	payload.Token.UserName = identity.UserName
+	payload.Token.ProjectID = identity.ProjectID
+	payload.Token.ProjectName = identity.ProjectName
+	payload.Token.DomainID = identity.DomainID
+	payload.Token.DomainName = identity.DomainName
+	payload.Token.Roles = identity.Roles
 
 	return json.Marshal(payload)
 }
 
+// provisionGrafanaUser makes sure that grafana user of keystone user exists
+// and is member of organization with role mapped from keystone roles. Login
+// of grafana user is keystone user id, it is unique across domains. Users
+// log in grafana through api, so password is random. User, whose keystone
+// roles are not mapped to grafana role any more, is removed from organization
+func provisionGrafanaUser(clients *common.ClientContainer, orgID int,
+	identity common.Identity) error {
+	role := clients.GrafanaUsers.OrgRole(identity.Roles)
+	if role == "" {
+		log.Logger.Debugf("Keystone roles of user '%s' are not mapped to "+
+			"grafana role, user is not provisioned", identity.UserID)
+		return removeGrafanaMember(clients, orgID, identity)
+	}
+
+	user, err := clients.Grafana.GetUserByLogin(identity.UserID)
+	if _, notFound := err.(grafanaclient.NotFound); notFound {
+		log.Logger.Debugf("Creating grafana user '%s' in organization %d",
+			identity.UserID, orgID)
+		err = clients.Grafana.CreateOrganizationUser(orgID,
+			grafanaclient.CreateOrganizationUser{
+				Login:    identity.UserID,
+				Name:     identity.UserName,
+				Role:     role,
+				Password: uuid.NewV4().String(),
+			})
+		if _, exists := err.(grafanaclient.Exists); !exists {
+			return err
+		}
+		// user was created by concurrent login, but it could be not added
+		// to organization yet
+		user, err = clients.Grafana.GetUserByLogin(identity.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	members, err := clients.Grafana.GetOrganizationUsers(orgID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.UserID != user.ID {
+			continue
+		}
+		if member.Role == role {
+			return nil
+		}
+		log.Logger.Debugf("Changing role of grafana user '%s' in "+
+			"organization %d from '%s' to '%s'", identity.UserID, orgID,
+			member.Role, role)
+		return clients.Grafana.UpdateOrganizationUserRole(orgID, user.ID,
+			role)
+	}
+
+	log.Logger.Debugf("Adding grafana user '%s' to organization %d",
+		identity.UserID, orgID)
+	err = clients.Grafana.AddOrganizationUser(orgID, identity.UserID, role)
+	if _, exists := err.(grafanaclient.Exists); exists {
+		return nil
+	}
+	return err
+}
+
+// removeGrafanaMember removes grafana user of keystone user from
+// organization, if user was provisioned to it before
+func removeGrafanaMember(clients *common.ClientContainer, orgID int,
+	identity common.Identity) error {
+	user, err := clients.Grafana.GetUserByLogin(identity.UserID)
+	if _, notFound := err.(grafanaclient.NotFound); notFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	members, err := clients.Grafana.GetOrganizationUsers(orgID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.UserID != user.ID {
+			continue
+		}
+		log.Logger.Debugf("Removing grafana user '%s' from organization %d",
+			identity.UserID, orgID)
+		return clients.Grafana.DeleteOrganizationUser(user.ID, orgID)
+	}
+	return nil
+}
+
 // GetUsers get list of users
 func (h *V1Handler) GetUsers(clients *common.ClientContainer) ([]byte, error) {
 	err := clients.Grafana.DoLogon()
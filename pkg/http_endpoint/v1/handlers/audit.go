@@ -0,0 +1,55 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// V1Audit implements part of handler interface
+type V1Audit struct{}
+
+// AuditRecordToResponse transforms audit record model to response format
+func AuditRecordToResponse(record *models.AuditRecord) *common.AuditRecordResponseEntry {
+	targets := map[string]string{}
+	err := json.Unmarshal([]byte(record.Targets), &targets)
+	if err != nil {
+		log.Logger.Errorf("Error on decoding targets of audit record '%d': '%s'",
+			record.ID, err)
+	}
+	return &common.AuditRecordResponseEntry{
+		ID:             record.ID,
+		UserID:         record.UserID,
+		UserName:       record.UserName,
+		OrganizationID: record.OrganizationID,
+		ProjectID:      record.ProjectID,
+		IsAdmin:        record.IsAdmin,
+		Method:         record.Method,
+		Route:          record.Route,
+		Path:           record.Path,
+		Targets:        targets,
+		Status:         record.Status,
+		Outcome:        record.Outcome,
+		CreatedAt:      record.CreatedAt,
+	}
+}
+
+// AuditRecordsGet handler queries audit records
+func (h *V1Audit) AuditRecordsGet(clients *common.ClientContainer,
+	filter db.AuditFilter) (*[]common.AuditRecordResponseEntry, error) {
+	log.Logger.Debugf("Querying audit records matching '%+v'", filter)
+
+	records, err := clients.DatabaseManager.QueryAuditRecords(filter)
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return nil, err
+	}
+
+	response := []common.AuditRecordResponseEntry{}
+	for _, record := range records {
+		response = append(response, *AuditRecordToResponse(record))
+	}
+	return &response, nil
+}
@@ -0,0 +1,131 @@
+package v1handlers
+
+import (
+	"strconv"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// V1Datasources implements part of handler interface
+type V1Datasources struct{}
+
+// DatasourceToResponse transforms grafana datasource to response format
+func DatasourceToResponse(datasource *grafanaclient.DataSource) *common.DatasourceResponseEntry {
+	return &common.DatasourceResponseEntry{
+		ID:            datasource.ID,
+		Name:          datasource.Name,
+		Type:          datasource.Type,
+		Access:        datasource.Access,
+		URL:           datasource.URL,
+		Database:      datasource.Database,
+		User:          datasource.User,
+		BasicAuth:     datasource.BasicAuth,
+		BasicAuthUser: datasource.BasicAuthUser,
+		IsDefault:     datasource.IsDefault,
+	}
+}
+
+// DatasourcesGet handler queries datasources of organization
+func (h *V1Datasources) DatasourcesGet(clients *common.ClientContainer,
+	organizationID, name string) (*[]common.DatasourceResponseEntry, error) {
+	log.Logger.Debugf("Querying datasources of organization '%s'", organizationID)
+
+	datasources, err := clients.Grafana.GetDataSourceList(organizationID)
+	if err != nil {
+		log.Logger.Errorf("Error getting datasources from grafana: '%s'", err)
+		return nil, err
+	}
+
+	response := []common.DatasourceResponseEntry{}
+	for index := range datasources {
+		if name != "" && datasources[index].Name != name {
+			continue
+		}
+		response = append(response, *DatasourceToResponse(&datasources[index]))
+	}
+	return &response, nil
+}
+
+// DatasourceGet handler returns single datasource of organization
+func (h *V1Datasources) DatasourceGet(clients *common.ClientContainer,
+	organizationID string, datasourceID int) (*common.DatasourceResponseEntry, error) {
+	log.Logger.Debugf("Getting datasource '%d' of organization '%s'",
+		datasourceID, organizationID)
+
+	datasource, err := clients.Grafana.GetDataSourceListID(datasourceID,
+		organizationID)
+	if err != nil {
+		log.Logger.Errorf("Error getting datasource from grafana: '%s'", err)
+		return nil, err
+	}
+	return DatasourceToResponse(&datasource), nil
+}
+
+// DatasourcesPost handler creates new datasource in organization
+func (h *V1Datasources) DatasourcesPost(clients *common.ClientContainer,
+	data common.DatasourcePOSTData, organizationID string) (
+	*common.DatasourceResponseEntry, error) {
+
+	orgID, err := strconv.Atoi(organizationID)
+	if err != nil {
+		log.Logger.Errorf("Organization id '%s' is not integer", organizationID)
+		return nil, err
+	}
+
+	// datasources of proxy access are queried by grafana server, so users
+	// could reach addresses available only to it
+	err = clients.Datasources.CheckURL(data.URL, data.Access)
+	if err != nil {
+		log.Logger.Debugf("Datasource url '%s' is rejected: '%s'", data.URL,
+			err)
+		return nil, err
+	}
+
+	datasource := grafanaclient.DataSource{
+		OrgID:             orgID,
+		Name:              data.Name,
+		Type:              data.Type,
+		Access:            data.Access,
+		URL:               data.URL,
+		Database:          data.Database,
+		User:              data.User,
+		Password:          data.Password,
+		BasicAuth:         data.BasicAuth,
+		BasicAuthUser:     data.BasicAuthUser,
+		BasicAuthPassword: data.BasicAuthPassword,
+		IsDefault:         data.IsDefault,
+	}
+
+	datasource.ID, err = clients.Grafana.CreateDataSource(datasource,
+		organizationID)
+	if err != nil {
+		log.Logger.Errorf("Error creating datasource in grafana: '%s'", err)
+		return nil, err
+	}
+	log.Logger.Infof("Created datasource '%s' in organization '%s'",
+		datasource.Name, organizationID)
+	return DatasourceToResponse(&datasource), nil
+}
+
+// DatasourceDelete removes datasource of organization
+func (h *V1Datasources) DatasourceDelete(clients *common.ClientContainer,
+	organizationID string, datasourceID int) (*common.DatasourceResponseEntry, error) {
+
+	// datasource is requested first to make sure, that it belongs to
+	// organization of user and to return it's data
+	datasource, err := clients.Grafana.GetDataSourceListID(datasourceID,
+		organizationID)
+	if err != nil {
+		log.Logger.Errorf("Error getting datasource from grafana: '%s'", err)
+		return nil, err
+	}
+
+	log.Logger.Debugf("Removing datasource '%d' from grafana", datasourceID)
+	err = clients.Grafana.DeleteDataSource(datasourceID, organizationID)
+	if err != nil {
+		log.Logger.Errorf("Error removing datasource from grafana: '%s'", err)
+		return nil, err
+	}
+	return DatasourceToResponse(&datasource), nil
+}
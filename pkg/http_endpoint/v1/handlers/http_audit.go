@@ -0,0 +1,122 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+const auditUserParam = "user"
+const auditOrganizationParam = "organization"
+const auditMethodParam = "method"
+const auditRouteParam = "route"
+const auditTargetParam = "target"
+const auditOutcomeParam = "outcome"
+const auditSinceParam = "since"
+const auditUntilParam = "until"
+const auditLimitParam = "limit"
+const auditOffsetParam = "offset"
+
+// defaultAuditPageLimit is amount of audit records returned if limit is not
+// provided, audit log grows with every api call
+const defaultAuditPageLimit = 100
+
+// maxAuditPageLimit is the biggest page of audit records user can request
+const maxAuditPageLimit = 1000
+
+// readAuditTime parses time parameter of audit query
+func readAuditTime(query url.Values, param string) (time.Time, error) {
+	value := query.Get(param)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsedTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return parsedTime, fmt.Errorf("%s has to be RFC3339 time", param)
+	}
+	return parsedTime, nil
+}
+
+// readAuditFilter parses query parameters of audit records query
+func readAuditFilter(query url.Values) (db.AuditFilter, error) {
+	filter := db.AuditFilter{
+		UserID:         query.Get(auditUserParam),
+		OrganizationID: query.Get(auditOrganizationParam),
+		Method:         strings.ToUpper(query.Get(auditMethodParam)),
+		Route:          query.Get(auditRouteParam),
+		Target:         query.Get(auditTargetParam),
+		Outcome:        query.Get(auditOutcomeParam),
+		Limit:          defaultAuditPageLimit,
+	}
+	if filter.Outcome != "" && filter.Outcome != models.AuditOutcomeSuccess &&
+		filter.Outcome != models.AuditOutcomeFailure {
+		return filter, fmt.Errorf("outcome has to be one of '%s', '%s'",
+			models.AuditOutcomeSuccess, models.AuditOutcomeFailure)
+	}
+
+	var err error
+	filter.Since, err = readAuditTime(query, auditSinceParam)
+	if err != nil {
+		return filter, err
+	}
+	filter.Until, err = readAuditTime(query, auditUntilParam)
+	if err != nil {
+		return filter, err
+	}
+
+	if limit := query.Get(auditLimitParam); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 || filter.Limit > maxAuditPageLimit {
+			return filter, fmt.Errorf("limit has to be integer between 1 and %d",
+				maxAuditPageLimit)
+		}
+	}
+	if offset := query.Get(auditOffsetParam); offset != "" {
+		filter.Offset, err = strconv.Atoi(offset)
+		if err != nil || filter.Offset < 0 {
+			return filter, errors.New("offset has to be non negative integer")
+		}
+	}
+	return filter, nil
+}
+
+// AuditRecordsGet returns http handler with stored clients and handler pointers
+func AuditRecordsGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := readAuditFilter(r.URL.Query())
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity), err.Error())
+			return
+		}
+
+		result, err := handler.AuditRecordsGet(clients, filter)
+		if err != nil {
+			log.Logger.Errorf("Error %s occured on handler func while "+
+				"querying audit records", err)
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
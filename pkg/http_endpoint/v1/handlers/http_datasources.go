@@ -0,0 +1,180 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pressly/chi"
+	"github.com/xeipuuv/gojsonschema"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/http_endpoint/common"
+	v1JsonSchema "visualization-api/pkg/http_endpoint/v1/json_schemas"
+	"visualization-api/pkg/logging"
+)
+
+const datasourceNameParam = "name"
+
+func writeDatasourceError(w http.ResponseWriter, err error, datasourceID int) {
+	switch err.(type) {
+	// grafanaclient.NotFound means, that datasource does not exist in
+	// organization of user. We return 404
+	case grafanaclient.NotFound:
+		common.WriteErrorToResponse(w, http.StatusNotFound,
+			http.StatusText(http.StatusNotFound),
+			fmt.Sprintf("Requested datasource '%d' was not found", datasourceID))
+	// grafanaclient.Exists means, that datasource with provided name
+	// already exists. We return 409
+	case grafanaclient.Exists:
+		common.WriteErrorToResponse(w, http.StatusConflict,
+			http.StatusText(http.StatusConflict), err.Error())
+	// common.UserDataError means, that datasource points to address it is
+	// not allowed to. We return 400
+	case common.UserDataError:
+		common.WriteErrorToResponse(w, http.StatusBadRequest,
+			http.StatusText(http.StatusBadRequest), err.Error())
+	// If any other error happened -> return 500 error
+	default:
+		log.Logger.Error(err)
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+	}
+}
+
+func writeDatasourceResult(w http.ResponseWriter, result interface{}) {
+	serializedResult, serializationError := json.Marshal(result)
+	if serializationError != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedResult)
+}
+
+// DatasourcesGet returns http handler with stored clients and handler pointers
+func DatasourcesGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+		name := r.URL.Query().Get(datasourceNameParam)
+
+		log.Logger.Debugf("%s call with query parameters: name='%s'",
+			r.URL.Path, name)
+
+		result, err := handler.DatasourcesGet(clients, organizationID, name)
+		if err != nil {
+			writeDatasourceError(w, err, 0)
+			return
+		}
+		writeDatasourceResult(w, result)
+	}
+}
+
+// DatasourceGet returns http handler with stored clients and handler pointers
+func DatasourceGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		datasourceID, err := strconv.Atoi(chi.URLParam(r, "datasourceID"))
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				"provided datasourceID is not integer")
+			return
+		}
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		result, err := handler.DatasourceGet(clients, organizationID, datasourceID)
+		if err != nil {
+			writeDatasourceError(w, err, datasourceID)
+			return
+		}
+		writeDatasourceResult(w, result)
+	}
+}
+
+// DatasourcesPost returns http handler with stored clients and handler pointers
+func DatasourcesPost(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+
+	// all passed data would be validated by json-schema checker
+	schemaLoader := gojsonschema.NewStringLoader(
+		v1JsonSchema.DatasourcesCreateJSONSchema)
+	return func(w http.ResponseWriter, r *http.Request) {
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		// validate data using jsonSchema
+		bodyData, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Error reading request body")
+			return
+		}
+
+		documentLoader := gojsonschema.NewStringLoader(string(bodyData))
+		validationResult, err := gojsonschema.Validate(schemaLoader, documentLoader)
+		if err != nil {
+			// something is wrong with user json schema
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("Error parsing json body '%s'", err))
+			return
+		}
+		if !validationResult.Valid() {
+			// provided json body does not correspond to json schema
+			errorList := "["
+			for _, desc := range validationResult.Errors() {
+				errorList += desc.String()
+			}
+			errorList += "]"
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("request body is not valid, list of erros %s", errorList))
+			return
+		}
+		// data is validated - we can parse it and proceed
+		payload := common.DatasourcePOSTData{}
+		err = json.Unmarshal(bodyData, &payload)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal Server Error")
+			return
+		}
+
+		result, err := handler.DatasourcesPost(clients, payload, organizationID)
+		if err != nil {
+			writeDatasourceError(w, err, 0)
+			return
+		}
+		common.AddAuditTarget(r.Context(), "datasourceID",
+			strconv.Itoa(result.ID))
+		writeDatasourceResult(w, result)
+	}
+}
+
+// DatasourceDelete returns http handler with stored clients and handler pointers
+func DatasourceDelete(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		datasourceID, err := strconv.Atoi(chi.URLParam(r, "datasourceID"))
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				"provided datasourceID is not integer")
+			return
+		}
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		result, err := handler.DatasourceDelete(clients, organizationID, datasourceID)
+		if err != nil {
+			writeDatasourceError(w, err, datasourceID)
+			return
+		}
+		writeDatasourceResult(w, result)
+	}
+}
@@ -0,0 +1,45 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pressly/chi"
+	"net/http"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// JobGet returns http handler with stored clients and handler pointers
+func JobGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := chi.URLParam(r, "jobID")
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		result, err := handler.JobGet(clients, organizationID, jobID)
+		if err != nil {
+			switch err.(type) {
+			// job does not exist or belongs to other organization
+			case common.NotFoundError:
+				common.WriteErrorToResponse(w, http.StatusNotFound,
+					http.StatusText(http.StatusNotFound),
+					fmt.Sprintf("Requested job '%s' was not found", jobID))
+			default:
+				log.Logger.Error(err)
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+			}
+			return
+		}
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
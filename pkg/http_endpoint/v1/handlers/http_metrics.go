@@ -0,0 +1,33 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// MetricsGet returns http handler with stored clients and handler pointers
+func MetricsGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := handler.MetricsGet(clients)
+		if err != nil {
+			log.Logger.Errorf("Error %s occured on handler func while "+
+				"getting metrics", err)
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
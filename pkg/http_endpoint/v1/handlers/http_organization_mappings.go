@@ -0,0 +1,66 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+const organizationsRemapDryRunParam = "dry_run"
+
+func writeOrganizationMappingsResult(w http.ResponseWriter,
+	result interface{}, err error) {
+	if err != nil {
+		log.Logger.Error(err)
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return
+	}
+	serializedResult, serializationError := json.Marshal(result)
+	if serializationError != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedResult)
+}
+
+// OrganizationMappingsGet returns http handler with stored clients and
+// handler pointers
+func OrganizationMappingsGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := handler.OrganizationMappingsGet(clients)
+		writeOrganizationMappingsResult(w, result, err)
+	}
+}
+
+// OrganizationsRemap returns http handler with stored clients and handler
+// pointers
+func OrganizationsRemap(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// dry_run query parameter defines, whether changes are only
+		// reported
+		dryRun := false
+		if dryRunValue := r.URL.Query().Get(organizationsRemapDryRunParam); dryRunValue != "" {
+			var parseErr error
+			dryRun, parseErr = strconv.ParseBool(dryRunValue)
+			if parseErr != nil {
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity),
+					fmt.Sprintf("provided dry_run value is not boolean '%s'",
+						dryRunValue))
+				return
+			}
+		}
+		result, err := handler.OrganizationsRemap(clients, dryRun)
+		writeOrganizationMappingsResult(w, result, err)
+	}
+}
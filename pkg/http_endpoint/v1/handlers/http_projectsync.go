@@ -0,0 +1,73 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/projectsync"
+)
+
+const projectSyncDryRunParam = "dry_run"
+
+func writeProjectSyncResult(w http.ResponseWriter, report *projectsync.Report,
+	err error) {
+	if err != nil {
+		switch err.(type) {
+		// synchronization was not run yet
+		case common.NotFoundError:
+			common.WriteErrorToResponse(w, http.StatusNotFound,
+				http.StatusText(http.StatusNotFound), err.Error())
+		default:
+			log.Logger.Error(err)
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+		}
+		return
+	}
+	serializedResult, serializationError := json.Marshal(report)
+	if serializationError != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedResult)
+}
+
+// ProjectSyncReport returns http handler with stored clients and handler
+// pointers
+func ProjectSyncReport(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := handler.ProjectSyncReport(clients)
+		writeProjectSyncResult(w, report, err)
+	}
+}
+
+// ProjectSync returns http handler with stored clients and handler pointers
+func ProjectSync(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// dry_run query parameter defines, whether changes are only
+		// reported
+		dryRun := false
+		if dryRunValue := r.URL.Query().Get(projectSyncDryRunParam); dryRunValue != "" {
+			var parseErr error
+			dryRun, parseErr = strconv.ParseBool(dryRunValue)
+			if parseErr != nil {
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity),
+					fmt.Sprintf("provided dry_run value is not boolean '%s'",
+						dryRunValue))
+				return
+			}
+		}
+		report, err := handler.ProjectSync(clients, dryRun)
+		writeProjectSyncResult(w, report, err)
+	}
+}
@@ -0,0 +1,54 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/reconciler"
+)
+
+func writeReconciliationResult(w http.ResponseWriter, report *reconciler.Report,
+	err error) {
+	if err != nil {
+		switch err.(type) {
+		// reconciliation was not run yet
+		case common.NotFoundError:
+			common.WriteErrorToResponse(w, http.StatusNotFound,
+				http.StatusText(http.StatusNotFound), err.Error())
+		default:
+			log.Logger.Error(err)
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+		}
+		return
+	}
+	serializedResult, serializationError := json.Marshal(report)
+	if serializationError != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedResult)
+}
+
+// ReconciliationReport returns http handler with stored clients and handler pointers
+func ReconciliationReport(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := handler.ReconciliationReport(clients)
+		writeReconciliationResult(w, report, err)
+	}
+}
+
+// Reconcile returns http handler with stored clients and handler pointers
+func Reconcile(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := handler.Reconcile(clients)
+		writeReconciliationResult(w, report, err)
+	}
+}
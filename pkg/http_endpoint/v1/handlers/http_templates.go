@@ -0,0 +1,190 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pressly/chi"
+	"github.com/xeipuuv/gojsonschema"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"visualization-api/pkg/http_endpoint/common"
+	v1JsonSchema "visualization-api/pkg/http_endpoint/v1/json_schemas"
+	"visualization-api/pkg/logging"
+)
+
+const templateNameParam = "name"
+const templateVersionParam = "version"
+
+// TemplatesGet returns http handler with stored clients and handler pointers
+func TemplatesGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// This function is triggered by router. We have to parse / validate
+		// all data from http request and call handle function
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+		name := r.URL.Query().Get(templateNameParam)
+
+		var version int
+		if versionParam := r.URL.Query().Get(templateVersionParam); versionParam != "" {
+			parsedVersion, err := strconv.Atoi(versionParam)
+			if err != nil {
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity),
+					"provided version is not integer")
+				return
+			}
+			version = parsedVersion
+		}
+
+		log.Logger.Debugf("%s call with query parameters: name='%s', version='%d'",
+			r.URL.Path, name, version)
+
+		result, err := handler.TemplatesGet(clients, organizationID, name,
+			version)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			log.Logger.Errorf("Error %s occured on handler func while"+
+				"querying templates", err.Error())
+			return
+		}
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
+
+// TemplatesPost returns http handler with stored clients and handler pointers
+func TemplatesPost(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+
+	// all passed data would be validated by json-schema checker
+	schemaLoader := gojsonschema.NewStringLoader(
+		v1JsonSchema.TemplatesCreateJSONSchema)
+	return func(w http.ResponseWriter, r *http.Request) {
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		// validate data using jsonSchema
+		bodyData, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Error reading request body")
+			return
+		}
+
+		documentLoader := gojsonschema.NewStringLoader(string(bodyData))
+		validationResult, err := gojsonschema.Validate(schemaLoader, documentLoader)
+		if err != nil {
+			// something is wrong with user json schema
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("Error parsing json body '%s'", err))
+			return
+		}
+		if !validationResult.Valid() {
+			// provided json body does not correspond to json schema
+			errorList := "["
+			for _, desc := range validationResult.Errors() {
+				errorList += desc.String()
+			}
+			errorList += "]"
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("request body is not valid, list of erros %s", errorList))
+			return
+		}
+		// data is validated - we can parse it and proceed
+		payload := common.TemplatePOSTData{}
+		err = json.Unmarshal(bodyData, &payload)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal Server Error")
+			return
+		}
+
+		result, err := handler.TemplatesPost(clients, payload, organizationID)
+		if err != nil {
+			log.Logger.Error(err)
+
+			switch err.(type) {
+			case common.UserDataError:
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity),
+					fmt.Sprintf("Error parsing template '%s'", err))
+				return
+			case common.ConflictError:
+				common.WriteErrorToResponse(w, http.StatusConflict,
+					http.StatusText(http.StatusConflict), err.Error())
+				return
+			default:
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+				return
+			}
+		}
+		common.AddAuditTarget(r.Context(), "templateID", strconv.Itoa(result.ID))
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
+
+// TemplateDelete returns http handler with stored clients and handler pointers
+func TemplateDelete(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		templateID, err := strconv.Atoi(chi.URLParam(r, "templateID"))
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				"provided templateID is not integer")
+			return
+		}
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		result, err := handler.TemplateDelete(clients, organizationID, templateID)
+		if err != nil {
+			switch err.(type) {
+			// template was not found in db
+			case common.UserDataError:
+				common.WriteErrorToResponse(w, http.StatusNotFound,
+					http.StatusText(http.StatusNotFound),
+					fmt.Sprintf("Requested template '%d' was not found",
+						templateID))
+				return
+			default:
+				log.Logger.Error(err)
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+				return
+			}
+		}
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
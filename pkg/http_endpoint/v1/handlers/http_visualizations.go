@@ -2,18 +2,79 @@ package v1handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/pressly/chi"
 	"github.com/satori/go.uuid"
 	"github.com/xeipuuv/gojsonschema"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"visualization-api/pkg/database"
 	"visualization-api/pkg/http_endpoint/common"
 	v1JsonSchema "visualization-api/pkg/http_endpoint/v1/json_schemas"
+	"visualization-api/pkg/jobs"
 	"visualization-api/pkg/logging"
+	"visualization-api/pkg/search"
 )
 
-const visualizationNameParam = "name"
+const visualizationIncludeParam = "include"
+const visualizationIncludeRenderedTemplate = "renderedTemplate"
+const visualizationAsyncParam = "async"
+const visualizationLimitParam = "limit"
+const visualizationOffsetParam = "offset"
+const visualizationMarkerParam = "marker"
+const visualizationSortParam = "sort"
+
+// maxVisualizationsPageLimit is the biggest page of visualizations user can
+// request
+const maxVisualizationsPageLimit = 1000
+
+// totalCountHeader contains amount of all visualizations matching query
+const totalCountHeader = "X-Total-Count"
+
+// paginationParams are query parameters, which are not used as tags
+var paginationParams = map[string]bool{
+	visualizationLimitParam:  true,
+	visualizationOffsetParam: true,
+	visualizationMarkerParam: true,
+	visualizationSortParam:   true,
+}
+
+// readPagination parses pagination parameters of visualizations query
+func readPagination(query url.Values) (db.Pagination, error) {
+	pagination := db.Pagination{Sort: db.SortByCreated}
+	var err error
+	if limit := query.Get(visualizationLimitParam); limit != "" {
+		pagination.Limit, err = strconv.Atoi(limit)
+		if err != nil || pagination.Limit <= 0 ||
+			pagination.Limit > maxVisualizationsPageLimit {
+			return pagination, fmt.Errorf(
+				"limit has to be integer between 1 and %d",
+				maxVisualizationsPageLimit)
+		}
+	}
+	if offset := query.Get(visualizationOffsetParam); offset != "" {
+		pagination.Offset, err = strconv.Atoi(offset)
+		if err != nil || pagination.Offset < 0 {
+			return pagination, errors.New("offset has to be non negative integer")
+		}
+	}
+	pagination.Marker = query.Get(visualizationMarkerParam)
+	if pagination.Marker != "" && pagination.Offset != 0 {
+		return pagination, errors.New("marker and offset can not be used together")
+	}
+	if sort := query.Get(visualizationSortParam); sort != "" {
+		if sort != db.SortByName && sort != db.SortByCreated {
+			return pagination, fmt.Errorf("sort has to be one of '%s', '%s'",
+				db.SortByName, db.SortByCreated)
+		}
+		pagination.Sort = sort
+	}
+	return pagination, nil
+}
 
 // VisualizationsGet returns http handler with stored clients and handler pointers
 func VisualizationsGet(clients *common.ClientContainer,
@@ -24,32 +85,39 @@ func VisualizationsGet(clients *common.ClientContainer,
 
 		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
 
-		var name string
-		tags := make(map[string]interface{})
-
 		providedArgs := r.URL.Query()
-		for paramName, paramValue := range providedArgs {
-			// only one tag value currently allowed for one tag name
-			tagValue := paramValue[0]
-			if paramName == visualizationNameParam {
-				// user provided name of visualization for query
-				name = tagValue
-			} else {
-				tags[paramName] = tagValue
-			}
+		pagination, err := readPagination(providedArgs)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity), err.Error())
+			return
+		}
+		// all other parameters describe name and tags of visualizations
+		query, err := search.Parse(providedArgs, paginationParams)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("Invalid search query: %s", err))
+			return
 		}
 
-		log.Logger.Debugf("%s call with query parameters: name='%s', tags='%s'",
-			r.URL.Path, name, tags)
+		log.Logger.Debugf("%s call with query parameters: query='%+v', "+
+			"pagination='%+v'", r.URL.Path, query, pagination)
 
-		result, err := handler.VisualizationsGet(clients, organizationID,
-			name, tags)
+		result, total, err := handler.VisualizationsGet(clients, organizationID,
+			query, pagination)
 		if err != nil {
-			common.WriteErrorToResponse(w, http.StatusInternalServerError,
-				http.StatusText(http.StatusInternalServerError),
-				"Internal server error occured")
 			log.Logger.Errorf("Error %s occured on handler func while"+
 				"querying visualizations", err.Error())
+			switch err.(type) {
+			case common.UserDataError:
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity), err.Error())
+			default:
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+			}
 		} else {
 			serializedResult, serializationError := json.Marshal(result)
 			if serializationError != nil {
@@ -58,12 +126,97 @@ func VisualizationsGet(clients *common.ClientContainer,
 					"Internal server error occured")
 				return
 			}
+			w.Header().Set(totalCountHeader, strconv.FormatInt(total, 10))
 			w.WriteHeader(http.StatusOK)
 			w.Write(serializedResult)
 		}
 	}
 }
 
+// readVisualizationPayload validates request body against provided json schema
+// and parses it. In case of error, it is written to response and false is returned
+func readVisualizationPayload(w http.ResponseWriter, r *http.Request,
+	schemaLoader gojsonschema.JSONLoader) (common.VisualizationPOSTData, bool) {
+	payload := common.VisualizationPOSTData{}
+
+	// validate data using jsonSchema
+	bodyData, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Error reading request body")
+		return payload, false
+	}
+
+	documentLoader := gojsonschema.NewStringLoader(string(bodyData))
+	validationResult, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	if err != nil {
+		// something is wrong with user json schema
+		common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+			http.StatusText(http.StatusUnprocessableEntity),
+			fmt.Sprintf("Error parsing json body '%s'", err))
+		return payload, false
+	}
+	if !validationResult.Valid() {
+		// provided json body does not correspond to json schema
+		errorList := "["
+		for _, desc := range validationResult.Errors() {
+			errorList += desc.String()
+		}
+		errorList += "]"
+		common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+			http.StatusText(http.StatusUnprocessableEntity),
+			fmt.Sprintf("request body is not valid, list of erros %s", errorList))
+		return payload, false
+	}
+	// data is validated - we can parse it and proceed
+	err = json.Unmarshal(bodyData, &payload)
+	if err != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal Server Error")
+		return payload, false
+	}
+	return payload, true
+}
+
+func visualizationsPostAsync(w http.ResponseWriter, r *http.Request,
+	clients *common.ClientContainer, handler common.HandlerInterface,
+	payload common.VisualizationPOSTData, organizationID string) {
+	job, err := handler.VisualizationsPostAsync(clients, payload, organizationID)
+	if err != nil {
+		log.Logger.Error(err)
+		if err == jobs.ErrQueueFull {
+			common.WriteErrorToResponse(w, http.StatusServiceUnavailable,
+				http.StatusText(http.StatusServiceUnavailable), err.Error())
+			return
+		}
+		switch err.(type) {
+		case common.UserDataError:
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("Error rendering template '%s'", err))
+		default:
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+		}
+		return
+	}
+	common.AddAuditTarget(r.Context(), "jobID", job.ID)
+	common.AddAuditTarget(r.Context(), "visualizationID", job.ResourceID)
+	serializedResult, serializationError := json.Marshal(job)
+	if serializationError != nil {
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/v1/jobs/%s", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(serializedResult)
+}
+
 // VisualizationsPost returns http handler with stored clients and handler pointers
 func VisualizationsPost(clients *common.ClientContainer,
 	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
@@ -77,48 +230,34 @@ func VisualizationsPost(clients *common.ClientContainer,
 
 		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
 
-		// validate data using jsonSchema
-		bodyData, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			common.WriteErrorToResponse(w, http.StatusInternalServerError,
-				http.StatusText(http.StatusInternalServerError),
-				"Error reading request body")
-			return
+		// async query parameter defines, whether dashboards are uploaded
+		// to grafana within request or by job
+		async := false
+		if asyncValue := r.URL.Query().Get(visualizationAsyncParam); asyncValue != "" {
+			var parseErr error
+			async, parseErr = strconv.ParseBool(asyncValue)
+			if parseErr != nil {
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity),
+					fmt.Sprintf("provided async value is not boolean '%s'",
+						asyncValue))
+				return
+			}
 		}
 
-		documentLoader := gojsonschema.NewStringLoader(string(bodyData))
-		validationResult, err := gojsonschema.Validate(schemaLoader, documentLoader)
-		if err != nil {
-			// something is wrong with user json schema
-			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
-				http.StatusText(http.StatusUnprocessableEntity),
-				fmt.Sprintf("Error parsing json body '%s'", err))
+		payload, ok := readVisualizationPayload(w, r, schemaLoader)
+		if !ok {
 			return
 		}
-		if !validationResult.Valid() {
-			// provided json body does not correspond to json schema
-			errorList := "["
-			for _, desc := range validationResult.Errors() {
-				errorList += desc.String()
-			}
-			errorList += "]"
-			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
-				http.StatusText(http.StatusUnprocessableEntity),
-				fmt.Sprintf("request body is not valid, list of erros %s", errorList))
-			return
-		}
-		// data is validated - we can parse it and proceed
-		payload := common.VisualizationPOSTData{}
-		err = json.Unmarshal(bodyData, &payload)
-		if err != nil {
-			common.WriteErrorToResponse(w, http.StatusInternalServerError,
-				http.StatusText(http.StatusInternalServerError),
-				"Internal Server Error")
+		if async {
+			visualizationsPostAsync(w, r, clients, handler, payload,
+				organizationID)
 			return
 		}
 		result, err := handler.VisualizationsPost(clients, payload, organizationID)
 		var encodedResult []byte
 		if result != nil {
+			common.AddAuditTarget(r.Context(), "visualizationID", result.Slug)
 			serializedResult, serializationError := json.Marshal(result)
 			if serializationError != nil {
 				common.WriteErrorToResponse(w, http.StatusInternalServerError,
@@ -156,6 +295,69 @@ func VisualizationsPost(clients *common.ClientContainer,
 	}
 }
 
+// VisualizationGet returns http handler with stored clients and handler pointers
+func VisualizationGet(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// get visualizationId from url and validate, that it matches expected format
+		visualizationID := chi.URLParam(r, "visualizationID")
+		_, err := uuid.FromString(visualizationID)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("provided id does not match UUIDv4 format '%s'",
+					visualizationID))
+			return
+		}
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		// include parameter contains comma separated list of optional fields
+		includeRenderedTemplate := false
+		for _, includeValue := range r.URL.Query()[visualizationIncludeParam] {
+			for _, field := range strings.Split(includeValue, ",") {
+				if field != visualizationIncludeRenderedTemplate {
+					common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+						http.StatusText(http.StatusUnprocessableEntity),
+						fmt.Sprintf("unknown value of include parameter '%s'",
+							field))
+					return
+				}
+				includeRenderedTemplate = true
+			}
+		}
+
+		result, err := handler.VisualizationGet(clients, organizationID,
+			visualizationID, includeRenderedTemplate)
+		if err != nil {
+			switch err.(type) {
+			// visualization was not found in db
+			case common.NotFoundError:
+				common.WriteErrorToResponse(w, http.StatusNotFound,
+					http.StatusText(http.StatusNotFound),
+					fmt.Sprintf("Requested visualization '%s' was not found",
+						visualizationID))
+				return
+			default:
+				log.Logger.Errorf("Error %s occured on handler func while"+
+					"getting visualization", err.Error())
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+				return
+			}
+		}
+		serializedResult, serializationError := json.Marshal(result)
+		if serializationError != nil {
+			common.WriteErrorToResponse(w, http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError),
+				"Internal server error occured")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(serializedResult)
+	}
+}
+
 // VisualizationDelete returns http handler with stored clients and handler pointers
 func VisualizationDelete(clients *common.ClientContainer,
 	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
@@ -185,15 +387,85 @@ func VisualizationDelete(clients *common.ClientContainer,
 			encodedResult = serializedResult
 		}
 		if err != nil {
+			switch err.(type) {
+			case common.NotFoundError:
+				common.WriteErrorToResponse(w, http.StatusNotFound,
+					http.StatusText(http.StatusNotFound),
+					fmt.Sprintf("Requested visualization '%s' was not found",
+						visualizationID))
+				return
+			case common.ClientError:
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write(encodedResult)
+				return
+			default:
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(encodedResult)
+	}
+}
+
+// VisualizationUpdate returns http handler with stored clients and handler pointers
+func VisualizationUpdate(clients *common.ClientContainer,
+	handler common.HandlerInterface) func(http.ResponseWriter, *http.Request) {
+
+	// visualization is updated with the same data as it is created
+	schemaLoader := gojsonschema.NewStringLoader(
+		v1JsonSchema.VisualizationsCreateJSONSchema)
+	return func(w http.ResponseWriter, r *http.Request) {
+		// get visualizationId from url and validate, that it matches expected format
+		visualizationID := chi.URLParam(r, "visualizationID")
+		_, err := uuid.FromString(visualizationID)
+		if err != nil {
+			common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+				http.StatusText(http.StatusUnprocessableEntity),
+				fmt.Sprintf("provided id does not match UUIDv4 format '%s'",
+					visualizationID))
+			return
+		}
+		organizationID := r.Context().Value(common.OrganizationIDContext).(string)
+
+		payload, ok := readVisualizationPayload(w, r, schemaLoader)
+		if !ok {
+			return
+		}
+		result, err := handler.VisualizationUpdate(clients, payload,
+			organizationID, visualizationID)
+		var encodedResult []byte
+		if result != nil {
+			serializedResult, serializationError := json.Marshal(result)
+			if serializationError != nil {
+				common.WriteErrorToResponse(w, http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError),
+					"Internal server error occured")
+				return
+			}
+			encodedResult = serializedResult
+		}
+		if err != nil {
+			log.Logger.Error(err)
+
 			switch err.(type) {
 			// visualization was not found in db
-			case common.UserDataError:
+			case common.NotFoundError:
 				common.WriteErrorToResponse(w, http.StatusNotFound,
 					http.StatusText(http.StatusNotFound),
 					fmt.Sprintf("Requested visualization '%s' was not found",
 						visualizationID))
 				return
+			case common.UserDataError:
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity),
+					fmt.Sprintf("Error rendering template '%s'", err))
+				return
 			case common.ClientError:
+				// grafana failed in the middle of update, data matching
+				// grafana state was stored in db, so we return it to user
 				w.WriteHeader(http.StatusInternalServerError)
 				w.Write(encodedResult)
 				return
@@ -0,0 +1,21 @@
+package v1handlers
+
+import (
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jobs"
+	"visualization-api/pkg/logging"
+)
+
+// V1Jobs implements part of handler interface
+type V1Jobs struct{}
+
+// JobGet returns job of organization
+func (h *V1Jobs) JobGet(clients *common.ClientContainer, organizationID,
+	jobID string) (*jobs.Job, error) {
+	job := clients.Jobs.Get(organizationID, jobID)
+	if job == nil {
+		log.Logger.Errorf("User requested job '%s' not found", jobID)
+		return nil, common.NewNotFoundError("No jobs found")
+	}
+	return job, nil
+}
@@ -0,0 +1,16 @@
+package v1handlers
+
+import (
+	"visualization-api/pkg/http_endpoint/common"
+)
+
+// V1Metrics implements part of handler interface
+type V1Metrics struct{}
+
+// MetricsGet returns usage metrics of api internals
+func (h *V1Metrics) MetricsGet(clients *common.ClientContainer) (
+	*common.MetricsResponseEntry, error) {
+	return &common.MetricsResponseEntry{
+		KeystoneTokenCache: clients.Openstack.TokenCacheStats(),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package v1handlers
+
+import (
+	"strconv"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// V1OrganizationMappings implements part of handler interface
+type V1OrganizationMappings struct{}
+
+// OrganizationMappingsGet returns grafana organizations of all projects
+func (h *V1OrganizationMappings) OrganizationMappingsGet(
+	clients *common.ClientContainer) (
+	*[]common.OrganizationMappingResponseEntry, error) {
+	mappings, err := clients.DatabaseManager.QueryOrganizationMappings()
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return nil, err
+	}
+
+	response := []common.OrganizationMappingResponseEntry{}
+	for _, mapping := range mappings {
+		response = append(response, common.OrganizationMappingResponseEntry{
+			ProjectID:        mapping.ProjectID,
+			OrganizationID:   mapping.OrganizationID,
+			OrganizationName: mapping.OrganizationName,
+			UpdatedAt:        mapping.UpdatedAt,
+		})
+	}
+	return &response, nil
+}
+
+// mappingIntact checks that organization of mapping was not recreated in
+// grafana. Organizations are matched by id, their names are used only to
+// find out that organization of the same id belongs to other project now
+func mappingIntact(mapping *models.OrganizationMapping,
+	organizationsByID map[string]grafanaclient.OrgList,
+	organizationsByName map[string]grafanaclient.OrgList,
+	mappedNames map[string]string) bool {
+	organization, exists := organizationsByID[mapping.OrganizationID]
+	if !exists {
+		return false
+	}
+	if organization.Name == mapping.OrganizationName {
+		return true
+	}
+	// organization was renamed, unless organization of mapped name
+	// exists or its name is mapped name of other project
+	_, recreated := organizationsByName[mapping.OrganizationName]
+	owner, taken := mappedNames[organization.Name]
+	return !recreated && (!taken || owner == mapping.ProjectID)
+}
+
+// OrganizationsRemap maps projects, which organizations were recreated in
+// grafana, to their current organizations and moves their visualizations
+// and templates there. Recreated organizations are found by mapped names,
+// missing organizations are created, nothing is changed on dry run. Dashboards are uploaded to new organizations by
+// reconciliation
+func (h *V1OrganizationMappings) OrganizationsRemap(
+	clients *common.ClientContainer, dryRun bool) (
+	*[]common.OrganizationRemapResponseEntry, error) {
+	log.Logger.Infof("Remap of organizations is requested by user, dry run: "+
+		"%t", dryRun)
+	mappings, err := clients.DatabaseManager.QueryOrganizationMappings()
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return nil, err
+	}
+	organizations, err := clients.Grafana.GetOrganizations()
+	if err != nil {
+		log.Logger.Errorf("Error during performing grafana call "+
+			"for organizations listing %s", err)
+		return nil, err
+	}
+	organizationsByID := map[string]grafanaclient.OrgList{}
+	organizationsByName := map[string]grafanaclient.OrgList{}
+	for _, organization := range organizations {
+		organizationsByID[strconv.Itoa(organization.ID)] = organization
+		organizationsByName[organization.Name] = organization
+	}
+	mappedNames := map[string]string{}
+	for _, mapping := range mappings {
+		mappedNames[mapping.OrganizationName] = mapping.ProjectID
+	}
+
+	response := []common.OrganizationRemapResponseEntry{}
+	remaps := []*db.OrganizationRemap{}
+	for _, mapping := range mappings {
+		if mappingIntact(mapping, organizationsByID, organizationsByName,
+			mappedNames) {
+			continue
+		}
+
+		entry := common.OrganizationRemapResponseEntry{
+			ProjectID:         mapping.ProjectID,
+			OrganizationName:  mapping.OrganizationName,
+			OldOrganizationID: mapping.OrganizationID,
+			Action:            common.OrganizationRemapped,
+		}
+		// recreated organization is found by mapped name
+		organization, exists := organizationsByName[mapping.OrganizationName]
+		if exists {
+			entry.NewOrganizationID = strconv.Itoa(organization.ID)
+		} else {
+			entry.Action = common.OrganizationCreated
+			if !dryRun {
+				log.Logger.Infof("Creating missing organization '%s' of "+
+					"project '%s'", mapping.OrganizationName, mapping.ProjectID)
+				organization, err := clients.Grafana.GetOrCreateOrgByName(
+					mapping.OrganizationName)
+				if err != nil {
+					log.Logger.Errorf("Error during performing grafana call "+
+						"for organization creation %s", err)
+					return nil, err
+				}
+				entry.NewOrganizationID = strconv.Itoa(organization.ID)
+			}
+		}
+		response = append(response, entry)
+		remaps = append(remaps, &db.OrganizationRemap{
+			ProjectID:           entry.ProjectID,
+			OldOrganizationID:   entry.OldOrganizationID,
+			NewOrganizationID:   entry.NewOrganizationID,
+			NewOrganizationName: entry.OrganizationName,
+		})
+	}
+
+	if dryRun || len(remaps) == 0 {
+		return &response, nil
+	}
+	err = clients.DatabaseManager.RemapOrganizations(remaps)
+	if err != nil {
+		log.Logger.Errorf("Error remapping organizations in db: '%s'", err)
+		return nil, err
+	}
+	for index, remap := range remaps {
+		log.Logger.Infof("Project '%s' is remapped from organization '%s' "+
+			"to '%s' with %d visualizations", remap.ProjectID,
+			remap.OldOrganizationID, remap.NewOrganizationID,
+			remap.RewrittenVisualizations)
+		response[index].RewrittenVisualizations = remap.RewrittenVisualizations
+	}
+	return &response, nil
+}
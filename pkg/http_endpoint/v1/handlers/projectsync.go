@@ -0,0 +1,31 @@
+package v1handlers
+
+import (
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/projectsync"
+)
+
+// V1ProjectSync implements part of handler interface
+type V1ProjectSync struct{}
+
+// ProjectSyncReport returns report of the latest synchronization of projects
+func (h *V1ProjectSync) ProjectSyncReport(clients *common.ClientContainer) (
+	*projectsync.Report, error) {
+	report := clients.ProjectSync.LastReport()
+	if report == nil {
+		log.Logger.Debug("Synchronization of projects has not been run yet")
+		return nil, common.NewNotFoundError(
+			"Synchronization of projects has not been run yet")
+	}
+	return report, nil
+}
+
+// ProjectSync runs synchronization of projects immediately and returns its
+// report, nothing is changed on dry run
+func (h *V1ProjectSync) ProjectSync(clients *common.ClientContainer,
+	dryRun bool) (*projectsync.Report, error) {
+	log.Logger.Infof("Synchronization of projects is requested by user, "+
+		"dry run: %t", dryRun)
+	return clients.ProjectSync.Run(dryRun), nil
+}
@@ -0,0 +1,28 @@
+package v1handlers
+
+import (
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/reconciler"
+)
+
+// V1Reconciliation implements part of handler interface
+type V1Reconciliation struct{}
+
+// ReconciliationReport returns report of the latest reconciliation run
+func (h *V1Reconciliation) ReconciliationReport(clients *common.ClientContainer) (
+	*reconciler.Report, error) {
+	report := clients.Reconciler.LastReport()
+	if report == nil {
+		log.Logger.Debug("Reconciliation has not been run yet")
+		return nil, common.NewNotFoundError("Reconciliation has not been run yet")
+	}
+	return report, nil
+}
+
+// Reconcile runs reconciliation immediately and returns its report
+func (h *V1Reconciliation) Reconcile(clients *common.ClientContainer) (
+	*reconciler.Report, error) {
+	log.Logger.Info("Reconciliation is requested by user")
+	return clients.Reconciler.Run(), nil
+}
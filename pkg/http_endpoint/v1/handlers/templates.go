@@ -0,0 +1,135 @@
+package v1handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/logging"
+)
+
+// V1Templates implements part of handler interface
+type V1Templates struct{}
+
+// TemplateToResponse transforms template model to response format
+func TemplateToResponse(templateDB *models.Template) *common.TemplateResponseEntry {
+	// parameters are stored json encoded, they are returned as an object
+	parameters := map[string]interface{}{}
+	if templateDB.Parameters != "" {
+		err := json.Unmarshal([]byte(templateDB.Parameters), &parameters)
+		if err != nil || parameters == nil {
+			log.Logger.Errorf("Parameters of template '%d' are not json "+
+				"object: '%s'", templateDB.ID, templateDB.Parameters)
+			parameters = map[string]interface{}{}
+		}
+	}
+	return &common.TemplateResponseEntry{
+		ID:           templateDB.ID,
+		Name:         templateDB.Name,
+		Version:      templateDB.Version,
+		Parameters:   parameters,
+		TemplateBody: templateDB.Body,
+	}
+}
+
+// TemplatesGet handler queries templates of organization
+func (h *V1Templates) TemplatesGet(clients *common.ClientContainer,
+	organizationID, name string, version int) (
+	*[]common.TemplateResponseEntry, error) {
+	log.Logger.Debug("Querying templates according to name and version")
+
+	templates, err := clients.DatabaseManager.QueryTemplates(organizationID,
+		name, version)
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return nil, err
+	}
+
+	response := []common.TemplateResponseEntry{}
+	for _, templateDB := range templates {
+		response = append(response, *TemplateToResponse(templateDB))
+	}
+	return &response, nil
+}
+
+// TemplatesPost handler publishes new template version of organization
+func (h *V1Templates) TemplatesPost(clients *common.ClientContainer,
+	data common.TemplatePOSTData, organizationID string) (
+	*common.TemplateResponseEntry, error) {
+
+	// validate that golang template is valid before storing it, so
+	// visualizations would not fail on broken catalog entries
+	_, err := template.New("").Option("missingkey=error").Parse(data.TemplateBody)
+	if err != nil {
+		return nil, common.NewUserDataError(err.Error())
+	}
+
+	if data.Version != 0 {
+		log.Logger.Debugf("Checking if template '%s' version '%d' exists",
+			data.Name, data.Version)
+		existingTemplates, err := clients.DatabaseManager.QueryTemplates(
+			organizationID, data.Name, data.Version)
+		if err != nil {
+			log.Logger.Errorf("Error getting data from db: '%s'", err)
+			return nil, err
+		}
+		if len(existingTemplates) != 0 {
+			return nil, common.NewConflictError(fmt.Sprintf(
+				"Template '%s' version '%d' already exists", data.Name,
+				data.Version))
+		}
+	}
+
+	templateDB, err := clients.DatabaseManager.CreateTemplate(data.Name,
+		organizationID, data.Version, data.Parameters, data.TemplateBody)
+	if err == db.ErrDuplicate {
+		return nil, common.NewConflictError(fmt.Sprintf(
+			"Template '%s' version '%d' already exists", data.Name,
+			data.Version))
+	}
+	if err != nil {
+		log.Logger.Errorf("Error creating template in db: '%s'", err)
+		return nil, err
+	}
+	log.Logger.Infof("Created template '%s' version '%d'", templateDB.Name,
+		templateDB.Version)
+	return TemplateToResponse(templateDB), nil
+}
+
+// TemplateDelete removes template
+func (h *V1Templates) TemplateDelete(clients *common.ClientContainer,
+	organizationID string, templateID int) (*common.TemplateResponseEntry, error) {
+	log.Logger.Debugf("removing template '%d' from db", templateID)
+	templateDB, err := clients.DatabaseManager.DeleteTemplate(templateID,
+		organizationID)
+	if err != nil {
+		log.Logger.Errorf("Error removing template from db: '%s'", err)
+		return nil, err
+	}
+
+	if templateDB == nil {
+		log.Logger.Errorf("User requested template '%d' not found in db", templateID)
+		return nil, common.NewUserDataError("No templates found")
+	}
+	log.Logger.Debugf("removed template '%d' from db", templateID)
+	return TemplateToResponse(templateDB), nil
+}
+
+func getStoredTemplateBody(clients *common.ClientContainer, organizationID,
+	name string, version int) (string, error) {
+	// this function resolves template from catalog of organization by name
+	// and version
+	templates, err := clients.DatabaseManager.QueryTemplates(organizationID,
+		name, version)
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return "", err
+	}
+	if len(templates) == 0 {
+		return "", common.NewUserDataError(fmt.Sprintf(
+			"Template '%s' version '%d' not found", name, version))
+	}
+	return templates[0].Body, nil
+}
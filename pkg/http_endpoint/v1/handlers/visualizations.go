@@ -2,12 +2,18 @@ package v1handlers
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"github.com/satori/go.uuid"
 	"github.com/ulule/deepcopier"
 	"text/template"
+	"visualization-api/pkg/database"
 	"visualization-api/pkg/database/models"
 	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/jobs"
 	"visualization-api/pkg/logging"
+	"visualization-api/pkg/outbox"
+	"visualization-api/pkg/search"
 )
 
 // V1Visualizations implements part of handler interface
@@ -22,6 +28,17 @@ func VisualizationDashboardToResponse(visualization *models.Visualization,
 	visualizationResponse := &common.VisualizationResponseEntry{}
 	dashboardResponse := []*common.DashboardResponseEntry{}
 	deepcopier.Copy(visualization).To(visualizationResponse)
+	// tags are stored json encoded, they are returned as an object
+	visualizationResponse.Tags = map[string]interface{}{}
+	if visualization.Tags != "" {
+		err := json.Unmarshal([]byte(visualization.Tags),
+			&visualizationResponse.Tags)
+		if err != nil || visualizationResponse.Tags == nil {
+			log.Logger.Errorf("Tags of visualization '%s' are not json object: '%s'",
+				visualization.Slug, visualization.Tags)
+			visualizationResponse.Tags = map[string]interface{}{}
+		}
+	}
 	for index := range dashboards {
 		dashboardRes := &common.DashboardResponseEntry{}
 		deepcopier.Copy(dashboards[index]).To(dashboardRes)
@@ -31,35 +48,40 @@ func VisualizationDashboardToResponse(visualization *models.Visualization,
 		visualizationResponse, dashboardResponse}
 }
 
-// GroupedVisualizationDashboardToResponse transforms map of visualizations to response format
-func GroupedVisualizationDashboardToResponse(
-	data *map[models.Visualization][]*models.Dashboard) *[]common.VisualizationWithDashboards {
-	// This function is used, when
-
+// VisualizationsPageToResponse transforms page of visualizations to response
+// format keeping order of visualizations
+func VisualizationsPageToResponse(
+	page *db.VisualizationsPage) *[]common.VisualizationWithDashboards {
 	log.Logger.Debug("rendering data to user")
 	response := []common.VisualizationWithDashboards{}
-	for visualizationPtr, dashboards := range *data {
+	for _, visualization := range page.Visualizations {
 		renderedVisualization := VisualizationDashboardToResponse(
-			&visualizationPtr, dashboards)
+			visualization, page.Dashboards[visualization.ID])
 		response = append(response, *renderedVisualization)
 	}
 	return &response
 }
 
-// VisualizationsGet handler queries visualizations
+// VisualizationsGet handler queries requested page of visualizations and
+// returns it together with total amount of matched visualizations
 func (h *V1Visualizations) VisualizationsGet(clients *common.ClientContainer,
-	organizationID, name string, tags map[string]interface{}) (
-	*[]common.VisualizationWithDashboards, error) {
-	log.Logger.Debug("Querying data to user according to name and tags")
+	organizationID string, query search.Query, pagination db.Pagination) (
+	*[]common.VisualizationWithDashboards, int64, error) {
+	log.Logger.Debug("Querying data to user according to search query")
 
-	data, err := clients.DatabaseManager.QueryVisualizationsDashboards(
-		"", name, organizationID, tags)
+	page, err := clients.DatabaseManager.QueryVisualizationsPage(
+		organizationID, query, pagination)
 	if err != nil {
+		if err == db.ErrMarkerNotFound {
+			return nil, 0, common.NewUserDataError(fmt.Sprintf(
+				"Visualization '%s' provided as marker was not found",
+				pagination.Marker))
+		}
 		log.Logger.Errorf("Error getting data from db: '%s'", err)
-		return nil, err
+		return nil, 0, err
 	}
 
-	return GroupedVisualizationDashboardToResponse(data), nil
+	return VisualizationsPageToResponse(page), page.Total, nil
 }
 
 func renderTemplates(templates []string, templateParamaters []interface{}) (
@@ -92,28 +114,30 @@ func renderTemplates(templates []string, templateParamaters []interface{}) (
 	return renderedTemplates, nil
 }
 
-// VisualizationsPost handler creates new visualizations
-func (h *V1Visualizations) VisualizationsPost(clients *common.ClientContainer,
-	data common.VisualizationPOSTData, organizationID string) (
-	*common.VisualizationWithDashboards, error) {
-
-	/*
-		1 - validate and render  all golang templates provided by user,
-		    if there are any errors, then immediately return error to user
-		2 - validate that rendered templates matches grafana json structure
-			if there are any mismatch - return error to user
-		3 - create db entry for visualization and every dashboard.
-		4 - for each validated template - upload it to grafana, store received
-			slug for future update of dashboard db entry
-		5 - return data to user
-	*/
-
+// renderDashboards resolves templates of organization and renders templates
+// of all dashboards provided by user. It returns dashboard names with
+// matching rendered templates
+func renderDashboards(clients *common.ClientContainer,
+	data common.VisualizationPOSTData, organizationID string) ([]string,
+	[]string, error) {
 	log.Logger.Debug("Extracting names, templates, data from provided user data")
 	templates := []string{}
 	templateParamaters := []interface{}{}
 	dashboardNames := []string{}
 	for _, dashboardData := range data.Dashboards {
-		templates = append(templates, dashboardData.TemplateBody)
+		templateBody := dashboardData.TemplateBody
+		if dashboardData.TemplateName != "" {
+			log.Logger.Debugf("Resolving template '%s' version '%d'",
+				dashboardData.TemplateName, dashboardData.TemplateVersion)
+			storedTemplateBody, err := getStoredTemplateBody(clients,
+				organizationID, dashboardData.TemplateName,
+				dashboardData.TemplateVersion)
+			if err != nil {
+				return nil, nil, err
+			}
+			templateBody = storedTemplateBody
+		}
+		templates = append(templates, templateBody)
 		templateParamaters = append(templateParamaters, dashboardData.TemplateParameters)
 		dashboardNames = append(dashboardNames, dashboardData.Name)
 	}
@@ -121,139 +145,215 @@ func (h *V1Visualizations) VisualizationsPost(clients *common.ClientContainer,
 
 	renderedTemplates, err := renderTemplates(templates, templateParamaters)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return dashboardNames, renderedTemplates, nil
+}
 
-	// create db entries for visualizations and dashboards
-	log.Logger.Debug("Creating database entries for visualizations and dashboards")
-	visualizationDB, dashboardsDB, err := clients.DatabaseManager.CreateVisualizationsWithDashboards(
-		data.Name, organizationID, data.Tags, dashboardNames, renderedTemplates)
-	log.Logger.Debug("Created database entries for visualizations and dashboards")
-	if err != nil {
-		return nil, err
+// uploadVisualization performs journaled uploads of created visualization.
+// Progress of every dashboard upload is reported to provided function
+func uploadVisualization(clients *common.ClientContainer, organizationID string,
+	visualizationDB *models.Visualization, dashboardsDB []*models.Dashboard,
+	operationsDB []*models.GrafanaOperation, progress jobs.ProgressFunc) (
+	*common.VisualizationWithDashboards, error) {
+	if progress == nil {
+		progress = func(int, string, error) {}
 	}
 
 	/*
 		Here concistency problem is faced. We can not guarantee, that data,
 		stored in database would successfully be updated in grafana, due to
-		possible errors on grafana side (service down, etc.). At the same time
-		we can not guarantee, that data created in grafana would successfully
-		stored into db.
-
-		To resolve such kind of issue - following approach is taken. The highest
-		priority is given to database data.
-		That means, that creation of visualization happens in 3 steps
-		1 - create database entry for visualizations and all dashboards.
-			Grafana slug field is left empty
-		2 - create grafana entries via grafana api, get slugs as the result
-		3 - update database entries with grafana slugs
-	*/
+		possible errors on grafana side (service down, etc.) or crash of
+		application itself.
 
-	uploadedGrafanaSlugs := []string{}
+		To resolve such kind of issue - every grafana call is journaled in
+		database in the same transaction with data it relates to. Journaled
+		operation is removed only after grafana call succeeded and its result
+		is stored to database. Operations left in journal (due to crash for
+		example) are performed by outbox worker.
+	*/
 
 	log.Logger.Debug("Uploading dashboard data to grafana")
-	for _, renderedTemplate := range renderedTemplates {
-		slug, grafanaUploadErr := clients.Grafana.UploadDashboard(
-			[]byte(renderedTemplate), organizationID, false)
+	for index, operation := range operationsDB {
+		progress(index, jobs.StatusRunning, nil)
+		slug, grafanaUploadErr := outbox.Execute(clients.DatabaseManager,
+			clients.Grafana, operation, false)
+		if grafanaUploadErr == outbox.ErrOperationClaimed {
+			// lease of upload expired before it was started (job was
+			// queued for too long for example), so uploads were taken over
+			// by outbox worker and created visualization is kept. It is not
+			// an error, remaining uploads are just pending
+			for pending := index; pending < len(operationsDB); pending++ {
+				progress(pending, jobs.StatusPending, nil)
+			}
+			log.Logger.Debugf("Uploads of visualization '%s' are left to "+
+				"outbox worker", visualizationDB.Slug)
+			return VisualizationDashboardToResponse(visualizationDB,
+				dashboardsDB), nil
+		}
 		if grafanaUploadErr != nil {
+			progress(index, jobs.StatusFailed, grafanaUploadErr)
 			// We can not create grafana dashboard using user-provided template
-			log.Logger.Errorf("Error during performing grafana call "+
-				" for dashboard upload %s", grafanaUploadErr)
 			log.Logger.Debugf("Due to error '%s' - already created grafana "+
 				" dashboards, matching the same visualization, would be deleted",
 				grafanaUploadErr)
 
-			updateDashboardsDB := []*models.Dashboard{}
-			deleteDashboardsDB := []*models.Dashboard{}
-			for index, slugToDelete := range uploadedGrafanaSlugs {
-				grafanaDeletionErr := clients.Grafana.DeleteDashboard(slugToDelete, organizationID)
-				// if already created dashboard was failed to delete -
-				// corresponding db entry has to be updated with grafanaSlug
-				// to guarantee consistency
-				if grafanaDeletionErr != nil {
-					log.Logger.Errorf("Error during performing grafana call "+
-						" for dashboard deletion %s", grafanaDeletionErr)
-					dashboard := dashboardsDB[index]
-					dashboard.Slug = uploadedGrafanaSlugs[index]
-					updateDashboardsDB = append(
-						updateDashboardsDB, dashboard)
-				} else {
-					log.Logger.Debug("deleted dashboard from grafana")
-					deleteDashboardsDB = append(deleteDashboardsDB,
-						dashboardsDB[index])
-				}
-			}
-
-			// Delete dashboards, that were not uploaded to grafana
-			deleteDashboardsDB = append(deleteDashboardsDB,
-				dashboardsDB[len(uploadedGrafanaSlugs):]...)
-			if len(updateDashboardsDB) > 0 {
-				dashboardsToReturn := []*models.Dashboard{}
-				dashboardsToReturn = append(dashboardsToReturn, updateDashboardsDB...)
-				log.Logger.Debug("Updating db dashboards with grafana slugs")
-				updateErrorDB := clients.DatabaseManager.BulkUpdateDashboard(
-					updateDashboardsDB)
-				if updateErrorDB != nil {
-					log.Logger.Errorf("Error during cleanup on grafana upload"+
-						" error '%s'. Unable to update db entities of dashboards"+
-						" with slugs of corresponding grafana dashboards for"+
-						"dashboards not deleted from grafana '%s'",
-						grafanaUploadErr, updateErrorDB)
-				}
-				log.Logger.Debug("Deleting db dashboards that are not uploaded" +
-					" to grafana")
-				deletionErrorDB := clients.DatabaseManager.BulkDeleteDashboard(
-					deleteDashboardsDB)
-				if deletionErrorDB != nil {
-					log.Logger.Debug("due to failed deletion operation - extend" +
-						" the slice of returned dashboards to user")
-					dashboardsToReturn = append(dashboardsToReturn, deleteDashboardsDB...)
-					log.Logger.Errorf("Error during cleanup on grafana upload"+
-						" error '%s'. Unable to delete entities of grafana "+
-						"dashboards deleted from grafana '%s'",
-						grafanaUploadErr, updateErrorDB)
-				}
+			// removal of uploaded dashboards is journaled in the same
+			// transaction as removal of visualization, so it would be
+			// finished by outbox worker in case of any failure
+			deleteOperationsDB, deletionErrorDB := clients.DatabaseManager.DeleteVisualizationWithOperations(
+				visualizationDB, dashboardsDB)
+			if deletionErrorDB != nil {
+				log.Logger.Errorf("Error during cleanup on grafana upload"+
+					" error '%s'. Unable to delete visualization from db '%s'",
+					grafanaUploadErr, deletionErrorDB)
+				// visualization is kept in db, pending uploads would be
+				// finished by outbox worker
 				result := VisualizationDashboardToResponse(
-					visualizationDB, dashboardsToReturn)
+					visualizationDB, dashboardsDB)
 				return result, common.NewClientError(
 					"Unable to create new grafana dashboards, and remove old ones")
 			}
-			log.Logger.Debug("trying to delete visualization with " +
-				"corresponding dashboards from database. dashboards have no " +
-				"matching grafana uploads")
-			visualizationDeletionErr := clients.DatabaseManager.DeleteVisualization(
-				visualizationDB)
-			if visualizationDeletionErr != nil {
-				log.Logger.Error("Unable to delete visualization entry " +
-					"from db with corresponding dashboards entries. " +
-					"all entries are returned to user")
-				result := VisualizationDashboardToResponse(
-					visualizationDB, updateDashboardsDB)
-				return result, common.NewClientError(
-					"Unable to create new grafana dashboards, and remove old ones")
+			for _, deleteOperation := range deleteOperationsDB {
+				_, grafanaDeletionErr := outbox.Execute(clients.DatabaseManager,
+					clients.Grafana, deleteOperation, false)
+				if grafanaDeletionErr != nil {
+					log.Logger.Debugf("Removal of grafana dashboard '%s' is "+
+						"left to outbox worker", deleteOperation.Slug)
+				}
 			}
-			log.Logger.Debug("All created data was deleted both from grafana " +
-				"and from database without errors. original grafana error is returned")
+			log.Logger.Debug("Created data was deleted from database, " +
+				"original grafana error is returned")
 			return nil, grafanaUploadErr
 		}
 		log.Logger.Infof("Created dashboard named '%s'", slug)
-		uploadedGrafanaSlugs = append(uploadedGrafanaSlugs, slug)
+		dashboardsDB[index].Slug = slug
+		progress(index, jobs.StatusSucceeded, nil)
 	}
 	log.Logger.Debug("Uploaded dashboard data to grafana")
 
-	// Positive outcome. All dashboards were created both in db and grafana
-	for index := range dashboardsDB {
-		dashboardsDB[index].Slug = uploadedGrafanaSlugs[index]
+	return VisualizationDashboardToResponse(visualizationDB, dashboardsDB), nil
+}
+
+// VisualizationsPost handler creates new visualizations
+func (h *V1Visualizations) VisualizationsPost(clients *common.ClientContainer,
+	data common.VisualizationPOSTData, organizationID string) (
+	*common.VisualizationWithDashboards, error) {
+
+	/*
+		0 - resolve templates referenced by templateName and templateVersion
+		    from templates catalog
+		1 - validate and render  all golang templates provided by user,
+		    if there are any errors, then immediately return error to user
+		2 - validate that rendered templates matches grafana json structure
+			if there are any mismatch - return error to user
+		3 - create db entry for visualization and every dashboard, journal
+			upload of every dashboard in the same transaction
+		4 - perform journaled uploads, received slugs are stored to db
+			entries of dashboards
+		5 - return data to user
+	*/
+
+	dashboardNames, renderedTemplates, err := renderDashboards(clients, data,
+		organizationID)
+	if err != nil {
+		return nil, err
 	}
-	log.Logger.Debug("Updating db entries of dashboards with corresponding" +
-		" grafana slugs")
-	updateErrorDB := clients.DatabaseManager.BulkUpdateDashboard(dashboardsDB)
-	if updateErrorDB != nil {
-		log.Logger.Errorf("Error updating db dashboard slugs '%s'", updateErrorDB)
+
+	// create db entries for visualizations and dashboards
+	log.Logger.Debug("Creating database entries for visualizations and dashboards")
+	visualizationDB, dashboardsDB, operationsDB, err := clients.DatabaseManager.CreateVisualizationsWithDashboards(
+		data.Name, organizationID, data.Tags, dashboardNames, renderedTemplates)
+	log.Logger.Debug("Created database entries for visualizations and dashboards")
+	if err != nil {
 		return nil, err
 	}
 
-	return VisualizationDashboardToResponse(visualizationDB, dashboardsDB), nil
+	return uploadVisualization(clients, organizationID, visualizationDB,
+		dashboardsDB, operationsDB, nil)
+}
+
+// VisualizationsPostAsync handler creates new visualization and queues upload
+// of its dashboards to grafana as a job
+func (h *V1Visualizations) VisualizationsPostAsync(clients *common.ClientContainer,
+	data common.VisualizationPOSTData, organizationID string) (*jobs.Job, error) {
+
+	/*
+		1 - validate and render all golang templates provided by user,
+		    if there are any errors, then immediately return error to user
+		2 - create db entry for visualization and every dashboard, journal
+			upload of every dashboard in the same transaction
+		3 - queue job performing journaled uploads and return it to user
+	*/
+
+	dashboardNames, renderedTemplates, err := renderDashboards(clients, data,
+		organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Logger.Debug("Creating database entries for visualizations and dashboards")
+	visualizationDB, dashboardsDB, operationsDB, err := clients.DatabaseManager.CreateVisualizationsWithDashboards(
+		data.Name, organizationID, data.Tags, dashboardNames, renderedTemplates)
+	log.Logger.Debug("Created database entries for visualizations and dashboards")
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := clients.Jobs.Submit(organizationID, visualizationDB.Slug,
+		dashboardNames, func(progress jobs.ProgressFunc) (interface{}, error) {
+			result, uploadErr := uploadVisualization(clients, organizationID,
+				visualizationDB, dashboardsDB, operationsDB, progress)
+			if result == nil {
+				// typed nil must not be stored as job result
+				return nil, uploadErr
+			}
+			return result, uploadErr
+		})
+	if err != nil {
+		log.Logger.Errorf("Unable to queue upload of visualization '%s': '%s'",
+			visualizationDB.Slug, err)
+		// nothing was uploaded to grafana yet, so removal of visualization
+		// does not journal any grafana operation
+		_, deletionErrorDB := clients.DatabaseManager.DeleteVisualizationWithOperations(
+			visualizationDB, dashboardsDB)
+		if deletionErrorDB != nil {
+			log.Logger.Errorf("Unable to delete visualization from db '%s'",
+				deletionErrorDB)
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// VisualizationGet returns single visualization with all its dashboards
+func (h *V1Visualizations) VisualizationGet(clients *common.ClientContainer,
+	organizationID, visualizationSlug string, includeRenderedTemplate bool) (
+	*common.VisualizationWithDashboards, error) {
+	log.Logger.Debug("getting data from db matching provided string")
+	visualizationDB, dashboardsDB, err := clients.DatabaseManager.GetVisualizationWithDashboardsBySlug(
+		visualizationSlug, organizationID)
+	log.Logger.Debug("got data from db matching provided string")
+
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return nil, err
+	}
+
+	if visualizationDB == nil {
+		log.Logger.Errorf("User requested visualization '%s' not found in db", visualizationSlug)
+		return nil, common.NewNotFoundError("No visualizations found")
+	}
+
+	result := VisualizationDashboardToResponse(visualizationDB, dashboardsDB)
+	if !includeRenderedTemplate {
+		// rendered templates could be large, so they are returned only
+		// when user explicitly asks for them
+		for index := range result.Dashboards {
+			result.Dashboards[index].RenderedTemplate = ""
+		}
+	}
+	return result, nil
 }
 
 // VisualizationDelete removes visualizations
@@ -272,47 +372,170 @@ func (h *V1Visualizations) VisualizationDelete(clients *common.ClientContainer,
 
 	if visualizationDB == nil {
 		log.Logger.Errorf("User requested visualization '%s' not found in db", visualizationSlug)
-		return nil, common.NewUserDataError("No visualizations found")
-	}
-
-	removedDashboardsFromGrafana := []*models.Dashboard{}
-	failedToRemoveDashboardsFromGrafana := []*models.Dashboard{}
-	for index, dashboardDB := range dashboardsDB {
-		if dashboardDB.Slug == "" {
-			// in case grafana slug is empty - just remove dashboard from db
-			removedDashboardsFromGrafana = append(removedDashboardsFromGrafana,
-				dashboardsDB[index])
-		} else {
-			log.Logger.Debugf("Removing grafana dashboard '%s'", dashboardDB.Slug)
-			err = clients.Grafana.DeleteDashboard(dashboardDB.Slug, organizationID)
-			if err != nil {
-				failedToRemoveDashboardsFromGrafana = append(
-					failedToRemoveDashboardsFromGrafana, dashboardsDB[index])
-			} else {
-				removedDashboardsFromGrafana = append(
-					removedDashboardsFromGrafana, dashboardsDB[index])
-			}
+		return nil, common.NewNotFoundError("No visualizations found")
+	}
+
+	// removal of grafana dashboards is journaled in the same transaction as
+	// removal of visualization, so failed grafana calls are retried by
+	// outbox worker
+	log.Logger.Debugf("removing visualization '%s' from db", visualizationSlug)
+	operationsDB, err := clients.DatabaseManager.DeleteVisualizationWithOperations(
+		visualizationDB, dashboardsDB)
+	if err != nil {
+		log.Logger.Errorf("Error removing visualization from db: '%s'", err)
+		return nil, err
+	}
+	log.Logger.Debugf("removed visualization '%s' from db", visualizationSlug)
+
+	for _, operation := range operationsDB {
+		log.Logger.Debugf("Removing grafana dashboard '%s'", operation.Slug)
+		_, err = outbox.Execute(clients.DatabaseManager, clients.Grafana,
+			operation, false)
+		if err != nil {
+			log.Logger.Debugf("Removal of grafana dashboard '%s' is left to "+
+				"outbox worker", operation.Slug)
 		}
 	}
+	return VisualizationDashboardToResponse(visualizationDB, dashboardsDB), nil
+}
 
-	if len(failedToRemoveDashboardsFromGrafana) != 0 {
-		log.Logger.Debug("Deleting dashboards from db")
-		deletionError := clients.DatabaseManager.BulkDeleteDashboard(
-			removedDashboardsFromGrafana)
-		if deletionError != nil {
-			log.Logger.Error(deletionError)
+// VisualizationUpdate updates existing visualization in place, keeping its
+// slug and history of grafana dashboards
+func (h *V1Visualizations) VisualizationUpdate(clients *common.ClientContainer,
+	data common.VisualizationPOSTData, organizationID,
+	visualizationSlug string) (*common.VisualizationWithDashboards, error) {
+
+	/*
+		1 - validate and render all golang templates provided by user,
+		    if there are any errors, then immediately return error to user
+		2 - get stored visualization with dashboards and match dashboards
+		    by name: stored dashboards with different rendered template are
+		    changed, not provided ones are removed, not stored ones are new
+		3 - in one transaction update db entry of visualization, update
+		    changed dashboards, create new and remove removed ones. Upload
+		    of every changed and new dashboard and removal of every removed
+		    one is journaled in the same transaction
+		4 - perform journaled operations: changed dashboards are uploaded
+		    with overwrite, new ones without it. Failed operations are left
+		    to outbox worker
+		5 - return data to user
+	*/
+
+	dashboardNames, renderedTemplates, err := renderDashboards(clients, data,
+		organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Logger.Debug("getting data from db matching provided string")
+	visualizationDB, dashboardsDB, err := clients.DatabaseManager.GetVisualizationWithDashboardsBySlug(
+		visualizationSlug, organizationID)
+	log.Logger.Debug("got data from db matching provided string")
+	if err != nil {
+		log.Logger.Errorf("Error getting data from db: '%s'", err)
+		return nil, err
+	}
+	if visualizationDB == nil {
+		log.Logger.Errorf("User requested visualization '%s' not found in db", visualizationSlug)
+		return nil, common.NewNotFoundError("No visualizations found")
+	}
+
+	storedDashboards := map[string]*models.Dashboard{}
+	for index := range dashboardsDB {
+		storedDashboards[dashboardsDB[index].Name] = dashboardsDB[index]
+	}
+
+	unchangedDashboards := []*models.Dashboard{}
+	changedDashboards := []*models.Dashboard{}
+	newDashboards := []*models.Dashboard{}
+	providedNames := map[string]bool{}
+	for index, name := range dashboardNames {
+		if providedNames[name] {
+			return nil, common.NewUserDataError(
+				fmt.Sprintf("Dashboard name '%s' is not unique", name))
 		}
-		log.Logger.Debug("Deleted dashboards from db")
+		providedNames[name] = true
 
-		result := VisualizationDashboardToResponse(visualizationDB,
-			failedToRemoveDashboardsFromGrafana)
-		return result, common.NewClientError("failed to remove data from grafana")
+		storedDashboard, ok := storedDashboards[name]
+		switch {
+		case !ok:
+			newDashboards = append(newDashboards, &models.Dashboard{
+				ID:               uuid.NewV4().String(),
+				Visualization:    visualizationDB.ID,
+				Name:             name,
+				RenderedTemplate: renderedTemplates[index],
+			})
+		case storedDashboard.RenderedTemplate != renderedTemplates[index] ||
+			storedDashboard.Slug == "":
+			storedDashboard.RenderedTemplate = renderedTemplates[index]
+			changedDashboards = append(changedDashboards, storedDashboard)
+		default:
+			unchangedDashboards = append(unchangedDashboards, storedDashboard)
+		}
 	}
-	log.Logger.Debugf("removing visualization '%s' from db", visualizationSlug)
-	err = clients.DatabaseManager.DeleteVisualization(visualizationDB)
+	removedDashboards := []*models.Dashboard{}
+	for index := range dashboardsDB {
+		if !providedNames[dashboardsDB[index].Name] {
+			removedDashboards = append(removedDashboards, dashboardsDB[index])
+		}
+	}
+	log.Logger.Debugf("Visualization '%s' update: %d unchanged, %d changed, "+
+		"%d new, %d removed dashboards", visualizationSlug,
+		len(unchangedDashboards), len(changedDashboards), len(newDashboards),
+		len(removedDashboards))
+
+	// the same approach as on visualization creation is taken - database
+	// changes and grafana operations they require are stored in one
+	// transaction, so grafana is eventually brought to state of database
+	log.Logger.Debug("Updating database entries for visualization and dashboards")
+	operationsDB, err := clients.DatabaseManager.UpdateVisualizationWithOperations(
+		visualizationDB, data.Name, data.Tags, changedDashboards, newDashboards,
+		removedDashboards)
 	if err != nil {
-		log.Logger.Error()
+		log.Logger.Errorf("Error updating visualization in db: '%s'", err)
+		return nil, err
 	}
-	log.Logger.Debugf("removed visualization '%s' from db", visualizationSlug)
-	return VisualizationDashboardToResponse(visualizationDB, dashboardsDB), nil
+	log.Logger.Debug("Updated database entries for visualization and dashboards")
+
+	dashboardsToReturn := []*models.Dashboard{}
+	dashboardsToReturn = append(dashboardsToReturn, unchangedDashboards...)
+	dashboardsToReturn = append(dashboardsToReturn, changedDashboards...)
+	dashboardsToReturn = append(dashboardsToReturn, newDashboards...)
+	uploadedDashboards := map[string]*models.Dashboard{}
+	for _, dashboard := range changedDashboards {
+		uploadedDashboards[dashboard.ID] = dashboard
+	}
+	createdDashboards := map[string]bool{}
+	for _, dashboard := range newDashboards {
+		uploadedDashboards[dashboard.ID] = dashboard
+		createdDashboards[dashboard.ID] = true
+	}
+
+	grafanaFailed := false
+	log.Logger.Debug("Performing journaled grafana operations")
+	for _, operation := range operationsDB {
+		// changed dashboards already exist in grafana, so they are
+		// overwritten. If title of dashboard was changed - removal of
+		// previous grafana dashboard is journaled on completion
+		slug, grafanaErr := outbox.Execute(clients.DatabaseManager,
+			clients.Grafana, operation, !createdDashboards[operation.DashboardID])
+		if grafanaErr != nil {
+			log.Logger.Debugf("Grafana operation '%d' is left to outbox worker",
+				operation.ID)
+			grafanaFailed = true
+			continue
+		}
+		if dashboard, ok := uploadedDashboards[operation.DashboardID]; ok &&
+			operation.Operation == models.GrafanaOperationUpload {
+			log.Logger.Infof("Uploaded dashboard named '%s'", slug)
+			dashboard.Slug = slug
+		}
+	}
+
+	result := VisualizationDashboardToResponse(visualizationDB, dashboardsToReturn)
+	if grafanaFailed {
+		return result, common.NewClientError(
+			"Unable to update grafana dashboards")
+	}
+	return result, nil
 }
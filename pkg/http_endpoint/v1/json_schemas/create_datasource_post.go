@@ -0,0 +1,53 @@
+package v1JsonSchema
+
+// DatasourcesCreateJSONSchema describes data expected by app on /datasources url
+const DatasourcesCreateJSONSchema = `{
+    "$schema": "http://json-schema.org/schema#",
+    "type": "object",
+    "properties": {
+        "name": {
+            "type": "string",
+            "minLength": 1
+        },
+        "type": {
+            "type": "string",
+            "minLength": 1
+        },
+        "access": {
+            "type": "string",
+            "enum": ["proxy", "direct"]
+        },
+        "url": {
+            "type": "string",
+            "minLength": 1
+        },
+        "database": {
+            "type": "string"
+        },
+        "user": {
+            "type": "string"
+        },
+        "password": {
+            "type": "string"
+        },
+        "basicAuth": {
+            "type": "boolean"
+        },
+        "basicAuthUser": {
+            "type": "string"
+        },
+        "basicAuthPassword": {
+            "type": "string"
+        },
+        "isDefault": {
+            "type": "boolean"
+        }
+    },
+    "required": [
+        "name",
+        "type",
+        "access",
+        "url"
+    ],
+	"additionalProperties": false
+}`
@@ -0,0 +1,28 @@
+package v1JsonSchema
+
+// TemplatesCreateJSONSchema describes data expected by app on /templates url
+const TemplatesCreateJSONSchema = `{
+    "$schema": "http://json-schema.org/schema#",
+    "type": "object",
+    "properties": {
+        "name": {
+            "type": "string",
+            "minLength": 1
+        },
+        "version": {
+            "type": "integer",
+            "minimum": 1
+        },
+        "parameters": {
+            "type": "object"
+        },
+        "templateBody": {
+            "type": "string"
+        }
+    },
+    "required": [
+        "name",
+        "templateBody"
+    ],
+	"additionalProperties": false
+}`
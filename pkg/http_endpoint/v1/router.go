@@ -1,133 +1,341 @@
 package v1Api
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/pressly/chi"
 	"net/http"
+	"time"
 
+	"visualization-api/pkg/grafanaproxy"
+	"visualization-api/pkg/http_endpoint/audit"
 	"visualization-api/pkg/http_endpoint/authentication"
 	"visualization-api/pkg/http_endpoint/common"
 	v1handlers "visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
 	"visualization-api/pkg/logging"
+	"visualization-api/pkg/policy"
 )
 
 const adminAPIPrefix = "/admin"
 const authPrefix = "/auth"
+const grafanaProxyPrefix = "/grafana"
+
+// tokenCookiePath limits token cookie to grafana proxy, other api calls
+// require Authorization header
+const tokenCookiePath = "/v1" + grafanaProxyPrefix + "/"
+
+// authErrorMsg is response to be returned with 401 code to user
+const authErrorMsg = "Unauthorized. Token is invalid or expired."
+
+// writeAuthError writes error of token issue to response
+func writeAuthError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	// common.InvalidOpenstackToken means, that user provided invalid
+	// token. We have to return 401 error then
+	case common.InvalidOpenstackToken:
+		common.WriteErrorToResponse(w, http.StatusUnauthorized,
+			authErrorMsg, err.Error())
+	// common.ConflictError means, that grafana organization of project has
+	// to be remapped by admin
+	case common.ConflictError:
+		common.WriteErrorToResponse(w, http.StatusConflict,
+			http.StatusText(http.StatusConflict), err.Error())
+	// If any other error happened -> return 500 error
+	default:
+		log.Logger.Error(err)
+		common.WriteErrorToResponse(w, http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError),
+			"Internal server error occured")
+	}
+}
+
+// setTokenCookie sets cookie of jwt token issued by auth api call, browsers
+// navigating to grafana proxy send it instead of Authorization header. Cookie
+// is not set if grafana proxy is disabled
+func setTokenCookie(w http.ResponseWriter, clients *common.ClientContainer,
+	token []byte) {
+	if clients.GrafanaProxy == nil {
+		return
+	}
+	var payload struct {
+		JWT   string `json:"jwt"`
+		Token struct {
+			ExpiresAt time.Time `json:"expiresAt"`
+		} `json:"token"`
+	}
+	err := json.Unmarshal(token, &payload)
+	if err != nil {
+		log.Logger.Errorf("Unable to set token cookie: '%s'", err)
+		return
+	}
+	w.Header().Add("Set-Cookie", grafanaproxy.TokenCookie(tokenCookiePath,
+		payload.JWT, payload.Token.ExpiresAt))
+}
 
 func authRouter(clients *common.ClientContainer,
-	handler common.HandlerInterface, secret string) *chi.Mux {
+	handler common.HandlerInterface, keys *jwtkeys.KeySet,
+	authMiddleware func(http.Handler) http.Handler) *chi.Mux {
 	router := chi.NewRouter()
 	router.Post("/openstack", func(w http.ResponseWriter, r *http.Request) {
 		// expected HEADER token name
 		const openstackHeaderName = "X-OpenStack-Auth-Token"
-		// response to be returned with 401 code to user
-		const errorMsg = "Unauthorized. Token is invalid or expired."
 
 		// check if openstack token is in request HEADERs
 		openstackToken := r.Header.Get(openstackHeaderName)
 		if openstackToken == "" {
-			common.WriteErrorToResponse(w, http.StatusUnauthorized, errorMsg,
-				fmt.Sprintf("header %s is not specified", openstackHeaderName))
+			common.WriteErrorToResponse(w, http.StatusUnauthorized,
+				authErrorMsg, fmt.Sprintf("header %s is not specified",
+					openstackHeaderName))
 			return
 		}
 
 		// try to authenticate with provided token
 		token, err := handler.AuthOpenstack(clients, &common.RealClock{},
-			openstackToken, secret)
+			openstackToken, keys)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+
+		setTokenCookie(w, clients, token)
+		w.WriteHeader(http.StatusOK)
+		w.Write(token)
+	})
+
+	// refresh and revoke require jwt token, they are audited the same way
+	// as other mutating api calls
+	auditMiddleware := httpAudit.AuditMiddleware(clients.DatabaseManager)
+	router.With(authMiddleware, auditMiddleware).Post("/refresh", func(
+		w http.ResponseWriter, r *http.Request) {
+		token, err := handler.AuthRefresh(clients, &common.RealClock{},
+			common.TokenFromContext(r.Context()), keys)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+
+		setTokenCookie(w, clients, token)
+		w.WriteHeader(http.StatusOK)
+		w.Write(token)
+	})
+	router.With(authMiddleware, auditMiddleware).Post("/revoke", func(
+		w http.ResponseWriter, r *http.Request) {
+		err := handler.AuthRevoke(clients, common.TokenFromContext(r.Context()))
 		if err != nil {
 			switch err.(type) {
-			// common.InvalidOpenstackToken means, that user provided invalid
-			// token. We have to return 401 error then
-			case common.InvalidOpenstackToken:
-				common.WriteErrorToResponse(w, http.StatusUnauthorized,
-					errorMsg, err.Error())
-				return
-			// If any other error happened -> return 500 error
+			case common.UserDataError:
+				common.WriteErrorToResponse(w, http.StatusUnprocessableEntity,
+					http.StatusText(http.StatusUnprocessableEntity), err.Error())
 			default:
 				log.Logger.Error(err)
 				common.WriteErrorToResponse(w, http.StatusInternalServerError,
 					http.StatusText(http.StatusInternalServerError),
 					"Internal server error occured")
-				return
 			}
+			return
 		}
-
-		w.WriteHeader(http.StatusOK)
-		w.Write(token)
+		if clients.GrafanaProxy != nil {
+			w.Header().Add("Set-Cookie",
+				grafanaproxy.ExpiredTokenCookie(tokenCookiePath))
+		}
+		w.WriteHeader(http.StatusNoContent)
 	})
 	return router
 }
 
+// authorize returns middleware checking that policy rule allows api call
+func authorize(clients *common.ClientContainer,
+	rule string) func(http.Handler) http.Handler {
+	return httpAuth.PolicyMiddleware(clients.Policy, rule)
+}
+
 func adminRouter(clients *common.ClientContainer,
 	authMiddleware func(http.Handler) http.Handler,
-	handler common.HandlerInterface, secret string) *chi.Mux {
+	handler common.HandlerInterface) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(authMiddleware)
-	r.Use(httpAuth.AdminAuthenticationMiddleware(secret))
+	// calls rejected by policy are audited too
+	r.Use(httpAudit.AuditMiddleware(clients.DatabaseManager))
 
 	// routes for users
 	r.Route("/users", func(r chi.Router) {
 		// Get users list
-		r.Get("/", v1handlers.GetUsers(clients, handler))
+		r.With(authorize(clients, policy.RuleUsersGet)).Get("/",
+			v1handlers.GetUsers(clients, handler))
 
 		// Get users  by id
-		r.Get("/{userID}", v1handlers.GetUsersID(clients, handler))
+		r.With(authorize(clients, policy.RuleUsersGet)).Get("/{userID}",
+			v1handlers.GetUsersID(clients, handler))
 
 		// Delete users by id
-		r.Delete("/{userID}", v1handlers.DeleteUser(clients, handler))
+		r.With(authorize(clients, policy.RuleUsersDelete)).Delete("/{userID}",
+			v1handlers.DeleteUser(clients, handler))
 
 		// Create user
-		r.Post("/", v1handlers.CreateUser(clients, handler))
+		r.With(authorize(clients, policy.RuleUsersCreate)).Post("/",
+			v1handlers.CreateUser(clients, handler))
 	})
 
 	// routes for organizations
 	r.Route("/organizations", func(r chi.Router) {
 		// Get organizations list
-		r.Get("/", v1handlers.GetOrganization(clients, handler))
+		r.With(authorize(clients, policy.RuleOrganizationsGet)).Get("/",
+			v1handlers.GetOrganization(clients, handler))
 
 		// Get organization by id
-		r.Get("/{organizationID}", v1handlers.GetOrganizationID(clients, handler))
+		r.With(authorize(clients, policy.RuleOrganizationsGet)).Get(
+			"/{organizationID}", v1handlers.GetOrganizationID(clients, handler))
 
 		// Delete organizations by id
-		r.Delete("/{organizationID}", v1handlers.DeleteOrganization(clients, handler))
+		r.With(authorize(clients, policy.RuleOrganizationsDelete)).Delete(
+			"/{organizationID}", v1handlers.DeleteOrganization(clients, handler))
 
 		// Create organization
-		r.Post("/", v1handlers.CreateOrganization(clients, handler))
+		r.With(authorize(clients, policy.RuleOrganizationsCreate)).Post("/",
+			v1handlers.CreateOrganization(clients, handler))
 
 		// Delete user in organizations by id
-		r.Delete("/{organizationID}/users/{userID}", v1handlers.DeleteOrganizationUser(clients, handler))
+		r.With(authorize(clients, policy.RuleOrgUsersDelete)).Delete(
+			"/{organizationID}/users/{userID}",
+			v1handlers.DeleteOrganizationUser(clients, handler))
 
 		// Get users in organization
-		r.Get("/{organizationID}/users", v1handlers.GetOrganizationUser(clients, handler))
+		r.With(authorize(clients, policy.RuleOrgUsersGet)).Get(
+			"/{organizationID}/users",
+			v1handlers.GetOrganizationUser(clients, handler))
 
 		// Post create user in organization
-		r.Post("/{organizationID}/users", v1handlers.CreateOrganizationUser(clients, handler))
+		r.With(authorize(clients, policy.RuleOrgUsersCreate)).Post(
+			"/{organizationID}/users",
+			v1handlers.CreateOrganizationUser(clients, handler))
+	})
+
+	// routes for db and grafana reconciliation
+	r.Route("/reconciliation", func(r chi.Router) {
+		// Get report of the latest reconciliation
+		r.With(authorize(clients, policy.RuleReconciliationGet)).Get("/",
+			v1handlers.ReconciliationReport(clients, handler))
+
+		// Run reconciliation immediately
+		r.With(authorize(clients, policy.RuleReconciliationRun)).Post("/",
+			v1handlers.Reconcile(clients, handler))
+	})
+
+	// routes for synchronization of openstack projects and grafana
+	// organizations
+	r.Route("/project-sync", func(r chi.Router) {
+		// Get report of the latest synchronization
+		r.With(authorize(clients, policy.RuleProjectSyncGet)).Get("/",
+			v1handlers.ProjectSyncReport(clients, handler))
+
+		// Run synchronization immediately, dry_run parameter only reports
+		// changes
+		r.With(authorize(clients, policy.RuleProjectSyncRun)).Post("/",
+			v1handlers.ProjectSync(clients, handler))
 	})
+
+	// routes for mapping of openstack projects to grafana organizations
+	r.Route("/organization-mappings", func(r chi.Router) {
+		// Get grafana organizations of all projects
+		r.With(authorize(clients, policy.RuleOrgMappingsGet)).Get("/",
+			v1handlers.OrganizationMappingsGet(clients, handler))
+
+		// Map projects to recreated organizations, dry_run parameter only
+		// reports changes
+		r.With(authorize(clients, policy.RuleOrgMappingsRemap)).Post(
+			"/remap", v1handlers.OrganizationsRemap(clients, handler))
+	})
+
+	// Get audit records of mutating api calls
+	r.With(authorize(clients, policy.RuleAuditGet)).Get("/audit",
+		v1handlers.AuditRecordsGet(clients, handler))
+
+	// Get usage metrics, like hit rate of keystone token cache
+	r.With(authorize(clients, policy.RuleMetricsGet)).Get("/metrics",
+		v1handlers.MetricsGet(clients, handler))
 	return r
 }
 
+// grafanaProxyRouter forwards requests to grafana on behalf of user, path of
+// forwarded request is the rest of path after proxy prefix
+func grafanaProxyRouter(clients *common.ClientContainer,
+	authMiddleware func(http.Handler) http.Handler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(httpAuth.CookieTokenMiddleware(grafanaproxy.TokenCookieName))
+	router.Use(authMiddleware)
+	router.Use(httpAudit.AuditMiddleware(clients.DatabaseManager))
+	router.With(authorize(clients, policy.RuleGrafanaProxy)).Handle("/*",
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forwardedURL := *r.URL
+			forwardedURL.Path = "/" + chi.URLParam(r, "*")
+			forwardedURL.RawPath = ""
+			forwarded := r.WithContext(r.Context())
+			forwarded.URL = &forwardedURL
+			clients.GrafanaProxy.ServeHTTP(w, forwarded)
+		}))
+	return router
+}
+
 func visualizationRouter(clients *common.ClientContainer,
 	handler common.HandlerInterface,
 	authMiddleware func(http.Handler) http.Handler) *chi.Mux {
 	router := chi.NewRouter()
 	// temporary commented to simplify testing
 	router.Use(authMiddleware)
-	router.Get("/visualizations", v1handlers.VisualizationsGet(
-		clients, handler))
-	router.Post("/visualizations", v1handlers.VisualizationsPost(
-		clients, handler))
-	router.Delete("/visualization/{visualizationID}", v1handlers.VisualizationDelete(
-		clients, handler))
+	router.Use(httpAudit.AuditMiddleware(clients.DatabaseManager))
+	router.With(authorize(clients, policy.RuleVisualizationsGet)).Get(
+		"/visualizations", v1handlers.VisualizationsGet(clients, handler))
+	router.With(authorize(clients, policy.RuleVisualizationsCreate)).Post(
+		"/visualizations", v1handlers.VisualizationsPost(clients, handler))
+	router.With(authorize(clients, policy.RuleVisualizationsGet)).Get(
+		"/visualization/{visualizationID}",
+		v1handlers.VisualizationGet(clients, handler))
+	router.With(authorize(clients, policy.RuleVisualizationsDelete)).Delete(
+		"/visualization/{visualizationID}",
+		v1handlers.VisualizationDelete(clients, handler))
+	router.With(authorize(clients, policy.RuleVisualizationsUpdate)).Put(
+		"/visualization/{visualizationID}",
+		v1handlers.VisualizationUpdate(clients, handler))
+	router.With(authorize(clients, policy.RuleJobsGet)).Get("/jobs/{jobID}",
+		v1handlers.JobGet(clients, handler))
+	router.With(authorize(clients, policy.RuleTemplatesGet)).Get("/templates",
+		v1handlers.TemplatesGet(clients, handler))
+	router.With(authorize(clients, policy.RuleTemplatesCreate)).Post(
+		"/templates", v1handlers.TemplatesPost(clients, handler))
+	router.With(authorize(clients, policy.RuleTemplatesDelete)).Delete(
+		"/template/{templateID}", v1handlers.TemplateDelete(clients, handler))
+	router.With(authorize(clients, policy.RuleDatasourcesGet)).Get(
+		"/datasources", v1handlers.DatasourcesGet(clients, handler))
+	router.With(authorize(clients, policy.RuleDatasourcesCreate)).Post(
+		"/datasources", v1handlers.DatasourcesPost(clients, handler))
+	router.With(authorize(clients, policy.RuleDatasourcesGet)).Get(
+		"/datasources/{datasourceID}", v1handlers.DatasourceGet(clients, handler))
+	router.With(authorize(clients, policy.RuleDatasourcesDelete)).Delete(
+		"/datasources/{datasourceID}",
+		v1handlers.DatasourceDelete(clients, handler))
 	return router
 }
 
 // InitializeRouter initializes /v1 routers
 func InitializeRouter(clients *common.ClientContainer,
-	handler common.HandlerInterface, secret string) *chi.Mux {
+	handler common.HandlerInterface, keys *jwtkeys.KeySet) *chi.Mux {
 	router := chi.NewRouter()
-	authMiddleware := httpAuth.AuthenticationMiddleware(secret)
-	router.Mount(adminAPIPrefix, adminRouter(clients, authMiddleware, handler, secret))
-	router.Mount(authPrefix, authRouter(clients, handler, secret))
+	var revalidator *httpAuth.KeystoneRevalidator
+	if clients.TokenSettings.KeystoneRevalidationInterval > 0 {
+		revalidator = httpAuth.NewKeystoneRevalidator(clients.Openstack,
+			clients.DatabaseManager,
+			clients.TokenSettings.KeystoneRevalidationInterval)
+	}
+	authMiddleware := httpAuth.AuthenticationMiddleware(keys,
+		clients.DatabaseManager, revalidator)
+	router.Mount(adminAPIPrefix, adminRouter(clients, authMiddleware, handler))
+	router.Mount(authPrefix, authRouter(clients, handler, keys,
+		authMiddleware))
+	if clients.GrafanaProxy != nil {
+		router.Mount(grafanaProxyPrefix, grafanaProxyRouter(clients,
+			authMiddleware))
+	}
 	router.Mount("/", visualizationRouter(clients, handler, authMiddleware))
 	return router
 }
@@ -0,0 +1,306 @@
+package v1Apitest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	const visualizationID = "0f29d63b-be6f-43cf-b99f-23271b3e6041"
+	tests := []struct {
+		description    string
+		method         string
+		url            string
+		handlerError   error
+		expectedCode   int
+		expectedRecord *models.AuditRecord
+	}{
+		{
+			description:  "successful removal is audited",
+			method:       "DELETE",
+			url:          "/v1/visualization/" + visualizationID,
+			expectedCode: 200,
+			expectedRecord: &models.AuditRecord{
+				UserID:         testHelper.TestIdentity.UserID,
+				UserName:       testHelper.TestIdentity.UserName,
+				OrganizationID: "3",
+				ProjectID:      testHelper.TestIdentity.ProjectID,
+				IsAdmin:        true,
+				Method:         "DELETE",
+				Route:          "/v1/visualization/{visualizationID}",
+				Path:           "/v1/visualization/" + visualizationID,
+				Targets:        `{"visualizationID":"` + visualizationID + `"}`,
+				Status:         200,
+				Outcome:        models.AuditOutcomeSuccess,
+			},
+		},
+		{
+			description:  "failed removal is audited",
+			method:       "DELETE",
+			url:          "/v1/visualization/" + visualizationID,
+			handlerError: errors.New("grafana is not available"),
+			expectedCode: 500,
+			expectedRecord: &models.AuditRecord{
+				UserID:         testHelper.TestIdentity.UserID,
+				UserName:       testHelper.TestIdentity.UserName,
+				OrganizationID: "3",
+				ProjectID:      testHelper.TestIdentity.ProjectID,
+				IsAdmin:        true,
+				Method:         "DELETE",
+				Route:          "/v1/visualization/{visualizationID}",
+				Path:           "/v1/visualization/" + visualizationID,
+				Targets:        `{"visualizationID":"` + visualizationID + `"}`,
+				Status:         500,
+				Outcome:        models.AuditOutcomeFailure,
+			},
+		},
+		{
+			description:  "id of created visualization is audited",
+			method:       "POST",
+			url:          "/v1/visualizations",
+			expectedCode: 200,
+			expectedRecord: &models.AuditRecord{
+				UserID:         testHelper.TestIdentity.UserID,
+				UserName:       testHelper.TestIdentity.UserName,
+				OrganizationID: "3",
+				ProjectID:      testHelper.TestIdentity.ProjectID,
+				IsAdmin:        true,
+				Method:         "POST",
+				Route:          "/v1/visualizations",
+				Path:           "/v1/visualizations",
+				Targets:        `{"visualizationID":"` + visualizationID + `"}`,
+				Status:         200,
+				Outcome:        models.AuditOutcomeSuccess,
+			},
+		},
+		{
+			description:  "reading is not audited",
+			method:       "GET",
+			url:          "/v1/visualization/" + visualizationID,
+			expectedCode: 200,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+		clientContainer.DatabaseManager = mockedDatabaseManager
+
+		visualization := &common.VisualizationWithDashboards{
+			&common.VisualizationResponseEntry{visualizationID, "name",
+				map[string]interface{}{}},
+			[]*common.DashboardResponseEntry{},
+		}
+		var body io.Reader
+		switch testCase.method {
+		case "DELETE":
+			mockedHandle.EXPECT().VisualizationDelete(clientContainer, projectID,
+				visualizationID).Return(visualization, testCase.handlerError)
+		case "POST":
+			body = strings.NewReader(`{"name": "name", "dashboards": []}`)
+			mockedHandle.EXPECT().VisualizationsPost(clientContainer,
+				gomock.Any(), projectID).Return(visualization, nil)
+		default:
+			mockedHandle.EXPECT().VisualizationGet(clientContainer, projectID,
+				visualizationID, false).Return(visualization, nil)
+		}
+		if testCase.expectedRecord != nil {
+			mockedDatabaseManager.EXPECT().CreateAuditRecord(
+				testCase.expectedRecord).Return(nil)
+		}
+
+		request, _ := http.NewRequest(testCase.method, testCase.url, body)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+	}
+}
+
+func TestAuditRecordsGetHttp(t *testing.T) {
+	createdAt := time.Date(2017, 8, 3, 0, 0, 0, 0, time.UTC)
+	records := &[]common.AuditRecordResponseEntry{
+		{
+			ID:             1,
+			UserID:         "user",
+			UserName:       "name",
+			OrganizationID: "3",
+			ProjectID:      "project",
+			Method:         "DELETE",
+			Route:          "/v1/visualization/{visualizationID}",
+			Path:           "/v1/visualization/slug",
+			Targets:        map[string]string{"visualizationID": "slug"},
+			Status:         200,
+			Outcome:        models.AuditOutcomeSuccess,
+			CreatedAt:      createdAt,
+		},
+	}
+	tests := []struct {
+		description    string
+		query          string
+		expectedFilter *db.AuditFilter
+		handlerError   error
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			description:    "default limit is applied",
+			query:          "",
+			expectedFilter: &db.AuditFilter{Limit: 100},
+			expectedCode:   200,
+			expectedResult: `[{"id":1,"userId":"user","userName":"name","organizationId":"3","projectId":"project","isAdmin":false,"method":"DELETE","route":"/v1/visualization/{visualizationID}","path":"/v1/visualization/slug","targets":{"visualizationID":"slug"},"status":200,"outcome":"success","createdAt":"2017-08-03T00:00:00Z"}]`,
+		},
+		{
+			description: "all filters are parsed",
+			query: "user=user&organization=3&method=delete&route=/v1/visualization/{visualizationID}" +
+				"&target=slug&outcome=failure&since=2017-08-01T00:00:00Z" +
+				"&until=2017-08-02T00:00:00Z&limit=10&offset=20",
+			expectedFilter: &db.AuditFilter{
+				UserID:         "user",
+				OrganizationID: "3",
+				Method:         "DELETE",
+				Route:          "/v1/visualization/{visualizationID}",
+				Target:         "slug",
+				Outcome:        models.AuditOutcomeFailure,
+				Since:          time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC),
+				Until:          time.Date(2017, 8, 2, 0, 0, 0, 0, time.UTC),
+				Limit:          10,
+				Offset:         20,
+			},
+			expectedCode:   200,
+			expectedResult: `[{"id":1,"userId":"user","userName":"name","organizationId":"3","projectId":"project","isAdmin":false,"method":"DELETE","route":"/v1/visualization/{visualizationID}","path":"/v1/visualization/slug","targets":{"visualizationID":"slug"},"status":200,"outcome":"success","createdAt":"2017-08-03T00:00:00Z"}]`,
+		},
+		{
+			description:    "unknown outcome",
+			query:          "outcome=unknown",
+			expectedCode:   422,
+			expectedResult: `{"code":422,"message":"Unprocessable Entity","details":"outcome has to be one of 'success', 'failure'"}`,
+		},
+		{
+			description:    "invalid time",
+			query:          "since=yesterday",
+			expectedCode:   422,
+			expectedResult: `{"code":422,"message":"Unprocessable Entity","details":"since has to be RFC3339 time"}`,
+		},
+		{
+			description:    "too big limit",
+			query:          "limit=1001",
+			expectedCode:   422,
+			expectedResult: `{"code":422,"message":"Unprocessable Entity","details":"limit has to be integer between 1 and 1000"}`,
+		},
+		{
+			description:    "negative offset",
+			query:          "offset=-1",
+			expectedCode:   422,
+			expectedResult: `{"code":422,"message":"Unprocessable Entity","details":"offset has to be non negative integer"}`,
+		},
+		{
+			description:    "handler error",
+			query:          "",
+			expectedFilter: &db.AuditFilter{Limit: 100},
+			handlerError:   errors.New("db error"),
+			expectedCode:   500,
+			expectedResult: `{"code":500,"message":"Internal Server Error","details":"Internal server error occured"}`,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		if testCase.expectedFilter != nil {
+			if testCase.handlerError != nil {
+				mockedHandle.EXPECT().AuditRecordsGet(clientContainer,
+					*testCase.expectedFilter).Return(nil, testCase.handlerError)
+			} else {
+				mockedHandle.EXPECT().AuditRecordsGet(clientContainer,
+					*testCase.expectedFilter).Return(records, nil)
+			}
+		}
+
+		request, _ := http.NewRequest("GET",
+			fmt.Sprintf("/v1/admin/audit?%s", testCase.query), nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestAuditRecordsGetHandler(t *testing.T) {
+	createdAt := time.Date(2017, 8, 3, 0, 0, 0, 0, time.UTC)
+	filter := db.AuditFilter{Target: "slug", Limit: 100}
+
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager).EXPECT().QueryAuditRecords(
+		filter).Return([]*models.AuditRecord{
+		{
+			ID:        2,
+			UserID:    "user",
+			Method:    "DELETE",
+			Targets:   `{"visualizationID":"slug"}`,
+			Status:    200,
+			Outcome:   models.AuditOutcomeSuccess,
+			CreatedAt: createdAt,
+		},
+	}, nil)
+
+	handler := v1handlers.V1Audit{}
+	result, err := handler.AuditRecordsGet(clientContainer, filter)
+	assert.Nil(t, err)
+	assert.Equal(t, &[]common.AuditRecordResponseEntry{
+		{
+			ID:        2,
+			UserID:    "user",
+			Method:    "DELETE",
+			Targets:   map[string]string{"visualizationID": "slug"},
+			Status:    200,
+			Outcome:   models.AuditOutcomeSuccess,
+			CreatedAt: createdAt,
+		},
+	}, result)
+}
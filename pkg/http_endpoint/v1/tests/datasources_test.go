@@ -0,0 +1,356 @@
+package v1Apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+)
+
+func TestDatasourcesGetHttp(t *testing.T) {
+	tests := []struct {
+		description      string
+		query            string
+		name             string
+		provideAuthToken bool
+		expectedCode     int
+	}{
+		{
+			description:      "make sure that handler reacts",
+			query:            "?name=influx",
+			name:             "influx",
+			provideAuthToken: true,
+			expectedCode:     200,
+		},
+		{
+			description:      "failed authorization",
+			provideAuthToken: false,
+			expectedCode:     401,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("GET", "/v1/datasources"+testCase.query, nil)
+		if testCase.provideAuthToken {
+			testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+				request)
+			mockedHandle.EXPECT().DatasourcesGet(clientContainer, projectID,
+				testCase.name).Return(&[]common.DatasourceResponseEntry{}, nil)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			"response code match")
+	}
+}
+
+func TestDatasourceGetDeleteHttp(t *testing.T) {
+	tests := []struct {
+		description   string
+		method        string
+		datasourceID  string
+		idValid       bool
+		returnedError error
+		expectedCode  int
+	}{
+		{
+			description:  "get existing datasource",
+			method:       "GET",
+			datasourceID: "1",
+			idValid:      true,
+			expectedCode: 200,
+		},
+		{
+			description:   "get datasource of other organization",
+			method:        "GET",
+			datasourceID:  "1",
+			idValid:       true,
+			returnedError: grafanaclient.NotFound{},
+			expectedCode:  404,
+		},
+		{
+			description:  "get datasource with not integer id",
+			method:       "GET",
+			datasourceID: "ID",
+			idValid:      false,
+			expectedCode: 422,
+		},
+		{
+			description:  "delete existing datasource",
+			method:       "DELETE",
+			datasourceID: "1",
+			idValid:      true,
+			expectedCode: 200,
+		},
+		{
+			description:   "delete missing datasource",
+			method:        "DELETE",
+			datasourceID:  "1",
+			idValid:       true,
+			returnedError: grafanaclient.NotFound{},
+			expectedCode:  404,
+		},
+		{
+			description:   "delete datasource with grafana failure",
+			method:        "DELETE",
+			datasourceID:  "1",
+			idValid:       true,
+			returnedError: errors.New("test"),
+			expectedCode:  500,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest(testCase.method,
+			fmt.Sprintf("/v1/datasources/%s", testCase.datasourceID), nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.idValid {
+			if testCase.method == "GET" {
+				mockedHandle.EXPECT().DatasourceGet(clientContainer, projectID, ID).Return(
+					&common.DatasourceResponseEntry{}, testCase.returnedError)
+			} else {
+				mockedHandle.EXPECT().DatasourceDelete(clientContainer, projectID, ID).Return(
+					&common.DatasourceResponseEntry{}, testCase.returnedError)
+			}
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+	}
+}
+
+func TestDatasourcesPostHttp(t *testing.T) {
+	tests := []struct {
+		description     string
+		payloadProvided string
+		payloadValid    bool
+		returnedError   error
+		expectedCode    int
+		expectedResult  string
+	}{
+		{
+			description:     "check 200 on positive outcome",
+			payloadProvided: "{\"name\": \"influx\", \"type\": \"influxdb\", \"access\": \"proxy\", \"url\": \"http://localhost:8086\", \"password\": \"secret\"}",
+			payloadValid:    true,
+			expectedCode:    200,
+			expectedResult:  "{\"id\":1,\"name\":\"influx\",\"type\":\"influxdb\",\"access\":\"proxy\",\"url\":\"http://localhost:8086\",\"database\":\"\",\"user\":\"\",\"basicAuth\":false,\"basicAuthUser\":\"\",\"isDefault\":false}",
+		},
+		{
+			description:     "check 409 on existing datasource",
+			payloadProvided: "{\"name\": \"influx\", \"type\": \"influxdb\", \"access\": \"proxy\", \"url\": \"http://localhost:8086\"}",
+			payloadValid:    true,
+			returnedError:   grafanaclient.Exists{},
+			expectedCode:    409,
+			expectedResult:  "{\"code\":409,\"message\":\"Conflict\",\"details\":\"name taken\"}",
+		},
+		{
+			description:     "check 400 on not allowed url",
+			payloadProvided: "{\"name\": \"influx\", \"type\": \"influxdb\", \"access\": \"proxy\", \"url\": \"http://127.0.0.1:8086\"}",
+			payloadValid:    true,
+			returnedError:   common.NewUserDataError("not allowed"),
+			expectedCode:    400,
+			expectedResult:  "{\"code\":400,\"message\":\"Bad Request\",\"details\":\"not allowed\"}",
+		},
+		{
+			description:     "check 422 on wrong access mode",
+			payloadProvided: "{\"name\": \"influx\", \"type\": \"influxdb\", \"access\": \"wrong\", \"url\": \"http://localhost:8086\"}",
+			payloadValid:    false,
+			expectedCode:    422,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("POST", "/v1/datasources",
+			bytes.NewBuffer([]byte(testCase.payloadProvided)))
+		request.Header.Set("Content-Type", "application/json")
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.payloadValid {
+			payload := common.DatasourcePOSTData{}
+			json.Unmarshal([]byte(testCase.payloadProvided), &payload)
+			var handlerResult *common.DatasourceResponseEntry
+			if testCase.returnedError == nil {
+				handlerResult = &common.DatasourceResponseEntry{ID: 1,
+					Name: payload.Name, Type: payload.Type,
+					Access: payload.Access, URL: payload.URL}
+			}
+			mockedHandle.EXPECT().DatasourcesPost(clientContainer, payload,
+				projectID).Return(handlerResult, testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		if testCase.expectedResult != "" {
+			responseData, _ := ioutil.ReadAll(response.Body)
+			assert.Equal(t, testCase.expectedResult, string(responseData),
+				"response body match")
+		}
+	}
+}
+
+func TestDatasourcesHandlers(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	clientContainer.Datasources = common.DatasourceSettings{
+		AllowedHosts: []string{"localhost"}}
+	mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+	handler := v1handlers.V1Datasources{}
+
+	// list is filtered by name
+	mockedGrafana.EXPECT().GetDataSourceList(projectID).Return([]grafanaclient.DataSource{
+		grafanaclient.DataSource{ID: 1, OrgID: 3, Name: "influx", Password: "secret"},
+		grafanaclient.DataSource{ID: 2, OrgID: 3, Name: "prometheus"},
+	}, nil)
+	datasources, err := handler.DatasourcesGet(clientContainer, projectID, "influx")
+	assert.Nil(t, err)
+	assert.Equal(t, &[]common.DatasourceResponseEntry{
+		common.DatasourceResponseEntry{ID: 1, Name: "influx"}}, datasources,
+		"datasources must be filtered by name")
+
+	// datasource is created in organization of user
+	mockedGrafana.EXPECT().CreateDataSource(grafanaclient.DataSource{OrgID: 3,
+		Name: "influx", Type: "influxdb", Access: "proxy",
+		URL: "http://localhost:8086"}, projectID).Return(5, nil)
+	created, err := handler.DatasourcesPost(clientContainer,
+		common.DatasourcePOSTData{Name: "influx", Type: "influxdb",
+			Access: "proxy", URL: "http://localhost:8086"}, projectID)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, created.ID, "id of created datasource must be returned")
+
+	// datasource of other organization is not deleted
+	mockedGrafana.EXPECT().GetDataSourceListID(1, projectID).Return(
+		grafanaclient.DataSource{}, grafanaclient.NotFound{})
+	_, err = handler.DatasourceDelete(clientContainer, projectID, 1)
+	assert.Equal(t, grafanaclient.NotFound{}, err)
+
+	// existing datasource is deleted
+	mockedGrafana.EXPECT().GetDataSourceListID(2, projectID).Return(
+		grafanaclient.DataSource{ID: 2, OrgID: 3, Name: "prometheus"}, nil)
+	mockedGrafana.EXPECT().DeleteDataSource(2, projectID).Return(nil)
+	deleted, err := handler.DatasourceDelete(clientContainer, projectID, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "prometheus", deleted.Name)
+}
+
+func TestDatasourcesPostURLCheck(t *testing.T) {
+	_, allowedNetwork, _ := net.ParseCIDR("10.1.0.0/16")
+	lookupIP := func(host string) ([]net.IP, error) {
+		switch host {
+		case "influx.internal":
+			return []net.IP{net.ParseIP("10.1.2.3")}, nil
+		case "influx.example.com":
+			return []net.IP{net.ParseIP("203.0.113.5")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	allowlist := common.DatasourceSettings{AllowedHosts: []string{"influx"},
+		AllowedNetworks: []*net.IPNet{allowedNetwork}, LookupIP: lookupIP}
+	defaults := common.DatasourceSettings{LookupIP: lookupIP}
+
+	tests := []struct {
+		description string
+		settings    common.DatasourceSettings
+		url         string
+		access      string
+		allowed     bool
+	}{
+		{"public address is allowed by default", defaults,
+			"http://influx.example.com:8086", "proxy", true},
+		{"private address is not allowed by default", defaults,
+			"http://influx.internal:8086", "proxy", false},
+		{"loopback address is not allowed by default", defaults,
+			"http://127.0.0.1:8086", "proxy", false},
+		{"link local address is not allowed by default", defaults,
+			"http://169.254.169.254/latest", "proxy", false},
+		{"ipv6 loopback address is not allowed by default", defaults,
+			"http://[::1]:8086", "proxy", false},
+		{"private address of direct access is allowed by default", defaults,
+			"http://10.0.0.1:8086", "direct", true},
+		{"unresolved host is not allowed", defaults,
+			"http://unknown:8086", "proxy", false},
+		{"url of other scheme is not allowed", defaults,
+			"file:///etc/passwd", "proxy", false},
+		{"allowed host", allowlist, "http://INFLUX:8086", "proxy", true},
+		{"address of allowed network", allowlist,
+			"http://influx.internal:8086", "proxy", true},
+		{"host out of allowlist", allowlist,
+			"http://influx.example.com:8086", "proxy", false},
+		{"direct access is restricted by allowlist too", allowlist,
+			"http://influx.example.com:8086", "direct", false},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		clientContainer.Datasources = testCase.settings
+		if testCase.allowed {
+			clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface).EXPECT().CreateDataSource(
+				gomock.Any(), "3").Return(1, nil)
+		}
+
+		handler := v1handlers.V1Datasources{}
+		_, err := handler.DatasourcesPost(clientContainer,
+			common.DatasourcePOSTData{Name: "influx", Type: "influxdb",
+				Access: testCase.access, URL: testCase.url}, "3")
+		if testCase.allowed {
+			assert.Nil(t, err, testCase.description)
+			continue
+		}
+		_, isUserDataError := err.(common.UserDataError)
+		assert.True(t, isUserDataError, testCase.description)
+	}
+}
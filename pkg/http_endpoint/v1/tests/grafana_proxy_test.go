@@ -0,0 +1,198 @@
+package v1Apitest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/grafanaproxy"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/authentication"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/policy"
+)
+
+func TestGrafanaProxyEndpoint(t *testing.T) {
+	var forwardedPath, forwardedUser, forwardedOrg string
+	grafana := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			forwardedPath = r.URL.Path
+			forwardedUser = r.Header.Get(grafanaproxy.UserHeader)
+			forwardedOrg = r.Header.Get(grafanaproxy.OrgHeader)
+		}))
+	defer grafana.Close()
+	proxy, err := grafanaproxy.NewProxy(grafana.URL)
+	assert.Nil(t, err)
+
+	const projectID = "3"
+	const apiHost = "api.example.com"
+	keys := jwtkeys.NewSecretKeySet(authSecret)
+	accessPolicy, err := policy.NewPolicy(map[string]string{
+		policy.RuleGrafanaProxy: "not role:reader",
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		description  string
+		method       string
+		roles        []string
+		header       bool
+		cookie       bool
+		origin       string
+		referer      string
+		proxy        http.Handler
+		expectedCode int
+		expectedPath string
+	}{
+		{"token of header is accepted", "GET", []string{"_member_"}, true,
+			false, "", "", proxy, 200, "/api/dashboards/home"},
+		{"token of cookie is accepted", "GET", []string{"_member_"}, false,
+			true, "", "", proxy, 200, "/api/dashboards/home"},
+		{"token of cookie is not accepted by changing request of unknown " +
+			"origin", "POST", []string{"_member_"}, false, true, "", "",
+			proxy, 403, ""},
+		{"token of cookie is accepted by changing request of same origin",
+			"POST", []string{"_member_"}, false, true, "https://" + apiHost,
+			"", proxy, 200, "/api/dashboards/home"},
+		{"token of cookie is not accepted by changing request of other " +
+			"origin", "POST", []string{"_member_"}, false, true,
+			"https://evil.example.com", "https://" + apiHost + "/", proxy,
+			403, ""},
+		{"token of cookie is accepted by changing request of same referer",
+			"POST", []string{"_member_"}, false, true, "",
+			"https://" + apiHost + "/v1/grafana/dashboard/db/home", proxy,
+			200, "/api/dashboards/home"},
+		{"token of header is accepted by changing request of other origin",
+			"POST", []string{"_member_"}, true, true,
+			"https://evil.example.com", "", proxy, 200,
+			"/api/dashboards/home"},
+		{"token is required", "GET", []string{"_member_"}, false, false, "",
+			"", proxy, 401, ""},
+		{"policy does not allow proxy", "GET", []string{"reader"}, true,
+			false, "", "", proxy, 403, ""},
+		{"proxy is disabled", "GET", []string{"_member_"}, true, false, "",
+			"", nil, 404, ""},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		forwardedPath, forwardedUser, forwardedOrg = "", "", ""
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		clientContainer.Policy = accessPolicy
+		clientContainer.GrafanaProxy = testCase.proxy
+
+		testHelper.ExpectOrganizationMapping(clientContainer, projectID)
+		identity := testHelper.TestIdentity
+		identity.Roles = testCase.roles
+		token, _ := httpAuth.JWTTokenFromParams(keys, projectID, identity,
+			common.Token{ExpiresAt: time.Now().Add(time.Hour)})
+		request, _ := http.NewRequest(testCase.method,
+			"/v1/grafana/api/dashboards/home", nil)
+		request.Host = apiHost
+		if testCase.origin != "" {
+			request.Header.Set("Origin", testCase.origin)
+		}
+		if testCase.referer != "" {
+			request.Header.Set("Referer", testCase.referer)
+		}
+		if testCase.header {
+			request.Header.Set("Authorization",
+				fmt.Sprintf("Bearer %s", token))
+		}
+		if testCase.cookie {
+			request.AddCookie(&http.Cookie{
+				Name: grafanaproxy.TokenCookieName, Value: token})
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			keys).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		assert.Equal(t, testCase.expectedPath, forwardedPath,
+			testCase.description)
+		if testCase.expectedPath != "" {
+			assert.Equal(t, identity.UserID, forwardedUser,
+				testCase.description)
+			assert.Equal(t, projectID, forwardedOrg, testCase.description)
+		}
+	}
+}
+
+func TestAuthEndpointTokenCookie(t *testing.T) {
+	proxy, err := grafanaproxy.NewProxy("http://grafana")
+	assert.Nil(t, err)
+	expiresAt := time.Date(2017, 6, 15, 0, 48, 41, 0, time.UTC)
+	token := []byte(`{"jwt":"jwt","token":{"expiresAt":"2017-06-15T00:48:41Z"}}`)
+
+	tests := []struct {
+		description    string
+		proxy          http.Handler
+		expectedCookie string
+	}{
+		{"cookie is set if proxy is enabled", proxy,
+			grafanaproxy.TokenCookie("/v1/grafana/", "jwt", expiresAt)},
+		{"cookie is not set if proxy is disabled", nil, ""},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		clientContainer.GrafanaProxy = testCase.proxy
+		keys := jwtkeys.NewSecretKeySet(authSecret)
+
+		request, _ := http.NewRequest("POST", "/v1/auth/openstack", nil)
+		request.Header.Set("X-OpenStack-Auth-Token", "token")
+		mockedHandle.EXPECT().AuthOpenstack(clientContainer,
+			&common.RealClock{}, "token", keys).Return(token, nil)
+
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			keys).ServeHTTP(response, request)
+		assert.Equal(t, 200, response.Code, testCase.description)
+		assert.Equal(t, testCase.expectedCookie,
+			response.Header().Get("Set-Cookie"), testCase.description)
+	}
+}
+
+func TestAuthRevokeEndpointExpiresTokenCookie(t *testing.T) {
+	proxy, err := grafanaproxy.NewProxy("http://grafana")
+	assert.Nil(t, err)
+	const projectID = "3"
+	keys := jwtkeys.NewSecretKeySet(authSecret)
+
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	clientContainer.GrafanaProxy = proxy
+
+	testHelper.ExpectOrganizationMapping(clientContainer, projectID)
+	token, _ := httpAuth.JWTTokenFromParams(keys, projectID,
+		testHelper.TestIdentity,
+		common.Token{ExpiresAt: time.Now().Add(time.Hour)})
+	mockedHandle.EXPECT().AuthRevoke(clientContainer,
+		gomock.Any()).Return(nil)
+
+	request, _ := http.NewRequest("POST", "/v1/auth/revoke", nil)
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	response := httptest.NewRecorder()
+	endpoint.InitializeRouter(clientContainer, mockedHandle,
+		keys).ServeHTTP(response, request)
+	assert.Equal(t, 204, response.Code)
+	assert.Equal(t, grafanaproxy.ExpiredTokenCookie("/v1/grafana/"),
+		response.Header().Get("Set-Cookie"))
+}
@@ -16,6 +16,7 @@ import (
 	"visualization-api/pkg/http_endpoint/common/mock"
 	"visualization-api/pkg/http_endpoint/common/tests"
 	"visualization-api/pkg/http_endpoint/v1"
+	"visualization-api/pkg/jwtkeys"
 )
 
 const ID = 1
@@ -57,13 +58,13 @@ func TestGrafanaUsersGetHttp(t *testing.T) {
 		request, _ := http.NewRequest("GET", "/v1/admin/users", nil)
 		response := httptest.NewRecorder()
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			mockedHandle.EXPECT().GetUsers(clientContainer)
 		}
 
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 
@@ -121,15 +122,15 @@ func TestGrafanaUserIDGetHttp(t *testing.T) {
 		response := httptest.NewRecorder()
 
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			if !testCase.provideString {
 				mockedHandle.EXPECT().GetUserID(clientContainer, ID)
 			}
 		}
 
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -190,7 +191,7 @@ func TestGrafanaUsersCreateHttp(t *testing.T) {
 		request.Header.Set("Content-Type", "application/json")
 
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret,
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
 				testCase.projectID, request)
 			if !testCase.errorInput {
 				mockedHandle.EXPECT().CreateUser(clientContainer, jsonStr)
@@ -199,7 +200,7 @@ func TestGrafanaUsersCreateHttp(t *testing.T) {
 
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 
@@ -256,15 +257,15 @@ func TestGrafanaDeleteUserHttp(t *testing.T) {
 		}
 		response := httptest.NewRecorder()
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			if !testCase.provideString {
 				mockedHandle.EXPECT().GetUserID(clientContainer, ID)
 				mockedHandle.EXPECT().DeleteUser(clientContainer, ID)
 			}
 		}
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -306,12 +307,12 @@ func TestOrganizationsGetHttp(t *testing.T) {
 		request, _ := http.NewRequest("GET", "/v1/admin/organizations", nil)
 		response := httptest.NewRecorder()
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			mockedHandle.EXPECT().GetOrganizations(clientContainer)
 		}
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -368,15 +369,15 @@ func TestGrafanaOrganizationIDGetHttp(t *testing.T) {
 		response := httptest.NewRecorder()
 
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			if !testCase.provideString {
 				mockedHandle.EXPECT().GetOrganizationID(clientContainer, ID)
 			}
 		}
 
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -437,7 +438,7 @@ func TestGrafanaOrganizationCreateHttp(t *testing.T) {
 		request.Header.Set("Content-Type", "application/json")
 
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret,
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
 				testCase.projectID, request)
 			if !testCase.errorInput {
 				mockedHandle.EXPECT().CreateOrganization(clientContainer,
@@ -447,7 +448,7 @@ func TestGrafanaOrganizationCreateHttp(t *testing.T) {
 
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 
@@ -504,15 +505,15 @@ func TestGrafanaDeleteOrganizationHttp(t *testing.T) {
 		}
 		response := httptest.NewRecorder()
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			if !testCase.provideString {
 				mockedHandle.EXPECT().GetOrganizationID(clientContainer, ID)
 				mockedHandle.EXPECT().DeleteOrganization(clientContainer, ID)
 			}
 		}
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -569,8 +570,8 @@ func TestGrafanaOrganizationUserIDGetHttp(t *testing.T) {
 		response := httptest.NewRecorder()
 
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			if !testCase.provideString {
 				mockedHandle.EXPECT().GetOrganizationID(clientContainer, ID)
 				mockedHandle.EXPECT().GetOrganizationUsers(clientContainer, ID)
@@ -578,7 +579,7 @@ func TestGrafanaOrganizationUserIDGetHttp(t *testing.T) {
 		}
 
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -651,8 +652,8 @@ func TestGrafanaDeleteOrganizationUserHttp(t *testing.T) {
 		}
 		response := httptest.NewRecorder()
 		if testCase.provideAuthToken {
-			testHelper.SetRequestAuthHeader(testCase.secret, testCase.projectID,
-				request)
+			testHelper.SetRequestAuthHeader(clientContainer, testCase.secret,
+				testCase.projectID, request)
 			if !testCase.provideString {
 				mockedHandle.EXPECT().GetUserID(clientContainer, ID)
 				mockedHandle.EXPECT().GetOrganizationID(clientContainer, OrgID)
@@ -660,7 +661,7 @@ func TestGrafanaDeleteOrganizationUserHttp(t *testing.T) {
 			}
 		}
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			testCase.secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(testCase.secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -0,0 +1,251 @@
+package v1Apitest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/openstack/mock"
+)
+
+var testGrafanaUserSettings = common.GrafanaUserSettings{
+	Provision: true,
+	RoleMapping: map[string]string{
+		"admin":    grafanaclient.RoleAdmin,
+		"_member_": grafanaclient.RoleEditor,
+	},
+	DefaultRole: grafanaclient.RoleViewer,
+}
+
+func TestGrafanaUserSettingsOrgRole(t *testing.T) {
+	tests := []struct {
+		roles        []string
+		defaultRole  string
+		expectedRole string
+	}{
+		{[]string{"_member_"}, grafanaclient.RoleViewer,
+			grafanaclient.RoleEditor},
+		{[]string{"_member_", "admin", "reader"}, grafanaclient.RoleViewer,
+			grafanaclient.RoleAdmin},
+		{[]string{"reader"}, grafanaclient.RoleViewer,
+			grafanaclient.RoleViewer},
+		{[]string{"reader"}, "", ""},
+		{[]string{}, grafanaclient.RoleEditor, grafanaclient.RoleEditor},
+	}
+	for _, testCase := range tests {
+		settings := testGrafanaUserSettings
+		settings.DefaultRole = testCase.defaultRole
+		assert.Equal(t, testCase.expectedRole, settings.OrgRole(testCase.roles),
+			"%v", testCase.roles)
+	}
+}
+
+func TestAuthProvisionGrafanaUser(t *testing.T) {
+	const orgID = 3
+	const userID = "2f1e5c7a9d8b4e3f"
+	tests := []struct {
+		description string
+		roles       []string
+		settings    common.GrafanaUserSettings
+		// expect sets up calls of grafana made to provision user
+		expect        func(grafana *mock_grafanaclient.MockSessionInterface)
+		expectedError error
+	}{
+		{
+			description: "user is created in organization",
+			roles:       []string{"_member_"},
+			settings:    testGrafanaUserSettings,
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{}, grafanaclient.NotFound{})
+				grafana.EXPECT().CreateOrganizationUser(orgID,
+					gomock.Any()).Do(func(orgID int,
+					user grafanaclient.CreateOrganizationUser) {
+					assert.Equal(t, userID, user.Login)
+					assert.Equal(t, "demo", user.Name)
+					assert.Equal(t, grafanaclient.RoleEditor, user.Role)
+					assert.NotEmpty(t, user.Password)
+				}).Return(nil)
+			},
+		},
+		{
+			description: "existing user is added to organization",
+			roles:       []string{"admin"},
+			settings:    testGrafanaUserSettings,
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{ID: 7, Login: userID}, nil)
+				grafana.EXPECT().GetOrganizationUsers(orgID).Return(
+					[]grafanaclient.OrgUserList{
+						{OrgID: orgID, UserID: 1, Role: grafanaclient.RoleAdmin},
+					}, nil)
+				grafana.EXPECT().AddOrganizationUser(orgID, userID,
+					grafanaclient.RoleAdmin).Return(nil)
+			},
+		},
+		{
+			description: "user created by concurrent login is added to organization",
+			roles:       []string{"reader"},
+			settings:    testGrafanaUserSettings,
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{}, grafanaclient.NotFound{})
+				grafana.EXPECT().CreateOrganizationUser(orgID,
+					gomock.Any()).Return(grafanaclient.Exists{})
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{ID: 7, Login: userID}, nil)
+				grafana.EXPECT().GetOrganizationUsers(orgID).Return(
+					[]grafanaclient.OrgUserList{}, nil)
+				grafana.EXPECT().AddOrganizationUser(orgID, userID,
+					grafanaclient.RoleViewer).Return(grafanaclient.Exists{})
+			},
+		},
+		{
+			description: "role of member is changed",
+			roles:       []string{"_member_"},
+			settings:    testGrafanaUserSettings,
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{ID: 7, Login: userID}, nil)
+				grafana.EXPECT().GetOrganizationUsers(orgID).Return(
+					[]grafanaclient.OrgUserList{
+						{OrgID: orgID, UserID: 7, Role: grafanaclient.RoleAdmin},
+					}, nil)
+				grafana.EXPECT().UpdateOrganizationUserRole(orgID, 7,
+					grafanaclient.RoleEditor).Return(nil)
+			},
+		},
+		{
+			description: "member with mapped role is not changed",
+			roles:       []string{"_member_"},
+			settings:    testGrafanaUserSettings,
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{ID: 7, Login: userID}, nil)
+				grafana.EXPECT().GetOrganizationUsers(orgID).Return(
+					[]grafanaclient.OrgUserList{
+						{OrgID: orgID, UserID: 7, Role: grafanaclient.RoleEditor},
+					}, nil)
+			},
+		},
+		{
+			description: "user without mapped roles is not provisioned",
+			roles:       []string{"reader"},
+			settings: common.GrafanaUserSettings{
+				Provision:   true,
+				RoleMapping: testGrafanaUserSettings.RoleMapping,
+			},
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{}, grafanaclient.NotFound{})
+			},
+		},
+		{
+			description: "member without mapped roles is removed from organization",
+			roles:       []string{"reader"},
+			settings: common.GrafanaUserSettings{
+				Provision:   true,
+				RoleMapping: testGrafanaUserSettings.RoleMapping,
+			},
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{ID: 7, Login: userID}, nil)
+				grafana.EXPECT().GetOrganizationUsers(orgID).Return(
+					[]grafanaclient.OrgUserList{
+						{OrgID: orgID, UserID: 7, Role: grafanaclient.RoleEditor},
+					}, nil)
+				grafana.EXPECT().DeleteOrganizationUser(7, orgID).Return(nil)
+			},
+		},
+		{
+			description: "user without mapped roles is not member of organization",
+			roles:       []string{"reader"},
+			settings: common.GrafanaUserSettings{
+				Provision:   true,
+				RoleMapping: testGrafanaUserSettings.RoleMapping,
+			},
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{ID: 7, Login: userID}, nil)
+				grafana.EXPECT().GetOrganizationUsers(orgID).Return(
+					[]grafanaclient.OrgUserList{
+						{OrgID: orgID, UserID: 1, Role: grafanaclient.RoleAdmin},
+					}, nil)
+			},
+		},
+		{
+			description: "provisioning is disabled",
+			roles:       []string{"admin"},
+			settings:    common.GrafanaUserSettings{},
+			expect:      func(grafana *mock_grafanaclient.MockSessionInterface) {},
+		},
+		{
+			description: "grafana error fails login",
+			roles:       []string{"admin"},
+			settings:    testGrafanaUserSettings,
+			expect: func(grafana *mock_grafanaclient.MockSessionInterface) {
+				grafana.EXPECT().GetUserByLogin(userID).Return(
+					grafanaclient.User{}, errors.New("grafana is not available"))
+			},
+			expectedError: errors.New("grafana is not available"),
+		},
+	}
+
+	testHelper.InitializeLogger()
+	parsedTime, _ := time.Parse(time.RFC3339, "2017-06-15T00:48:41Z")
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		clientContainer.GrafanaUsers = testCase.settings
+		roles := []map[string]string{}
+		for _, role := range testCase.roles {
+			roles = append(roles, map[string]string{"name": role})
+		}
+		mockedOpenstack := clientContainer.Openstack.(*mock_openstack.MockClientInterface)
+		mockedOpenstack.EXPECT().ValidateToken("token").Return(true, nil)
+		mockedOpenstack.EXPECT().GetTokenInfo("token").Return(
+			&openstack.TokenInfo{
+				ProjectName: "test",
+				ProjectID:   "821fb77b2ab94232a1ff3d40028f63b4",
+				UserID:      userID,
+				UserName:    "demo",
+				Roles:       roles,
+				ExpiresAt:   parsedTime,
+			}, nil)
+		mockedDatabase := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+		mockedDatabase.EXPECT().GetOrganizationMapping(
+			"821fb77b2ab94232a1ff3d40028f63b4").Return(nil, nil)
+		mockedDatabase.EXPECT().SaveOrganizationMapping(gomock.Any()).Return(
+			nil)
+		mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+		mockedGrafana.EXPECT().GetOrCreateOrgByName(
+			"test-821fb77b2ab94232a1ff3d40028f63b4").Return(
+			&grafanaclient.OrgID{ID: orgID}, nil)
+		testCase.expect(mockedGrafana)
+		mockedClock := mock_common.NewMockClockInterface(mockCtrl)
+		mockedClock.EXPECT().Now().Return(parsedTime.Add(
+			-common.DefaultTokenLifetime))
+		if testCase.expectedError == nil {
+			mockedDatabase.EXPECT().SaveKeystoneToken(gomock.Any(), "token",
+				parsedTime).Return(nil)
+		}
+
+		handler := v1Api.V1Handler{}
+		_, err := handler.AuthOpenstack(clientContainer, mockedClock, "token",
+			jwtkeys.NewSecretKeySet(authSecret))
+		assert.Equal(t, testCase.expectedError, err, testCase.description)
+	}
+}
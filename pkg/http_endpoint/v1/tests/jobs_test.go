@@ -0,0 +1,337 @@
+package v1Apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jobs"
+	"visualization-api/pkg/jobs/mock"
+	"visualization-api/pkg/jwtkeys"
+)
+
+func TestVisualizationPostAsyncResponses(t *testing.T) {
+	const payloadProvided = "{\"name\": \"test_name\", \"dashboards\": [{\"name\": \"dashboard_name\", \"templateBody\": \"template\", \"templateParameters\": {}}]}"
+	job := &jobs.Job{
+		ID:         "job_id",
+		ResourceID: "visualization_slug",
+		Status:     jobs.StatusPending,
+		Steps: []jobs.Step{
+			{Name: "dashboard_name", Status: jobs.StatusPending},
+		},
+		CreatedAt: time.Date(2017, 7, 20, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		description      string
+		asyncValue       string
+		handlerExpected  bool
+		returnedJob      *jobs.Job
+		returnedError    error
+		expectedCode     int
+		expectedLocation string
+		expectedResult   string
+	}{
+		{
+			description:      "check 202 on queued job",
+			asyncValue:       "true",
+			handlerExpected:  true,
+			returnedJob:      job,
+			expectedCode:     202,
+			expectedLocation: "/v1/jobs/job_id",
+			expectedResult:   "{\"id\":\"job_id\",\"resourceId\":\"visualization_slug\",\"status\":\"pending\",\"steps\":[{\"name\":\"dashboard_name\",\"status\":\"pending\"}],\"createdAt\":\"2017-07-20T00:00:00Z\"}",
+		},
+		{
+			description:    "check 422 on invalid async value",
+			asyncValue:     "sometimes",
+			expectedCode:   422,
+			expectedResult: "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"provided async value is not boolean 'sometimes'\"}",
+		},
+		{
+			description:     "check 422 template error",
+			asyncValue:      "1",
+			handlerExpected: true,
+			returnedError:   common.NewUserDataError("test"),
+			expectedCode:    422,
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"Error rendering template 'test'\"}",
+		},
+		{
+			description:     "check 503 on full job queue",
+			asyncValue:      "true",
+			handlerExpected: true,
+			returnedError:   jobs.ErrQueueFull,
+			expectedCode:    503,
+			expectedResult:  "{\"code\":503,\"message\":\"Service Unavailable\",\"details\":\"job queue is full\"}",
+		},
+		{
+			description:     "check 500",
+			asyncValue:      "true",
+			handlerExpected: true,
+			returnedError:   errors.New("test"),
+			expectedCode:    500,
+			expectedResult:  "{\"code\":500,\"message\":\"Internal Server Error\",\"details\":\"Internal server error occured\"}",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("POST",
+			"/v1/visualizations?async="+testCase.asyncValue,
+			bytes.NewBuffer([]byte(payloadProvided)))
+		request.Header.Set("Content-Type", "application/json")
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.handlerExpected {
+			payload := common.VisualizationPOSTData{}
+			json.Unmarshal([]byte(payloadProvided), &payload)
+			mockedHandle.EXPECT().VisualizationsPostAsync(clientContainer,
+				payload, projectID).Return(testCase.returnedJob,
+				testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		assert.Equal(t, testCase.expectedLocation,
+			response.Header().Get("Location"), testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestJobGetResponses(t *testing.T) {
+	tests := []struct {
+		description    string
+		returnedJob    *jobs.Job
+		returnedError  error
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			description: "check 200 on existing job",
+			returnedJob: &jobs.Job{
+				ID:         "job_id",
+				ResourceID: "visualization_slug",
+				Status:     jobs.StatusFailed,
+				Steps: []jobs.Step{
+					{Name: "dashboard_name", Status: jobs.StatusFailed,
+						Error: "test"},
+				},
+				Error:     "test",
+				CreatedAt: time.Date(2017, 7, 20, 0, 0, 0, 0, time.UTC),
+			},
+			expectedCode:   200,
+			expectedResult: "{\"id\":\"job_id\",\"resourceId\":\"visualization_slug\",\"status\":\"failed\",\"steps\":[{\"name\":\"dashboard_name\",\"status\":\"failed\",\"error\":\"test\"}],\"error\":\"test\",\"createdAt\":\"2017-07-20T00:00:00Z\"}",
+		},
+		{
+			description:    "check 404 on missing job",
+			returnedError:  common.NewNotFoundError("No jobs found"),
+			expectedCode:   404,
+			expectedResult: "{\"code\":404,\"message\":\"Not Found\",\"details\":\"Requested job 'job_id' was not found\"}",
+		},
+		{
+			description:    "check 500",
+			returnedError:  errors.New("test"),
+			expectedCode:   500,
+			expectedResult: "{\"code\":500,\"message\":\"Internal Server Error\",\"details\":\"Internal server error occured\"}",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("GET", "/v1/jobs/job_id", nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		mockedHandle.EXPECT().JobGet(clientContainer, projectID, "job_id").Return(
+			testCase.returnedJob, testCase.returnedError)
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestJobGetHandler(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	mockedJobs := clientContainer.Jobs.(*mock_jobs.MockManagerInterface)
+	handler := v1handlers.V1Jobs{}
+
+	mockedJobs.EXPECT().Get("3", "missing").Return(nil)
+	job, err := handler.JobGet(clientContainer, "3", "missing")
+	assert.Nil(t, job)
+	assert.Equal(t, common.NewNotFoundError("No jobs found"), err)
+
+	expectedJob := &jobs.Job{ID: "job_id"}
+	mockedJobs.EXPECT().Get("3", "job_id").Return(expectedJob)
+	job, err = handler.JobGet(clientContainer, "3", "job_id")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedJob, job)
+}
+
+func TestVisualizationsPostAsyncHandler(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+
+	data := common.VisualizationPOSTData{}
+	json.Unmarshal([]byte(`{"name": "visualization_name", "dashboards": [
+		{"name": "first", "templateBody": "first_template", "templateParameters": {}}
+	]}`), &data)
+
+	visualization := &models.Visualization{1, "visualization_slug",
+		"visualization_name", projectID, "{}"}
+	dashboards := []*models.Dashboard{
+		&models.Dashboard{"1", 1, "first", "first_template", ""},
+	}
+	uploadOperations := []*models.GrafanaOperation{
+		&models.GrafanaOperation{ID: 1, Operation: models.GrafanaOperationUpload,
+			OrganizationID: projectID, DashboardID: "1", Payload: "first_template"},
+	}
+
+	tests := []struct {
+		description   string
+		submitError   error
+		expectedJob   *jobs.Job
+		expectedError error
+	}{
+		{
+			description: "job is returned on successful submit",
+			expectedJob: &jobs.Job{ID: "job_id"},
+		},
+		{
+			description:   "visualization is removed when job is not queued",
+			submitError:   jobs.ErrQueueFull,
+			expectedError: jobs.ErrQueueFull,
+		},
+	}
+
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+		mockedJobs := clientContainer.Jobs.(*mock_jobs.MockManagerInterface)
+
+		mockedDatabaseManager.EXPECT().CreateVisualizationsWithDashboards(
+			"visualization_name", projectID, data.Tags, []string{"first"},
+			[]string{"first_template"}).Return(visualization, dashboards,
+			uploadOperations, nil)
+		mockedJobs.EXPECT().Submit(projectID, "visualization_slug",
+			[]string{"first"}, gomock.Any()).Return(testCase.expectedJob,
+			testCase.submitError)
+		if testCase.submitError != nil {
+			mockedDatabaseManager.EXPECT().DeleteVisualizationWithOperations(
+				visualization, dashboards).Return(
+				[]*models.GrafanaOperation{}, nil)
+		}
+
+		handler := v1handlers.V1Visualizations{}
+		job, err := handler.VisualizationsPostAsync(clientContainer, data,
+			projectID)
+		assert.Equal(t, testCase.expectedJob, job, testCase.description)
+		assert.Equal(t, testCase.expectedError, err, testCase.description)
+	}
+}
+
+func TestVisualizationsPostAsyncLeftToOutboxWorker(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+	mockedJobs := clientContainer.Jobs.(*mock_jobs.MockManagerInterface)
+
+	data := common.VisualizationPOSTData{}
+	json.Unmarshal([]byte(`{"name": "visualization_name", "dashboards": [
+		{"name": "first", "templateBody": "first_template", "templateParameters": {}},
+		{"name": "second", "templateBody": "second_template", "templateParameters": {}}
+	]}`), &data)
+
+	visualization := &models.Visualization{1, "visualization_slug",
+		"visualization_name", projectID, "{}"}
+	dashboards := []*models.Dashboard{
+		&models.Dashboard{"1", 1, "first", "first_template", ""},
+		&models.Dashboard{"2", 1, "second", "second_template", ""},
+	}
+	uploadOperations := []*models.GrafanaOperation{
+		&models.GrafanaOperation{ID: 1, Operation: models.GrafanaOperationUpload,
+			OrganizationID: projectID, DashboardID: "1", Payload: "first_template",
+			Owner: "creator"},
+		&models.GrafanaOperation{ID: 2, Operation: models.GrafanaOperationUpload,
+			OrganizationID: projectID, DashboardID: "2", Payload: "second_template",
+			Owner: "creator"},
+	}
+
+	var task jobs.Task
+	mockedDatabaseManager.EXPECT().CreateVisualizationsWithDashboards(
+		"visualization_name", projectID, data.Tags, []string{"first", "second"},
+		[]string{"first_template", "second_template"}).Return(visualization,
+		dashboards, uploadOperations, nil)
+	mockedJobs.EXPECT().Submit(projectID, "visualization_slug",
+		[]string{"first", "second"}, gomock.Any()).Do(func(_, _ string,
+		_ []string, submitted jobs.Task) {
+		task = submitted
+	}).Return(&jobs.Job{ID: "job_id"}, nil)
+
+	handler := v1handlers.V1Visualizations{}
+	_, err := handler.VisualizationsPostAsync(clientContainer, data, projectID)
+	assert.Nil(t, err)
+
+	// lease of first upload expired while job was queued
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(uploadOperations[0],
+		"creator").Return(false, nil)
+	statuses := []string{"", ""}
+	result, err := task(func(step int, status string, stepErr error) {
+		assert.Nil(t, stepErr)
+		statuses[step] = status
+	})
+	assert.Nil(t, err, "uploads taken over by outbox worker are not an error")
+	assert.Equal(t, []string{jobs.StatusPending, jobs.StatusPending}, statuses,
+		"uploads left to outbox worker must be pending")
+	assert.Equal(t, &common.VisualizationWithDashboards{
+		&common.VisualizationResponseEntry{"visualization_slug",
+			"visualization_name", map[string]interface{}{}},
+		[]*common.DashboardResponseEntry{
+			&common.DashboardResponseEntry{"first", "first_template", ""},
+			&common.DashboardResponseEntry{"second", "second_template", ""},
+		},
+	}, result, "created visualization must be returned")
+}
@@ -0,0 +1,109 @@
+package v1Apitest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/authentication"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/openstack/mock"
+)
+
+func TestMetricsGetHttp(t *testing.T) {
+	metrics := &common.MetricsResponseEntry{
+		KeystoneTokenCache: openstack.TokenCacheStats{
+			Enabled: true, Hits: 3, NegativeHits: 1, Misses: 1, Entries: 1,
+			HitRate: 0.75,
+		},
+	}
+	tests := []struct {
+		description    string
+		roles          []string
+		handlerError   error
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			description:    "admin gets metrics",
+			roles:          []string{"admin"},
+			expectedCode:   200,
+			expectedResult: `{"keystoneTokenCache":{"enabled":true,"hits":3,"negativeHits":1,"misses":1,"evictions":0,"entries":1,"hitRate":0.75}}`,
+		},
+		{
+			description:    "member is not allowed to get metrics",
+			roles:          []string{"_member_"},
+			expectedCode:   403,
+			expectedResult: `{"code":403,"message":"Forbidden","details":"Policy does not allow 'metrics:get'"}`,
+		},
+		{
+			description:    "handler error",
+			roles:          []string{"admin"},
+			handlerError:   errors.New("internal error"),
+			expectedCode:   500,
+			expectedResult: `{"code":500,"message":"Internal Server Error","details":"Internal server error occured"}`,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+	keys := jwtkeys.NewSecretKeySet(secret)
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		if testCase.expectedCode != 403 {
+			mockedHandle.EXPECT().MetricsGet(clientContainer).Return(metrics,
+				testCase.handlerError)
+		}
+
+		testHelper.ExpectOrganizationMapping(clientContainer, projectID)
+		identity := testHelper.TestIdentity
+		identity.Roles = testCase.roles
+		token, _ := httpAuth.JWTTokenFromParams(keys, projectID, identity,
+			common.Token{ExpiresAt: time.Now().Add(time.Hour)})
+		request, _ := http.NewRequest("GET", "/v1/admin/metrics", nil)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			keys).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestMetricsGetHandler(t *testing.T) {
+	stats := openstack.TokenCacheStats{Enabled: true, Hits: 1, Misses: 1,
+		HitRate: 0.5}
+
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	clientContainer.Openstack.(*mock_openstack.MockClientInterface).EXPECT().TokenCacheStats().Return(stats)
+
+	handler := v1handlers.V1Metrics{}
+	result, err := handler.MetricsGet(clientContainer)
+	assert.Nil(t, err)
+	assert.Equal(t, &common.MetricsResponseEntry{KeystoneTokenCache: stats},
+		result)
+}
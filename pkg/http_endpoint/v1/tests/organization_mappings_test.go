@@ -0,0 +1,254 @@
+package v1Apitest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+)
+
+const (
+	recreatedProjectID = "0123456789abcdef0123456789abcdef"
+	missingProjectID   = "11111111111111111111111111111111"
+	keptProjectID      = "22222222222222222222222222222222"
+	renamedProjectID   = "33333333333333333333333333333333"
+	customProjectID    = "44444444444444444444444444444444"
+)
+
+func TestOrganizationMappingsHttp(t *testing.T) {
+	mappings := &[]common.OrganizationMappingResponseEntry{
+		{ProjectID: keptProjectID, OrganizationID: "3",
+			OrganizationName: "kept-" + keptProjectID,
+			UpdatedAt:        time.Date(2017, 8, 11, 0, 0, 0, 0, time.UTC)},
+	}
+	remaps := &[]common.OrganizationRemapResponseEntry{
+		{ProjectID: recreatedProjectID, OrganizationName: "test",
+			OldOrganizationID: "3", NewOrganizationID: "5",
+			Action: common.OrganizationRemapped, RewrittenVisualizations: 2},
+	}
+	tests := []struct {
+		description      string
+		method           string
+		path             string
+		expectedDryRun   bool
+		returnedMappings *[]common.OrganizationMappingResponseEntry
+		returnedRemaps   *[]common.OrganizationRemapResponseEntry
+		returnedError    error
+		expectedCode     int
+		expectedResult   string
+	}{
+		{
+			description:      "check 200 on mappings listing",
+			method:           "GET",
+			path:             "/",
+			returnedMappings: mappings,
+			expectedCode:     200,
+			expectedResult:   "[{\"projectId\":\"22222222222222222222222222222222\",\"organizationId\":\"3\",\"organizationName\":\"kept-22222222222222222222222222222222\",\"updatedAt\":\"2017-08-11T00:00:00Z\"}]",
+		},
+		{
+			description:    "check 500 on failed mappings listing",
+			method:         "GET",
+			path:           "/",
+			returnedError:  errors.New("test"),
+			expectedCode:   500,
+			expectedResult: "{\"code\":500,\"message\":\"Internal Server Error\",\"details\":\"Internal server error occured\"}",
+		},
+		{
+			description:    "check 200 on remap",
+			method:         "POST",
+			path:           "/remap",
+			returnedRemaps: remaps,
+			expectedCode:   200,
+			expectedResult: "[{\"projectId\":\"0123456789abcdef0123456789abcdef\",\"organizationName\":\"test\",\"oldOrganizationId\":\"3\",\"newOrganizationId\":\"5\",\"action\":\"remapped\",\"rewrittenVisualizations\":2}]",
+		},
+		{
+			description:    "check 200 on dry run of remap",
+			method:         "POST",
+			path:           "/remap?dry_run=1",
+			expectedDryRun: true,
+			returnedRemaps: &[]common.OrganizationRemapResponseEntry{},
+			expectedCode:   200,
+			expectedResult: "[]",
+		},
+		{
+			description:    "check 422 on invalid dry_run",
+			method:         "POST",
+			path:           "/remap?dry_run=maybe",
+			expectedCode:   422,
+			expectedResult: "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"provided dry_run value is not boolean 'maybe'\"}",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest(testCase.method,
+			"/v1/admin/organization-mappings"+testCase.path, nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.method == "GET" {
+			mockedHandle.EXPECT().OrganizationMappingsGet(
+				clientContainer).Return(testCase.returnedMappings,
+				testCase.returnedError)
+		} else if testCase.expectedCode != 422 {
+			mockedHandle.EXPECT().OrganizationsRemap(clientContainer,
+				testCase.expectedDryRun).Return(testCase.returnedRemaps,
+				testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestOrganizationsRemap(t *testing.T) {
+	testMappings := []*models.OrganizationMapping{
+		// organization was recreated with other id
+		{ProjectID: recreatedProjectID, OrganizationID: "3",
+			OrganizationName: "test-" + recreatedProjectID},
+		// organization is missing, its id was reused by other project
+		{ProjectID: missingProjectID, OrganizationID: "4",
+			OrganizationName: "missing-" + missingProjectID},
+		{ProjectID: keptProjectID, OrganizationID: "5",
+			OrganizationName: "kept-" + keptProjectID},
+		// organization was renamed in grafana after mapping was stored
+		{ProjectID: renamedProjectID, OrganizationID: "7",
+			OrganizationName: "old-" + renamedProjectID},
+		// name of organization has no project id
+		{ProjectID: customProjectID, OrganizationID: "8",
+			OrganizationName: "team-prod"},
+	}
+	testOrganizations := []grafanaclient.OrgList{
+		{ID: 1, Name: "Main Org."},
+		{ID: 4, Name: "test-" + recreatedProjectID},
+		{ID: 2, Name: "archived-test-" + recreatedProjectID},
+		{ID: 5, Name: "kept-" + keptProjectID},
+		{ID: 7, Name: "team-dev"},
+		{ID: 8, Name: "team-prod"},
+	}
+	remapped := common.OrganizationRemapResponseEntry{
+		ProjectID: recreatedProjectID, OldOrganizationID: "3",
+		NewOrganizationID: "4", OrganizationName: "test-" + recreatedProjectID,
+		Action: common.OrganizationRemapped,
+	}
+	created := common.OrganizationRemapResponseEntry{
+		ProjectID: missingProjectID, OldOrganizationID: "4",
+		OrganizationName: "missing-" + missingProjectID,
+		Action:           common.OrganizationCreated,
+	}
+
+	tests := []struct {
+		description     string
+		dryRun          bool
+		remapErr        error
+		expectedEntries *[]common.OrganizationRemapResponseEntry
+		expectedError   error
+	}{
+		{
+			description: "visualizations are moved to new organizations",
+			expectedEntries: func() *[]common.OrganizationRemapResponseEntry {
+				remappedResult := remapped
+				remappedResult.RewrittenVisualizations = 2
+				createdResult := created
+				createdResult.NewOrganizationID = "6"
+				return &[]common.OrganizationRemapResponseEntry{
+					remappedResult, createdResult}
+			}(),
+		},
+		{
+			description: "dry run makes no changes",
+			dryRun:      true,
+			expectedEntries: &[]common.OrganizationRemapResponseEntry{
+				remapped, created},
+		},
+		{
+			description:   "failed remap is reported",
+			remapErr:      errors.New("test"),
+			expectedError: errors.New("test"),
+		},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		mockedDatabase := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+		mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+
+		organizations := make([]grafanaclient.OrgList, len(testOrganizations))
+		copy(organizations, testOrganizations)
+		mockedDatabase.EXPECT().QueryOrganizationMappings().Return(
+			testMappings, nil)
+		mockedGrafana.EXPECT().GetOrganizations().Return(organizations, nil)
+		if !testCase.dryRun {
+			mockedGrafana.EXPECT().GetOrCreateOrgByName(
+				"missing-"+missingProjectID).Return(
+				&grafanaclient.OrgID{ID: 6}, nil)
+			mockedDatabase.EXPECT().RemapOrganizations([]*db.OrganizationRemap{
+				{ProjectID: recreatedProjectID, OldOrganizationID: "3",
+					NewOrganizationID:   "4",
+					NewOrganizationName: "test-" + recreatedProjectID},
+				{ProjectID: missingProjectID, OldOrganizationID: "4",
+					NewOrganizationID:   "6",
+					NewOrganizationName: "missing-" + missingProjectID},
+			}).Do(func(remaps []*db.OrganizationRemap) {
+				remaps[0].RewrittenVisualizations = 2
+			}).Return(testCase.remapErr)
+		}
+
+		handler := v1handlers.V1OrganizationMappings{}
+		entries, err := handler.OrganizationsRemap(clientContainer,
+			testCase.dryRun)
+		assert.Equal(t, testCase.expectedError, err, testCase.description)
+		assert.Equal(t, testCase.expectedEntries, entries,
+			testCase.description)
+	}
+}
+
+func TestOrganizationMappingsGet(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	updatedAt := time.Date(2017, 8, 11, 0, 0, 0, 0, time.UTC)
+	clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager).EXPECT().QueryOrganizationMappings().Return(
+		[]*models.OrganizationMapping{{ProjectID: keptProjectID,
+			OrganizationID: "5", OrganizationName: "kept", UpdatedAt: updatedAt}},
+		nil)
+
+	handler := v1handlers.V1OrganizationMappings{}
+	entries, err := handler.OrganizationMappingsGet(clientContainer)
+	assert.Nil(t, err)
+	assert.Equal(t, &[]common.OrganizationMappingResponseEntry{
+		{ProjectID: keptProjectID, OrganizationID: "5",
+			OrganizationName: "kept", UpdatedAt: updatedAt}}, entries)
+}
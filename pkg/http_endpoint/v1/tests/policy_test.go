@@ -0,0 +1,132 @@
+package v1Apitest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/authentication"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/policy"
+)
+
+func TestPolicyMiddleware(t *testing.T) {
+	const visualizationID = "0f29d63b-be6f-43cf-b99f-23271b3e6041"
+	tests := []struct {
+		description    string
+		roles          []string
+		method         string
+		url            string
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			description:  "reader is allowed to get visualizations",
+			roles:        []string{"reader"},
+			method:       "GET",
+			url:          "/v1/visualization/" + visualizationID,
+			expectedCode: 200,
+		},
+		{
+			description:    "reader is not allowed to delete visualizations",
+			roles:          []string{"reader"},
+			method:         "DELETE",
+			url:            "/v1/visualization/" + visualizationID,
+			expectedCode:   403,
+			expectedResult: `{"code":403,"message":"Forbidden","details":"Policy does not allow 'visualizations:delete'"}`,
+		},
+		{
+			description:    "reader is not allowed to create templates",
+			roles:          []string{"_member_", "reader"},
+			method:         "POST",
+			url:            "/v1/templates",
+			expectedCode:   403,
+			expectedResult: `{"code":403,"message":"Forbidden","details":"Policy does not allow 'templates:create'"}`,
+		},
+		{
+			description:  "member is allowed to delete visualizations",
+			roles:        []string{"_member_"},
+			method:       "DELETE",
+			url:          "/v1/visualization/" + visualizationID,
+			expectedCode: 200,
+		},
+		{
+			description:    "member is not allowed to use admin api",
+			roles:          []string{"_member_"},
+			method:         "GET",
+			url:            "/v1/admin/audit",
+			expectedCode:   403,
+			expectedResult: `{"code":403,"message":"Forbidden","details":"Policy does not allow 'audit:get'"}`,
+		},
+		{
+			description:    "admin role of policy is allowed to use admin api",
+			roles:          []string{"cloud_admin"},
+			method:         "GET",
+			url:            "/v1/admin/audit",
+			expectedCode:   200,
+			expectedResult: `[]`,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	accessPolicy, err := policy.NewPolicy(map[string]string{
+		policy.RuleAdmin:                "role:cloud_admin",
+		"reader":                        "role:reader",
+		policy.RuleVisualizationsDelete: "not rule:reader",
+		policy.RuleTemplatesCreate:      "not rule:reader",
+	})
+	assert.Nil(t, err)
+	keys := jwtkeys.NewSecretKeySet(secret)
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		clientContainer.Policy = accessPolicy
+
+		visualization := &common.VisualizationWithDashboards{
+			&common.VisualizationResponseEntry{visualizationID, "name",
+				map[string]interface{}{}},
+			[]*common.DashboardResponseEntry{},
+		}
+		// handlers are called only if policy allows api call
+		mockedHandle.EXPECT().VisualizationGet(clientContainer, projectID,
+			visualizationID, false).Return(visualization, nil).AnyTimes()
+		mockedHandle.EXPECT().VisualizationDelete(clientContainer, projectID,
+			visualizationID).Return(visualization, nil).AnyTimes()
+		mockedHandle.EXPECT().AuditRecordsGet(clientContainer,
+			gomock.Any()).Return(&[]common.AuditRecordResponseEntry{},
+			nil).AnyTimes()
+
+		testHelper.ExpectOrganizationMapping(clientContainer, projectID)
+		identity := testHelper.TestIdentity
+		identity.Roles = testCase.roles
+		token, _ := httpAuth.JWTTokenFromParams(keys, projectID, identity,
+			common.Token{ExpiresAt: time.Now().Add(time.Hour)})
+		request, _ := http.NewRequest(testCase.method, testCase.url, nil)
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			keys).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		if testCase.expectedResult != "" {
+			responseData, _ := ioutil.ReadAll(response.Body)
+			assert.Equal(t, testCase.expectedResult, string(responseData),
+				testCase.description)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package v1Apitest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/projectsync"
+	"visualization-api/pkg/projectsync/mock"
+)
+
+func TestProjectSyncHttp(t *testing.T) {
+	report := &projectsync.Report{
+		DryRun:     true,
+		StartedAt:  time.Date(2017, 7, 20, 0, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2017, 7, 20, 0, 0, 1, 0, time.UTC),
+		Entries: []projectsync.ReportEntry{
+			{OrganizationID: "3", OrganizationName: "old-3f1e",
+				ProjectID: "3f1e", NewName: "new-3f1e",
+				Action: projectsync.ActionRenamed},
+		},
+		Errors: []string{},
+	}
+	serializedReport := "{\"dryRun\":true,\"startedAt\":\"2017-07-20T00:00:00Z\",\"finishedAt\":\"2017-07-20T00:00:01Z\",\"entries\":[{\"organizationId\":\"3\",\"organizationName\":\"old-3f1e\",\"projectId\":\"3f1e\",\"newName\":\"new-3f1e\",\"action\":\"renamed\"}],\"errors\":[]}"
+	tests := []struct {
+		description    string
+		method         string
+		query          string
+		expectedDryRun bool
+		returnedReport *projectsync.Report
+		returnedError  error
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			description:    "check 200 on existing report",
+			method:         "GET",
+			returnedReport: report,
+			expectedCode:   200,
+			expectedResult: serializedReport,
+		},
+		{
+			description:    "check 404 when synchronization was not run",
+			method:         "GET",
+			returnedError:  common.NewNotFoundError("Synchronization of projects has not been run yet"),
+			expectedCode:   404,
+			expectedResult: "{\"code\":404,\"message\":\"Not Found\",\"details\":\"Synchronization of projects has not been run yet\"}",
+		},
+		{
+			description:    "check 200 on triggered run",
+			method:         "POST",
+			returnedReport: report,
+			expectedCode:   200,
+			expectedResult: serializedReport,
+		},
+		{
+			description:    "check 200 on triggered dry run",
+			method:         "POST",
+			query:          "?dry_run=true",
+			expectedDryRun: true,
+			returnedReport: report,
+			expectedCode:   200,
+			expectedResult: serializedReport,
+		},
+		{
+			description:    "check 422 on invalid dry_run",
+			method:         "POST",
+			query:          "?dry_run=maybe",
+			expectedCode:   422,
+			expectedResult: "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"provided dry_run value is not boolean 'maybe'\"}",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest(testCase.method,
+			"/v1/admin/project-sync/"+testCase.query, nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.method == "GET" {
+			mockedHandle.EXPECT().ProjectSyncReport(clientContainer).Return(
+				testCase.returnedReport, testCase.returnedError)
+		} else if testCase.expectedCode == 200 {
+			mockedHandle.EXPECT().ProjectSync(clientContainer,
+				testCase.expectedDryRun).Return(testCase.returnedReport,
+				testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestProjectSyncHandlers(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	mockedSyncer := clientContainer.ProjectSync.(*mock_projectsync.MockSyncerInterface)
+	handler := v1handlers.V1ProjectSync{}
+
+	mockedSyncer.EXPECT().LastReport().Return(nil)
+	report, err := handler.ProjectSyncReport(clientContainer)
+	assert.Nil(t, report)
+	assert.Equal(t, common.NewNotFoundError(
+		"Synchronization of projects has not been run yet"), err)
+
+	expectedReport := &projectsync.Report{DryRun: true}
+	mockedSyncer.EXPECT().Run(true).Return(expectedReport)
+	report, err = handler.ProjectSync(clientContainer, true)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedReport, report)
+}
@@ -0,0 +1,112 @@
+package v1Apitest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/reconciler"
+	"visualization-api/pkg/reconciler/mock"
+)
+
+func TestReconciliationHttp(t *testing.T) {
+	report := &reconciler.Report{
+		StartedAt:  time.Date(2017, 7, 20, 0, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2017, 7, 20, 0, 0, 1, 0, time.UTC),
+		Entries: []reconciler.ReportEntry{
+			{OrganizationID: "3", Name: "Orphan", Slug: "orphan",
+				Action: reconciler.ActionOrphanFlagged},
+		},
+		Errors: []string{},
+	}
+	tests := []struct {
+		description    string
+		method         string
+		returnedReport *reconciler.Report
+		returnedError  error
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			description:    "check 200 on existing report",
+			method:         "GET",
+			returnedReport: report,
+			expectedCode:   200,
+			expectedResult: "{\"startedAt\":\"2017-07-20T00:00:00Z\",\"finishedAt\":\"2017-07-20T00:00:01Z\",\"entries\":[{\"organizationId\":\"3\",\"name\":\"Orphan\",\"slug\":\"orphan\",\"action\":\"orphan_flagged\"}],\"errors\":[]}",
+		},
+		{
+			description:    "check 404 when reconciliation was not run",
+			method:         "GET",
+			returnedError:  common.NewNotFoundError("Reconciliation has not been run yet"),
+			expectedCode:   404,
+			expectedResult: "{\"code\":404,\"message\":\"Not Found\",\"details\":\"Reconciliation has not been run yet\"}",
+		},
+		{
+			description:    "check 200 on triggered run",
+			method:         "POST",
+			returnedReport: report,
+			expectedCode:   200,
+			expectedResult: "{\"startedAt\":\"2017-07-20T00:00:00Z\",\"finishedAt\":\"2017-07-20T00:00:01Z\",\"entries\":[{\"organizationId\":\"3\",\"name\":\"Orphan\",\"slug\":\"orphan\",\"action\":\"orphan_flagged\"}],\"errors\":[]}",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest(testCase.method, "/v1/admin/reconciliation/", nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.method == "GET" {
+			mockedHandle.EXPECT().ReconciliationReport(clientContainer).Return(
+				testCase.returnedReport, testCase.returnedError)
+		} else {
+			mockedHandle.EXPECT().Reconcile(clientContainer).Return(
+				testCase.returnedReport, testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestReconciliationHandlers(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	mockedReconciler := clientContainer.Reconciler.(*mock_reconciler.MockRunnerInterface)
+	handler := v1handlers.V1Reconciliation{}
+
+	mockedReconciler.EXPECT().LastReport().Return(nil)
+	report, err := handler.ReconciliationReport(clientContainer)
+	assert.Nil(t, report)
+	assert.Equal(t, common.NewNotFoundError("Reconciliation has not been run yet"), err)
+
+	expectedReport := &reconciler.Report{}
+	mockedReconciler.EXPECT().Run().Return(expectedReport)
+	report, err = handler.Reconcile(clientContainer)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedReport, report)
+}
@@ -0,0 +1,397 @@
+package v1Apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint"
+	"visualization-api/pkg/http_endpoint/common"
+	"visualization-api/pkg/http_endpoint/common/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+)
+
+func TestTemplatesGetNameAndVersion(t *testing.T) {
+	tests := []struct {
+		description   string
+		query         string
+		name          string
+		version       int
+		handlerCalled bool
+		expectedCode  int
+	}{
+		{
+			description:   "both name and version are provided",
+			query:         "?name=name&version=2",
+			name:          "name",
+			version:       2,
+			handlerCalled: true,
+			expectedCode:  200,
+		},
+		{
+			description:   "both name and version are not provided",
+			query:         "",
+			handlerCalled: true,
+			expectedCode:  200,
+		},
+		{
+			description:   "version is not integer",
+			query:         "?name=name&version=latest",
+			handlerCalled: false,
+			expectedCode:  422,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("GET", "/v1/templates"+testCase.query, nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.handlerCalled {
+			mockedHandle.EXPECT().TemplatesGet(clientContainer, projectID,
+				testCase.name, testCase.version).Return(&[]common.TemplateResponseEntry{}, nil)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			"response code match")
+	}
+}
+
+func TestTemplatesPostResponses(t *testing.T) {
+	tests := []struct {
+		description     string
+		tokenProvided   bool
+		payloadProvided string
+		payloadValid    bool
+		expectedCode    int
+		expectedResult  string
+		returnedError   error
+		handlerResult   *common.TemplateResponseEntry
+	}{
+		{
+			description:   "check 401 on auth token missing",
+			tokenProvided: false,
+			expectedCode:  401,
+		},
+		{
+			description:     "check 200 on positive outcome",
+			payloadProvided: "{\"name\": \"template_name\", \"parameters\": {\"param1\": \"value1\"}, \"templateBody\": \"template\"}",
+			payloadValid:    true,
+			tokenProvided:   true,
+			expectedCode:    200,
+			expectedResult:  "{\"id\":1,\"name\":\"template_name\",\"version\":1,\"parameters\":{\"param1\":\"value1\"},\"templateBody\":\"template\"}",
+			handlerResult: &common.TemplateResponseEntry{
+				ID:           1,
+				Name:         "template_name",
+				Version:      1,
+				Parameters:   map[string]interface{}{"param1": "value1"},
+				TemplateBody: "template",
+			},
+		},
+		{
+			description:     "check 422 on invalid json schema",
+			payloadProvided: "{\"name\": \"template_name\"}",
+			payloadValid:    false,
+			tokenProvided:   true,
+			expectedCode:    422,
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"request body is not valid, list of erros [templateBody: templateBody is required]\"}",
+		},
+		{
+			description:     "check 409 on existing version",
+			payloadProvided: "{\"name\": \"template_name\", \"version\": 1, \"templateBody\": \"template\"}",
+			payloadValid:    true,
+			tokenProvided:   true,
+			expectedCode:    409,
+			returnedError:   common.NewConflictError("test"),
+			expectedResult:  "{\"code\":409,\"message\":\"Conflict\",\"details\":\"test\"}",
+		},
+		{
+			description:     "check 422 on template error",
+			payloadProvided: "{\"name\": \"template_name\", \"templateBody\": \"{{\"}",
+			payloadValid:    true,
+			tokenProvided:   true,
+			expectedCode:    422,
+			returnedError:   common.NewUserDataError("test"),
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"Error parsing template 'test'\"}",
+		},
+		{
+			description:     "check 500",
+			payloadProvided: "{\"name\": \"template_name\", \"templateBody\": \"template\"}",
+			payloadValid:    true,
+			tokenProvided:   true,
+			expectedCode:    500,
+			returnedError:   errors.New("test"),
+			expectedResult:  "{\"code\":500,\"message\":\"Internal Server Error\",\"details\":\"Internal server error occured\"}",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("POST", "/v1/templates",
+			bytes.NewBuffer([]byte(testCase.payloadProvided)))
+		request.Header.Set("Content-Type", "application/json")
+		if testCase.tokenProvided {
+			testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+				request)
+		}
+		if testCase.payloadValid {
+			payload := common.TemplatePOSTData{}
+			json.Unmarshal([]byte(testCase.payloadProvided), &payload)
+			mockedHandle.EXPECT().TemplatesPost(clientContainer, payload,
+				projectID).Return(testCase.handlerResult, testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			"response code match")
+		if testCase.tokenProvided {
+			responseData, _ := ioutil.ReadAll(response.Body)
+			assert.Equal(t, testCase.expectedResult, string(responseData),
+				"response body match")
+		}
+	}
+}
+
+func TestTemplateDeleteResponses(t *testing.T) {
+	tests := []struct {
+		description   string
+		templateID    string
+		idValid       bool
+		expectedCode  int
+		returnedError error
+	}{
+		{
+			description:  "provided id is not valid",
+			templateID:   "not_integer",
+			idValid:      false,
+			expectedCode: 422,
+		},
+		{
+			description:   "template was not found",
+			templateID:    "1",
+			idValid:       true,
+			expectedCode:  404,
+			returnedError: common.NewUserDataError("test"),
+		},
+		{
+			description:  "template was deleted",
+			templateID:   "1",
+			idValid:      true,
+			expectedCode: 200,
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("DELETE",
+			fmt.Sprintf("/v1/template/%s", testCase.templateID), nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.idValid {
+			mockedHandle.EXPECT().TemplateDelete(clientContainer, projectID,
+				1).Return(&common.TemplateResponseEntry{}, testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			"response code match")
+	}
+}
+
+func TestTemplatesPostHandler(t *testing.T) {
+	tests := []struct {
+		description      string
+		data             common.TemplatePOSTData
+		existingVersions []*models.Template
+		expectCreation   bool
+		creationError    error
+		expectedError    error
+	}{
+		{
+			description:    "new version is created without explicit version",
+			data:           common.TemplatePOSTData{Name: "name", TemplateBody: "{{.param}}"},
+			expectCreation: true,
+		},
+		{
+			description:      "explicit version already exists",
+			data:             common.TemplatePOSTData{Name: "name", Version: 1, TemplateBody: "{{.param}}"},
+			existingVersions: []*models.Template{&models.Template{ID: 1, Name: "name", Version: 1}},
+			expectCreation:   false,
+			expectedError:    common.NewConflictError("Template 'name' version '1' already exists"),
+		},
+		{
+			description:    "version is created by concurrent request",
+			data:           common.TemplatePOSTData{Name: "name", Version: 1, TemplateBody: "{{.param}}"},
+			expectCreation: true,
+			creationError:  db.ErrDuplicate,
+			expectedError:  common.NewConflictError("Template 'name' version '1' already exists"),
+		},
+		{
+			description:    "template body is not valid",
+			data:           common.TemplatePOSTData{Name: "name", TemplateBody: "{{.param"},
+			expectCreation: false,
+			expectedError:  common.NewUserDataError("template: :1: unclosed action"),
+		},
+	}
+
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+
+		if testCase.data.Version != 0 {
+			mockedDatabaseManager.EXPECT().QueryTemplates(projectID,
+				testCase.data.Name, testCase.data.Version).Return(
+				testCase.existingVersions, nil)
+		}
+		createdTemplate := &models.Template{ID: 2, Name: testCase.data.Name,
+			Version: 1, OrganizationID: projectID, Body: testCase.data.TemplateBody}
+		if testCase.creationError != nil {
+			createdTemplate = nil
+		}
+		if testCase.expectCreation {
+			mockedDatabaseManager.EXPECT().CreateTemplate(testCase.data.Name,
+				projectID, testCase.data.Version, testCase.data.Parameters,
+				testCase.data.TemplateBody).Return(createdTemplate,
+				testCase.creationError)
+		}
+
+		handler := v1handlers.V1Templates{}
+		result, err := handler.TemplatesPost(clientContainer, testCase.data, projectID)
+		assert.Equal(t, testCase.expectedError, err, testCase.description)
+		if testCase.expectCreation && testCase.creationError == nil {
+			assert.Equal(t, v1handlers.TemplateToResponse(createdTemplate), result,
+				testCase.description)
+		}
+	}
+}
+
+func TestTemplateToResponse(t *testing.T) {
+	tests := []struct {
+		description        string
+		parameters         string
+		expectedParameters map[string]interface{}
+	}{
+		{"parameters are decoded", "{\"param1\": \"value1\", \"count\": 2}",
+			map[string]interface{}{"param1": "value1", "count": 2.0}},
+		{"empty parameters", "", map[string]interface{}{}},
+		{"parameters are not object", "[1]", map[string]interface{}{}},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		result := v1handlers.TemplateToResponse(&models.Template{ID: 1,
+			Name: "name", Version: 2, Parameters: testCase.parameters,
+			Body: "body"})
+		assert.Equal(t, &common.TemplateResponseEntry{ID: 1, Name: "name",
+			Version: 2, Parameters: testCase.expectedParameters,
+			TemplateBody: "body"}, result, testCase.description)
+	}
+}
+
+func TestVisualizationsPostTemplateResolution(t *testing.T) {
+	tests := []struct {
+		description     string
+		storedTemplates []*models.Template
+		expectedError   error
+	}{
+		{
+			description:     "template is not found in catalog",
+			storedTemplates: []*models.Template{},
+			expectedError:   common.NewUserDataError("Template 'template_name' version '2' not found"),
+		},
+		{
+			description: "template is resolved from catalog",
+			storedTemplates: []*models.Template{
+				&models.Template{ID: 1, Name: "template_name", Version: 2,
+					Body: "{\"title\": \"{{.title}}\"}"},
+			},
+		},
+	}
+
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+		mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+
+		data := common.VisualizationPOSTData{}
+		json.Unmarshal([]byte("{\"name\": \"visualization_name\", \"dashboards\": [{\"name\": \"dashboard_name\", \"templateName\": \"template_name\", \"templateVersion\": 2, \"templateParameters\": {\"title\": \"test\"}}]}"), &data)
+
+		mockedDatabaseManager.EXPECT().QueryTemplates(projectID, "template_name",
+			2).Return(
+			testCase.storedTemplates, nil)
+		if testCase.expectedError == nil {
+			renderedTemplate := "{\"title\": \"test\"}"
+			visualization := &models.Visualization{ID: 1, Slug: "visualization_slug",
+				Name: "visualization_name", OrganizationID: projectID}
+			dashboard := &models.Dashboard{ID: "id", Visualization: 1,
+				Name: "dashboard_name", RenderedTemplate: renderedTemplate}
+			operation := &models.GrafanaOperation{ID: 1,
+				Operation: models.GrafanaOperationUpload, OrganizationID: projectID,
+				DashboardID: "id", Payload: renderedTemplate}
+			mockedDatabaseManager.EXPECT().CreateVisualizationsWithDashboards(
+				"visualization_name", projectID, data.Tags,
+				[]string{"dashboard_name"}, []string{renderedTemplate}).Return(
+				visualization, []*models.Dashboard{dashboard},
+				[]*models.GrafanaOperation{operation}, nil)
+			mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(operation,
+				"").Return(true, nil)
+			mockedGrafana.EXPECT().UploadDashboard([]byte(renderedTemplate),
+				projectID, false).Return("dashboard_slug", nil)
+			mockedDatabaseManager.EXPECT().CompleteGrafanaOperation(
+				operation, "dashboard_slug").Return(nil)
+		}
+
+		handler := v1handlers.V1Visualizations{}
+		_, err := handler.VisualizationsPost(clientContainer, data, projectID)
+		assert.Equal(t, testCase.expectedError, err, testCase.description)
+	}
+}
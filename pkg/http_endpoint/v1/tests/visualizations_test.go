@@ -8,10 +8,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database"
 	"visualization-api/pkg/database/mock"
 	"visualization-api/pkg/database/models"
 	"visualization-api/pkg/grafanaclient/mock"
@@ -20,6 +23,8 @@ import (
 	"visualization-api/pkg/http_endpoint/common/mock"
 	"visualization-api/pkg/http_endpoint/common/tests"
 	"visualization-api/pkg/http_endpoint/v1/handlers"
+	"visualization-api/pkg/jwtkeys"
+	"visualization-api/pkg/search"
 )
 
 func TestVisualizationsGetTagsAndName(t *testing.T) {
@@ -27,32 +32,65 @@ func TestVisualizationsGetTagsAndName(t *testing.T) {
 	tests := []struct {
 		description string
 		query       string
-		name        string
-		tags        map[string]interface{}
+		searchQuery search.Query
+		pagination  db.Pagination
 	}{
 		{
 			description: "both name and tags are provided",
 			query:       "?name=name&tag1=tag1&tag2=tag2",
-			name:        "name",
-			tags:        map[string]interface{}{"tag1": "tag1", "tag2": "tag2"},
+			searchQuery: search.Query{
+				{{"name", search.Equal, []interface{}{"name"}}},
+				{{"tag1", search.Equal, []interface{}{"tag1"}}},
+				{{"tag2", search.Equal, []interface{}{"tag2"}}},
+			},
 		},
 		{
 			description: "both name and tags are not provided",
 			query:       "",
-			name:        "",
-			tags:        map[string]interface{}{},
+			searchQuery: search.Query{},
 		},
 		{
 			description: "only tags are provided",
 			query:       "?tag1=tag1&tag2=tag2",
-			name:        "",
-			tags:        map[string]interface{}{"tag1": "tag1", "tag2": "tag2"},
+			searchQuery: search.Query{
+				{{"tag1", search.Equal, []interface{}{"tag1"}}},
+				{{"tag2", search.Equal, []interface{}{"tag2"}}},
+			},
 		},
 		{
 			description: "only name is provided",
 			query:       "?name=name",
-			name:        "name",
-			tags:        map[string]interface{}{},
+			searchQuery: search.Query{
+				{{"name", search.Equal, []interface{}{"name"}}},
+			},
+		},
+		{
+			description: "operators are provided",
+			query:       "?env=prod&env=dev&team!=ops&owner&name^=web&or=size=1|size",
+			searchQuery: search.Query{
+				{{"env", search.Equal, []interface{}{"prod", "dev"}}},
+				{{"name", search.Prefix, []interface{}{"web"}}},
+				{
+					{"size", search.Equal, []interface{}{1.0, "1"}},
+					{"size", search.Exists, nil},
+				},
+				{{"owner", search.Exists, nil}},
+				{{"team", search.NotEqual, []interface{}{"ops"}}},
+			},
+		},
+		{
+			description: "pagination parameters are not used as tags",
+			query:       "?tag1=tag1&limit=10&marker=slug&sort=name",
+			searchQuery: search.Query{
+				{{"tag1", search.Equal, []interface{}{"tag1"}}},
+			},
+			pagination: db.Pagination{Limit: 10, Marker: "slug", Sort: db.SortByName},
+		},
+		{
+			description: "offset is provided",
+			query:       "?offset=20",
+			searchQuery: search.Query{},
+			pagination:  db.Pagination{Offset: 20},
 		},
 	}
 
@@ -67,12 +105,107 @@ func TestVisualizationsGetTagsAndName(t *testing.T) {
 		clientContainer := testHelper.MockClientContainer(mockCtrl)
 
 		request, _ := http.NewRequest("GET", "/v1/visualizations"+testCase.query, nil)
-		testHelper.SetRequestAuthHeader(secret, projectID, request)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		// visualizations are sorted by creation by default
+		if testCase.pagination.Sort == "" {
+			testCase.pagination.Sort = db.SortByCreated
+		}
 		mockedHandle.EXPECT().VisualizationsGet(clientContainer, projectID,
-			testCase.name, testCase.tags)
+			testCase.searchQuery, testCase.pagination)
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+	}
+}
+
+func TestVisualizationsGetQueryErrors(t *testing.T) {
+	tests := []struct {
+		description     string
+		query           string
+		handlerCalled   bool
+		expectedMessage string
+	}{
+		{
+			description:     "limit is not integer",
+			query:           "?limit=ten",
+			expectedMessage: "limit has to be integer between 1 and 1000",
+		},
+		{
+			description:     "limit is too big",
+			query:           "?limit=1001",
+			expectedMessage: "limit has to be integer between 1 and 1000",
+		},
+		{
+			description:     "limit is zero",
+			query:           "?limit=0",
+			expectedMessage: "limit has to be integer between 1 and 1000",
+		},
+		{
+			description:     "offset is negative",
+			query:           "?offset=-1",
+			expectedMessage: "offset has to be non negative integer",
+		},
+		{
+			description:     "marker is provided together with offset",
+			query:           "?offset=10&marker=slug",
+			expectedMessage: "marker and offset can not be used together",
+		},
+		{
+			description:     "unsupported sort",
+			query:           "?sort=tags",
+			expectedMessage: "sort has to be one of 'name', 'created'",
+		},
+		{
+			description:     "invalid tag name",
+			query:           "?tag%27%3B=value",
+			expectedMessage: "Invalid search query: invalid field name 'tag';'",
+		},
+		{
+			description:     "prefix match of tag",
+			query:           "?env^=prod",
+			expectedMessage: "Invalid search query: operator '^=' is supported only by name",
+		},
+		{
+			description:     "empty condition of or group",
+			query:           "?or=env=prod||team",
+			expectedMessage: "Invalid search query: or group contains empty condition",
+		},
+		{
+			description:     "marker is not found",
+			query:           "?marker=slug",
+			handlerCalled:   true,
+			expectedMessage: "marker was not found",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("GET", "/v1/visualizations"+testCase.query, nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.handlerCalled {
+			mockedHandle.EXPECT().VisualizationsGet(clientContainer, projectID,
+				search.Query{}, db.Pagination{Marker: "slug",
+					Sort: db.SortByCreated}).Return(nil, int64(0),
+				common.NewUserDataError(testCase.expectedMessage))
+		}
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Contains(t, string(responseData), testCase.expectedMessage,
+			testCase.description)
 	}
 }
 
@@ -105,13 +238,13 @@ func TestVisualizationsGetResponses(t *testing.T) {
 			tokenProvided:        true,
 			expectedCode:         200,
 			handlerErrorExpected: false,
-			expectedResult:       "[{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":\"{\\\"tag1\\\": \\\"tag1\\\"}\",\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}]",
+			expectedResult:       "[{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{\"tag1\":\"tag1\"},\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}]",
 			handlerResult: &[]common.VisualizationWithDashboards{
 				common.VisualizationWithDashboards{
 					&common.VisualizationResponseEntry{
 						"visualization_id",
 						"visualization_name",
-						"{\"tag1\": \"tag1\"}"},
+						map[string]interface{}{"tag1": "tag1"}},
 					[]*common.DashboardResponseEntry{
 						&common.DashboardResponseEntry{
 							"dashboard_name",
@@ -135,24 +268,29 @@ func TestVisualizationsGetResponses(t *testing.T) {
 
 		request, _ := http.NewRequest("GET", "/v1/visualizations", nil)
 		if testCase.tokenProvided {
-			testHelper.SetRequestAuthHeader(secret, projectID, request)
+			testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+				request)
 			if testCase.handlerErrorExpected {
 				mockedHandle.EXPECT().VisualizationsGet(clientContainer, projectID,
-					"", map[string]interface{}{}).Return(testCase.handlerResult, errors.New(""))
+					search.Query{}, db.Pagination{Sort: db.SortByCreated}).Return(
+					testCase.handlerResult, int64(0), errors.New(""))
 			} else {
 				mockedHandle.EXPECT().VisualizationsGet(clientContainer, projectID,
-					"", map[string]interface{}{}).Return(testCase.handlerResult, nil)
+					search.Query{}, db.Pagination{Sort: db.SortByCreated}).Return(
+					testCase.handlerResult, int64(len(*testCase.handlerResult)), nil)
 			}
 		}
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 		if !testCase.handlerErrorExpected && testCase.tokenProvided {
 			responseData, _ := ioutil.ReadAll(response.Body)
 			assert.Equal(t, testCase.expectedResult, string(responseData),
 				"response body match")
+			assert.Equal(t, "1", response.Header().Get("X-Total-Count"),
+				"total count header match")
 		}
 	}
 }
@@ -191,13 +329,14 @@ func TestVisualizationDeleteUUIDArg(t *testing.T) {
 
 		url := fmt.Sprintf("/v1/visualization/%s", testCase.visualizationID)
 		request, _ := http.NewRequest("DELETE", url, nil)
-		testHelper.SetRequestAuthHeader(secret, projectID, request)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
 		if testCase.visualizationIDValid {
 			mockedHandle.EXPECT().VisualizationDelete(clientContainer, projectID, testCase.visualizationID)
 		}
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 	}
@@ -224,11 +363,11 @@ func TestVisualizationDeleteResponses(t *testing.T) {
 			visualizationID:      "0f29d63b-be6f-43cf-b99f-23271b3e6041",
 		},
 		{
-			description:          "check 404 on auth token missing",
+			description:          "check 404 on missing visualization",
 			tokenProvided:        true,
 			expectedCode:         404,
 			handlerErrorExpected: true,
-			returnedError:        common.NewUserDataError("test"),
+			returnedError:        common.NewNotFoundError("test"),
 			handlerResult:        nil,
 			visualizationID:      "0f29d63b-be6f-43cf-b99f-23271b3e6041",
 			expectedResult:       "{\"code\":404,\"message\":\"Not Found\",\"details\":\"Requested visualization '0f29d63b-be6f-43cf-b99f-23271b3e6041' was not found\"}",
@@ -239,13 +378,13 @@ func TestVisualizationDeleteResponses(t *testing.T) {
 			expectedCode:         500,
 			handlerErrorExpected: true,
 			returnedError:        common.NewClientError("test"),
-			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":\"{\\\"tag1\\\": \\\"tag1\\\"}\",\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
+			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{\"tag1\":\"tag1\"},\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
 			visualizationID:      "0f29d63b-be6f-43cf-b99f-23271b3e6041",
 			handlerResult: &common.VisualizationWithDashboards{
 				&common.VisualizationResponseEntry{
 					"visualization_id",
 					"visualization_name",
-					"{\"tag1\": \"tag1\"}"},
+					map[string]interface{}{"tag1": "tag1"}},
 				[]*common.DashboardResponseEntry{
 					&common.DashboardResponseEntry{
 						"dashboard_name",
@@ -270,12 +409,12 @@ func TestVisualizationDeleteResponses(t *testing.T) {
 			visualizationID:      "0f29d63b-be6f-43cf-b99f-23271b3e6041",
 			expectedCode:         200,
 			handlerErrorExpected: false,
-			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":\"{\\\"tag1\\\": \\\"tag1\\\"}\",\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
+			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{\"tag1\":\"tag1\"},\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
 			handlerResult: &common.VisualizationWithDashboards{
 				&common.VisualizationResponseEntry{
 					"visualization_id",
 					"visualization_name",
-					"{\"tag1\": \"tag1\"}"},
+					map[string]interface{}{"tag1": "tag1"}},
 				[]*common.DashboardResponseEntry{
 					&common.DashboardResponseEntry{
 						"dashboard_name",
@@ -299,7 +438,8 @@ func TestVisualizationDeleteResponses(t *testing.T) {
 		request, _ := http.NewRequest("DELETE",
 			fmt.Sprintf("/v1/visualization/%s", testCase.visualizationID), nil)
 		if testCase.tokenProvided {
-			testHelper.SetRequestAuthHeader(secret, projectID, request)
+			testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+				request)
 			if testCase.handlerErrorExpected {
 				mockedHandle.EXPECT().VisualizationDelete(clientContainer, projectID,
 					testCase.visualizationID).Return(testCase.handlerResult, testCase.returnedError)
@@ -310,7 +450,7 @@ func TestVisualizationDeleteResponses(t *testing.T) {
 		}
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 		if testCase.tokenProvided {
@@ -349,12 +489,12 @@ func TestVisualizationPostResponses(t *testing.T) {
 			expectedCode:         200,
 			handlerErrorExpected: false,
 			returnedError:        nil,
-			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":\"{\\\"tag1\\\": \\\"tag1\\\"}\",\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
+			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{\"tag1\":\"tag1\"},\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
 			handlerResult: &common.VisualizationWithDashboards{
 				&common.VisualizationResponseEntry{
 					"visualization_id",
 					"visualization_name",
-					"{\"tag1\": \"tag1\"}"},
+					map[string]interface{}{"tag1": "tag1"}},
 				[]*common.DashboardResponseEntry{
 					&common.DashboardResponseEntry{
 						"dashboard_name",
@@ -425,12 +565,12 @@ func TestVisualizationPostResponses(t *testing.T) {
 			expectedCode:         500,
 			handlerErrorExpected: true,
 			returnedError:        common.NewClientError("test"),
-			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":\"{\\\"tag1\\\": \\\"tag1\\\"}\",\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
+			expectedResult:       "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{\"tag1\":\"tag1\"},\"dashboards\":[{\"name\":\"dashboard_name\",\"renderedTemplate\":\"dashboard_template\",\"id\":\"dashboard_slug\"}]}",
 			handlerResult: &common.VisualizationWithDashboards{
 				&common.VisualizationResponseEntry{
 					"visualization_id",
 					"visualization_name",
-					"{\"tag1\": \"tag1\"}"},
+					map[string]interface{}{"tag1": "tag1"}},
 				[]*common.DashboardResponseEntry{
 					&common.DashboardResponseEntry{
 						"dashboard_name",
@@ -475,7 +615,8 @@ func TestVisualizationPostResponses(t *testing.T) {
 		request, _ := http.NewRequest("POST", "/v1/visualizations", bytes.NewBuffer(jsonStr))
 		request.Header.Set("Content-Type", "application/json")
 		if testCase.tokenProvided {
-			testHelper.SetRequestAuthHeader(secret, projectID, request)
+			testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+				request)
 		}
 		if testCase.payloadValid {
 			payload := common.VisualizationPOSTData{}
@@ -488,7 +629,7 @@ func TestVisualizationPostResponses(t *testing.T) {
 		}
 		response := httptest.NewRecorder()
 		endpoint.InitializeRouter(clientContainer, mockedHandle,
-			secret).ServeHTTP(response, request)
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
 		assert.Equal(t, testCase.expectedCode, response.Code,
 			"response code match")
 		if testCase.tokenProvided {
@@ -506,12 +647,12 @@ func TestVisualizationDashboardToResponse(t *testing.T) {
 		result        *common.VisualizationWithDashboards
 	}{
 		{
-			visualization: &models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "visualization_tags"},
+			visualization: &models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "{\"tag1\": \"tag1\"}"},
 			dashboards: []*models.Dashboard{
 				&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"},
 			},
 			result: &common.VisualizationWithDashboards{
-				&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", "visualization_tags"},
+				&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", map[string]interface{}{"tag1": "tag1"}},
 				[]*common.DashboardResponseEntry{
 					&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
 				},
@@ -527,74 +668,86 @@ func TestVisualizationDashboardToResponse(t *testing.T) {
 	}
 }
 
-func TestGroupedVisualizationDashboardToResponse(t *testing.T) {
-	tests := []struct {
-		inputDataMap *map[models.Visualization][]*models.Dashboard
-		result       *[]common.VisualizationWithDashboards
-	}{
-		{
-			inputDataMap: &map[models.Visualization][]*models.Dashboard{
-				models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "visualization_tags"}: []*models.Dashboard{
-					&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"}},
-			},
-			result: &[]common.VisualizationWithDashboards{
-				common.VisualizationWithDashboards{
-					&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", "visualization_tags"},
-					[]*common.DashboardResponseEntry{
-						&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
-					},
-				},
+func TestVisualizationsPageToResponse(t *testing.T) {
+	page := &db.VisualizationsPage{
+		Visualizations: []*models.Visualization{
+			&models.Visualization{2, "second_slug", "second_name", "organization_id", "{\"tag2\": 2}"},
+			&models.Visualization{1, "first_slug", "first_name", "organization_id", ""},
+		},
+		Dashboards: map[int][]*models.Dashboard{
+			1: []*models.Dashboard{
+				&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"}},
+		},
+		Total: 5,
+	}
+	// order of visualizations is kept, visualization without dashboards
+	// gets empty list of them
+	result := &[]common.VisualizationWithDashboards{
+		common.VisualizationWithDashboards{
+			&common.VisualizationResponseEntry{"second_slug", "second_name", map[string]interface{}{"tag2": 2.0}},
+			[]*common.DashboardResponseEntry{},
+		},
+		common.VisualizationWithDashboards{
+			&common.VisualizationResponseEntry{"first_slug", "first_name", map[string]interface{}{}},
+			[]*common.DashboardResponseEntry{
+				&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
 			},
 		},
 	}
 
 	testHelper.InitializeLogger()
-	for _, testCase := range tests {
-		returnedResult := v1handlers.GroupedVisualizationDashboardToResponse(testCase.inputDataMap)
-		assert.Equal(t, testCase.result, returnedResult,
-			"result must match")
-	}
+	assert.Equal(t, result, v1handlers.VisualizationsPageToResponse(page),
+		"result must match")
 }
 
 func TestVisualizationsGetHandler(t *testing.T) {
 	tests := []struct {
-		dbData        *map[models.Visualization][]*models.Dashboard
+		description   string
+		dbData        *db.VisualizationsPage
+		dbError       error
 		result        *[]common.VisualizationWithDashboards
-		name          string
-		tags          map[string]interface{}
-		expectDBError bool
+		total         int64
+		searchQuery   search.Query
+		pagination    db.Pagination
+		expectedError error
 	}{
 		{
-			dbData: &map[models.Visualization][]*models.Dashboard{
-				models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "visualization_tags"}: []*models.Dashboard{
-					&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"}},
+			description: "page is returned with total count",
+			dbData: &db.VisualizationsPage{
+				Visualizations: []*models.Visualization{
+					&models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "{\"tag1\": \"tag1\"}"},
+				},
+				Dashboards: map[int][]*models.Dashboard{
+					1: []*models.Dashboard{
+						&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"}},
+				},
+				Total: 3,
 			},
 			result: &[]common.VisualizationWithDashboards{
 				common.VisualizationWithDashboards{
-					&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", "visualization_tags"},
+					&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", map[string]interface{}{"tag1": "tag1"}},
 					[]*common.DashboardResponseEntry{
 						&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
 					},
 				},
 			},
-			name:          "name",
-			expectDBError: false,
+			total:       3,
+			searchQuery: search.FromTags("name", map[string]interface{}{}),
+			pagination:  db.Pagination{Limit: 1, Sort: db.SortByName},
 		},
 		{
-			dbData: &map[models.Visualization][]*models.Dashboard{
-				models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "visualization_tags"}: []*models.Dashboard{
-					&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"}},
-			},
-			result: &[]common.VisualizationWithDashboards{
-				common.VisualizationWithDashboards{
-					&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", "visualization_tags"},
-					[]*common.DashboardResponseEntry{
-						&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
-					},
-				},
-			},
-			name:          "name",
-			expectDBError: true,
+			description:   "db error is returned",
+			dbError:       errors.New("test"),
+			searchQuery:   search.FromTags("name", map[string]interface{}{}),
+			pagination:    db.Pagination{Sort: db.SortByCreated},
+			expectedError: errors.New("test"),
+		},
+		{
+			description: "marker is not found",
+			dbError:     db.ErrMarkerNotFound,
+			pagination:  db.Pagination{Marker: "slug", Sort: db.SortByCreated},
+			expectedError: common.NewUserDataError(
+				"Visualization 'slug' provided as marker was not found"),
 		},
 	}
 
@@ -605,20 +758,15 @@ func TestVisualizationsGetHandler(t *testing.T) {
 		defer mockCtrl.Finish()
 		clientContainer := testHelper.MockClientContainer(mockCtrl)
 		mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
-		if testCase.expectDBError {
-			mockedDatabaseManager.EXPECT().QueryVisualizationsDashboards("", testCase.name, projectID, testCase.tags).Return(nil, errors.New("test"))
-		} else {
-			mockedDatabaseManager.EXPECT().QueryVisualizationsDashboards("", testCase.name, projectID, testCase.tags).Return(testCase.dbData, nil)
-		}
+		mockedDatabaseManager.EXPECT().QueryVisualizationsPage(projectID,
+			testCase.searchQuery, testCase.pagination).Return(
+			testCase.dbData, testCase.dbError)
 		handler := v1handlers.V1Visualizations{}
-		visualizationsData, returnedError := handler.VisualizationsGet(clientContainer,
-			projectID, testCase.name, testCase.tags)
-		if testCase.expectDBError {
-			assert.NotNil(t, returnedError)
-		} else {
-			assert.Equal(t, testCase.result, visualizationsData,
-				"result must match")
-		}
+		visualizationsData, total, returnedError := handler.VisualizationsGet(
+			clientContainer, projectID, testCase.searchQuery, testCase.pagination)
+		assert.Equal(t, testCase.expectedError, returnedError, testCase.description)
+		assert.Equal(t, testCase.result, visualizationsData, testCase.description)
+		assert.Equal(t, testCase.total, total, testCase.description)
 	}
 }
 
@@ -631,12 +779,12 @@ func TestVisualizationsDeleteHandler(t *testing.T) {
 		slugFoundInDB         bool
 	}{
 		{
-			databaseVisualization: &models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "visualization_tags"},
+			databaseVisualization: &models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "{\"tag1\": \"tag1\"}"},
 			databaseDashboards: []*models.Dashboard{
 				&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"},
 			},
 			result: &common.VisualizationWithDashboards{
-				&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", "visualization_tags"},
+				&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", map[string]interface{}{"tag1": "tag1"}},
 				[]*common.DashboardResponseEntry{
 					&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
 				},
@@ -661,10 +809,20 @@ func TestVisualizationsDeleteHandler(t *testing.T) {
 		mockedDatabaseManager.EXPECT().GetVisualizationWithDashboardsBySlug(testCase.visualizationSlug, projectID).Return(testCase.databaseVisualization, testCase.databaseDashboards, nil)
 
 		if testCase.slugFoundInDB {
-			for _, dashboard := range testCase.databaseDashboards {
+			operations := []*models.GrafanaOperation{}
+			for index, dashboard := range testCase.databaseDashboards {
+				operation := &models.GrafanaOperation{ID: index,
+					Operation: models.GrafanaOperationDelete, OrganizationID: projectID,
+					DashboardID: dashboard.ID, Slug: dashboard.Slug}
+				operations = append(operations, operation)
+				mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(operation,
+					"").Return(true, nil)
 				mockedGrafana.EXPECT().DeleteDashboard(dashboard.Slug, projectID)
+				mockedDatabaseManager.EXPECT().CompleteGrafanaOperation(operation, "")
 			}
-			mockedDatabaseManager.EXPECT().DeleteVisualization(testCase.databaseVisualization)
+			mockedDatabaseManager.EXPECT().DeleteVisualizationWithOperations(
+				testCase.databaseVisualization, testCase.databaseDashboards).Return(
+				operations, nil)
 		}
 
 		handler := v1handlers.V1Visualizations{}
@@ -674,7 +832,524 @@ func TestVisualizationsDeleteHandler(t *testing.T) {
 			"result must match")
 
 		if !testCase.slugFoundInDB {
-			assert.NotNil(t, returnedError)
+			assert.Equal(t, common.NewNotFoundError("No visualizations found"),
+				returnedError)
+		}
+	}
+}
+
+func TestVisualizationUpdateResponses(t *testing.T) {
+	tests := []struct {
+		description     string
+		method          string
+		visualizationID string
+		payloadProvided string
+		handlerCalled   bool
+		returnedError   error
+		expectedCode    int
+		expectedResult  string
+	}{
+		{
+			description:     "check 422 on not uuid visualization id",
+			method:          "PUT",
+			visualizationID: "visualization",
+			payloadProvided: "{\"name\": \"name\", \"dashboards\": []}",
+			expectedCode:    422,
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"provided id does not match UUIDv4 format 'visualization'\"}",
+		},
+		{
+			description:     "check 404 on missing visualization",
+			method:          "PUT",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			payloadProvided: "{\"name\": \"name\", \"dashboards\": []}",
+			handlerCalled:   true,
+			returnedError:   common.NewNotFoundError("test"),
+			expectedCode:    404,
+			expectedResult:  "{\"code\":404,\"message\":\"Not Found\",\"details\":\"Requested visualization '0f29d63b-be6f-43cf-b99f-23271b3e6041' was not found\"}",
+		},
+		{
+			description:     "check 422 on template rendering error",
+			method:          "PUT",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			payloadProvided: "{\"name\": \"name\", \"dashboards\": []}",
+			handlerCalled:   true,
+			returnedError:   common.NewUserDataError("test"),
+			expectedCode:    422,
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"Error rendering template 'test'\"}",
+		},
+		{
+			description:     "check 500 on client error with returned outcome",
+			method:          "PUT",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			payloadProvided: "{\"name\": \"name\", \"dashboards\": []}",
+			handlerCalled:   true,
+			returnedError:   common.NewClientError("test"),
+			expectedCode:    500,
+			expectedResult:  "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{},\"dashboards\":[]}",
+		},
+		{
+			description:     "check 200 in positive outcome",
+			method:          "PUT",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			payloadProvided: "{\"name\": \"name\", \"dashboards\": []}",
+			handlerCalled:   true,
+			expectedCode:    200,
+			expectedResult:  "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{},\"dashboards\":[]}",
+		},
+		{
+			description:     "check 405 on partial update",
+			method:          "PATCH",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			payloadProvided: "{\"name\": \"name\"}",
+			expectedCode:    405,
+			expectedResult:  "",
+		},
+	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest(testCase.method,
+			fmt.Sprintf("/v1/visualization/%s", testCase.visualizationID),
+			bytes.NewBuffer([]byte(testCase.payloadProvided)))
+		request.Header.Set("Content-Type", "application/json")
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.handlerCalled {
+			payload := common.VisualizationPOSTData{}
+			json.Unmarshal([]byte(testCase.payloadProvided), &payload)
+			var handlerResult *common.VisualizationWithDashboards
+			if testCase.expectedCode != 404 && testCase.expectedCode != 422 {
+				handlerResult = &common.VisualizationWithDashboards{
+					&common.VisualizationResponseEntry{
+						"visualization_id", "visualization_name",
+						map[string]interface{}{}},
+					[]*common.DashboardResponseEntry{},
+				}
+			}
+			mockedHandle.EXPECT().VisualizationUpdate(clientContainer, payload,
+				projectID, testCase.visualizationID).Return(handlerResult,
+				testCase.returnedError)
 		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestVisualizationsUpdateHandler(t *testing.T) {
+	const projectID = "3"
+	const slug = "visualization_slug"
+	testHelper.InitializeLogger()
+
+	newDashboardData := func() common.VisualizationPOSTData {
+		data := common.VisualizationPOSTData{}
+		json.Unmarshal([]byte(`{"name": "new_name", "tags": {"tag": "value"},
+			"dashboards": [
+				{"name": "unchanged", "templateBody": "unchanged_template", "templateParameters": {}},
+				{"name": "changed", "templateBody": "new_template", "templateParameters": {}},
+				{"name": "new", "templateBody": "created_template", "templateParameters": {}}
+			]}`), &data)
+		return data
+	}
+	storedData := func() (*models.Visualization, []*models.Dashboard) {
+		return &models.Visualization{1, slug, "name", projectID, "{}"},
+			[]*models.Dashboard{
+				&models.Dashboard{"1", 1, "unchanged", "unchanged_template", "unchanged_slug"},
+				&models.Dashboard{"2", 1, "changed", "old_template", "changed_slug"},
+				&models.Dashboard{"3", 1, "removed", "removed_template", "removed_slug"},
+			}
+	}
+
+	// visualization is not found
+	mockCtrl := gomock.NewController(t)
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+	mockedDatabaseManager.EXPECT().GetVisualizationWithDashboardsBySlug(
+		slug, projectID).Return(nil, []*models.Dashboard{}, nil)
+	handler := v1handlers.V1Visualizations{}
+	result, err := handler.VisualizationUpdate(clientContainer,
+		newDashboardData(), projectID, slug)
+	assert.Nil(t, result)
+	assert.Equal(t, common.NewNotFoundError("No visualizations found"), err)
+	mockCtrl.Finish()
+
+	// dashboards are diffed against stored ones
+	mockCtrl = gomock.NewController(t)
+	clientContainer = testHelper.MockClientContainer(mockCtrl)
+	mockedDatabaseManager = clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+	mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+	visualizationDB, dashboardsDB := storedData()
+	mockedDatabaseManager.EXPECT().GetVisualizationWithDashboardsBySlug(
+		slug, projectID).Return(visualizationDB, dashboardsDB, nil)
+	newOperations := func() []*models.GrafanaOperation {
+		return []*models.GrafanaOperation{
+			&models.GrafanaOperation{ID: 1, Operation: models.GrafanaOperationUpload,
+				OrganizationID: projectID, DashboardID: "2", Payload: "new_template"},
+			&models.GrafanaOperation{ID: 2, Operation: models.GrafanaOperationUpload,
+				OrganizationID: projectID, DashboardID: "4", Payload: "created_template"},
+			&models.GrafanaOperation{ID: 3, Operation: models.GrafanaOperationDelete,
+				OrganizationID: projectID, DashboardID: "3", Slug: "removed_slug"},
+		}
+	}
+	operations := newOperations()
+	mockedDatabaseManager.EXPECT().UpdateVisualizationWithOperations(
+		visualizationDB, "new_name", map[string]interface{}{"tag": "value"},
+		[]*models.Dashboard{dashboardsDB[1]}, gomock.Any(),
+		[]*models.Dashboard{dashboardsDB[2]}).Do(func(
+		_ *models.Visualization, _ string, _ map[string]interface{},
+		_, created, _ []*models.Dashboard) {
+		// id of created dashboard is generated by handler
+		operations[1].DashboardID = created[0].ID
+	}).Return(operations, nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("new_template"), projectID,
+		true).Return("changed_slug", nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("created_template"),
+		projectID, false).Return("created_slug", nil)
+	mockedGrafana.EXPECT().DeleteDashboard("removed_slug", projectID).Return(nil)
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(gomock.Any(),
+		"").Return(true, nil).Times(3)
+	mockedDatabaseManager.EXPECT().CompleteGrafanaOperation(gomock.Any(),
+		gomock.Any()).Return(nil).Times(3)
+
+	result, err = handler.VisualizationUpdate(clientContainer,
+		newDashboardData(), projectID, slug)
+	assert.Nil(t, err)
+	assert.Equal(t, &common.VisualizationWithDashboards{
+		&common.VisualizationResponseEntry{slug, "name", map[string]interface{}{}},
+		[]*common.DashboardResponseEntry{
+			&common.DashboardResponseEntry{"unchanged", "unchanged_template", "unchanged_slug"},
+			&common.DashboardResponseEntry{"changed", "new_template", "changed_slug"},
+			&common.DashboardResponseEntry{"new", "created_template", "created_slug"},
+		},
+	}, result, "updated visualization must be returned")
+	mockCtrl.Finish()
+
+	// failed grafana operations are left to outbox worker
+	mockCtrl = gomock.NewController(t)
+	clientContainer = testHelper.MockClientContainer(mockCtrl)
+	mockedDatabaseManager = clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+	mockedGrafana = clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+	visualizationDB, dashboardsDB = storedData()
+	mockedDatabaseManager.EXPECT().GetVisualizationWithDashboardsBySlug(
+		slug, projectID).Return(visualizationDB, dashboardsDB, nil)
+	operations = newOperations()
+	mockedDatabaseManager.EXPECT().UpdateVisualizationWithOperations(
+		visualizationDB, "new_name", map[string]interface{}{"tag": "value"},
+		gomock.Any(), gomock.Any(), gomock.Any()).Do(func(
+		_ *models.Visualization, _ string, _ map[string]interface{},
+		_, created, _ []*models.Dashboard) {
+		operations[1].DashboardID = created[0].ID
+	}).Return(operations, nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("new_template"), projectID,
+		true).Return("", errors.New("test"))
+	mockedGrafana.EXPECT().UploadDashboard([]byte("created_template"),
+		projectID, false).Return("", errors.New("test"))
+	mockedGrafana.EXPECT().DeleteDashboard("removed_slug", projectID).Return(
+		errors.New("test"))
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(gomock.Any(),
+		"").Return(true, nil).Times(3)
+	mockedDatabaseManager.EXPECT().FailGrafanaOperation(gomock.Any(),
+		"test").Return(nil).Times(3)
+
+	result, err = handler.VisualizationUpdate(clientContainer,
+		newDashboardData(), projectID, slug)
+	assert.Equal(t, common.NewClientError("Unable to update grafana dashboards"), err)
+	assert.Equal(t, &common.VisualizationWithDashboards{
+		&common.VisualizationResponseEntry{slug, "name", map[string]interface{}{}},
+		[]*common.DashboardResponseEntry{
+			&common.DashboardResponseEntry{"unchanged", "unchanged_template", "unchanged_slug"},
+			&common.DashboardResponseEntry{"changed", "new_template", "changed_slug"},
+			&common.DashboardResponseEntry{"new", "created_template", ""},
+		},
+	}, result, "state of database must be returned")
+	mockCtrl.Finish()
+}
+
+func TestVisualizationGetResponses(t *testing.T) {
+	tests := []struct {
+		description             string
+		visualizationID         string
+		query                   string
+		handlerCalled           bool
+		includeRenderedTemplate bool
+		returnedError           error
+		expectedCode            int
+		expectedResult          string
+	}{
+		{
+			description:     "check 422 on not uuid visualization id",
+			visualizationID: "visualization",
+			expectedCode:    422,
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"provided id does not match UUIDv4 format 'visualization'\"}",
+		},
+		{
+			description:     "check 422 on unknown include value",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			query:           "?include=unknown",
+			expectedCode:    422,
+			expectedResult:  "{\"code\":422,\"message\":\"Unprocessable Entity\",\"details\":\"unknown value of include parameter 'unknown'\"}",
+		},
+		{
+			description:     "check 404 on missing visualization",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			handlerCalled:   true,
+			returnedError:   common.NewNotFoundError("test"),
+			expectedCode:    404,
+			expectedResult:  "{\"code\":404,\"message\":\"Not Found\",\"details\":\"Requested visualization '0f29d63b-be6f-43cf-b99f-23271b3e6041' was not found\"}",
+		},
+		{
+			description:     "check 500 on handler error",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			handlerCalled:   true,
+			returnedError:   errors.New("test"),
+			expectedCode:    500,
+			expectedResult:  "{\"code\":500,\"message\":\"Internal Server Error\",\"details\":\"Internal server error occured\"}",
+		},
+		{
+			description:     "check 200 without rendered templates",
+			visualizationID: "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			handlerCalled:   true,
+			expectedCode:    200,
+			expectedResult:  "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{},\"dashboards\":[]}",
+		},
+		{
+			description:             "check 200 with rendered templates",
+			visualizationID:         "0f29d63b-be6f-43cf-b99f-23271b3e6041",
+			query:                   "?include=renderedTemplate",
+			handlerCalled:           true,
+			includeRenderedTemplate: true,
+			expectedCode:            200,
+			expectedResult:          "{\"id\":\"visualization_id\",\"name\":\"visualization_name\",\"tags\":{},\"dashboards\":[]}",
+		},
 	}
+
+	const projectID = "3"
+	const secret = "secret"
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedHandle := mock_common.NewMockHandlerInterface(mockCtrl)
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+
+		request, _ := http.NewRequest("GET", fmt.Sprintf("/v1/visualization/%s%s",
+			testCase.visualizationID, testCase.query), nil)
+		testHelper.SetRequestAuthHeader(clientContainer, secret, projectID,
+			request)
+		if testCase.handlerCalled {
+			var handlerResult *common.VisualizationWithDashboards
+			if testCase.returnedError == nil {
+				handlerResult = &common.VisualizationWithDashboards{
+					&common.VisualizationResponseEntry{
+						"visualization_id", "visualization_name",
+						map[string]interface{}{}},
+					[]*common.DashboardResponseEntry{},
+				}
+			}
+			mockedHandle.EXPECT().VisualizationGet(clientContainer, projectID,
+				testCase.visualizationID, testCase.includeRenderedTemplate).Return(
+				handlerResult, testCase.returnedError)
+		}
+		response := httptest.NewRecorder()
+		endpoint.InitializeRouter(clientContainer, mockedHandle,
+			jwtkeys.NewSecretKeySet(secret)).ServeHTTP(response, request)
+		assert.Equal(t, testCase.expectedCode, response.Code,
+			testCase.description)
+		responseData, _ := ioutil.ReadAll(response.Body)
+		assert.Equal(t, testCase.expectedResult, string(responseData),
+			testCase.description)
+	}
+}
+
+func TestVisualizationGetHandler(t *testing.T) {
+	tests := []struct {
+		includeRenderedTemplate bool
+		databaseVisualization   *models.Visualization
+		result                  *common.VisualizationWithDashboards
+		expectedError           error
+	}{
+		{
+			includeRenderedTemplate: true,
+			databaseVisualization:   &models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "{\"tag1\": \"tag1\"}"},
+			result: &common.VisualizationWithDashboards{
+				&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", map[string]interface{}{"tag1": "tag1"}},
+				[]*common.DashboardResponseEntry{
+					&common.DashboardResponseEntry{"dashboard_name", "rendered_template", "dashboard_slug"},
+				},
+			},
+		},
+		{
+			includeRenderedTemplate: false,
+			databaseVisualization:   &models.Visualization{1, "visualization_slug", "visualization_name", "organization_id", "{\"tag1\": \"tag1\"}"},
+			result: &common.VisualizationWithDashboards{
+				&common.VisualizationResponseEntry{"visualization_slug", "visualization_name", map[string]interface{}{"tag1": "tag1"}},
+				[]*common.DashboardResponseEntry{
+					&common.DashboardResponseEntry{"dashboard_name", "", "dashboard_slug"},
+				},
+			},
+		},
+		{
+			databaseVisualization: nil,
+			expectedError:         common.NewNotFoundError("No visualizations found"),
+		},
+	}
+
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		clientContainer := testHelper.MockClientContainer(mockCtrl)
+		mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+		mockedDatabaseManager.EXPECT().GetVisualizationWithDashboardsBySlug("slug", projectID).Return(
+			testCase.databaseVisualization, []*models.Dashboard{
+				&models.Dashboard{"id", 1, "dashboard_name", "rendered_template", "dashboard_slug"},
+			}, nil)
+
+		handler := v1handlers.V1Visualizations{}
+		visualizationData, returnedError := handler.VisualizationGet(clientContainer,
+			projectID, "slug", testCase.includeRenderedTemplate)
+		assert.Equal(t, testCase.result, visualizationData, "result must match")
+		assert.Equal(t, testCase.expectedError, returnedError, "error must match")
+	}
+}
+
+func TestVisualizationsPostRollback(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	mockedDatabaseManager := clientContainer.DatabaseManager.(*mock_database.MockDatabaseManager)
+	mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+
+	data := common.VisualizationPOSTData{}
+	json.Unmarshal([]byte(`{"name": "visualization_name", "dashboards": [
+		{"name": "first", "templateBody": "first_template", "templateParameters": {}},
+		{"name": "second", "templateBody": "second_template", "templateParameters": {}}
+	]}`), &data)
+
+	visualization := &models.Visualization{1, "visualization_slug",
+		"visualization_name", projectID, "{}"}
+	dashboards := []*models.Dashboard{
+		&models.Dashboard{"1", 1, "first", "first_template", ""},
+		&models.Dashboard{"2", 1, "second", "second_template", ""},
+	}
+	uploadOperations := []*models.GrafanaOperation{
+		&models.GrafanaOperation{ID: 1, Operation: models.GrafanaOperationUpload,
+			OrganizationID: projectID, DashboardID: "1", Payload: "first_template",
+			Owner: "creator"},
+		&models.GrafanaOperation{ID: 2, Operation: models.GrafanaOperationUpload,
+			OrganizationID: projectID, DashboardID: "2", Payload: "second_template",
+			Owner: "creator"},
+	}
+	deleteOperation := &models.GrafanaOperation{ID: 3,
+		Operation: models.GrafanaOperationDelete, OrganizationID: projectID,
+		DashboardID: "1", Slug: "first_slug", Owner: "creator"}
+
+	mockedDatabaseManager.EXPECT().CreateVisualizationsWithDashboards(
+		"visualization_name", projectID, data.Tags, []string{"first", "second"},
+		[]string{"first_template", "second_template"}).Return(visualization,
+		dashboards, uploadOperations, nil)
+	// every operation is claimed by its creator before it is performed
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(gomock.Any(),
+		"creator").Return(true, nil).Times(3)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("first_template"), projectID,
+		false).Return("first_slug", nil)
+	mockedDatabaseManager.EXPECT().CompleteGrafanaOperation(uploadOperations[0],
+		"first_slug").Return(nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("second_template"), projectID,
+		false).Return("", errors.New("test"))
+	mockedDatabaseManager.EXPECT().FailGrafanaOperation(uploadOperations[1],
+		"test").Return(nil)
+	mockedDatabaseManager.EXPECT().DeleteVisualizationWithOperations(
+		visualization, dashboards).Return(
+		[]*models.GrafanaOperation{deleteOperation}, nil)
+	// failed removal is left in journal for outbox worker
+	mockedGrafana.EXPECT().DeleteDashboard("first_slug", projectID).Return(
+		errors.New("test"))
+	mockedDatabaseManager.EXPECT().FailGrafanaOperation(deleteOperation,
+		"test").Return(nil)
+
+	handler := v1handlers.V1Visualizations{}
+	result, err := handler.VisualizationsPost(clientContainer, data, projectID)
+	assert.Nil(t, result)
+	assert.Equal(t, errors.New("test"), err, "grafana error must be returned")
+}
+
+func TestVisualizationsLifecycleWithMemoryDatabase(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	clientContainer := testHelper.MockClientContainer(mockCtrl)
+	clientContainer.DatabaseManager = db.NewMemoryManager()
+	mockedGrafana := clientContainer.Grafana.(*mock_grafanaclient.MockSessionInterface)
+	handler := v1handlers.V1Visualizations{}
+
+	data := common.VisualizationPOSTData{}
+	json.Unmarshal([]byte(`{"name": "visualization_name",
+		"tags": {"env": "prod"}, "dashboards": [
+		{"name": "first", "templateBody": "first_template", "templateParameters": {}},
+		{"name": "second", "templateBody": "second_template", "templateParameters": {}}
+	]}`), &data)
+
+	mockedGrafana.EXPECT().UploadDashboard([]byte("first_template"), projectID,
+		false).Return("first_slug", nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("second_template"), projectID,
+		false).Return("second_slug", nil)
+	created, err := handler.VisualizationsPost(clientContainer, data, projectID)
+	assert.Nil(t, err)
+
+	visualizations, total, err := handler.VisualizationsGet(clientContainer,
+		projectID, search.FromTags("", map[string]interface{}{"env": "prod"}),
+		db.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*visualizations))
+	assert.Equal(t, int64(1), total)
+	visualizations, total, err = handler.VisualizationsGet(clientContainer,
+		projectID, search.Query{{{"env", search.NotEqual, []interface{}{"prod"}}}},
+		db.Pagination{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(*visualizations))
+	assert.Equal(t, int64(0), total)
+
+	// received slugs are stored by journaled uploads
+	visualization, err := handler.VisualizationGet(clientContainer, projectID,
+		created.Slug, false)
+	assert.Nil(t, err)
+	slugs := []string{}
+	for _, dashboard := range visualization.Dashboards {
+		slugs = append(slugs, dashboard.Slug)
+	}
+	sort.Strings(slugs)
+	assert.Equal(t, []string{"first_slug", "second_slug"}, slugs)
+
+	mockedGrafana.EXPECT().DeleteDashboard("first_slug", projectID).Return(nil)
+	mockedGrafana.EXPECT().DeleteDashboard("second_slug", projectID).Return(nil)
+	_, err = handler.VisualizationDelete(clientContainer, projectID,
+		created.Slug)
+	assert.Nil(t, err)
+
+	_, err = handler.VisualizationGet(clientContainer, projectID,
+		created.Slug, false)
+	assert.NotNil(t, err, "removed visualization is not found")
+	operations, err := clientContainer.DatabaseManager.QueryGrafanaOperations(
+		time.Now().Add(time.Second), 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(operations), "all operations are performed")
 }
@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"visualization-api/pkg/logging"
+)
+
+// StatusPending means that job or its step is waiting to be performed
+const StatusPending = "pending"
+
+// StatusRunning means that job or its step is performed right now
+const StatusRunning = "running"
+
+// StatusSucceeded means that job or its step is finished successfully
+const StatusSucceeded = "succeeded"
+
+// StatusFailed means that job or its step is failed
+const StatusFailed = "failed"
+
+// finishedJobRetention defines how long finished jobs are kept in memory
+const finishedJobRetention = time.Hour
+
+// ErrQueueFull is returned when there is no room for new jobs in queue
+var ErrQueueFull = errors.New("job queue is full")
+
+// Step represents progress of single step of job
+type Step struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Job represents asynchronous task performed by worker pool
+type Job struct {
+	ID             string      `json:"id"`
+	OrganizationID string      `json:"-"`
+	ResourceID     string      `json:"resourceId"`
+	Status         string      `json:"status"`
+	Steps          []Step      `json:"steps"`
+	Result         interface{} `json:"result,omitempty"`
+	Error          string      `json:"error,omitempty"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	FinishedAt     *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// copy returns snapshot of job, safe to be used without lock
+func (j *Job) copy() *Job {
+	jobCopy := *j
+	jobCopy.Steps = make([]Step, len(j.Steps))
+	copy(jobCopy.Steps, j.Steps)
+	return &jobCopy
+}
+
+// ProgressFunc reports status of job step with provided index
+type ProgressFunc func(step int, status string, err error)
+
+// Task is function performed by job. Returned result is stored to job
+type Task func(progress ProgressFunc) (interface{}, error)
+
+// ManagerInterface represents what functionality we are expecting from
+// jobs manager. It was created to have mockable architecture
+type ManagerInterface interface {
+	Submit(string, string, []string, Task) (*Job, error)
+	Get(string, string) *Job
+}
+
+type queuedJob struct {
+	job  *Job
+	task Task
+}
+
+// Manager stores jobs and performs them in worker pool
+type Manager struct {
+	queue chan queuedJob
+
+	// lock protects jobs and all data of stored jobs
+	lock sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewManager is Manager constructor
+func NewManager(queueSize int) *Manager {
+	return &Manager{
+		queue: make(chan queuedJob, queueSize),
+		jobs:  map[string]*Job{},
+	}
+}
+
+// Start runs provided amount of workers performing queued jobs
+func (m *Manager) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for queued := range m.queue {
+				m.perform(queued)
+			}
+		}()
+	}
+}
+
+// Submit queues task of organization. Every step is created in pending
+// state. ResourceID is an id of resource job is working on
+func (m *Manager) Submit(organizationID, resourceID string,
+	stepNames []string, task Task) (*Job, error) {
+	job := &Job{
+		ID:             uuid.NewV4().String(),
+		OrganizationID: organizationID,
+		ResourceID:     resourceID,
+		Status:         StatusPending,
+		Steps:          []Step{},
+		CreatedAt:      time.Now(),
+	}
+	for _, name := range stepNames {
+		job.Steps = append(job.Steps, Step{Name: name, Status: StatusPending})
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.removeExpired()
+
+	select {
+	case m.queue <- queuedJob{job, task}:
+	default:
+		return nil, ErrQueueFull
+	}
+	m.jobs[job.ID] = job
+	log.Logger.Debugf("Job '%s' is queued", job.ID)
+	return job.copy(), nil
+}
+
+// Get returns snapshot of job, nil is returned if job does not exist or
+// belongs to other organization
+func (m *Manager) Get(organizationID, jobID string) *Job {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	job, ok := m.jobs[jobID]
+	if !ok || job.OrganizationID != organizationID {
+		return nil
+	}
+	return job.copy()
+}
+
+func (m *Manager) removeExpired() {
+	// must be called under lock
+	for jobID, job := range m.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > finishedJobRetention {
+			delete(m.jobs, jobID)
+		}
+	}
+}
+
+func (m *Manager) perform(queued queuedJob) {
+	job := queued.job
+	log.Logger.Debugf("Performing job '%s'", job.ID)
+	m.lock.Lock()
+	job.Status = StatusRunning
+	m.lock.Unlock()
+
+	progress := func(step int, status string, err error) {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		if step < 0 || step >= len(job.Steps) {
+			return
+		}
+		job.Steps[step].Status = status
+		if err != nil {
+			job.Steps[step].Error = err.Error()
+		}
+	}
+	result, err := queued.task(progress)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	job.Result = result
+	if err != nil {
+		log.Logger.Errorf("Job '%s' failed: '%s'", job.ID, err)
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		return
+	}
+	log.Logger.Debugf("Job '%s' succeeded", job.ID)
+	job.Status = StatusSucceeded
+}
@@ -0,0 +1,111 @@
+package jobs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/jobs"
+)
+
+func waitFinished(manager *jobs.Manager, organizationID, jobID string) *jobs.Job {
+	for i := 0; i < 100; i++ {
+		job := manager.Get(organizationID, jobID)
+		if job != nil && job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestManagerJobLifecycle(t *testing.T) {
+	tests := []struct {
+		description    string
+		taskResult     interface{}
+		taskError      error
+		expectedStatus string
+		expectedSteps  []jobs.Step
+		expectedError  string
+	}{
+		{
+			description:    "successful job",
+			taskResult:     "result",
+			expectedStatus: jobs.StatusSucceeded,
+			expectedSteps: []jobs.Step{
+				{Name: "first", Status: jobs.StatusSucceeded},
+				{Name: "second", Status: jobs.StatusSucceeded},
+			},
+		},
+		{
+			description:    "failed job",
+			taskError:      errors.New("test"),
+			expectedStatus: jobs.StatusFailed,
+			expectedSteps: []jobs.Step{
+				{Name: "first", Status: jobs.StatusSucceeded},
+				{Name: "second", Status: jobs.StatusFailed, Error: "test"},
+			},
+			expectedError: "test",
+		},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		manager := jobs.NewManager(1)
+		manager.Start(1)
+		task := func(progress jobs.ProgressFunc) (interface{}, error) {
+			progress(0, jobs.StatusSucceeded, nil)
+			if testCase.taskError != nil {
+				progress(1, jobs.StatusFailed, testCase.taskError)
+			} else {
+				progress(1, jobs.StatusSucceeded, nil)
+			}
+			// out of range steps are ignored
+			progress(2, jobs.StatusSucceeded, nil)
+			return testCase.taskResult, testCase.taskError
+		}
+		submitted, err := manager.Submit("3", "resource",
+			[]string{"first", "second"}, task)
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, "resource", submitted.ResourceID, testCase.description)
+
+		job := waitFinished(manager, "3", submitted.ID)
+		if !assert.NotNil(t, job, testCase.description) {
+			continue
+		}
+		assert.Equal(t, testCase.expectedStatus, job.Status, testCase.description)
+		assert.Equal(t, testCase.expectedSteps, job.Steps, testCase.description)
+		assert.Equal(t, testCase.taskResult, job.Result, testCase.description)
+		assert.Equal(t, testCase.expectedError, job.Error, testCase.description)
+	}
+}
+
+func TestManagerGetOrganizationScope(t *testing.T) {
+	testHelper.InitializeLogger()
+	// workers are not started, so job stays in queue
+	manager := jobs.NewManager(1)
+	task := func(jobs.ProgressFunc) (interface{}, error) { return nil, nil }
+	submitted, err := manager.Submit("3", "resource", []string{"first"}, task)
+	assert.Nil(t, err)
+	assert.Equal(t, jobs.StatusPending, submitted.Status)
+	assert.Equal(t, []jobs.Step{{Name: "first", Status: jobs.StatusPending}},
+		submitted.Steps)
+
+	assert.NotNil(t, manager.Get("3", submitted.ID), "own job is returned")
+	assert.Nil(t, manager.Get("4", submitted.ID),
+		"job of other organization is not returned")
+	assert.Nil(t, manager.Get("3", "unknown"), "unknown job is not returned")
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	testHelper.InitializeLogger()
+	manager := jobs.NewManager(1)
+	task := func(jobs.ProgressFunc) (interface{}, error) { return nil, nil }
+	_, err := manager.Submit("3", "resource", []string{}, task)
+	assert.Nil(t, err)
+	job, err := manager.Submit("3", "resource", []string{}, task)
+	assert.Nil(t, job)
+	assert.Equal(t, jobs.ErrQueueFull, err)
+}
@@ -0,0 +1,295 @@
+// Package jwtkeys manages keys of json web token signature. Tokens are signed
+// either with shared secret (HS256) or with private key (RS256, ES256).
+// Public parts of private keys are published as json web key set, so other
+// services can verify tokens without knowing secret. Every key has id, which
+// is stored in "kid" header of token, several keys can be accepted at the same
+// time to rotate them
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// KeyFileExtension is extension of files loaded by LoadKeySet
+const KeyFileExtension = ".pem"
+
+// keyUseSignature is "use" of published json web keys
+const keyUseSignature = "sig"
+
+// Key is a named key of json web token signature
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signingKey is nil for keys, which are only used to verify tokens
+	signingKey      interface{}
+	verificationKey interface{}
+}
+
+// CanSign tells if key has private part, which is required to sign tokens
+func (k *Key) CanSign() bool {
+	return k.signingKey != nil
+}
+
+// NewSecretKey returns HS256 key of shared secret
+func NewSecretKey(id, secret string) *Key {
+	return &Key{
+		ID:              id,
+		Method:          jwt.SigningMethodHS256,
+		signingKey:      []byte(secret),
+		verificationKey: []byte(secret),
+	}
+}
+
+// ecdsaSigningMethod returns signing method matching curve of ecdsa key
+func ecdsaSigningMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	case elliptic.P521():
+		return jwt.SigningMethodES512, nil
+	}
+	return nil, fmt.Errorf("unsupported curve '%s'", curve.Params().Name)
+}
+
+// newKey returns key of parsed private or public rsa or ecdsa key
+func newKey(id string, parsedKey interface{}) (*Key, error) {
+	key := &Key{ID: id}
+	switch typedKey := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.signingKey = typedKey
+		key.verificationKey = &typedKey.PublicKey
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+		key.verificationKey = typedKey
+	case *ecdsa.PrivateKey:
+		method, err := ecdsaSigningMethod(typedKey.Curve)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = method
+		key.signingKey = typedKey
+		key.verificationKey = &typedKey.PublicKey
+	case *ecdsa.PublicKey:
+		method, err := ecdsaSigningMethod(typedKey.Curve)
+		if err != nil {
+			return nil, err
+		}
+		key.Method = method
+		key.verificationKey = typedKey
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsedKey)
+	}
+	return key, nil
+}
+
+// ParseKey parses PEM encoded rsa or ecdsa key. Private keys are used to sign
+// and verify tokens, public keys are used only to verify them
+func ParseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key '%s' is not PEM encoded", id)
+	}
+
+	var parsedKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsedKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key '%s' has unsupported PEM block '%s'", id,
+			block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing key '%s': %s", id, err)
+	}
+
+	key, err := newKey(id, parsedKey)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing key '%s': %s", id, err)
+	}
+	return key, nil
+}
+
+// KeySet is a set of keys accepted by api, one of them is used to sign
+// issued tokens
+type KeySet struct {
+	signingKey *Key
+	keys       map[string]*Key
+}
+
+// NewKeySet returns set of provided keys signing tokens with key of
+// signingKeyID
+func NewKeySet(signingKeyID string, keys []*Key) (*KeySet, error) {
+	keySet := &KeySet{keys: map[string]*Key{}}
+	for _, key := range keys {
+		if _, ok := keySet.keys[key.ID]; ok {
+			return nil, fmt.Errorf("key '%s' is defined twice", key.ID)
+		}
+		keySet.keys[key.ID] = key
+	}
+
+	signingKey, ok := keySet.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key '%s' is not defined",
+			signingKeyID)
+	}
+	if !signingKey.CanSign() {
+		return nil, fmt.Errorf("signing key '%s' has no private part",
+			signingKeyID)
+	}
+	keySet.signingKey = signingKey
+	return keySet, nil
+}
+
+// NewSecretKeySet returns set of the only HS256 key. Key has empty id, so
+// tokens signed by it have no "kid" header
+func NewSecretKeySet(secret string) *KeySet {
+	secretKey := NewSecretKey("", secret)
+	return &KeySet{
+		signingKey: secretKey,
+		keys:       map[string]*Key{secretKey.ID: secretKey},
+	}
+}
+
+// LoadKeySet loads keys stored in PEM files of directory, id of key is name
+// of its file without extension. If secret is not empty, it is accepted as
+// key without id, so tokens issued before keys rotation stay valid. Empty
+// signingKeyID means that tokens are signed with secret
+func LoadKeySet(secret, directory, signingKeyID string) (*KeySet, error) {
+	keys := []*Key{}
+	if secret != "" {
+		keys = append(keys, NewSecretKey("", secret))
+	}
+	if directory != "" {
+		paths, err := filepath.Glob(filepath.Join(directory,
+			"*"+KeyFileExtension))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			id := strings.TrimSuffix(filepath.Base(path), KeyFileExtension)
+			key, err := ParseKey(id, data)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+	}
+	return NewKeySet(signingKeyID, keys)
+}
+
+// Sign returns token of claims signed by signing key
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signingKey.Method, claims)
+	if s.signingKey.ID != "" {
+		token.Header["kid"] = s.signingKey.ID
+	}
+	return token.SignedString(s.signingKey.signingKey)
+}
+
+// VerificationKey returns key verifying signature of token, it is used as
+// jwt.Keyfunc. Token has to be signed with algorithm of key referred by its
+// "kid" header, otherwise public key could be used as HS256 secret
+func (s *KeySet) VerificationKey(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("key '%s' is unknown", id)
+	}
+	if token.Method == nil || token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("key '%s' does not support algorithm '%v'",
+			id, token.Header["alg"])
+	}
+	return key.verificationKey, nil
+}
+
+// JSONWebKey is public key in format of RFC 7517
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// rsa public key
+	Modulus  string `json:"n,omitempty"`
+	Exponent string `json:"e,omitempty"`
+	// ecdsa public key
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is a set of public keys in format of RFC 7517
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// encodeCoordinate encodes ecdsa point coordinate padded to size of curve
+func encodeCoordinate(value *big.Int, curve elliptic.Curve) string {
+	size := (curve.Params().BitSize + 7) / 8
+	data := value.Bytes()
+	padded := make([]byte, size-len(data), size)
+	return base64.RawURLEncoding.EncodeToString(append(padded, data...))
+}
+
+// JWKS returns public keys of set ordered by id. Secret keys are not
+// published
+func (s *KeySet) JWKS() *JSONWebKeySet {
+	ids := []string{}
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := &JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, id := range ids {
+		key := s.keys[id]
+		webKey := JSONWebKey{
+			ID:        key.ID,
+			Use:       keyUseSignature,
+			Algorithm: key.Method.Alg(),
+		}
+		switch publicKey := key.verificationKey.(type) {
+		case *rsa.PublicKey:
+			webKey.KeyType = "RSA"
+			webKey.Modulus = base64.RawURLEncoding.EncodeToString(
+				publicKey.N.Bytes())
+			webKey.Exponent = base64.RawURLEncoding.EncodeToString(
+				big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			webKey.KeyType = "EC"
+			webKey.Curve = publicKey.Curve.Params().Name
+			webKey.X = encodeCoordinate(publicKey.X, publicKey.Curve)
+			webKey.Y = encodeCoordinate(publicKey.Y, publicKey.Curve)
+		default:
+			continue
+		}
+		result.Keys = append(result.Keys, webKey)
+	}
+	return result
+}
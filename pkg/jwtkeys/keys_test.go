@@ -0,0 +1,175 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKeys(t *testing.T, directory string) (*rsa.PrivateKey,
+	*ecdsa.PrivateKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	ecdsaData, err := x509.MarshalECPrivateKey(ecdsaKey)
+	assert.Nil(t, err)
+	publicData, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.Nil(t, err)
+
+	files := map[string]*pem.Block{
+		"rsa.pem": {Type: "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"ecdsa.pem":  {Type: "EC PRIVATE KEY", Bytes: ecdsaData},
+		"public.pem": {Type: "PUBLIC KEY", Bytes: publicData},
+	}
+	for name, block := range files {
+		err = ioutil.WriteFile(filepath.Join(directory, name),
+			pem.EncodeToMemory(block), 0600)
+		assert.Nil(t, err)
+	}
+	// files of other extensions are ignored
+	err = ioutil.WriteFile(filepath.Join(directory, "README"), []byte("keys"),
+		0600)
+	assert.Nil(t, err)
+	return rsaKey, ecdsaKey
+}
+
+func TestSignAndVerify(t *testing.T) {
+	directory, err := ioutil.TempDir("", "jwtkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+	writeKeys(t, directory)
+
+	tests := []struct {
+		signingKeyID string
+		expectedAlg  string
+		expectedKID  interface{}
+	}{
+		{"rsa", "RS256", "rsa"},
+		{"ecdsa", "ES256", "ecdsa"},
+		{"", "HS256", nil},
+	}
+	for _, testCase := range tests {
+		keys, err := LoadKeySet("secret", directory, testCase.signingKeyID)
+		assert.Nil(t, err)
+		signed, err := keys.Sign(jwt.StandardClaims{Subject: "user"})
+		assert.Nil(t, err)
+
+		claims := &jwt.StandardClaims{}
+		token, err := jwt.ParseWithClaims(signed, claims, keys.VerificationKey)
+		assert.Nil(t, err, testCase.signingKeyID)
+		assert.True(t, token.Valid)
+		assert.Equal(t, "user", claims.Subject)
+		assert.Equal(t, testCase.expectedAlg, token.Header["alg"])
+		assert.Equal(t, testCase.expectedKID, token.Header["kid"])
+	}
+}
+
+func TestVerifyRotatedKeys(t *testing.T) {
+	directory, err := ioutil.TempDir("", "jwtkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+	writeKeys(t, directory)
+
+	oldKeys, err := LoadKeySet("secret", directory, "rsa")
+	assert.Nil(t, err)
+	newKeys, err := LoadKeySet("", directory, "ecdsa")
+	assert.Nil(t, err)
+	secretKeys := NewSecretKeySet("secret")
+
+	signedByOldKey, _ := oldKeys.Sign(jwt.StandardClaims{})
+	_, err = jwt.Parse(signedByOldKey, newKeys.VerificationKey)
+	assert.Nil(t, err)
+
+	// secret is not accepted after it is removed from configuration
+	signedBySecret, _ := secretKeys.Sign(jwt.StandardClaims{})
+	_, err = jwt.Parse(signedBySecret, oldKeys.VerificationKey)
+	assert.Nil(t, err)
+	_, err = jwt.Parse(signedBySecret, newKeys.VerificationKey)
+	assert.EqualError(t, err, "key '' is unknown")
+
+	// public key of rsa key can not be used as HS256 secret
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{})
+	forged.Header["kid"] = "public"
+	signedForged, _ := forged.SignedString([]byte("public"))
+	_, err = jwt.Parse(signedForged, newKeys.VerificationKey)
+	assert.EqualError(t, err, "key 'public' does not support algorithm 'HS256'")
+}
+
+func TestInvalidKeySets(t *testing.T) {
+	directory, err := ioutil.TempDir("", "jwtkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+	writeKeys(t, directory)
+
+	_, err = LoadKeySet("secret", directory, "missing")
+	assert.EqualError(t, err, "signing key 'missing' is not defined")
+	_, err = LoadKeySet("", directory, "")
+	assert.EqualError(t, err, "signing key '' is not defined")
+	_, err = LoadKeySet("", directory, "public")
+	assert.EqualError(t, err, "signing key 'public' has no private part")
+	_, err = NewKeySet("a", []*Key{NewSecretKey("a", "1"),
+		NewSecretKey("a", "2")})
+	assert.EqualError(t, err, "key 'a' is defined twice")
+
+	_, err = ParseKey("broken", []byte("secret"))
+	assert.EqualError(t, err, "key 'broken' is not PEM encoded")
+	_, err = ParseKey("certificate", pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: []byte{}}))
+	assert.EqualError(t, err,
+		"key 'certificate' has unsupported PEM block 'CERTIFICATE'")
+}
+
+func TestJWKS(t *testing.T) {
+	directory, err := ioutil.TempDir("", "jwtkeys")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+	rsaKey, ecdsaKey := writeKeys(t, directory)
+
+	keys, err := LoadKeySet("secret", directory, "rsa")
+	assert.Nil(t, err)
+	serialized, err := json.Marshal(keys.JWKS())
+	assert.Nil(t, err)
+	decoded := JSONWebKeySet{}
+	assert.Nil(t, json.Unmarshal(serialized, &decoded))
+
+	// secret is not published, keys are ordered by id
+	assert.Equal(t, 3, len(decoded.Keys))
+	assert.Equal(t, "ecdsa", decoded.Keys[0].ID)
+	assert.Equal(t, "public", decoded.Keys[1].ID)
+	assert.Equal(t, "rsa", decoded.Keys[2].ID)
+
+	ecdsaWebKey := decoded.Keys[0]
+	assert.Equal(t, "EC", ecdsaWebKey.KeyType)
+	assert.Equal(t, "ES256", ecdsaWebKey.Algorithm)
+	assert.Equal(t, "P-256", ecdsaWebKey.Curve)
+	assert.Equal(t, "sig", ecdsaWebKey.Use)
+	x, _ := base64.RawURLEncoding.DecodeString(ecdsaWebKey.X)
+	y, _ := base64.RawURLEncoding.DecodeString(ecdsaWebKey.Y)
+	assert.Equal(t, 32, len(x))
+	assert.Equal(t, 32, len(y))
+	assert.Equal(t, ecdsaKey.X, new(big.Int).SetBytes(x))
+	assert.Equal(t, ecdsaKey.Y, new(big.Int).SetBytes(y))
+
+	rsaWebKey := decoded.Keys[2]
+	assert.Equal(t, "RSA", rsaWebKey.KeyType)
+	assert.Equal(t, "RS256", rsaWebKey.Algorithm)
+	assert.Equal(t, "AQAB", rsaWebKey.Exponent)
+	modulus, _ := base64.RawURLEncoding.DecodeString(rsaWebKey.Modulus)
+	assert.Equal(t, rsaKey.N, new(big.Int).SetBytes(modulus))
+	assert.Equal(t, decoded.Keys[1].Modulus, rsaWebKey.Modulus)
+}
@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+	"visualization-api/pkg/logging"
+)
+
+// amqpReconnectInterval is interval between attempts to connect to broker
+const amqpReconnectInterval = 10 * time.Second
+
+// AMQPSettings describe queue notifications are consumed from. Queue is
+// declared durable and bound to exchange keystone publishes notifications
+// to, so notifications emitted while api is stopped are not lost
+type AMQPSettings struct {
+	URL        string
+	Exchange   string
+	RoutingKey string
+	Queue      string
+}
+
+// AMQPSource delivers notifications consumed from AMQP queue. Connection is
+// restored when it is lost. Failed message is delivered again once, then it
+// is dropped
+type AMQPSource struct {
+	settings AMQPSettings
+
+	// lock protects connection and closed
+	lock       sync.Mutex
+	connection *amqp.Connection
+	closed     bool
+}
+
+// NewAMQPSource is AMQPSource constructor
+func NewAMQPSource(settings AMQPSettings) *AMQPSource {
+	return &AMQPSource{settings: settings}
+}
+
+// consume connects to broker and returns deliveries of queue, they are
+// closed when connection is lost
+func (s *AMQPSource) consume() (<-chan amqp.Delivery, error) {
+	connection, err := amqp.Dial(s.settings.URL)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.connection = connection
+	s.lock.Unlock()
+
+	channel, err := connection.Channel()
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	// exchange is declared by keystone
+	_, err = channel.QueueDeclare(s.settings.Queue, true, false, false,
+		false, nil)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	err = channel.QueueBind(s.settings.Queue, s.settings.RoutingKey,
+		s.settings.Exchange, false, nil)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	deliveries, err := channel.Consume(s.settings.Queue, "", false, false,
+		false, false, nil)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (s *AMQPSource) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+// Messages returns channel of notifications, it is closed only when source
+// is closed
+func (s *AMQPSource) Messages() (<-chan Message, error) {
+	messages := make(chan Message)
+	go func() {
+		defer close(messages)
+		for !s.isClosed() {
+			deliveries, err := s.consume()
+			if err != nil {
+				log.Logger.Errorf("Error consuming notifications from "+
+					"queue '%s': '%s'", s.settings.Queue, err)
+				time.Sleep(amqpReconnectInterval)
+				continue
+			}
+			log.Logger.Infof("Consuming notifications from queue '%s'",
+				s.settings.Queue)
+			for delivery := range deliveries {
+				delivery := delivery
+				messages <- Message{
+					Body: delivery.Body,
+					Done: func(handled bool) {
+						if handled {
+							delivery.Ack(false)
+							return
+						}
+						delivery.Nack(false, !delivery.Redelivered)
+					},
+				}
+			}
+			log.Logger.Info("Connection to message broker is lost")
+		}
+	}()
+	return messages, nil
+}
+
+// Close closes connection to broker
+func (s *AMQPSource) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	if s.connection != nil {
+		return s.connection.Close()
+	}
+	return nil
+}
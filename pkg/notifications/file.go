@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"visualization-api/pkg/logging"
+)
+
+// StdinPath is path of file source reading standard input
+const StdinPath = "-"
+
+// FileSource delivers newline separated notifications read from file. It
+// stands in for message bus in tests and demo, failed messages are not
+// delivered again
+type FileSource struct {
+	reader io.Reader
+	closer io.Closer
+}
+
+// NewFileSource returns source of notifications read from reader
+func NewFileSource(reader io.Reader) *FileSource {
+	return &FileSource{reader: reader}
+}
+
+// OpenFileSource returns source of notifications stored in file, StdinPath
+// reads standard input
+func OpenFileSource(path string) (*FileSource, error) {
+	if path == StdinPath {
+		return NewFileSource(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSource{reader: file, closer: file}, nil
+}
+
+// Messages returns channel of notifications, it is closed at end of file
+func (s *FileSource) Messages() (<-chan Message, error) {
+	messages := make(chan Message)
+	go func() {
+		defer close(messages)
+		scanner := bufio.NewScanner(s.reader)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			// scanner reuses its buffer
+			body := make([]byte, len(line))
+			copy(body, line)
+			messages <- Message{Body: body, Done: func(bool) {}}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Logger.Errorf("Error reading notifications: '%s'", err)
+		}
+	}()
+	return messages, nil
+}
+
+// Close closes file of source, standard input is left open
+func (s *FileSource) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package notifications consumes notifications keystone emits on lifecycle
+// events of projects and users. Resources of removed projects and users are
+// cleaned up at once, instead of waiting for periodic synchronization.
+// Notifications are delivered by Source, which is either AMQP queue bound
+// to keystone exchange or file of newline separated messages
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/logging"
+)
+
+// DriverAMQP is a name of driver consuming notifications from AMQP queue
+const DriverAMQP = "amqp"
+
+// DriverFile is a name of driver reading notifications from file
+const DriverFile = "file"
+
+// EventProjectDeleted is emitted by keystone when project is removed
+const EventProjectDeleted = "identity.project.deleted"
+
+// EventUserDeleted is emitted by keystone when user is removed
+const EventUserDeleted = "identity.user.deleted"
+
+// osloMessageKey is key of serialized message in envelope of oslo.messaging
+const osloMessageKey = "oslo.message"
+
+// Message is single raw notification delivered by source
+type Message struct {
+	Body []byte
+	// Done is called after message is handled. Sources supporting
+	// redelivery deliver failed message again
+	Done func(handled bool)
+}
+
+// Source delivers notifications to listener
+type Source interface {
+	// Messages returns channel of delivered messages, channel is closed
+	// when source is exhausted or closed
+	Messages() (<-chan Message, error)
+	Close() error
+}
+
+// Notification is keystone notification of single event. Basic payload
+// holds id of resource in resource_info, CADF payload holds it in target
+// as well
+type Notification struct {
+	MessageID string `json:"message_id"`
+	EventType string `json:"event_type"`
+	Payload   struct {
+		ResourceInfo string `json:"resource_info"`
+		Target       struct {
+			ID string `json:"id"`
+		} `json:"target"`
+	} `json:"payload"`
+}
+
+// ResourceID returns id of resource notification is about
+func (n *Notification) ResourceID() string {
+	if n.Payload.ResourceInfo != "" {
+		return n.Payload.ResourceInfo
+	}
+	return n.Payload.Target.ID
+}
+
+// ParseNotification parses notification either wrapped in envelope of
+// oslo.messaging or sent as is
+func ParseNotification(body []byte) (*Notification, error) {
+	var envelope map[string]json.RawMessage
+	err := json.Unmarshal(body, &envelope)
+	if err != nil {
+		return nil, err
+	}
+	if serializedMessage, ok := envelope[osloMessageKey]; ok {
+		var message string
+		err = json.Unmarshal(serializedMessage, &message)
+		if err != nil {
+			return nil, err
+		}
+		body = []byte(message)
+	}
+
+	notification := &Notification{}
+	err = json.Unmarshal(body, notification)
+	if err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// Listener cleans up resources of removed projects and users
+type Listener struct {
+	source          Source
+	grafana         grafanaclient.SessionInterface
+	databaseManager db.DatabaseManager
+}
+
+// NewListener is Listener constructor
+func NewListener(source Source, grafana grafanaclient.SessionInterface,
+	databaseManager db.DatabaseManager) *Listener {
+	return &Listener{
+		source:          source,
+		grafana:         grafana,
+		databaseManager: databaseManager,
+	}
+}
+
+// Start handles messages of source in background goroutine
+func (l *Listener) Start() error {
+	messages, err := l.source.Messages()
+	if err != nil {
+		return err
+	}
+	go func() {
+		for message := range messages {
+			err := l.Handle(message.Body)
+			if err != nil {
+				log.Logger.Errorf("Error handling keystone notification: '%s'",
+					err)
+			}
+			message.Done(err == nil)
+		}
+		log.Logger.Info("Source of keystone notifications is exhausted")
+	}()
+	return nil
+}
+
+// Handle performs cleanup required by notification, notifications of other
+// events are ignored
+func (l *Listener) Handle(body []byte) error {
+	notification, err := ParseNotification(body)
+	if err != nil {
+		return fmt.Errorf("notification is not parsed: %s", err)
+	}
+	resourceID := notification.ResourceID()
+	switch notification.EventType {
+	case EventProjectDeleted:
+		if resourceID == "" {
+			return fmt.Errorf("notification '%s' has no project id",
+				notification.MessageID)
+		}
+		return l.removeProject(resourceID)
+	case EventUserDeleted:
+		if resourceID == "" {
+			return fmt.Errorf("notification '%s' has no user id",
+				notification.MessageID)
+		}
+		return l.removeUser(resourceID)
+	}
+	log.Logger.Debugf("Ignoring keystone notification '%s' of event '%s'",
+		notification.MessageID, notification.EventType)
+	return nil
+}
+
+// removeProject removes grafana organization project is mapped to with its
+// visualizations and mapping of project. Organization, which is mapped to
+// other project too, is kept with its visualizations. Organizations of
+// project, which are not mapped, are left to project synchronization
+func (l *Listener) removeProject(projectID string) error {
+	mapping, err := l.databaseManager.GetOrganizationMapping(projectID)
+	if err != nil {
+		return err
+	}
+	if mapping == nil {
+		log.Logger.Debugf("Removed project '%s' is not mapped to "+
+			"organization", projectID)
+		return nil
+	}
+	organizationID, err := strconv.Atoi(mapping.OrganizationID)
+	if err != nil {
+		return err
+	}
+	owners, err := l.databaseManager.QueryOrganizationMappingsOfOrganization(
+		mapping.OrganizationID)
+	if err != nil {
+		return err
+	}
+	shared := false
+	for _, owner := range owners {
+		if owner.ProjectID != projectID {
+			shared = true
+		}
+	}
+
+	if shared {
+		log.Logger.Infof("Keeping organization '%s' of removed project, it "+
+			"is mapped to other project", mapping.OrganizationID)
+	} else {
+		// visualizations are removed first, otherwise they would be left
+		// without organization if grafana call succeeded and db call failed
+		log.Logger.Infof("Removing organization '%s' of removed project",
+			mapping.OrganizationID)
+		removed, err := l.databaseManager.DeleteOrganizationVisualizations(
+			mapping.OrganizationID)
+		if err != nil {
+			return err
+		}
+		log.Logger.Infof("Removed %d visualizations of organization '%s'",
+			removed, mapping.OrganizationID)
+		err = l.grafana.DeleteOrganization(organizationID)
+		if _, notFound := err.(grafanaclient.NotFound); err != nil &&
+			!notFound {
+			return err
+		}
+	}
+	return l.databaseManager.DeleteOrganizationMapping(projectID)
+}
+
+// removeUser removes grafana user provisioned for keystone user, login of
+// provisioned user is id of keystone user
+func (l *Listener) removeUser(userID string) error {
+	user, err := l.grafana.GetUserByLogin(userID)
+	if err != nil {
+		if _, notFound := err.(grafanaclient.NotFound); notFound {
+			log.Logger.Debugf("Removed user '%s' has no grafana user", userID)
+			return nil
+		}
+		return err
+	}
+	log.Logger.Infof("Removing grafana user of removed user '%s'", userID)
+	return l.grafana.DeleteUser(user.ID)
+}
@@ -0,0 +1,205 @@
+package notifications_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/notifications"
+)
+
+const projectID = "0123456789abcdef0123456789abcdef"
+const userID = "b7e3f5d09e1e4a4c8cc2b9a4e8d2ff10"
+
+// basicNotification is notification of basic format wrapped in envelope of
+// oslo.messaging
+const basicNotification = `{"oslo.version": "2.0", "oslo.message": "{\"message_id\": \"1\", \"event_type\": \"identity.project.deleted\", \"payload\": {\"resource_info\": \"` + projectID + `\"}}"}`
+
+// cadfNotification is notification of cadf format sent without envelope
+const cadfNotification = `{"message_id": "2", "event_type": "identity.user.deleted", "payload": {"typeURI": "http://schemas.dmtf.org/cloud/audit/1.0/event", "action": "deleted.user", "target": {"id": "` + userID + `", "typeURI": "data/security/account/user"}}}`
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		description        string
+		body               string
+		expectedEventType  string
+		expectedResourceID string
+		expectedError      bool
+	}{
+		{"basic notification", basicNotification,
+			notifications.EventProjectDeleted, projectID, false},
+		{"cadf notification", cadfNotification,
+			notifications.EventUserDeleted, userID, false},
+		{"broken envelope", `{"oslo.message": {}}`, "", "", true},
+		{"broken notification", `[]`, "", "", true},
+	}
+	for _, testCase := range tests {
+		notification, err := notifications.ParseNotification(
+			[]byte(testCase.body))
+		if testCase.expectedError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, testCase.expectedEventType, notification.EventType,
+			testCase.description)
+		assert.Equal(t, testCase.expectedResourceID,
+			notification.ResourceID(), testCase.description)
+	}
+}
+
+func TestListenerRemovesProject(t *testing.T) {
+	mapping := &models.OrganizationMapping{ProjectID: projectID,
+		OrganizationID: "3"}
+	otherMapping := &models.OrganizationMapping{
+		ProjectID: "11111111111111111111111111111111", OrganizationID: "3"}
+	tests := []struct {
+		description         string
+		mapping             *models.OrganizationMapping
+		owners              []*models.OrganizationMapping
+		organizationRemoved bool
+		deletionErr         error
+	}{
+		{description: "mapped organization is removed", mapping: mapping,
+			owners:              []*models.OrganizationMapping{mapping},
+			organizationRemoved: true},
+		{description: "missing organization is ignored", mapping: mapping,
+			owners:              []*models.OrganizationMapping{mapping},
+			organizationRemoved: true, deletionErr: grafanaclient.NotFound{}},
+		{description: "organization of other project is kept",
+			mapping: mapping,
+			owners: []*models.OrganizationMapping{mapping,
+				otherMapping}},
+		{description: "project without mapping is ignored"},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+		mockedDatabaseManager.EXPECT().GetOrganizationMapping(
+			projectID).Return(testCase.mapping, nil)
+		if testCase.mapping != nil {
+			mockedDatabaseManager.EXPECT().QueryOrganizationMappingsOfOrganization(
+				"3").Return(testCase.owners, nil)
+			calls := []*gomock.Call{}
+			if testCase.organizationRemoved {
+				calls = append(calls,
+					mockedDatabaseManager.EXPECT().DeleteOrganizationVisualizations(
+						"3").Return(int64(2), nil),
+					mockedGrafana.EXPECT().DeleteOrganization(3).Return(
+						testCase.deletionErr))
+			}
+			calls = append(calls,
+				mockedDatabaseManager.EXPECT().DeleteOrganizationMapping(
+					projectID).Return(nil))
+			gomock.InOrder(calls...)
+		}
+
+		listener := notifications.NewListener(nil, mockedGrafana,
+			mockedDatabaseManager)
+		assert.Nil(t, listener.Handle([]byte(basicNotification)),
+			testCase.description)
+	}
+}
+
+func TestListenerRemovesUser(t *testing.T) {
+	tests := []struct {
+		description   string
+		lookupErr     error
+		deletionErr   error
+		expectedError error
+	}{
+		{description: "grafana user is removed"},
+		{description: "user without grafana user is ignored",
+			lookupErr: grafanaclient.NotFound{}},
+		{description: "failed lookup is reported",
+			lookupErr: errors.New("test"), expectedError: errors.New("test")},
+		{description: "failed removal is reported",
+			deletionErr: errors.New("test"), expectedError: errors.New("test")},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+		mockedGrafana.EXPECT().GetUserByLogin(userID).Return(
+			grafanaclient.User{ID: 7, Login: userID}, testCase.lookupErr)
+		if testCase.lookupErr == nil {
+			mockedGrafana.EXPECT().DeleteUser(7).Return(testCase.deletionErr)
+		}
+
+		listener := notifications.NewListener(nil, mockedGrafana,
+			mockedDatabaseManager)
+		assert.Equal(t, testCase.expectedError,
+			listener.Handle([]byte(cadfNotification)), testCase.description)
+	}
+}
+
+func TestListenerErrors(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+	listener := notifications.NewListener(nil, mockedGrafana,
+		mockedDatabaseManager)
+
+	// other events are ignored
+	assert.Nil(t, listener.Handle([]byte(
+		`{"event_type": "identity.project.created", "payload": {}}`)))
+	assert.EqualError(t, listener.Handle([]byte(
+		`{"message_id": "1", "event_type": "identity.project.deleted"}`)),
+		"notification '1' has no project id")
+	assert.NotNil(t, listener.Handle([]byte("not json")))
+
+	// organization is kept if its visualizations are not removed
+	mapping := &models.OrganizationMapping{ProjectID: projectID,
+		OrganizationID: "3"}
+	mockedDatabaseManager.EXPECT().GetOrganizationMapping(projectID).Return(
+		mapping, nil)
+	mockedDatabaseManager.EXPECT().QueryOrganizationMappingsOfOrganization(
+		"3").Return([]*models.OrganizationMapping{mapping}, nil)
+	mockedDatabaseManager.EXPECT().DeleteOrganizationVisualizations(
+		"3").Return(int64(0), errors.New("test"))
+	assert.EqualError(t, listener.Handle([]byte(basicNotification)), "test")
+
+	// mapping is kept if organization is not removed
+	mockedDatabaseManager.EXPECT().GetOrganizationMapping(projectID).Return(
+		mapping, nil)
+	mockedDatabaseManager.EXPECT().QueryOrganizationMappingsOfOrganization(
+		"3").Return([]*models.OrganizationMapping{mapping}, nil)
+	mockedDatabaseManager.EXPECT().DeleteOrganizationVisualizations(
+		"3").Return(int64(1), nil)
+	mockedGrafana.EXPECT().DeleteOrganization(3).Return(errors.New("test"))
+	assert.EqualError(t, listener.Handle([]byte(basicNotification)), "test")
+}
+
+func TestFileSource(t *testing.T) {
+	testHelper.InitializeLogger()
+	source := notifications.NewFileSource(strings.NewReader(
+		basicNotification + "\n\n" + cadfNotification + "\n"))
+	messages, err := source.Messages()
+	assert.Nil(t, err)
+
+	bodies := []string{}
+	for message := range messages {
+		bodies = append(bodies, string(message.Body))
+		message.Done(true)
+	}
+	assert.Equal(t, []string{basicNotification, cadfNotification}, bodies)
+	assert.Nil(t, source.Close())
+}
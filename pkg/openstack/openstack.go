@@ -54,6 +54,14 @@ func (cli *Client) GetTokenInfo(token string) (*TokenInfo, error) {
 				ID   string `mapstructure:"id"`
 				Name string `mapstructure:"name"`
 			} `mapstructure:"project"`
+			User struct {
+				ID     string `mapstructure:"id"`
+				Name   string `mapstructure:"name"`
+				Domain struct {
+					ID   string `mapstructure:"id"`
+					Name string `mapstructure:"name"`
+				} `mapstructure:"domain"`
+			} `mapstructure:"user"`
 		} `mapstructure:"token"`
 	}
 
@@ -75,12 +83,22 @@ func (cli *Client) GetTokenInfo(token string) (*TokenInfo, error) {
 	resultToken.Roles = parsedResponse.Token.Roles
 	resultToken.ProjectID = parsedResponse.Token.Project.ID
 	resultToken.ProjectName = parsedResponse.Token.Project.Name
+	resultToken.UserID = parsedResponse.Token.User.ID
+	resultToken.UserName = parsedResponse.Token.User.Name
+	resultToken.DomainID = parsedResponse.Token.User.Domain.ID
+	resultToken.DomainName = parsedResponse.Token.User.Domain.Name
 
 	log.Logger.Debugf("Successfully retrieved openstack data for token %s",
 		token)
 	return &resultToken, nil
 }
 
+// TokenCacheStats returns stats of disabled cache, tokens are not cached by
+// Client, see CachingClient
+func (cli *Client) TokenCacheStats() TokenCacheStats {
+	return TokenCacheStats{}
+}
+
 // NewOpenstackClient creates new Client structure from provided arguments
 func NewOpenstackClient(identityEndpoint, username, password,
 	tenantName, domainName string) (*Client, error) {
@@ -117,4 +135,6 @@ Interface for openstack functionality usage
 type ClientInterface interface {
 	ValidateToken(string) (bool, error)
 	GetTokenInfo(string) (*TokenInfo, error)
+	TokenCacheStats() TokenCacheStats
+	ListProjects() ([]Project, error)
 }
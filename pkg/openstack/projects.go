@@ -0,0 +1,38 @@
+package openstack
+
+import (
+	"visualization-api/pkg/logging"
+)
+
+// Project is a keystone project
+type Project struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	DomainID string `json:"domain_id"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// ListProjects - get all projects known to keystone. Keystone paginates
+// projects only if it is configured to, next pages are requested by links
+// returned with every page
+func (cli *Client) ListProjects() ([]Project, error) {
+	projects := []Project{}
+	url := cli.keystoneClient.ServiceURL("projects")
+	for url != "" {
+		var page struct {
+			Projects []Project `json:"projects"`
+			Links    struct {
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+		_, err := cli.keystoneClient.Get(url, &page, nil)
+		if err != nil {
+			log.Logger.Errorf("Error listing projects %s", err)
+			return nil, err
+		}
+		projects = append(projects, page.Projects...)
+		url = page.Links.Next
+	}
+	log.Logger.Debugf("Retrieved %d projects from keystone", len(projects))
+	return projects, nil
+}
@@ -0,0 +1,226 @@
+package openstack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"visualization-api/pkg/logging"
+)
+
+// TokenCacheStats describes usage of keystone token cache
+type TokenCacheStats struct {
+	Enabled bool `json:"enabled"`
+	// Hits are requests answered by cache, NegativeHits are part of them
+	// answered by entries of invalid tokens
+	Hits         int64 `json:"hits"`
+	NegativeHits int64 `json:"negativeHits"`
+	Misses       int64 `json:"misses"`
+	// Evictions are entries removed before expiration to free space
+	Evictions int64   `json:"evictions"`
+	Entries   int     `json:"entries"`
+	HitRate   float64 `json:"hitRate"`
+}
+
+// tokenCacheEntry is result of keystone token validation. Invalid tokens
+// have no info
+type tokenCacheEntry struct {
+	valid     bool
+	info      *TokenInfo
+	expiresAt time.Time
+}
+
+// CachingClient is realization of openstack.ClientInterface, which caches
+// results of token validation made by wrapped client. Login storms use the
+// same keystone tokens, so they are validated by keystone only once per ttl.
+// Tokens are cached not longer than they are valid, invalid tokens are cached
+// for negativeTTL. Tokens are stored by their hashes, errors of keystone are
+// not cached
+type CachingClient struct {
+	client      ClientInterface
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	lock    sync.Mutex
+	entries map[string]*tokenCacheEntry
+	stats   TokenCacheStats
+	// now returns current time, it is replaced in tests
+	now func() time.Time
+}
+
+// NewCachingClient wraps client with cache of maxEntries token validation
+// results
+func NewCachingClient(client ClientInterface, ttl, negativeTTL time.Duration,
+	maxEntries int) *CachingClient {
+	return &CachingClient{
+		client:      client,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		entries:     map[string]*tokenCacheEntry{},
+		stats:       TokenCacheStats{Enabled: true},
+		now:         time.Now,
+	}
+}
+
+// hashToken returns cache key of token, tokens are credentials and are not
+// kept in memory
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// lookup returns not expired entry of token and counts hit or miss. Entries
+// of invalid tokens are not used to get token info
+func (c *CachingClient) lookup(key string, negative bool) *tokenCacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok || (!entry.valid && !negative) {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	if !entry.valid {
+		c.stats.NegativeHits++
+	}
+	return entry
+}
+
+// store saves entry of token, which expires after ttl. Entries of valid
+// tokens expire not later than tokens themselves
+func (c *CachingClient) store(key string, info *TokenInfo) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+	entry := &tokenCacheEntry{valid: info != nil, info: info,
+		expiresAt: now.Add(c.negativeTTL)}
+	if entry.valid {
+		entry.expiresAt = now.Add(c.ttl)
+		if info.ExpiresAt.Before(entry.expiresAt) {
+			entry.expiresAt = info.ExpiresAt
+		}
+	}
+	if !now.Before(entry.expiresAt) {
+		return
+	}
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = entry
+}
+
+// evict removes expired entries, if there are none, arbitrary entry is
+// removed to free space
+func (c *CachingClient) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			break
+		}
+		delete(c.entries, key)
+		c.stats.Evictions++
+	}
+}
+
+// Uncached returns wrapped client, it asks keystone on every call
+func (c *CachingClient) Uncached() ClientInterface {
+	return c.client
+}
+
+// Forget removes cached result of token validation, so token is validated by
+// keystone on next use
+func (c *CachingClient) Forget(token string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, hashToken(token))
+}
+
+// copyTokenInfo returns copy of cached token info, so callers can not change
+// cached one
+func copyTokenInfo(info *TokenInfo) *TokenInfo {
+	infoCopy := *info
+	infoCopy.Roles = []map[string]string{}
+	for _, role := range info.Roles {
+		roleCopy := map[string]string{}
+		for name, value := range role {
+			roleCopy[name] = value
+		}
+		infoCopy.Roles = append(infoCopy.Roles, roleCopy)
+	}
+	return &infoCopy
+}
+
+// ValidateToken - ask keystone if token is still valid, unless result is
+// cached. Info of valid token is requested at once to know when it expires
+func (c *CachingClient) ValidateToken(token string) (bool, error) {
+	key := hashToken(token)
+	if entry := c.lookup(key, true); entry != nil {
+		return entry.valid, nil
+	}
+
+	valid, err := c.client.ValidateToken(token)
+	if err != nil {
+		return valid, err
+	}
+	if !valid {
+		c.store(key, nil)
+		return false, nil
+	}
+
+	info, err := c.client.GetTokenInfo(token)
+	if err != nil {
+		// token is valid, info is requested again by GetTokenInfo
+		log.Logger.Debugf("Token info is not cached: %s", err)
+		return true, nil
+	}
+	c.store(key, info)
+	return true, nil
+}
+
+// GetTokenInfo - get data from token, unless it is cached
+func (c *CachingClient) GetTokenInfo(token string) (*TokenInfo, error) {
+	key := hashToken(token)
+	if entry := c.lookup(key, false); entry != nil {
+		return copyTokenInfo(entry.info), nil
+	}
+
+	info, err := c.client.GetTokenInfo(token)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, info)
+	return copyTokenInfo(info), nil
+}
+
+// ListProjects - get all projects from wrapped client, projects are not
+// cached
+func (c *CachingClient) ListProjects() ([]Project, error) {
+	return c.client.ListProjects()
+}
+
+// TokenCacheStats returns usage of cache
+func (c *CachingClient) TokenCacheStats() TokenCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	if requests := stats.Hits + stats.Misses; requests > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(requests)
+	}
+	return stats
+}
@@ -0,0 +1,163 @@
+package openstack
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/logging"
+)
+
+// fakeClient answers token requests and counts calls made to keystone
+type fakeClient struct {
+	tokens        map[string]*TokenInfo
+	err           error
+	validateCalls int
+	getInfoCalls  int
+}
+
+func (c *fakeClient) ValidateToken(token string) (bool, error) {
+	c.validateCalls++
+	_, ok := c.tokens[token]
+	return ok, c.err
+}
+
+func (c *fakeClient) GetTokenInfo(token string) (*TokenInfo, error) {
+	c.getInfoCalls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	info, ok := c.tokens[token]
+	if !ok {
+		return nil, errors.New("token is not found")
+	}
+	return info, nil
+}
+
+func (c *fakeClient) TokenCacheStats() TokenCacheStats {
+	return TokenCacheStats{}
+}
+
+func (c *fakeClient) ListProjects() ([]Project, error) {
+	return nil, c.err
+}
+
+func newTestCache(client ClientInterface, now *time.Time) *CachingClient {
+	cache := NewCachingClient(client, time.Minute, 10*time.Second, 2)
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestCachingClientValidToken(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	now := time.Date(2017, 8, 7, 12, 0, 0, 0, time.UTC)
+	client := &fakeClient{tokens: map[string]*TokenInfo{
+		"valid": {ProjectID: "project", ExpiresAt: now.Add(time.Hour),
+			Roles: []map[string]string{{"name": "admin"}}},
+	}}
+	cache := newTestCache(client, &now)
+
+	// login validates token and gets its info, keystone is requested once
+	for i := 0; i < 3; i++ {
+		valid, err := cache.ValidateToken("valid")
+		assert.Nil(t, err)
+		assert.True(t, valid)
+		info, err := cache.GetTokenInfo("valid")
+		assert.Nil(t, err)
+		assert.Equal(t, "project", info.ProjectID)
+		info.Roles[0]["name"] = "changed"
+	}
+	assert.Equal(t, 1, client.validateCalls)
+	assert.Equal(t, 1, client.getInfoCalls)
+	assert.Equal(t, TokenCacheStats{Enabled: true, Hits: 5, Misses: 1,
+		Entries: 1, HitRate: 5.0 / 6.0}, cache.TokenCacheStats())
+
+	// entry expires after ttl
+	now = now.Add(time.Minute)
+	cache.ValidateToken("valid")
+	assert.Equal(t, 2, client.validateCalls)
+}
+
+func TestCachingClientTokenExpiration(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	now := time.Date(2017, 8, 7, 12, 0, 0, 0, time.UTC)
+	client := &fakeClient{tokens: map[string]*TokenInfo{
+		"expiring": {ExpiresAt: now.Add(time.Second)},
+		"expired":  {ExpiresAt: now},
+	}}
+	cache := newTestCache(client, &now)
+
+	cache.ValidateToken("expiring")
+	cache.ValidateToken("expired")
+	assert.Equal(t, 1, cache.TokenCacheStats().Entries,
+		"expired token is not cached")
+
+	// token is not cached longer than it is valid
+	now = now.Add(time.Second)
+	cache.ValidateToken("expiring")
+	assert.Equal(t, 3, client.validateCalls)
+}
+
+func TestCachingClientInvalidToken(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	now := time.Date(2017, 8, 7, 12, 0, 0, 0, time.UTC)
+	client := &fakeClient{tokens: map[string]*TokenInfo{}}
+	cache := newTestCache(client, &now)
+
+	for i := 0; i < 2; i++ {
+		valid, err := cache.ValidateToken("invalid")
+		assert.Nil(t, err)
+		assert.False(t, valid)
+	}
+	assert.Equal(t, 1, client.validateCalls)
+	assert.Equal(t, 0, client.getInfoCalls)
+
+	// info of invalid tokens is not cached
+	_, err := cache.GetTokenInfo("invalid")
+	assert.EqualError(t, err, "token is not found")
+	assert.Equal(t, TokenCacheStats{Enabled: true, Hits: 1, NegativeHits: 1,
+		Misses: 2, Entries: 1, HitRate: 1.0 / 3.0}, cache.TokenCacheStats())
+
+	// negative entry expires after negative ttl
+	now = now.Add(10 * time.Second)
+	cache.ValidateToken("invalid")
+	assert.Equal(t, 2, client.validateCalls)
+}
+
+func TestCachingClientErrors(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	now := time.Date(2017, 8, 7, 12, 0, 0, 0, time.UTC)
+	client := &fakeClient{err: errors.New("keystone is not available")}
+	cache := newTestCache(client, &now)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.ValidateToken("token")
+		assert.EqualError(t, err, "keystone is not available")
+	}
+	assert.Equal(t, 2, client.validateCalls, "errors are not cached")
+	assert.Equal(t, 0, cache.TokenCacheStats().Entries)
+}
+
+func TestCachingClientEviction(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	now := time.Date(2017, 8, 7, 12, 0, 0, 0, time.UTC)
+	client := &fakeClient{tokens: map[string]*TokenInfo{}}
+	cache := newTestCache(client, &now)
+
+	cache.ValidateToken("first")
+	now = now.Add(5 * time.Second)
+	cache.ValidateToken("second")
+	// first entry is expired, it is removed to free space
+	now = now.Add(5 * time.Second)
+	cache.ValidateToken("third")
+	assert.Equal(t, int64(0), cache.TokenCacheStats().Evictions)
+	assert.Equal(t, 2, cache.TokenCacheStats().Entries)
+
+	// cache is full of not expired entries
+	cache.ValidateToken("fourth")
+	stats := cache.TokenCacheStats()
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+}
@@ -13,6 +13,11 @@ type TokenInfo struct {
 	Roles       []map[string]string
 	ProjectID   string
 	ProjectName string
+	UserID      string
+	UserName    string
+	// domain of user
+	DomainID   string
+	DomainName string
 }
 
 // IsAdmin tries to find 'admin' role in Roles map
@@ -24,3 +29,12 @@ func (t *TokenInfo) IsAdmin() bool {
 	}
 	return false
 }
+
+// RoleNames returns names of all roles of token
+func (t *TokenInfo) RoleNames() []string {
+	names := []string{}
+	for _, role := range t.Roles {
+		names = append(names, role["name"])
+	}
+	return names
+}
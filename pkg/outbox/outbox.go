@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/satori/go.uuid"
+
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/logging"
+)
+
+// ErrOperationClaimed is returned if journaled operation is reserved by
+// another owner, outbox worker for example, or was already performed
+var ErrOperationClaimed = errors.New("grafana operation is claimed by another owner")
+
+// Execute performs grafana call described by journaled operation on behalf
+// of its creator. Operation is claimed first, so it is never performed by
+// creator and worker at the same time. On success operation is removed from
+// journal, otherwise failed attempt is stored and operation is left to be
+// retried by worker. overwrite is passed to grafana on dashboard upload.
+// Slug of uploaded dashboard is returned
+func Execute(databaseManager db.DatabaseManager,
+	grafana grafanaclient.SessionInterface,
+	operation *models.GrafanaOperation, overwrite bool) (string, error) {
+	return execute(databaseManager, grafana, operation, operation.Owner,
+		overwrite)
+}
+
+func execute(databaseManager db.DatabaseManager,
+	grafana grafanaclient.SessionInterface,
+	operation *models.GrafanaOperation, owner string, overwrite bool) (
+	string, error) {
+	claimed, err := databaseManager.ClaimGrafanaOperation(operation, owner)
+	if err != nil {
+		log.Logger.Errorf("Unable to claim grafana operation '%d': '%s'",
+			operation.ID, err)
+		return "", err
+	}
+	if !claimed {
+		log.Logger.Debugf("Grafana operation '%d' is claimed by another owner",
+			operation.ID)
+		return "", ErrOperationClaimed
+	}
+
+	var slug string
+	switch operation.Operation {
+	case models.GrafanaOperationUpload:
+		slug, err = grafana.UploadDashboard([]byte(operation.Payload),
+			operation.OrganizationID, overwrite)
+	case models.GrafanaOperationDelete:
+		err = grafana.DeleteDashboard(operation.Slug, operation.OrganizationID)
+		if _, ok := err.(grafanaclient.NotFound); ok {
+			// dashboard was already removed, by previous attempt for example
+			log.Logger.Debugf("Grafana dashboard '%s' is already removed",
+				operation.Slug)
+			err = nil
+		}
+	default:
+		err = fmt.Errorf("unknown grafana operation '%s'", operation.Operation)
+	}
+
+	if err != nil {
+		log.Logger.Errorf("Error performing grafana operation '%d' (%s): '%s'",
+			operation.ID, operation.Operation, err)
+		failErr := databaseManager.FailGrafanaOperation(operation, err.Error())
+		if failErr != nil {
+			log.Logger.Errorf("Unable to store failed attempt of grafana "+
+				"operation '%d': '%s'", operation.ID, failErr)
+		}
+		return "", err
+	}
+
+	err = databaseManager.CompleteGrafanaOperation(operation, slug)
+	if err != nil {
+		log.Logger.Errorf("Unable to complete grafana operation '%d': '%s'",
+			operation.ID, err)
+		return "", err
+	}
+	return slug, nil
+}
+
+// Worker drains journal of grafana operations, retrying failed ones
+type Worker struct {
+	databaseManager db.DatabaseManager
+	grafana         grafanaclient.SessionInterface
+	maxAttempts     int
+	// owner identifies worker in claims of operations
+	owner string
+}
+
+// NewWorker is Worker constructor
+func NewWorker(databaseManager db.DatabaseManager,
+	grafana grafanaclient.SessionInterface, maxAttempts int) *Worker {
+	return &Worker{
+		databaseManager: databaseManager,
+		grafana:         grafana,
+		maxAttempts:     maxAttempts,
+		owner:           uuid.NewV4().String(),
+	}
+}
+
+// Start drains journal periodically in background goroutine
+func (w *Worker) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.Drain(time.Now())
+		}
+	}()
+}
+
+// Drain performs all journaled operations created before provided time,
+// which did not exceed maximum amount of attempts. Operations reserved by
+// their creators, request handlers or jobs, are skipped until lease of
+// creator expires. Amount of successfully performed operations is returned
+func (w *Worker) Drain(now time.Time) int {
+	operations, err := w.databaseManager.QueryGrafanaOperations(now,
+		w.maxAttempts)
+	if err != nil {
+		log.Logger.Errorf("Unable to get journaled grafana operations: '%s'", err)
+		return 0
+	}
+	if len(operations) == 0 {
+		return 0
+	}
+
+	log.Logger.Infof("Performing %d journaled grafana operations", len(operations))
+	performed := 0
+	for _, operation := range operations {
+		// overwrite is used, because dashboard could be already uploaded
+		// by interrupted attempt
+		_, err = execute(w.databaseManager, w.grafana, operation, w.owner, true)
+		if err == nil {
+			performed++
+		} else if err == ErrOperationClaimed {
+			continue
+		} else if operation.Attempts >= w.maxAttempts {
+			log.Logger.Errorf("Grafana operation '%d' exceeded %d attempts "+
+				"and would not be retried", operation.ID, w.maxAttempts)
+		}
+	}
+	return performed
+}
@@ -0,0 +1,154 @@
+package outbox_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/outbox"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		description   string
+		operation     *models.GrafanaOperation
+		grafanaError  error
+		expectedSlug  string
+		expectedError error
+	}{
+		{
+			description: "upload is completed with received slug",
+			operation: &models.GrafanaOperation{ID: 1,
+				Operation: models.GrafanaOperationUpload, OrganizationID: "3",
+				DashboardID: "id", Payload: "template", Owner: "creator"},
+			expectedSlug: "slug",
+		},
+		{
+			description: "failed upload is left in journal",
+			operation: &models.GrafanaOperation{ID: 1,
+				Operation: models.GrafanaOperationUpload, OrganizationID: "3",
+				DashboardID: "id", Payload: "template", Owner: "creator"},
+			grafanaError:  errors.New("test"),
+			expectedError: errors.New("test"),
+		},
+		{
+			description: "delete is completed",
+			operation: &models.GrafanaOperation{ID: 1,
+				Operation: models.GrafanaOperationDelete, OrganizationID: "3",
+				DashboardID: "id", Slug: "slug", Owner: "creator"},
+		},
+		{
+			description: "delete of missing dashboard is completed",
+			operation: &models.GrafanaOperation{ID: 1,
+				Operation: models.GrafanaOperationDelete, OrganizationID: "3",
+				DashboardID: "id", Slug: "slug", Owner: "creator"},
+			grafanaError: grafanaclient.NotFound{},
+		},
+		{
+			description: "failed delete is left in journal",
+			operation: &models.GrafanaOperation{ID: 1,
+				Operation: models.GrafanaOperationDelete, OrganizationID: "3",
+				DashboardID: "id", Slug: "slug", Owner: "creator"},
+			grafanaError:  errors.New("test"),
+			expectedError: errors.New("test"),
+		},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+		mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+
+		mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(
+			testCase.operation, "creator").Return(true, nil)
+		if testCase.operation.Operation == models.GrafanaOperationUpload {
+			mockedGrafana.EXPECT().UploadDashboard([]byte("template"), "3",
+				true).Return(testCase.expectedSlug, testCase.grafanaError)
+		} else {
+			mockedGrafana.EXPECT().DeleteDashboard("slug", "3").Return(
+				testCase.grafanaError)
+		}
+		if testCase.expectedError == nil {
+			mockedDatabaseManager.EXPECT().CompleteGrafanaOperation(
+				testCase.operation, testCase.expectedSlug).Return(nil)
+		} else {
+			mockedDatabaseManager.EXPECT().FailGrafanaOperation(
+				testCase.operation, testCase.expectedError.Error()).Return(nil)
+		}
+
+		slug, err := outbox.Execute(mockedDatabaseManager, mockedGrafana,
+			testCase.operation, true)
+		assert.Equal(t, testCase.expectedSlug, slug, testCase.description)
+		assert.Equal(t, testCase.expectedError, err, testCase.description)
+	}
+}
+
+func TestExecuteClaimedOperation(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+
+	// operation taken over by worker is not performed by its creator
+	operation := &models.GrafanaOperation{ID: 1,
+		Operation: models.GrafanaOperationUpload, OrganizationID: "3",
+		DashboardID: "id", Payload: "template", Owner: "creator"}
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(operation,
+		"creator").Return(false, nil)
+
+	slug, err := outbox.Execute(mockedDatabaseManager, mockedGrafana,
+		operation, false)
+	assert.Equal(t, "", slug)
+	assert.Equal(t, outbox.ErrOperationClaimed, err)
+}
+
+func TestWorkerDrain(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+
+	now := time.Date(2017, 7, 20, 12, 0, 0, 0, time.UTC)
+	uploadOperation := &models.GrafanaOperation{ID: 1,
+		Operation: models.GrafanaOperationUpload, OrganizationID: "3",
+		DashboardID: "id", Payload: "template"}
+	deleteOperation := &models.GrafanaOperation{ID: 2,
+		Operation: models.GrafanaOperationDelete, OrganizationID: "3",
+		DashboardID: "id", Slug: "slug"}
+	leasedOperation := &models.GrafanaOperation{ID: 3,
+		Operation: models.GrafanaOperationUpload, OrganizationID: "3",
+		DashboardID: "leased", Payload: "leased_template", Owner: "creator"}
+
+	// operations are claimed by worker, operations leased by their creators
+	// are skipped, because they are performed by request handlers right now
+	mockedDatabaseManager.EXPECT().QueryGrafanaOperations(now, 5).Return(
+		[]*models.GrafanaOperation{uploadOperation, deleteOperation,
+			leasedOperation}, nil)
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(uploadOperation,
+		gomock.Not("creator")).Return(true, nil)
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(deleteOperation,
+		gomock.Not("creator")).Return(true, nil)
+	mockedDatabaseManager.EXPECT().ClaimGrafanaOperation(leasedOperation,
+		gomock.Not("creator")).Return(false, nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("template"), "3",
+		true).Return("slug", nil)
+	mockedDatabaseManager.EXPECT().CompleteGrafanaOperation(uploadOperation,
+		"slug").Return(nil)
+	mockedGrafana.EXPECT().DeleteDashboard("slug", "3").Return(errors.New("test"))
+	mockedDatabaseManager.EXPECT().FailGrafanaOperation(deleteOperation,
+		"test").Return(nil)
+
+	performed := outbox.NewWorker(mockedDatabaseManager, mockedGrafana, 5).Drain(now)
+	assert.Equal(t, 1, performed, "only successful operations are counted")
+}
@@ -0,0 +1,373 @@
+// Package policy implements role based access control of api calls. Policy
+// maps names of rules to expressions in syntax of oslo.policy:
+//
+//	""  or "@"             always allowed
+//	"!"                    never allowed
+//	"role:reader"          user has keystone role reader
+//	"rule:admin"           rule named admin allows
+//	"not", "and", "or"     combine checks, parentheses group them
+//
+// Every route of api is protected by its own rule, e.g. visualizations:delete.
+// Rules of policy file override default ones, rules missing in both fall back
+// to rule named default
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"visualization-api/pkg/logging"
+)
+
+// RuleAdmin lists roles of admin users, it is used to issue admin tokens
+const RuleAdmin = "admin"
+
+// RuleAdminAPI protects admin api by default
+const RuleAdminAPI = "admin_api"
+
+// RuleDefault is used for rules, which are not defined
+const RuleDefault = "default"
+
+// Rules protecting api routes
+const (
+	RuleVisualizationsGet    = "visualizations:get"
+	RuleVisualizationsCreate = "visualizations:create"
+	RuleVisualizationsUpdate = "visualizations:update"
+	RuleVisualizationsDelete = "visualizations:delete"
+	RuleJobsGet              = "jobs:get"
+	RuleTemplatesGet         = "templates:get"
+	RuleTemplatesCreate      = "templates:create"
+	RuleTemplatesDelete      = "templates:delete"
+	RuleDatasourcesGet       = "datasources:get"
+	RuleDatasourcesCreate    = "datasources:create"
+	RuleDatasourcesDelete    = "datasources:delete"
+	RuleGrafanaProxy         = "grafana:proxy"
+	RuleUsersGet             = "users:get"
+	RuleUsersCreate          = "users:create"
+	RuleUsersDelete          = "users:delete"
+	RuleOrganizationsGet     = "organizations:get"
+	RuleOrganizationsCreate  = "organizations:create"
+	RuleOrganizationsDelete  = "organizations:delete"
+	RuleOrgUsersGet          = "organization_users:get"
+	RuleOrgUsersCreate       = "organization_users:create"
+	RuleOrgUsersDelete       = "organization_users:delete"
+	RuleReconciliationGet    = "reconciliation:get"
+	RuleReconciliationRun    = "reconciliation:run"
+	RuleProjectSyncGet       = "project_sync:get"
+	RuleProjectSyncRun       = "project_sync:run"
+	RuleOrgMappingsGet       = "organization_mappings:get"
+	RuleOrgMappingsRemap     = "organization_mappings:remap"
+	RuleAuditGet             = "audit:get"
+	RuleMetricsGet           = "metrics:get"
+)
+
+// DefaultRules allow all routes of visualization api to every authenticated
+// user and admin api to users with admin role
+var DefaultRules = map[string]string{
+	RuleAdmin:    "role:admin",
+	RuleAdminAPI: "rule:admin",
+	RuleDefault:  "rule:admin_api",
+
+	RuleVisualizationsGet:    "",
+	RuleVisualizationsCreate: "",
+	RuleVisualizationsUpdate: "",
+	RuleVisualizationsDelete: "",
+	RuleJobsGet:              "",
+	RuleTemplatesGet:         "",
+	RuleTemplatesCreate:      "",
+	RuleTemplatesDelete:      "",
+	RuleDatasourcesGet:       "",
+	RuleDatasourcesCreate:    "",
+	RuleDatasourcesDelete:    "",
+	RuleGrafanaProxy:         "",
+
+	RuleUsersGet:            "rule:admin_api",
+	RuleUsersCreate:         "rule:admin_api",
+	RuleUsersDelete:         "rule:admin_api",
+	RuleOrganizationsGet:    "rule:admin_api",
+	RuleOrganizationsCreate: "rule:admin_api",
+	RuleOrganizationsDelete: "rule:admin_api",
+	RuleOrgUsersGet:         "rule:admin_api",
+	RuleOrgUsersCreate:      "rule:admin_api",
+	RuleOrgUsersDelete:      "rule:admin_api",
+	RuleReconciliationGet:   "rule:admin_api",
+	RuleReconciliationRun:   "rule:admin_api",
+	RuleProjectSyncGet:      "rule:admin_api",
+	RuleProjectSyncRun:      "rule:admin_api",
+	RuleOrgMappingsGet:      "rule:admin_api",
+	RuleOrgMappingsRemap:    "rule:admin_api",
+	RuleAuditGet:            "rule:admin_api",
+	RuleMetricsGet:          "rule:admin_api",
+}
+
+// Policy is a set of parsed rules
+type Policy struct {
+	rules map[string]check
+}
+
+// NewPolicy parses provided rules over default ones. Rules referring to
+// missing rules or to themselves are rejected
+func NewPolicy(rules map[string]string) (*Policy, error) {
+	policy := &Policy{rules: map[string]check{}}
+	for _, source := range []map[string]string{DefaultRules, rules} {
+		for name, expression := range source {
+			parsed, err := parseRule(expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule '%s': %s", name, err)
+			}
+			policy.rules[name] = parsed
+		}
+	}
+
+	for name := range policy.rules {
+		err := policy.validate(name, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// NewDefaultPolicy returns policy of default rules
+func NewDefaultPolicy() *Policy {
+	policy, err := NewPolicy(map[string]string{})
+	if err != nil {
+		// default rules are valid, this is programming error
+		panic(err)
+	}
+	return policy
+}
+
+// Load reads policy file, which is json object mapping names of rules to
+// their expressions. Empty path means that default rules are used
+func Load(path string) (*Policy, error) {
+	rules := map[string]string{}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, &rules)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing policy file '%s': %s",
+				path, err)
+		}
+	}
+	return NewPolicy(rules)
+}
+
+// validate checks that rules referred by rule exist and do not refer back
+// to it. visiting contains rules being validated up the stack
+func (p *Policy) validate(name string, visiting map[string]bool) error {
+	if visiting[name] {
+		return fmt.Errorf("rule '%s' refers to itself", name)
+	}
+	rule, ok := p.rules[name]
+	if !ok {
+		return fmt.Errorf("rule '%s' is not defined", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+	for _, reference := range rule.references() {
+		err := p.validate(reference, visiting)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enforce checks if rule allows api call to user having provided roles
+func (p *Policy) Enforce(rule string, roles []string) bool {
+	parsed, ok := p.rules[rule]
+	if !ok {
+		log.Logger.Debugf("Rule '%s' is not defined, '%s' is used", rule,
+			RuleDefault)
+		parsed = p.rules[RuleDefault]
+	}
+	roleSet := map[string]bool{}
+	for _, role := range roles {
+		roleSet[role] = true
+	}
+	return parsed.allows(p, roleSet)
+}
+
+// check is parsed expression of rule
+type check interface {
+	allows(p *Policy, roles map[string]bool) bool
+	references() []string
+}
+
+type constantCheck bool
+
+func (c constantCheck) allows(p *Policy, roles map[string]bool) bool {
+	return bool(c)
+}
+
+func (c constantCheck) references() []string {
+	return nil
+}
+
+type roleCheck string
+
+func (c roleCheck) allows(p *Policy, roles map[string]bool) bool {
+	return roles[string(c)]
+}
+
+func (c roleCheck) references() []string {
+	return nil
+}
+
+type ruleCheck string
+
+func (c ruleCheck) allows(p *Policy, roles map[string]bool) bool {
+	rule, ok := p.rules[string(c)]
+	return ok && rule.allows(p, roles)
+}
+
+func (c ruleCheck) references() []string {
+	return []string{string(c)}
+}
+
+type notCheck struct {
+	check check
+}
+
+func (c notCheck) allows(p *Policy, roles map[string]bool) bool {
+	return !c.check.allows(p, roles)
+}
+
+func (c notCheck) references() []string {
+	return c.check.references()
+}
+
+// groupCheck is a conjunction or disjunction of checks
+type groupCheck struct {
+	checks []check
+	all    bool
+}
+
+func (c groupCheck) allows(p *Policy, roles map[string]bool) bool {
+	for _, nested := range c.checks {
+		if nested.allows(p, roles) != c.all {
+			return !c.all
+		}
+	}
+	return c.all
+}
+
+func (c groupCheck) references() []string {
+	result := []string{}
+	for _, nested := range c.checks {
+		result = append(result, nested.references()...)
+	}
+	return result
+}
+
+// parser builds check of expression tokens using recursive descent. "or"
+// has lower precedence than "and", which has lower precedence than "not"
+type parser struct {
+	tokens []string
+	index  int
+}
+
+var tokenReplacer = strings.NewReplacer("(", " ( ", ")", " ) ")
+
+func parseRule(expression string) (check, error) {
+	p := &parser{tokens: strings.Fields(tokenReplacer.Replace(expression))}
+	if len(p.tokens) == 0 {
+		return constantCheck(true), nil
+	}
+	parsed, err := p.parseGroup("or")
+	if err != nil {
+		return nil, err
+	}
+	if p.index < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s'", p.tokens[p.index])
+	}
+	return parsed, nil
+}
+
+func (p *parser) peek() string {
+	if p.index < len(p.tokens) {
+		return p.tokens[p.index]
+	}
+	return ""
+}
+
+// parseGroup parses checks joined with operator, "and" groups are parsed
+// as operands of "or" groups
+func (p *parser) parseGroup(operator string) (check, error) {
+	parseOperand := p.parseNot
+	if operator == "or" {
+		parseOperand = func() (check, error) { return p.parseGroup("and") }
+	}
+	checks := []check{}
+	for {
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, operand)
+		if p.peek() != operator {
+			break
+		}
+		p.index++
+	}
+	if len(checks) == 1 {
+		return checks[0], nil
+	}
+	return groupCheck{checks: checks, all: operator == "and"}, nil
+}
+
+func (p *parser) parseNot() (check, error) {
+	if p.peek() == "not" {
+		p.index++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCheck{operand}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *parser) parseAtom() (check, error) {
+	token := p.peek()
+	if token == "" {
+		return nil, errors.New("unexpected end of rule")
+	}
+	p.index++
+	switch token {
+	case "(":
+		parsed, err := p.parseGroup("or")
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing ')'")
+		}
+		p.index++
+		return parsed, nil
+	case "@":
+		return constantCheck(true), nil
+	case "!":
+		return constantCheck(false), nil
+	}
+
+	index := strings.Index(token, ":")
+	if index <= 0 || index == len(token)-1 {
+		return nil, fmt.Errorf("unexpected '%s'", token)
+	}
+	kind, value := token[:index], token[index+1:]
+	switch kind {
+	case "role":
+		return roleCheck(value), nil
+	case "rule":
+		return ruleCheck(value), nil
+	}
+	return nil, fmt.Errorf("unsupported check '%s'", kind)
+}
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/logging"
+)
+
+func TestEnforce(t *testing.T) {
+	log.InitializeLogger(ioutil.Discard, false, "critical")
+	policy, err := NewPolicy(map[string]string{
+		RuleAdmin:                "role:admin or role:cloud_admin",
+		"reader":                 "role:reader or role:observer",
+		RuleVisualizationsDelete: "rule:admin or (not rule:reader and role:member)",
+		RuleTemplatesCreate:      "!",
+		RuleTemplatesGet:         "@",
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		rule     string
+		roles    []string
+		expected bool
+	}{
+		{RuleVisualizationsGet, []string{}, true},
+		{RuleVisualizationsDelete, []string{"member"}, true},
+		{RuleVisualizationsDelete, []string{"member", "observer"}, false},
+		{RuleVisualizationsDelete, []string{"reader", "cloud_admin"}, true},
+		{RuleVisualizationsDelete, []string{}, false},
+		{RuleTemplatesCreate, []string{"admin"}, false},
+		{RuleTemplatesGet, []string{}, true},
+		{RuleAuditGet, []string{"cloud_admin"}, true},
+		{RuleAuditGet, []string{"member"}, false},
+		{"unknown:rule", []string{"admin"}, true},
+		{"unknown:rule", []string{"member"}, false},
+	}
+	for _, testCase := range tests {
+		assert.Equal(t, testCase.expected, policy.Enforce(testCase.rule,
+			testCase.roles), "%s %v", testCase.rule, testCase.roles)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	tests := []struct {
+		rule          string
+		expectedError string
+	}{
+		{"role:admin or", "invalid rule 'test': unexpected end of rule"},
+		{"(role:admin", "invalid rule 'test': missing ')'"},
+		{"role:admin)", "invalid rule 'test': unexpected ')'"},
+		{"role:admin role:member", "invalid rule 'test': unexpected 'role:member'"},
+		{"admin", "invalid rule 'test': unexpected 'admin'"},
+		{"is_admin:True", "invalid rule 'test': unsupported check 'is_admin'"},
+		{"rule:missing", "rule 'missing' is not defined"},
+		{"rule:test", "rule 'test' refers to itself"},
+	}
+	for _, testCase := range tests {
+		policy, err := NewPolicy(map[string]string{"test": testCase.rule})
+		assert.Nil(t, policy, testCase.rule)
+		assert.EqualError(t, err, testCase.expectedError, testCase.rule)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	directory, err := ioutil.TempDir("", "policy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	path := filepath.Join(directory, "policy.json")
+	err = ioutil.WriteFile(path, []byte(`{"admin": "role:cloud_admin"}`), 0600)
+	assert.Nil(t, err)
+	policy, err := Load(path)
+	assert.Nil(t, err)
+	assert.True(t, policy.Enforce(RuleAdminAPI, []string{"cloud_admin"}))
+	assert.False(t, policy.Enforce(RuleAdminAPI, []string{"admin"}))
+
+	policy, err = Load("")
+	assert.Nil(t, err)
+	assert.True(t, policy.Enforce(RuleAdminAPI, []string{"admin"}))
+
+	err = ioutil.WriteFile(path, []byte(`["role:admin"]`), 0600)
+	assert.Nil(t, err)
+	_, err = Load(path)
+	assert.Contains(t, err.Error(), "error parsing policy file")
+}
+
+func TestSamplePolicy(t *testing.T) {
+	policy, err := Load("../../etc/platformvisibility/visualization-api/policy.json")
+	assert.Nil(t, err)
+	assert.True(t, policy.Enforce(RuleVisualizationsGet, []string{"reader"}))
+	assert.False(t, policy.Enforce(RuleVisualizationsCreate, []string{"reader"}))
+	assert.True(t, policy.Enforce(RuleVisualizationsCreate, []string{"_member_"}))
+	assert.True(t, policy.Enforce(RuleAuditGet, []string{"cloud_admin"}))
+}
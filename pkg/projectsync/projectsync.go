@@ -0,0 +1,341 @@
+// Package projectsync keeps grafana organizations in line with openstack
+// projects. Every project has its own organization named after project name
+// and id, organization is created on first login of project user. Projects
+// are renamed and removed in keystone without notice, so organizations are
+// periodically matched to projects by organization mappings stored in db,
+// organizations of renamed projects are renamed and organizations of removed
+// projects are archived or removed. Names of organizations are only displayed,
+// they are never parsed
+package projectsync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/logging"
+	"visualization-api/pkg/openstack"
+)
+
+// ActionRenamed means that project was renamed and its organization got new
+// name
+const ActionRenamed = "renamed"
+
+// ActionArchived means that project was removed and its organization was
+// renamed with ArchivedPrefix, visualizations of organization are kept
+const ActionArchived = "archived"
+
+// ActionDeleted means that project was removed and its organization was
+// removed from grafana together with its visualizations
+const ActionDeleted = "deleted"
+
+// ActionDuplicateFlagged means that organization, which is not mapped to
+// project, has name of project organization, so mapped organization can not
+// be renamed. Duplicates are left to administrator
+const ActionDuplicateFlagged = "duplicate_flagged"
+
+// ArchivedPrefix is prepended to names of archived organizations
+const ArchivedPrefix = "archived-"
+
+// OrganizationName returns name of grafana organization of project
+func OrganizationName(projectName, projectID string) string {
+	return projectName + "-" + projectID
+}
+
+// ReportEntry describes single organization changed by synchronization
+type ReportEntry struct {
+	OrganizationID   string `json:"organizationId"`
+	OrganizationName string `json:"organizationName"`
+	ProjectID        string `json:"projectId"`
+	NewName          string `json:"newName,omitempty"`
+	Action           string `json:"action"`
+	// RemovedVisualizations is amount of visualizations removed together
+	// with organization
+	RemovedVisualizations int64  `json:"removedVisualizations,omitempty"`
+	Error                 string `json:"error,omitempty"`
+}
+
+// Report describes results of single synchronization run. Dry run report
+// lists changes, which would be made, without making them
+type Report struct {
+	DryRun     bool          `json:"dryRun"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Entries    []ReportEntry `json:"entries"`
+	Errors     []string      `json:"errors"`
+}
+
+// SyncerInterface represents what functionality we are expecting from
+// project synchronization. It was created to have mockable architecture
+type SyncerInterface interface {
+	Run(bool) *Report
+	LastReport() *Report
+}
+
+// Syncer synchronizes openstack projects and grafana organizations
+type Syncer struct {
+	openstack           openstack.ClientInterface
+	grafana             grafanaclient.SessionInterface
+	databaseManager     db.DatabaseManager
+	deleteOrganizations bool
+
+	// lock guarantees that only one run is performed at a time
+	// and protects lastReport
+	lock       sync.Mutex
+	lastReport *Report
+}
+
+// NewSyncer is Syncer constructor. Organizations of removed projects are
+// removed if deleteOrganizations is set, otherwise they are archived
+func NewSyncer(openstackClient openstack.ClientInterface,
+	grafana grafanaclient.SessionInterface, databaseManager db.DatabaseManager,
+	deleteOrganizations bool) *Syncer {
+	return &Syncer{
+		openstack:           openstackClient,
+		grafana:             grafana,
+		databaseManager:     databaseManager,
+		deleteOrganizations: deleteOrganizations,
+	}
+}
+
+// Start runs synchronization periodically in background goroutine
+func (s *Syncer) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.Run(false)
+		}
+	}()
+}
+
+// LastReport returns report of the latest finished run, nil if there was
+// none. Dry runs are not stored
+func (s *Syncer) LastReport() *Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastReport
+}
+
+// Run performs synchronization of all projects, nothing is changed on dry run
+func (s *Syncer) Run(dryRun bool) *Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	log.Logger.Infof("Starting synchronization of projects and grafana "+
+		"organizations, dry run: %t", dryRun)
+	report := &Report{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+		Entries:   []ReportEntry{},
+		Errors:    []string{},
+	}
+
+	projects, err := s.openstack.ListProjects()
+	if err != nil {
+		report.Errors = append(report.Errors,
+			fmt.Sprintf("Unable to get projects from keystone: '%s'", err))
+	}
+	organizations, err := s.grafana.GetOrganizations()
+	if err != nil {
+		report.Errors = append(report.Errors,
+			fmt.Sprintf("Unable to get organizations from grafana: '%s'", err))
+	}
+	mappings, err := s.databaseManager.QueryOrganizationMappings()
+	if err != nil {
+		report.Errors = append(report.Errors,
+			fmt.Sprintf("Unable to get organization mappings: '%s'", err))
+	}
+	// organizations are never removed without complete list of projects
+	if len(report.Errors) == 0 {
+		s.synchronize(projects, organizations, mappings, dryRun, report)
+	}
+
+	report.FinishedAt = time.Now()
+	log.Logger.Infof("Finished synchronization of projects with %d changes "+
+		"and %d errors", len(report.Entries), len(report.Errors))
+	if !dryRun {
+		s.lastReport = report
+	}
+	return report
+}
+
+func (s *Syncer) synchronize(projects []openstack.Project,
+	organizations []grafanaclient.OrgList,
+	mappings []*models.OrganizationMapping, dryRun bool, report *Report) {
+	/*
+		1 - organizations are matched to projects by mappings, unmapped
+			organizations are never changed. Mapped organizations missing
+			in grafana are left to remap of organizations
+		2 - organization of existing project is renamed if it has not
+			expected name. Other organization having expected name is
+			flagged
+		3 - organizations of projects missing in keystone are archived or
+			removed, unless they are already archived or mapped to existing
+			project too
+	*/
+	projectsByID := map[string]openstack.Project{}
+	for _, project := range projects {
+		projectsByID[project.ID] = project
+	}
+	organizationsByID := map[string]grafanaclient.OrgList{}
+	for _, organization := range organizations {
+		organizationsByID[strconv.Itoa(organization.ID)] = organization
+	}
+	usedOrganizations := map[string]bool{}
+	for _, mapping := range mappings {
+		if _, exists := projectsByID[mapping.ProjectID]; exists {
+			usedOrganizations[mapping.OrganizationID] = true
+		}
+	}
+
+	removedMappings := []*models.OrganizationMapping{}
+	for _, mapping := range mappings {
+		organization, exists := organizationsByID[mapping.OrganizationID]
+		if !exists {
+			continue
+		}
+		if project, exists := projectsByID[mapping.ProjectID]; exists {
+			s.synchronizeProject(project, organization, organizations,
+				dryRun, report)
+			continue
+		}
+		if strings.HasPrefix(organization.Name, ArchivedPrefix) ||
+			usedOrganizations[mapping.OrganizationID] {
+			continue
+		}
+		removedMappings = append(removedMappings, mapping)
+	}
+
+	if len(projects) == 0 && len(removedMappings) > 0 {
+		report.Errors = append(report.Errors, "Keystone returned no projects, "+
+			"organizations of removed projects are kept")
+		removedMappings = nil
+	}
+	for _, mapping := range removedMappings {
+		s.removeOrganization(mapping.ProjectID,
+			organizationsByID[mapping.OrganizationID], dryRun, report)
+	}
+}
+
+// renameMapping stores new name of organization in mapping of project, it is
+// displayed by api
+func (s *Syncer) renameMapping(projectID string,
+	organization grafanaclient.OrgList, name string) error {
+	return s.databaseManager.SaveOrganizationMapping(
+		&models.OrganizationMapping{
+			ProjectID:        projectID,
+			OrganizationID:   strconv.Itoa(organization.ID),
+			OrganizationName: name,
+		})
+}
+
+func (s *Syncer) synchronizeProject(project openstack.Project,
+	organization grafanaclient.OrgList, organizations []grafanaclient.OrgList,
+	dryRun bool, report *Report) {
+	expectedName := OrganizationName(project.Name, project.ID)
+	if organization.Name == expectedName {
+		return
+	}
+
+	for _, duplicate := range organizations {
+		if duplicate.Name == expectedName {
+			report.Entries = append(report.Entries, ReportEntry{
+				OrganizationID:   strconv.Itoa(duplicate.ID),
+				OrganizationName: duplicate.Name,
+				ProjectID:        project.ID,
+				Action:           ActionDuplicateFlagged,
+			})
+		}
+	}
+
+	entry := ReportEntry{
+		OrganizationID:   strconv.Itoa(organization.ID),
+		OrganizationName: organization.Name,
+		ProjectID:        project.ID,
+		NewName:          expectedName,
+		Action:           ActionRenamed,
+	}
+	if !dryRun {
+		log.Logger.Infof("Renaming organization '%s' to '%s'",
+			organization.Name, expectedName)
+		err := s.grafana.UpdateOrganization(organization.ID, expectedName)
+		if err == nil {
+			err = s.renameMapping(project.ID, organization, expectedName)
+		} else {
+			log.Logger.Errorf("Error during performing grafana call "+
+				"for organization update %s", err)
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+	}
+	report.Entries = append(report.Entries, entry)
+}
+
+// removeOrganization archives or deletes organization of removed project,
+// mapping of deleted organization is removed as well
+func (s *Syncer) removeOrganization(projectID string,
+	organization grafanaclient.OrgList, dryRun bool, report *Report) {
+	entry := ReportEntry{
+		OrganizationID:   strconv.Itoa(organization.ID),
+		OrganizationName: organization.Name,
+		ProjectID:        projectID,
+		Action:           ActionArchived,
+		NewName:          ArchivedPrefix + organization.Name,
+	}
+	if s.deleteOrganizations {
+		entry.Action = ActionDeleted
+		entry.NewName = ""
+	}
+	defer func() {
+		report.Entries = append(report.Entries, entry)
+	}()
+	if dryRun {
+		return
+	}
+
+	if !s.deleteOrganizations {
+		log.Logger.Infof("Archiving organization '%s' of removed project",
+			organization.Name)
+		err := s.grafana.UpdateOrganization(organization.ID, entry.NewName)
+		if err == nil {
+			err = s.renameMapping(projectID, organization, entry.NewName)
+		} else {
+			log.Logger.Errorf("Error during performing grafana call "+
+				"for organization update %s", err)
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		return
+	}
+
+	// visualizations are removed first, otherwise they would be left
+	// without organization if grafana call succeeded and db call failed
+	log.Logger.Infof("Removing organization '%s' of removed project",
+		organization.Name)
+	removed, err := s.databaseManager.DeleteOrganizationVisualizations(
+		entry.OrganizationID)
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+	entry.RemovedVisualizations = removed
+	err = s.grafana.DeleteOrganization(organization.ID)
+	if err != nil {
+		log.Logger.Errorf("Error during performing grafana call "+
+			"for organization deletion %s", err)
+		entry.Error = err.Error()
+		return
+	}
+	err = s.databaseManager.DeleteOrganizationMapping(projectID)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+}
@@ -0,0 +1,356 @@
+package projectsync_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/openstack"
+	"visualization-api/pkg/openstack/mock"
+	"visualization-api/pkg/projectsync"
+)
+
+const (
+	keptID    = "0123456789abcdef0123456789abcdef"
+	renamedID = "11111111111111111111111111111111"
+	removedID = "22222222222222222222222222222222"
+	customID  = "33333333333333333333333333333333"
+	sharedID  = "44444444444444444444444444444444"
+	missingID = "55555555555555555555555555555555"
+)
+
+var testProjects = []openstack.Project{
+	{ID: keptID, Name: "kept", Enabled: true},
+	{ID: renamedID, Name: "new-name", Enabled: true},
+}
+
+var testOrganizations = []grafanaclient.OrgList{
+	{ID: 1, Name: "Main Org."},
+	{ID: 7, Name: "kept-" + keptID},
+	{ID: 3, Name: "old-name-" + renamedID},
+	{ID: 4, Name: "removed-" + removedID},
+	// organizations, which are not mapped, are never changed
+	{ID: 5, Name: "archived-older-" + removedID},
+	{ID: 6, Name: "copy-" + keptID},
+	{ID: 2, Name: "team-prod"},
+}
+
+var testMappings = []*models.OrganizationMapping{
+	{ProjectID: keptID, OrganizationID: "7",
+		OrganizationName: "kept-" + keptID},
+	{ProjectID: renamedID, OrganizationID: "3",
+		OrganizationName: "old-name-" + renamedID},
+	{ProjectID: removedID, OrganizationID: "4",
+		OrganizationName: "removed-" + removedID},
+}
+
+func TestSyncerRun(t *testing.T) {
+	renamed := projectsync.ReportEntry{OrganizationID: "3",
+		OrganizationName: "old-name-" + renamedID, ProjectID: renamedID,
+		NewName: "new-name-" + renamedID, Action: projectsync.ActionRenamed}
+	archived := projectsync.ReportEntry{OrganizationID: "4",
+		OrganizationName: "removed-" + removedID, ProjectID: removedID,
+		NewName: "archived-removed-" + removedID,
+		Action:  projectsync.ActionArchived}
+	deleted := projectsync.ReportEntry{OrganizationID: "4",
+		OrganizationName: "removed-" + removedID, ProjectID: removedID,
+		Action: projectsync.ActionDeleted}
+
+	failedRename := renamed
+	failedRename.Error = "name taken"
+	removedWithVisualizations := deleted
+	removedWithVisualizations.RemovedVisualizations = 2
+	failedDeletion := deleted
+	failedDeletion.Error = "test"
+
+	tests := []struct {
+		description         string
+		dryRun              bool
+		deleteOrganizations bool
+		renameErr           error
+		deletionErr         error
+		dbErr               error
+		expectedEntries     []projectsync.ReportEntry
+	}{
+		{
+			description:     "organizations of removed projects are archived",
+			expectedEntries: []projectsync.ReportEntry{renamed, archived},
+		},
+		{
+			description:         "organizations of removed projects are deleted",
+			deleteOrganizations: true,
+			expectedEntries:     []projectsync.ReportEntry{renamed, removedWithVisualizations},
+		},
+		{
+			description:         "dry run makes no changes",
+			dryRun:              true,
+			deleteOrganizations: true,
+			expectedEntries:     []projectsync.ReportEntry{renamed, deleted},
+		},
+		{
+			description:     "failed rename is reported",
+			renameErr:       grafanaclient.Exists{},
+			expectedEntries: []projectsync.ReportEntry{failedRename, archived},
+		},
+		{
+			description:         "organization is kept if visualizations are not removed",
+			deleteOrganizations: true,
+			dbErr:               errors.New("test"),
+			expectedEntries:     []projectsync.ReportEntry{renamed, failedDeletion},
+		},
+		{
+			description:         "failed organization deletion is reported",
+			deleteOrganizations: true,
+			deletionErr:         errors.New("test"),
+			expectedEntries: []projectsync.ReportEntry{renamed,
+				func() projectsync.ReportEntry {
+					entry := removedWithVisualizations
+					entry.Error = "test"
+					return entry
+				}()},
+		},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+		mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+		organizations := make([]grafanaclient.OrgList, len(testOrganizations))
+		copy(organizations, testOrganizations)
+		mockedOpenstack.EXPECT().ListProjects().Return(testProjects, nil)
+		mockedGrafana.EXPECT().GetOrganizations().Return(organizations, nil)
+		mockedDatabaseManager.EXPECT().QueryOrganizationMappings().Return(
+			testMappings, nil)
+		if !testCase.dryRun {
+			mockedGrafana.EXPECT().UpdateOrganization(3,
+				"new-name-"+renamedID).Return(testCase.renameErr)
+			if testCase.renameErr == nil {
+				mockedDatabaseManager.EXPECT().SaveOrganizationMapping(
+					&models.OrganizationMapping{ProjectID: renamedID,
+						OrganizationID:   "3",
+						OrganizationName: "new-name-" + renamedID}).Return(nil)
+			}
+			if testCase.deleteOrganizations {
+				mockedDatabaseManager.EXPECT().DeleteOrganizationVisualizations(
+					"4").Return(int64(2), testCase.dbErr)
+				if testCase.dbErr == nil {
+					mockedGrafana.EXPECT().DeleteOrganization(4).Return(
+						testCase.deletionErr)
+				}
+				if testCase.dbErr == nil && testCase.deletionErr == nil {
+					mockedDatabaseManager.EXPECT().DeleteOrganizationMapping(
+						removedID).Return(nil)
+				}
+			} else {
+				mockedGrafana.EXPECT().UpdateOrganization(4,
+					"archived-removed-"+removedID).Return(nil)
+				mockedDatabaseManager.EXPECT().SaveOrganizationMapping(
+					&models.OrganizationMapping{ProjectID: removedID,
+						OrganizationID:   "4",
+						OrganizationName: "archived-removed-" + removedID,
+					}).Return(nil)
+			}
+		}
+
+		syncer := projectsync.NewSyncer(mockedOpenstack, mockedGrafana,
+			mockedDatabaseManager, testCase.deleteOrganizations)
+		report := syncer.Run(testCase.dryRun)
+		assert.Equal(t, testCase.expectedEntries, report.Entries,
+			testCase.description)
+		assert.Equal(t, []string{}, report.Errors, testCase.description)
+		assert.Equal(t, testCase.dryRun, report.DryRun, testCase.description)
+		if testCase.dryRun {
+			assert.Nil(t, syncer.LastReport(), "dry run must not be stored")
+		} else {
+			assert.Equal(t, report, syncer.LastReport(),
+				"last report must be stored")
+		}
+	}
+}
+
+func TestSyncerSkipsUnmappedOrganizations(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+	// names of organizations are not parsed, organizations are matched to
+	// projects only by mappings
+	mockedOpenstack.EXPECT().ListProjects().Return(testProjects, nil)
+	mockedGrafana.EXPECT().GetOrganizations().Return([]grafanaclient.OrgList{
+		{ID: 3, Name: "old-name-" + renamedID},
+		{ID: 4, Name: "removed-" + removedID},
+	}, nil)
+	mockedDatabaseManager.EXPECT().QueryOrganizationMappings().Return(
+		[]*models.OrganizationMapping{}, nil)
+
+	report := projectsync.NewSyncer(mockedOpenstack, mockedGrafana,
+		mockedDatabaseManager, false).Run(false)
+	assert.Equal(t, []projectsync.ReportEntry{}, report.Entries)
+	assert.Equal(t, []string{}, report.Errors)
+}
+
+func TestSyncerMappedOrganizations(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+	mockedOpenstack.EXPECT().ListProjects().Return(testProjects, nil)
+	mockedGrafana.EXPECT().GetOrganizations().Return([]grafanaclient.OrgList{
+		{ID: 7, Name: "kept-" + keptID},
+		{ID: 3, Name: "team-dev"},
+		{ID: 2, Name: "team-prod"},
+	}, nil)
+	mockedDatabaseManager.EXPECT().QueryOrganizationMappings().Return(
+		[]*models.OrganizationMapping{
+			{ProjectID: keptID, OrganizationID: "7",
+				OrganizationName: "kept-" + keptID},
+			// organization was renamed in grafana
+			{ProjectID: renamedID, OrganizationID: "3",
+				OrganizationName: "team-dev"},
+			// organization of removed project has no project id in name
+			{ProjectID: customID, OrganizationID: "2",
+				OrganizationName: "team-prod"},
+			// organization of removed project is used by existing one
+			{ProjectID: sharedID, OrganizationID: "7",
+				OrganizationName: "kept-" + keptID},
+			// missing organization is left to remap
+			{ProjectID: missingID, OrganizationID: "10",
+				OrganizationName: "missing-" + missingID},
+		}, nil)
+	mockedGrafana.EXPECT().UpdateOrganization(3, "new-name-"+renamedID).Return(
+		nil)
+	mockedDatabaseManager.EXPECT().SaveOrganizationMapping(
+		&models.OrganizationMapping{ProjectID: renamedID,
+			OrganizationID: "3", OrganizationName: "new-name-" + renamedID,
+		}).Return(nil)
+	mockedGrafana.EXPECT().UpdateOrganization(2, "archived-team-prod").Return(
+		nil)
+	mockedDatabaseManager.EXPECT().SaveOrganizationMapping(
+		&models.OrganizationMapping{ProjectID: customID,
+			OrganizationID: "2", OrganizationName: "archived-team-prod",
+		}).Return(nil)
+
+	report := projectsync.NewSyncer(mockedOpenstack, mockedGrafana,
+		mockedDatabaseManager, false).Run(false)
+	assert.Equal(t, []projectsync.ReportEntry{
+		{OrganizationID: "3", OrganizationName: "team-dev",
+			ProjectID: renamedID, NewName: "new-name-" + renamedID,
+			Action: projectsync.ActionRenamed},
+		{OrganizationID: "2", OrganizationName: "team-prod",
+			ProjectID: customID, NewName: "archived-team-prod",
+			Action: projectsync.ActionArchived},
+	}, report.Entries)
+	assert.Equal(t, []string{}, report.Errors)
+}
+
+func TestSyncerKeepsMappedOrganization(t *testing.T) {
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+	// mapped organization is renamed even if other organization has
+	// expected name, the other one is flagged
+	mockedOpenstack.EXPECT().ListProjects().Return(testProjects[:1], nil)
+	mockedGrafana.EXPECT().GetOrganizations().Return([]grafanaclient.OrgList{
+		{ID: 7, Name: "kept-" + keptID},
+		{ID: 6, Name: "copy-" + keptID},
+	}, nil)
+	mockedDatabaseManager.EXPECT().QueryOrganizationMappings().Return(
+		[]*models.OrganizationMapping{{ProjectID: keptID,
+			OrganizationID: "6", OrganizationName: "copy-" + keptID}}, nil)
+	mockedGrafana.EXPECT().UpdateOrganization(6, "kept-"+keptID).Return(
+		grafanaclient.Exists{})
+
+	report := projectsync.NewSyncer(mockedOpenstack, mockedGrafana,
+		mockedDatabaseManager, false).Run(false)
+	assert.Equal(t, []projectsync.ReportEntry{
+		{OrganizationID: "7", OrganizationName: "kept-" + keptID,
+			ProjectID: keptID, Action: projectsync.ActionDuplicateFlagged},
+		{OrganizationID: "6", OrganizationName: "copy-" + keptID,
+			ProjectID: keptID, NewName: "kept-" + keptID,
+			Action: projectsync.ActionRenamed,
+			Error:  grafanaclient.Exists{}.Error()},
+	}, report.Entries)
+	assert.Equal(t, []string{}, report.Errors)
+}
+
+func TestSyncerRunErrors(t *testing.T) {
+	tests := []struct {
+		description      string
+		projects         []openstack.Project
+		projectsErr      error
+		organizationsErr error
+		mappingsErr      error
+		expectedErrors   []string
+	}{
+		{
+			description: "keystone is not available",
+			projectsErr: errors.New("test"),
+			expectedErrors: []string{
+				"Unable to get projects from keystone: 'test'"},
+		},
+		{
+			description:      "grafana is not available",
+			projects:         testProjects,
+			organizationsErr: errors.New("test"),
+			expectedErrors: []string{
+				"Unable to get organizations from grafana: 'test'"},
+		},
+		{
+			description: "database is not available",
+			projects:    testProjects,
+			mappingsErr: errors.New("test"),
+			expectedErrors: []string{
+				"Unable to get organization mappings: 'test'"},
+		},
+		{
+			description: "no projects are returned",
+			projects:    []openstack.Project{},
+			expectedErrors: []string{"Keystone returned no projects, " +
+				"organizations of removed projects are kept"},
+		},
+	}
+
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedOpenstack := mock_openstack.NewMockClientInterface(mockCtrl)
+		mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+
+		mockedOpenstack.EXPECT().ListProjects().Return(testCase.projects,
+			testCase.projectsErr)
+		mockedGrafana.EXPECT().GetOrganizations().Return(
+			[]grafanaclient.OrgList{{ID: 4, Name: "removed-" + removedID}},
+			testCase.organizationsErr)
+		mockedDatabaseManager.EXPECT().QueryOrganizationMappings().Return(
+			testMappings[2:], testCase.mappingsErr)
+
+		report := projectsync.NewSyncer(mockedOpenstack, mockedGrafana,
+			mockedDatabaseManager, true).Run(false)
+		assert.Equal(t, []projectsync.ReportEntry{}, report.Entries,
+			testCase.description)
+		assert.Equal(t, testCase.expectedErrors, report.Errors,
+			testCase.description)
+	}
+}
@@ -0,0 +1,299 @@
+package reconciler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"visualization-api/pkg/database"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/logging"
+)
+
+// ActionReuploaded means that dashboard stored in db was missing in grafana
+// and was uploaded again
+const ActionReuploaded = "reuploaded"
+
+// ActionOrphanFlagged means that grafana dashboard has no matching db entry
+const ActionOrphanFlagged = "orphan_flagged"
+
+// ActionOrphanDeleted means that grafana dashboard had no matching db entry
+// and was removed from grafana
+const ActionOrphanDeleted = "orphan_deleted"
+
+// ActionSkipped means that dashboard has journaled grafana operations, which
+// are performed by outbox worker, so it is not reconciled
+const ActionSkipped = "skipped"
+
+// ReportEntry describes single drift between db and grafana
+type ReportEntry struct {
+	OrganizationID string `json:"organizationId"`
+	DashboardID    string `json:"dashboardId,omitempty"`
+	Name           string `json:"name"`
+	Slug           string `json:"slug"`
+	Action         string `json:"action"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Report describes results of single reconciliation run
+type Report struct {
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Entries    []ReportEntry `json:"entries"`
+	Errors     []string      `json:"errors"`
+}
+
+// RunnerInterface represents what functionality we are expecting from
+// reconciler. It was created to have mockable architecture
+type RunnerInterface interface {
+	Run() *Report
+	LastReport() *Report
+}
+
+// Reconciler repairs drift between dashboards stored in db and grafana
+type Reconciler struct {
+	databaseManager db.DatabaseManager
+	grafana         grafanaclient.SessionInterface
+	deleteOrphans   bool
+	maxAttempts     int
+
+	// lock guarantees that only one run is performed at a time
+	// and protects lastReport
+	lock       sync.Mutex
+	lastReport *Report
+}
+
+// NewReconciler is Reconciler constructor. maxAttempts is maximum amount of
+// attempts outbox worker performs journaled grafana operation with
+func NewReconciler(databaseManager db.DatabaseManager,
+	grafana grafanaclient.SessionInterface, deleteOrphans bool,
+	maxAttempts int) *Reconciler {
+	return &Reconciler{
+		databaseManager: databaseManager,
+		grafana:         grafana,
+		deleteOrphans:   deleteOrphans,
+		maxAttempts:     maxAttempts,
+	}
+}
+
+// Start runs reconciliation periodically in background goroutine
+func (r *Reconciler) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			r.Run()
+		}
+	}()
+}
+
+// LastReport returns report of the latest finished run, nil if there was none
+func (r *Reconciler) LastReport() *Report {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lastReport
+}
+
+// Run performs reconciliation of all organizations having visualizations
+func (r *Reconciler) Run() *Report {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	log.Logger.Info("Starting reconciliation of db and grafana dashboards")
+	report := &Report{
+		StartedAt: time.Now(),
+		Entries:   []ReportEntry{},
+		Errors:    []string{},
+	}
+
+	organizationIDs, err := r.databaseManager.QueryOrganizationIDs()
+	if err != nil {
+		report.Errors = append(report.Errors,
+			fmt.Sprintf("Unable to get organizations from db: '%s'", err))
+	}
+	for _, organizationID := range organizationIDs {
+		err = r.reconcileOrganization(organizationID, report)
+		if err != nil {
+			log.Logger.Errorf("Error reconciling organization '%s': '%s'",
+				organizationID, err)
+			report.Errors = append(report.Errors, fmt.Sprintf(
+				"Unable to reconcile organization '%s': '%s'", organizationID, err))
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	log.Logger.Infof("Finished reconciliation with %d drifts and %d errors",
+		len(report.Entries), len(report.Errors))
+	r.lastReport = report
+	return report
+}
+
+// pendingOperations returns ids of dashboards of organization, which have
+// journaled grafana operations, that are still retried by outbox worker
+func (r *Reconciler) pendingOperations(organizationID string,
+	pendingDashboards map[string]bool) error {
+	operations, err := r.databaseManager.QueryGrafanaOperations(time.Now(),
+		r.maxAttempts)
+	if err != nil {
+		return err
+	}
+	for _, operation := range operations {
+		if operation.OrganizationID == organizationID {
+			pendingDashboards[operation.DashboardID] = true
+		}
+	}
+	return nil
+}
+
+// storeSlug stores slug of reuploaded dashboard to db and returns error
+// message for report if it is not stored. Dashboards are read before grafana
+// calls, so only slug is written back and only to unchanged dashboard
+func (r *Reconciler) storeSlug(dashboard *models.Dashboard,
+	slug string) string {
+	if dashboard.Slug == slug {
+		return ""
+	}
+	updated, err := r.databaseManager.UpdateDashboardSlug(dashboard.ID,
+		dashboard.Slug, slug)
+	if err != nil {
+		log.Logger.Errorf("Error on storing slug of dashboard '%s': '%s'",
+			dashboard.ID, err)
+		return err.Error()
+	}
+	if !updated {
+		log.Logger.Infof("Dashboard '%s' was changed or removed during "+
+			"reconciliation, slug '%s' is not stored", dashboard.ID, slug)
+		return "dashboard was changed or removed during reconciliation"
+	}
+	return ""
+}
+
+func (r *Reconciler) reconcileOrganization(organizationID string,
+	report *Report) error {
+	/*
+		1 - get all dashboards of organization from grafana, then journaled
+			grafana operations and dashboards from db. Operations are got
+			once again after db dashboards, so operations started during
+			the scan are taken into account as well
+		2 - dashboards having journaled operations are performed right now
+			by request handlers or outbox worker, so they are skipped
+		3 - upload to grafana every other db dashboard with empty slug or
+			slug missing in grafana, store received slug to db. Only slug
+			is stored and only if dashboard was not changed or removed
+			during the upload, otherwise the uploaded dashboard is left
+			for the next run
+		4 - grafana dashboards not referenced by any db dashboard are
+			orphans. They are flagged, only orphans uploaded by this api are
+			removed from grafana if it is configured. No orphans are removed
+			while there are journaled operations, as slugs of dashboards
+			being uploaded are not known yet
+	*/
+	log.Logger.Debugf("Reconciling organization '%s'", organizationID)
+	grafanaDashboards, err := r.grafana.SearchDashboards(organizationID)
+	if err != nil {
+		return err
+	}
+	pendingDashboards := map[string]bool{}
+	err = r.pendingOperations(organizationID, pendingDashboards)
+	if err != nil {
+		return err
+	}
+	noTagsProvided := map[string]interface{}{}
+	visualizations, err := r.databaseManager.QueryVisualizationsDashboards(
+		"", "", organizationID, noTagsProvided)
+	if err != nil {
+		return err
+	}
+	err = r.pendingOperations(organizationID, pendingDashboards)
+	if err != nil {
+		return err
+	}
+
+	grafanaSlugs := map[string]bool{}
+	for _, grafanaDashboard := range grafanaDashboards {
+		grafanaSlugs[grafanaDashboard.Slug] = true
+	}
+
+	referencedSlugs := map[string]bool{}
+	for _, dashboards := range *visualizations {
+		for _, dashboard := range dashboards {
+			if dashboard.Slug != "" {
+				referencedSlugs[dashboard.Slug] = true
+			}
+			if dashboard.Slug != "" && grafanaSlugs[dashboard.Slug] {
+				continue
+			}
+			if pendingDashboards[dashboard.ID] {
+				log.Logger.Debugf("Dashboard '%s' has journaled grafana "+
+					"operations, skipping it", dashboard.ID)
+				report.Entries = append(report.Entries, ReportEntry{
+					OrganizationID: organizationID,
+					DashboardID:    dashboard.ID,
+					Name:           dashboard.Name,
+					Slug:           dashboard.Slug,
+					Action:         ActionSkipped,
+				})
+				continue
+			}
+			// overwrite is used, because dashboard with the same title
+			// could be left in grafana by interrupted upload
+			entry := ReportEntry{
+				OrganizationID: organizationID,
+				DashboardID:    dashboard.ID,
+				Name:           dashboard.Name,
+				Action:         ActionReuploaded,
+			}
+			slug, uploadErr := r.grafana.UploadDashboard(
+				[]byte(dashboard.RenderedTemplate), organizationID, true)
+			if uploadErr != nil {
+				log.Logger.Errorf("Error during performing grafana call "+
+					" for dashboard upload %s", uploadErr)
+				entry.Slug = dashboard.Slug
+				entry.Error = uploadErr.Error()
+				report.Entries = append(report.Entries, entry)
+				continue
+			}
+			log.Logger.Infof("Reuploaded dashboard named '%s'", slug)
+			entry.Slug = slug
+			referencedSlugs[slug] = true
+			entry.Error = r.storeSlug(dashboard, slug)
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+
+	for _, grafanaDashboard := range grafanaDashboards {
+		if referencedSlugs[grafanaDashboard.Slug] {
+			continue
+		}
+		entry := ReportEntry{
+			OrganizationID: organizationID,
+			Name:           grafanaDashboard.Title,
+			Slug:           grafanaDashboard.Slug,
+			Action:         ActionOrphanFlagged,
+		}
+		switch {
+		case !r.deleteOrphans:
+		case !grafanaDashboard.HasTag(grafanaclient.ManagedDashboardTag):
+			log.Logger.Debugf("Orphan grafana dashboard '%s' was not uploaded "+
+				"by api, it is kept", grafanaDashboard.Slug)
+		case len(pendingDashboards) > 0:
+			log.Logger.Debugf("Orphan grafana dashboard '%s' is kept, as "+
+				"there are journaled grafana operations", grafanaDashboard.Slug)
+		default:
+			log.Logger.Debugf("Removing orphan grafana dashboard '%s'",
+				grafanaDashboard.Slug)
+			deletionErr := r.grafana.DeleteDashboard(grafanaDashboard.Slug,
+				organizationID)
+			if deletionErr != nil {
+				log.Logger.Errorf("Error during performing grafana call "+
+					" for dashboard deletion %s", deletionErr)
+				entry.Error = deletionErr.Error()
+			} else {
+				entry.Action = ActionOrphanDeleted
+			}
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package reconciler_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"visualization-api/pkg/database/mock"
+	"visualization-api/pkg/database/models"
+	"visualization-api/pkg/grafanaclient"
+	"visualization-api/pkg/grafanaclient/mock"
+	"visualization-api/pkg/http_endpoint/common/tests"
+	"visualization-api/pkg/reconciler"
+)
+
+func TestReconcilerRun(t *testing.T) {
+	reuploadEntries := []reconciler.ReportEntry{
+		{OrganizationID: "3", DashboardID: "2", Name: "empty_slug",
+			Slug: "empty_slug", Action: reconciler.ActionReuploaded},
+		{OrganizationID: "3", DashboardID: "3", Name: "missing_slug",
+			Slug: "", Action: reconciler.ActionReuploaded,
+			Error: "test"},
+	}
+	tests := []struct {
+		description      string
+		deleteOrphans    bool
+		orphanDeleteErr  error
+		pendingOperation bool
+		expectedEntries  []reconciler.ReportEntry
+	}{
+		{
+			description:   "orphans are flagged",
+			deleteOrphans: false,
+			expectedEntries: append(reuploadEntries,
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Orphan",
+					Slug: "orphan", Action: reconciler.ActionOrphanFlagged},
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Manual",
+					Slug: "manual", Action: reconciler.ActionOrphanFlagged}),
+		},
+		{
+			description:   "orphans uploaded by api are deleted",
+			deleteOrphans: true,
+			expectedEntries: append(reuploadEntries,
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Orphan",
+					Slug: "orphan", Action: reconciler.ActionOrphanDeleted},
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Manual",
+					Slug: "manual", Action: reconciler.ActionOrphanFlagged}),
+		},
+		{
+			description:     "failed orphan deletion is reported",
+			deleteOrphans:   true,
+			orphanDeleteErr: errors.New("test"),
+			expectedEntries: append(reuploadEntries,
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Orphan",
+					Slug: "orphan", Action: reconciler.ActionOrphanFlagged,
+					Error: "test"},
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Manual",
+					Slug: "manual", Action: reconciler.ActionOrphanFlagged}),
+		},
+		{
+			description:      "dashboards with journaled operations are skipped",
+			deleteOrphans:    true,
+			pendingOperation: true,
+			expectedEntries: append(reuploadEntries,
+				reconciler.ReportEntry{OrganizationID: "3", DashboardID: "4",
+					Name: "pending", Action: reconciler.ActionSkipped},
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Orphan",
+					Slug: "orphan", Action: reconciler.ActionOrphanFlagged},
+				reconciler.ReportEntry{OrganizationID: "3", Name: "Manual",
+					Slug: "manual", Action: reconciler.ActionOrphanFlagged}),
+		},
+	}
+
+	const projectID = "3"
+	const maxAttempts = 10
+	testHelper.InitializeLogger()
+	for _, testCase := range tests {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+		mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+
+		// dashboards are reconciled in order they are stored, that's why
+		// single visualization is used
+		okDashboard := &models.Dashboard{"1", 1, "ok", "ok_template", "ok"}
+		emptySlugDashboard := &models.Dashboard{"2", 1, "empty_slug", "empty_slug_template", ""}
+		missingSlugDashboard := &models.Dashboard{"3", 1, "missing_slug", "missing_slug_template", ""}
+		dashboards := []*models.Dashboard{okDashboard, emptySlugDashboard,
+			missingSlugDashboard}
+		// operations of other organizations are not taken into account
+		operations := []*models.GrafanaOperation{
+			{ID: 1, OrganizationID: "4", DashboardID: "5"},
+		}
+		if testCase.pendingOperation {
+			dashboards = append(dashboards,
+				&models.Dashboard{"4", 1, "pending", "pending_template", ""})
+			operations = append(operations, &models.GrafanaOperation{
+				ID: 2, OrganizationID: projectID, DashboardID: "4"})
+		}
+		dbData := map[models.Visualization][]*models.Dashboard{
+			models.Visualization{1, "slug", "name", projectID, "{}"}: dashboards,
+		}
+
+		mockedDatabaseManager.EXPECT().QueryOrganizationIDs().Return(
+			[]string{projectID}, nil)
+		mockedDatabaseManager.EXPECT().QueryVisualizationsDashboards("", "",
+			projectID, map[string]interface{}{}).Return(&dbData, nil)
+		mockedDatabaseManager.EXPECT().QueryGrafanaOperations(gomock.Any(),
+			maxAttempts).Return(operations, nil).Times(2)
+		mockedGrafana.EXPECT().SearchDashboards(projectID).Return(
+			[]grafanaclient.DashboardSearchResult{
+				{Title: "Ok", Slug: "ok"},
+				{Title: "Orphan", Slug: "orphan",
+					Tags: []string{grafanaclient.ManagedDashboardTag}},
+				{Title: "Manual", Slug: "manual"},
+			}, nil)
+		mockedGrafana.EXPECT().UploadDashboard([]byte("empty_slug_template"),
+			projectID, true).Return("empty_slug", nil)
+		mockedGrafana.EXPECT().UploadDashboard([]byte("missing_slug_template"),
+			projectID, true).Return("", errors.New("test"))
+		mockedDatabaseManager.EXPECT().UpdateDashboardSlug("2", "",
+			"empty_slug").Return(true, nil)
+		if testCase.deleteOrphans && !testCase.pendingOperation {
+			mockedGrafana.EXPECT().DeleteDashboard("orphan", projectID).Return(
+				testCase.orphanDeleteErr)
+		}
+
+		dashboardReconciler := reconciler.NewReconciler(mockedDatabaseManager,
+			mockedGrafana, testCase.deleteOrphans, maxAttempts)
+		assert.Nil(t, dashboardReconciler.LastReport(),
+			"no report before first run")
+		report := dashboardReconciler.Run()
+		assert.Equal(t, testCase.expectedEntries, report.Entries,
+			testCase.description)
+		assert.Equal(t, []string{}, report.Errors, testCase.description)
+		assert.Equal(t, report, dashboardReconciler.LastReport(),
+			"last report must be stored")
+	}
+}
+
+func TestReconcilerRunErrors(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+
+	dbData := map[models.Visualization][]*models.Dashboard{}
+	mockedDatabaseManager.EXPECT().QueryOrganizationIDs().Return(
+		[]string{projectID, "4"}, nil)
+	mockedGrafana.EXPECT().SearchDashboards(projectID).Return(nil,
+		errors.New("test"))
+	mockedDatabaseManager.EXPECT().QueryGrafanaOperations(gomock.Any(),
+		10).Return([]*models.GrafanaOperation{}, nil).Times(2)
+	mockedDatabaseManager.EXPECT().QueryVisualizationsDashboards("", "",
+		"4", map[string]interface{}{}).Return(&dbData, nil)
+	mockedGrafana.EXPECT().SearchDashboards("4").Return(
+		[]grafanaclient.DashboardSearchResult{}, nil)
+
+	report := reconciler.NewReconciler(mockedDatabaseManager, mockedGrafana,
+		false, 10).Run()
+	assert.Equal(t, []reconciler.ReportEntry{}, report.Entries)
+	assert.Equal(t, []string{"Unable to reconcile organization '3': 'test'"},
+		report.Errors, "failed organization must not stop reconciliation")
+}
+
+func TestReconcilerStoresOnlySlugOfUnchangedDashboards(t *testing.T) {
+	const projectID = "3"
+	testHelper.InitializeLogger()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockedDatabaseManager := mock_database.NewMockDatabaseManager(mockCtrl)
+	mockedGrafana := mock_grafanaclient.NewMockSessionInterface(mockCtrl)
+
+	dbData := map[models.Visualization][]*models.Dashboard{
+		models.Visualization{1, "slug", "name", projectID, "{}"}: {
+			&models.Dashboard{"1", 1, "changed", "changed_template", ""},
+			&models.Dashboard{"2", 1, "failed", "failed_template", "old"},
+			&models.Dashboard{"3", 1, "same", "same_template", "same"},
+		},
+	}
+	mockedDatabaseManager.EXPECT().QueryOrganizationIDs().Return(
+		[]string{projectID}, nil)
+	mockedDatabaseManager.EXPECT().QueryVisualizationsDashboards("", "",
+		projectID, map[string]interface{}{}).Return(&dbData, nil)
+	mockedDatabaseManager.EXPECT().QueryGrafanaOperations(gomock.Any(),
+		10).Return([]*models.GrafanaOperation{}, nil).Times(2)
+	mockedGrafana.EXPECT().SearchDashboards(projectID).Return(
+		[]grafanaclient.DashboardSearchResult{}, nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("changed_template"),
+		projectID, true).Return("changed", nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("failed_template"),
+		projectID, true).Return("failed", nil)
+	mockedGrafana.EXPECT().UploadDashboard([]byte("same_template"),
+		projectID, true).Return("same", nil)
+	// dashboard was changed or removed by api call during upload
+	mockedDatabaseManager.EXPECT().UpdateDashboardSlug("1", "",
+		"changed").Return(false, nil)
+	mockedDatabaseManager.EXPECT().UpdateDashboardSlug("2", "old",
+		"failed").Return(false, errors.New("test"))
+
+	report := reconciler.NewReconciler(mockedDatabaseManager, mockedGrafana,
+		false, 10).Run()
+	assert.Equal(t, []reconciler.ReportEntry{
+		{OrganizationID: projectID, DashboardID: "1", Name: "changed",
+			Slug: "changed", Action: reconciler.ActionReuploaded,
+			Error: "dashboard was changed or removed during reconciliation"},
+		{OrganizationID: projectID, DashboardID: "2", Name: "failed",
+			Slug: "failed", Action: reconciler.ActionReuploaded,
+			Error: "test"},
+		{OrganizationID: projectID, DashboardID: "3", Name: "same",
+			Slug: "same", Action: reconciler.ActionReuploaded},
+	}, report.Entries)
+	assert.Equal(t, []string{}, report.Errors)
+}
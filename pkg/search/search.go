@@ -0,0 +1,224 @@
+// Package search describes queries of visualizations by name and tags.
+// Queries are parsed from url parameters:
+//
+//	tag=value           tag equals value
+//	tag=a&tag=b         tag equals any of values
+//	tag!=value          tag is missing or differs from all values
+//	tag                 tag exists
+//	tag!                tag is missing
+//	name^=prefix        name starts with prefix
+//	name*=substring     name contains substring
+//	or=a=1|b!=2|c       any of conditions separated by | matches
+//
+// All parameters have to match. Nested tags are separated by dots. Tag values
+// being valid json, like 1, true or {"team":"ops"}, match tags of the same
+// type and string tags equal to the value as written, so env=1 matches both
+// 1 and "1". Quoted value "1" matches only string. Other values are strings
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NameField is a field of condition matching visualization name instead of tag
+const NameField = "name"
+
+// orParam is a url parameter describing group of alternative conditions
+const orParam = "or"
+
+// orSeparator separates conditions of or group
+const orSeparator = "|"
+
+// maxValues limits amount of values in query, every value becomes sql
+// parameter
+const maxValues = 100
+
+// Operator describes how field of visualization is compared to values
+type Operator string
+
+// Supported operators
+const (
+	Equal     Operator = "="
+	NotEqual  Operator = "!="
+	Exists    Operator = "exists"
+	NotExists Operator = "!exists"
+	Prefix    Operator = "^="
+	Contains  Operator = "*="
+)
+
+// operatorSuffixes map last character of parameter name to operator. Url
+// parameter tag!=value has name "tag!"
+var operatorSuffixes = map[string]Operator{
+	"!": NotEqual,
+	"^": Prefix,
+	"*": Contains,
+}
+
+var fieldPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// Condition compares visualization name or tag to values. Equal, Prefix and
+// Contains match if any of values matches, NotEqual matches if none does.
+// Exists and NotExists have no values
+type Condition struct {
+	Field    string
+	Operator Operator
+	Values   []interface{}
+}
+
+// Group matches visualization if any of its conditions matches
+type Group []Condition
+
+// Query matches visualization if all of its groups match
+type Query []Group
+
+// FromTags returns query matching exact name and tag values. Empty name is
+// not used as a filter
+func FromTags(name string, tags map[string]interface{}) Query {
+	query := Query{}
+	if name != "" {
+		query = append(query, Group{{NameField, Equal, []interface{}{name}}})
+	}
+	tagNames := []string{}
+	for tagName := range tags {
+		tagNames = append(tagNames, tagName)
+	}
+	sort.Strings(tagNames)
+	for _, tagName := range tagNames {
+		query = append(query, Group{{tagName, Equal,
+			[]interface{}{tags[tagName]}}})
+	}
+	return query
+}
+
+// Parse builds query of provided url parameters. Parameters listed in
+// reserved are not part of query and are skipped
+func Parse(params url.Values, reserved map[string]bool) (Query, error) {
+	names := []string{}
+	for name := range params {
+		if !reserved[name] {
+			names = append(names, name)
+		}
+	}
+	// parameters are sorted to build the same query for the same url
+	sort.Strings(names)
+
+	query := Query{}
+	for _, name := range names {
+		if name == orParam {
+			for _, value := range params[name] {
+				group, err := parseGroup(value)
+				if err != nil {
+					return nil, err
+				}
+				query = append(query, group)
+			}
+		} else {
+			condition, err := parseCondition(name, params[name])
+			if err != nil {
+				return nil, err
+			}
+			query = append(query, Group{condition})
+		}
+	}
+
+	valuesCount := 0
+	for _, group := range query {
+		for _, condition := range group {
+			valuesCount += len(condition.Values)
+		}
+	}
+	if valuesCount > maxValues {
+		return nil, fmt.Errorf("query can not have more than %d values",
+			maxValues)
+	}
+	return query, nil
+}
+
+// parseGroup parses value of or parameter
+func parseGroup(value string) (Group, error) {
+	group := Group{}
+	for _, expression := range strings.Split(value, orSeparator) {
+		if expression == "" {
+			return nil, errors.New("or group contains empty condition")
+		}
+		name, conditionValue := expression, ""
+		if index := strings.Index(expression, "="); index >= 0 {
+			name, conditionValue = expression[:index], expression[index+1:]
+		}
+		condition, err := parseCondition(name, []string{conditionValue})
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, condition)
+	}
+	return group, nil
+}
+
+// parseValue returns values tag is compared with. Typed value matches its
+// string form as well, unless it was quoted
+func parseValue(value string) []interface{} {
+	var typedValue interface{}
+	if err := json.Unmarshal([]byte(value), &typedValue); err != nil {
+		return []interface{}{value}
+	}
+	if _, ok := typedValue.(string); ok {
+		return []interface{}{typedValue}
+	}
+	return []interface{}{typedValue, value}
+}
+
+// parseCondition builds condition of parameter name and its values
+func parseCondition(name string, values []string) (Condition, error) {
+	condition := Condition{Field: name, Operator: Equal}
+	for suffix, operator := range operatorSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			condition.Field = strings.TrimSuffix(name, suffix)
+			condition.Operator = operator
+		}
+	}
+	if !fieldPattern.MatchString(condition.Field) {
+		return condition, fmt.Errorf("invalid field name '%s'", condition.Field)
+	}
+
+	if len(values) == 1 && values[0] == "" {
+		// parameter without value checks existence of tag
+		switch condition.Operator {
+		case Equal:
+			condition.Operator = Exists
+		case NotEqual:
+			condition.Operator = NotExists
+		default:
+			return condition, fmt.Errorf("operator '%s' of '%s' requires value",
+				condition.Operator, condition.Field)
+		}
+	} else {
+		for _, value := range values {
+			if value == "" {
+				return condition, fmt.Errorf("'%s' has empty value",
+					condition.Field)
+			}
+			if condition.Field == NameField {
+				condition.Values = append(condition.Values, value)
+			} else {
+				condition.Values = append(condition.Values,
+					parseValue(value)...)
+			}
+		}
+	}
+
+	if condition.Field == NameField {
+		if condition.Operator == Exists || condition.Operator == NotExists {
+			return condition, errors.New("name requires value")
+		}
+	} else if condition.Operator == Prefix || condition.Operator == Contains {
+		return condition, fmt.Errorf("operator '%s' is supported only by name",
+			condition.Operator)
+	}
+	return condition, nil
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		description   string
+		query         string
+		expectedQuery Query
+		expectedError string
+	}{
+		{
+			description:   "empty query",
+			query:         "",
+			expectedQuery: Query{},
+		},
+		{
+			description: "repeated parameter matches any of values",
+			query:       "env=prod&env=dev",
+			expectedQuery: Query{
+				{{"env", Equal, []interface{}{"prod", "dev"}}},
+			},
+		},
+		{
+			description: "typed values",
+			query:       "size=1&critical=true&owner=" + url.QueryEscape(`{"team":"ops"}`) + "&env=" + url.QueryEscape(`"1"`) + "&name=1",
+			expectedQuery: Query{
+				{{"critical", Equal, []interface{}{true, "true"}}},
+				{{"env", Equal, []interface{}{"1"}}},
+				{{"name", Equal, []interface{}{"1"}}},
+				{{"owner", Equal, []interface{}{map[string]interface{}{"team": "ops"}, `{"team":"ops"}`}}},
+				{{"size", Equal, []interface{}{1.0, "1"}}},
+			},
+		},
+		{
+			description: "typed value differs from its string form too",
+			query:       "size!=1",
+			expectedQuery: Query{
+				{{"size", NotEqual, []interface{}{1.0, "1"}}},
+			},
+		},
+		{
+			description: "not equal and existence",
+			query:       "env!=prod&owner&team!",
+			expectedQuery: Query{
+				{{"env", NotEqual, []interface{}{"prod"}}},
+				{{"owner", Exists, nil}},
+				{{"team", NotExists, nil}},
+			},
+		},
+		{
+			description: "name prefix and substring",
+			query:       "name^=web&name*=prod",
+			expectedQuery: Query{
+				{{"name", Contains, []interface{}{"prod"}}},
+				{{"name", Prefix, []interface{}{"web"}}},
+			},
+		},
+		{
+			description: "or groups",
+			query:       "or=" + url.QueryEscape("env=prod|owner.team!=ops|critical") + "&or=a=1",
+			expectedQuery: Query{
+				{
+					{"env", Equal, []interface{}{"prod"}},
+					{"owner.team", NotEqual, []interface{}{"ops"}},
+					{"critical", Exists, nil},
+				},
+				{{"a", Equal, []interface{}{1.0, "1"}}},
+			},
+		},
+		{
+			description: "reserved parameters are skipped",
+			query:       "limit=10&env=prod",
+			expectedQuery: Query{
+				{{"env", Equal, []interface{}{"prod"}}},
+			},
+		},
+		{
+			description:   "invalid field",
+			query:         "env%3D1%20OR%201=1",
+			expectedError: "invalid field name 'env=1 OR 1'",
+		},
+		{
+			description:   "prefix of tag",
+			query:         "env^=prod",
+			expectedError: "operator '^=' is supported only by name",
+		},
+		{
+			description:   "prefix without value",
+			query:         "name^=",
+			expectedError: "operator '^=' of 'name' requires value",
+		},
+		{
+			description:   "name existence",
+			query:         "name",
+			expectedError: "name requires value",
+		},
+		{
+			description:   "empty value among values",
+			query:         "env=prod&env=",
+			expectedError: "'env' has empty value",
+		},
+		{
+			description:   "empty condition of or group",
+			query:         "or=" + url.QueryEscape("env=prod|"),
+			expectedError: "or group contains empty condition",
+		},
+		{
+			description:   "too many values",
+			query:         strings.Repeat("env=prod&", 101),
+			expectedError: "query can not have more than 100 values",
+		},
+	}
+
+	for _, testCase := range tests {
+		params, err := url.ParseQuery(testCase.query)
+		assert.Nil(t, err, testCase.description)
+		query, err := Parse(params, map[string]bool{"limit": true})
+		if testCase.expectedError != "" {
+			assert.EqualError(t, err, testCase.expectedError, testCase.description)
+			continue
+		}
+		assert.Nil(t, err, testCase.description)
+		assert.Equal(t, testCase.expectedQuery, query,
+			fmt.Sprintf("%s: %+v", testCase.description, query))
+	}
+}
+
+func TestFromTags(t *testing.T) {
+	assert.Equal(t, Query{
+		{{"name", Equal, []interface{}{"name"}}},
+		{{"a", Equal, []interface{}{1}}},
+		{{"b", Equal, []interface{}{"value"}}},
+	}, FromTags("name", map[string]interface{}{"b": "value", "a": 1}))
+	assert.Equal(t, Query{}, FromTags("", map[string]interface{}{}))
+}
@@ -9,9 +9,7 @@ fpm -s dir -t deb \
   -v $VERSION \
   --after-install /app/tools/build/debian/postinstall \
   $PROJECT_ROOT/etc/platformvisibility/=/etc/platformvisibility/ \
-  $PROJECT_ROOT/build/linux-amd64/visualizationapi=/usr/bin/ \
-  $PROJECT_ROOT/build/linux-amd64/sql-migrate=/usr/bin/ \
-  $PROJECT_ROOT/tools/database-migrations/=/var/lib/platformvisibility/database-migrations/
+  $PROJECT_ROOT/build/linux-amd64/visualizationapi=/usr/bin/
 
 mkdir -p $PROJECT_ROOT/build/deb
 mv platform-visibility-all* $PROJECT_ROOT/build/deb/